@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -12,17 +14,27 @@ import (
 	"github.com/witslab-sahil/fiber-boilerplate/internal/handlers"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/middleware"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/bundle"
+	opaClient "github.com/witslab-sahil/fiber-boilerplate/internal/opa/client"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/decisionlog"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/engine"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/filter"
 	opaMiddleware "github.com/witslab-sahil/fiber-boilerplate/internal/opa/middleware"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/policies"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/database"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/storage"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/telemetry"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/workflows"
 	pkgTemporal "github.com/witslab-sahil/fiber-boilerplate/pkg/temporal"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/valyala/fasthttp"
 )
 
 func main() {
@@ -38,7 +50,7 @@ func main() {
 	}
 
 	// Run migrations
-	if err := database.Migrate(db, &models.User{}); err != nil {
+	if err := database.Migrate(db, &models.User{}, &decisionlog.Entry{}, &models.PolicyBundle{}, &models.PolicyActivation{}, &models.UserImport{}, &models.UserImportRow{}, &models.UserExport{}, &models.ErasureRequest{}, &models.ErasureCertificate{}, &models.DataExport{}, &models.IdempotencyKey{}, &models.UserStatusTransition{}, &models.UserRevision{}, &models.UserAttributeSchema{}, &models.Group{}, &models.GroupMember{}); err != nil {
 		logger.Fatal("Failed to run migrations: ", err)
 	}
 
@@ -70,9 +82,67 @@ func main() {
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
+	userAttributeSchemaRepo := repository.NewUserAttributeSchemaRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, logger)
+	userAttributeService := service.NewUserAttributeService(userAttributeSchemaRepo, logger)
+	userService := service.NewUserService(userRepo, userAttributeService, logger)
+	groupService := service.NewGroupService(groupRepo, userRepo, logger)
+
+	// Initialize authorization policies
+	var opaQuerier opaMiddleware.Querier
+	var policyService service.PolicyService
+	var policyRevision func() string
+	var rowFilters *filter.Builder
+	if cfg.OPAEnabled {
+		var loader service.PolicyLoader
+		switch cfg.OPAMode {
+		case "embedded":
+			policyEngine := engine.New()
+			opaQuerier, loader = policyEngine, policyEngine
+			rowFilters = filter.NewBuilder(policyEngine)
+		default:
+			remote := opaClient.New(opaClient.Config{
+				URL:              cfg.OPAURL,
+				Timeout:          cfg.OPATimeout,
+				MaxRetries:       cfg.OPAMaxRetries,
+				RetryBackoff:     cfg.OPARetryBackoff,
+				BreakerThreshold: cfg.OPABreakerThreshold,
+				BreakerCooldown:  cfg.OPABreakerCooldown,
+			})
+			opaQuerier, loader = remote, remote
+			rowFilters = filter.NewBuilder(remote)
+		}
+
+		policyService = service.NewPolicyService(repository.NewPolicyBundleRepository(db), loader, logger)
+		defaults, err := bundle.LoadFS(policies.FS)
+		if err != nil {
+			logger.Fatal("Failed to load built-in policies: ", err)
+		}
+		if err := policyService.Bootstrap(context.Background(), defaults); err != nil {
+			logger.Fatal("Failed to load policy bundle: ", err)
+		}
+		logger.Info("Policy bundle active: ", policyService.ActiveRevision())
+
+		// Pick up activations made by other replicas or the policyctl CLI
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		go policyService.Watch(watchCtx, cfg.OPABundlePoll)
+
+		policyRevision = policyService.ActiveRevision
+
+		// List endpoints only return the rows the policy lets the caller see
+		rowFilters.Register("users", filter.Resource{
+			Rule: "data.authz.filters.users",
+			Columns: map[string]string{
+				"id":       "id",
+				"email":    "email",
+				"username": "username",
+				"status":   "status",
+			},
+		})
+	}
 
 	// Initialize Temporal client
 	var temporalClient *pkgTemporal.Client
@@ -85,20 +155,70 @@ func main() {
 		}
 	}
 
+	// Imports are processed by the worker; without Temporal they cannot start
+	var importStarter service.ImportStarter
+	if temporalClient != nil {
+		importStarter = workflows.NewUserImportStarter(temporalClient.GetClient())
+	}
+	importService := service.NewUserImportService(repository.NewUserImportRepository(db), userRepo, importStarter, logger)
+
+	// Without export storage only streaming exports are available
+	var exportFiles service.FileStore
+	if files, err := storage.NewLocal(cfg.ExportDir); err != nil {
+		logger.Warn("Failed to open export storage, background exports will be disabled: ", err)
+	} else {
+		exportFiles = files
+	}
+	exportService := service.NewUserExportService(userRepo, repository.NewUserExportRepository(db), exportFiles, logger)
+
+	// Erasures are carried out by the worker after their grace period, so the
+	// erasure hooks are registered there
+	var erasureWorkflows service.ErasureWorkflows
+	if temporalClient != nil {
+		erasureWorkflows = workflows.NewUserErasureStarter(temporalClient.GetClient())
+	}
+	erasureService := service.NewErasureService(repository.NewErasureRepository(db), userRepo, erasureWorkflows, cfg.ErasureGracePeriod, cfg.ErasureSigningKey, logger)
+
+	// Data exports are assembled by the worker, which registers their
+	// sections; the API starts them and serves the archives
+	var dataExportWorkflows service.DataExportWorkflows
+	if temporalClient != nil {
+		dataExportWorkflows = workflows.NewDataExportStarter(temporalClient.GetClient())
+	}
+	dataExportService := service.NewDataExportService(repository.NewDataExportRepository(db), userRepo, exportFiles, dataExportWorkflows, cfg.PublicURL, cfg.DataExportLinkTTL, cfg.DataExportSigningKey, logger)
+
+	// Suspensions with an end are lifted by a worker timer; without Temporal
+	// logins treat them as over once they run out, but their status stays
+	// suspended until reactivated
+	var suspensionWorkflows service.SuspensionWorkflows
+	if temporalClient != nil {
+		suspensionWorkflows = workflows.NewUserSuspensionStarter(temporalClient.GetClient())
+	}
+	statusService := service.NewUserStatusService(userRepo, suspensionWorkflows, logger)
+
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler,
+		ErrorHandler: middleware.ErrorHandler(logger),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	})
+	// Streamed exports and export downloads can outlast the write timeout
+	// above, so they get their own
+	app.Server().HeaderReceived = func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		if streamsExport(header) {
+			return fasthttp.RequestConfig{WriteTimeout: cfg.ExportWriteTimeout}
+		}
+		return fasthttp.RequestConfig{}
+	}
 
 	// Global middleware
 	app.Use(recover.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Content-Type,Authorization",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Content-Type,Authorization,If-Match,If-None-Match,Idempotency-Key",
+		ExposeHeaders:    "ETag,Idempotent-Replayed",
 		AllowCredentials: true,
 	}))
 	app.Use(middleware.RequestID())
@@ -110,42 +230,202 @@ func main() {
 	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(userService, logger, cfg.JWTSecret)
+	authHandler := handlers.NewAuthHandler(userService, groupService, logger, cfg.JWTSecret)
 	userHandler := handlers.NewUserHandler(userService, logger)
+	userAttributeHandler := handlers.NewUserAttributeHandler(userAttributeService, logger)
+	groupHandler := handlers.NewGroupHandler(groupService, logger)
+	importHandler := handlers.NewUserImportHandler(importService, logger)
+	exportHandler := handlers.NewUserExportHandler(exportService, logger, cfg.ExportWriteTimeout)
+	erasureHandler := handlers.NewErasureHandler(erasureService, logger)
+	userHandler.Include("erasure", erasureHandler.IncludeErasure)
+	userHandler.Include("groups", groupHandler.IncludeGroups)
+	userHandler.Include("effective_roles", groupHandler.IncludeEffectiveRoles)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService, logger)
+	statusHandler := handlers.NewUserStatusHandler(statusService, logger)
 	workflowHandler := handlers.NewWorkflowHandler(temporalClient, logger)
 
+	// Retried POSTs with the same Idempotency-Key get the first response
+	// instead of being processed again
+	idempotency := middleware.NewIdempotency(repository.NewIdempotencyRepository(db), cfg.IdempotencyTTL, opaMiddleware.UserID, logger)
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	go idempotency.Sweep(sweepCtx, time.Hour)
+	idempotent := idempotency.Handler()
+
 	// Health check
-	app.Get("/health", handlers.HealthCheck)
+	healthHandler := handlers.NewHealthHandler(policyRevision)
+	app.Get("/health", healthHandler.Check)
 
 	// API routes
 	api := app.Group("/api/v1")
 
+	// Routes declare the action and resource they need; the permissions are
+	// enforced once OPA is configured below
+	authz := opaMiddleware.NewPermissions(app)
+
+	// Changes to users are recorded against the caller and request
+	actor := middleware.Actor(opaMiddleware.UserID)
+
 	// Auth routes (public)
 	auth := api.Group("/auth")
-	auth.Post("/register", authHandler.Register)
+	auth.Post("/register", actor, idempotent, authHandler.Register)
 	auth.Post("/login", authHandler.Login)
 
+	// Data export downloads (public; the signed link is the credential)
+	api.Get("/data-exports/:id/download", dataExportHandler.Download)
+
 	// Protected routes
+	filterUsers := func(c *fiber.Ctx) error { return c.Next() }
+	filterUserFields := func(c *fiber.Ctx) error { return c.Next() }
 	if cfg.OPAEnabled {
 		// Initialize OPA middleware
-		opaMiddleware := opaMiddleware.NewOPAMiddleware(cfg.OPAURL, logger)
-		api.Use(opaMiddleware.Authorize())
+		var shadow *opaMiddleware.Shadow
+		if cfg.OPAShadowEnabled {
+			shadow = opaMiddleware.NewShadow(opaQuerier, logger, cfg.OPATimeout, 0)
+		}
+		authorizer := opaMiddleware.NewOPAMiddleware(opaQuerier, opaMiddleware.FailMode(cfg.OPAFailMode), logger)
+		authorizer.SetJWTSecret(cfg.JWTSecret)
+		// Tokens of users suspended, locked or deactivated since they signed
+		// in stop working straight away
+		authorizer.SetAccountCheck(func(ctx context.Context, userID string) error {
+			id, err := strconv.ParseUint(userID, 10, 32)
+			if err != nil {
+				return problem.New(problem.CodeUnauthorized, "Invalid token")
+			}
+			return statusService.CheckAccount(ctx, uint(id))
+		})
+		// Policies see the roles and groups the caller has now, not those
+		// in the token, so changes to groups apply straight away
+		authorizer.SetUserResolver(func(ctx context.Context, user *opaMiddleware.User) error {
+			id, err := strconv.ParseUint(user.ID, 10, 32)
+			if err != nil {
+				return problem.New(problem.CodeUnauthorized, "Invalid token")
+			}
+			access, err := groupService.UserAccess(ctx, uint(id))
+			if err != nil {
+				return err
+			}
+			user.Roles, user.Groups = access.Roles, access.Groups
+			return nil
+		})
+		authorizer.SetRevisionFunc(policyRevision)
+		if shadow != nil {
+			authorizer.SetShadow(shadow)
+		}
+		authorizer.SetRowFilters(rowFilters)
+		filterUsers = authorizer.FilterRows("users")
+		authorizer.SetFieldFilter("users", "authz/data/filtered_user_fields")
+		filterUserFields = authorizer.FilterFields("users")
+		authz.SetEnforcer(authorizer)
+
+		// Initialize decision log
+		var sink decisionlog.Sink
+		switch cfg.DecisionLogSink {
+		case "db":
+			sink = decisionlog.NewDBSink(db)
+		case "file":
+			sink, err = decisionlog.NewFileSink(cfg.DecisionLogFile, int64(cfg.DecisionLogMaxSizeMB)<<20, cfg.DecisionLogMaxBackups)
+			if err != nil {
+				logger.Fatal("Failed to open decision log: ", err)
+			}
+		}
+		if sink != nil {
+			decisionLog := decisionlog.NewRecorder(sink, logger, 0)
+			defer decisionLog.Close()
+			authorizer.SetDecisionLog(decisionLog)
+		}
+
+		api.Use(authorizer.Authorize())
+
+		// Authorization routes
+		authzHandler := handlers.NewAuthzHandler(authorizer, authz, logger)
+		authzGroup := api.Group("/authz")
+		authzGroup.Post("/explain", authz.Require("authz:explain", "decision", ""), authzHandler.Explain)
+		authzGroup.Get("/routes", authz.Require("authz:read", "route", ""), authzHandler.Routes)
+
+		// Policy bundle routes
+		policyHandler := handlers.NewPolicyHandler(policyService, logger)
+		policyGroup := api.Group("/policies")
+		policyGroup.Get("/bundles", authz.Require("policies:list", "policy_bundle", ""), policyHandler.List)
+		policyGroup.Post("/bundles", authz.Require("policies:upload", "policy_bundle", ""), policyHandler.Upload)
+		policyGroup.Post("/bundles/validate", authz.Require("policies:validate", "policy_bundle", ""), policyHandler.Validate)
+		policyGroup.Get("/bundles/:revision", authz.Require("policies:read", "policy_bundle", "revision"), policyHandler.Get)
+		policyGroup.Post("/bundles/:revision/activate", authz.Require("policies:activate", "policy_bundle", "revision"), policyHandler.Activate)
+		policyGroup.Post("/rollback", authz.Require("policies:rollback", "policy_bundle", ""), policyHandler.Rollback)
 	}
 
 	// User routes (protected)
-	users := api.Group("/users")
-	users.Get("/", userHandler.GetAll)
-	users.Get("/:id", userHandler.GetByID)
-	users.Post("/", userHandler.Create)
-	users.Put("/:id", userHandler.Update)
-	users.Delete("/:id", userHandler.Delete)
+	users := api.Group("/users", actor)
+	listsDeleted := func(c *fiber.Ctx) bool { return c.Query("deleted") != "" }
+	purges := func(c *fiber.Ctx) bool { return c.QueryBool("purge") }
+	requireIfMatch := func(c *fiber.Ctx) error { return c.Next() }
+	// The field filter is only needed to check ?fields= and ?include=
+	filterProjection := func(c *fiber.Ctx) error {
+		if c.Query("fields") == "" && c.Query("include") == "" {
+			return c.Next()
+		}
+		return filterUserFields(c)
+	}
+	if cfg.RequireIfMatch {
+		requireIfMatch = middleware.RequireIfMatch(purges)
+	}
+	users.Get("/", authz.Require("users:list", "user", ""), authz.When(listsDeleted, "users:list_deleted", "user", ""), filterUsers, filterProjection, userHandler.GetAll)
+	// Custom method, in the style of "POST /users:batchUpdate"; the colon is
+	// escaped so fiber does not read it as a parameter
+	api.Post("/users\\:batchUpdate", authz.Require("users:batch_update", "user", ""), actor, idempotent, userHandler.BatchUpdate)
+	users.Post("/import", authz.Require("users:import", "user_import", ""), importHandler.Start)
+	users.Get("/import/:id", authz.Require("users:import_status", "user_import", "id"), importHandler.Get)
+	users.Get("/export", authz.Require("users:export", "user", ""), authz.When(listsDeleted, "users:list_deleted", "user", ""), filterUsers, filterUserFields, exportHandler.Export)
+	users.Post("/export", authz.Require("users:export", "user", ""), authz.When(listsDeleted, "users:list_deleted", "user", ""), filterUsers, filterUserFields, exportHandler.Start)
+	users.Get("/export/:id", authz.Require("users:export", "user_export", "id"), exportHandler.Get)
+	users.Get("/export/:id/download", authz.Require("users:export", "user_export", "id"), exportHandler.Download)
+	users.Get("/attributes/schema", authz.Require("users:read_attribute_schema", "user_attribute_schema", ""), userAttributeHandler.GetSchema)
+	users.Put("/attributes/schema", authz.Require("users:update_attribute_schema", "user_attribute_schema", ""), userAttributeHandler.PutSchema)
+	users.Post("/me/data-export", authz.Require("users:data_export", "data_export", ""), dataExportHandler.Request)
+	users.Get("/me/data-export/:id", authz.Require("users:data_export", "data_export", "id"), dataExportHandler.Get)
+	users.Get("/:id", authz.Require("users:read", "user", "id"), filterProjection, userHandler.GetByID)
+	users.Post("/", authz.Require("users:create", "user", ""), idempotent, userHandler.Create)
+	users.Put("/:id", authz.Require("users:update", "user", "id"), requireIfMatch, userHandler.Update)
+	users.Patch("/:id", authz.Require("users:update", "user", "id"), requireIfMatch, userHandler.Patch)
+	users.Post("/:id/restore", authz.Require("users:restore", "user", "id"), userHandler.Restore)
+	users.Post("/:id/suspend", authz.Require("users:suspend", "user", "id"), requireIfMatch, statusHandler.Suspend)
+	users.Post("/:id/reactivate", authz.Require("users:reactivate", "user", "id"), requireIfMatch, statusHandler.Reactivate)
+	users.Get("/:id/history", authz.Require("users:history", "user", "id"), userHandler.History)
+	users.Get("/:id/access", authz.Require("users:read_access", "user", "id"), groupHandler.UserAccess)
+	users.Get("/:id/status-history", authz.Require("users:status_history", "user", "id"), statusHandler.History)
+	users.Post("/:id/erasure", authz.Require("users:erase", "user", "id"), erasureHandler.Request)
+	users.Get("/:id/erasure", authz.Require("users:erase", "user", "id"), erasureHandler.Get)
+	users.Delete("/:id/erasure", authz.Require("users:erase", "user", "id"), erasureHandler.Cancel)
+	users.Delete("/:id", authz.Require("users:delete", "user", "id"), authz.When(purges, "users:purge", "user", "id"), requireIfMatch, userHandler.Delete)
+
+	// Group routes (protected)
+	groups := api.Group("/groups")
+	groups.Get("/", authz.Require("groups:list", "group", ""), groupHandler.GetAll)
+	groups.Post("/", authz.Require("groups:create", "group", ""), groupHandler.Create)
+	groups.Get("/:id", authz.Require("groups:read", "group", "id"), groupHandler.GetByID)
+	groups.Put("/:id", authz.Require("groups:update", "group", "id"), groupHandler.Update)
+	groups.Delete("/:id", authz.Require("groups:delete", "group", "id"), groupHandler.Delete)
+	groups.Get("/:id/members", authz.Require("groups:read", "group", "id"), groupHandler.ListMembers)
+	groups.Post("/:id/members", authz.Require("groups:add_member", "group", "id"), groupHandler.AddMember)
+	groups.Delete("/:id/members/users/:memberId", authz.Require("groups:remove_member", "group", "id"), groupHandler.RemoveUser)
+	groups.Delete("/:id/members/groups/:memberId", authz.Require("groups:remove_member", "group", "id"), groupHandler.RemoveGroup)
 
 	// Workflow routes (protected)
 	if temporalClient != nil {
 		workflows := api.Group("/workflows")
-		workflows.Post("/user-onboarding", workflowHandler.StartUserOnboarding)
-		workflows.Get("/user-onboarding/:id/status", workflowHandler.GetWorkflowStatus)
-		workflows.Get("/", workflowHandler.ListWorkflows)
+		workflows.Post("/user-onboarding", authz.Require("workflows:start", "user-onboarding", ""), idempotent, workflowHandler.StartUserOnboarding)
+		workflows.Get("/user-onboarding/:id/status", authz.Require("workflows:read", "user-onboarding", "id"), workflowHandler.GetWorkflowStatus)
+		workflows.Get("/", authz.Require("workflows:list", "workflow", ""), workflowHandler.ListWorkflows)
+	}
+
+	// Every protected route must declare a permission; anything else would be
+	// open to any authenticated caller
+	if cfg.OPAEnabled {
+		for _, route := range authz.Matrix() {
+			if route.Permission == nil && strings.HasPrefix(route.Path, "/api/v1/") && !strings.HasPrefix(route.Path, "/api/v1/auth/") {
+				logger.Warn("Route has no permission declared: ", route.Method, " ", route.Path)
+			}
+		}
 	}
 
 	// Graceful shutdown
@@ -167,5 +447,19 @@ func main() {
 		logger.Fatal("Server forced to shutdown: ", err)
 	}
 
+	// Let background exports finish writing their files
+	exportService.Wait()
+
 	logger.Info("Server exited")
-}
\ No newline at end of file
+}
+
+// streamsExport reports whether a request is for a streamed user export or
+// an export download.
+func streamsExport(header *fasthttp.RequestHeader) bool {
+	if !header.IsGet() {
+		return false
+	}
+	path, _, _ := strings.Cut(string(header.RequestURI()), "?")
+	return path == "/api/v1/users/export" ||
+		(strings.HasPrefix(path, "/api/v1/") && strings.HasSuffix(path, "/download"))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/config"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/bundle"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/policytest"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/database"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+const usage = `Usage: policyctl <command> [arguments]
+
+Commands:
+  validate <dir>                      Compile the .rego files and data.json in dir
+  test [-coverage] [-json] <dir> <suite>...
+                                      Run YAML/JSON test suites against the bundle in dir
+  upload [-activate] [-description d] <dir>
+                                      Store the bundle in dir and print its revision
+  activate <revision>                 Make revision the active policy bundle
+  rollback                            Re-activate the previously active revision
+  list                                List stored bundles
+
+Running API instances pick up activations within OPA_BUNDLE_POLL_INTERVAL.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	command, args := os.Args[1], os.Args[2:]
+
+	switch command {
+	case "validate":
+		exit(validate(ctx, args))
+	case "test":
+		exit(test(ctx, args))
+	}
+
+	cfg := config.Load()
+	log := logger.New("warn")
+
+	db, err := database.New(cfg.DatabaseURL)
+	if err != nil {
+		fail(err)
+	}
+	if err := database.Migrate(db, &models.PolicyBundle{}, &models.PolicyActivation{}); err != nil {
+		fail(err)
+	}
+
+	policyService := service.NewPolicyService(repository.NewPolicyBundleRepository(db), nil, log)
+
+	switch command {
+	case "upload":
+		exit(upload(ctx, policyService, args))
+	case "activate":
+		if len(args) != 1 {
+			fail(fmt.Errorf("activate takes exactly one revision"))
+		}
+		exit(printResult(policyService.Activate(ctx, args[0], operator())))
+	case "rollback":
+		exit(printResult(policyService.Rollback(ctx, operator())))
+	case "list":
+		exit(printResult(policyService.List(ctx)))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func validate(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("validate takes exactly one directory")
+	}
+	b, err := bundle.LoadFS(os.DirFS(args[0]))
+	if err != nil {
+		return err
+	}
+	if _, err := bundle.Compile(b); err != nil {
+		return err
+	}
+	fmt.Printf("valid, revision %s\n", b.Revision)
+	return nil
+}
+
+func test(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	coverage := flags.Bool("coverage", false, "list the rules no case exercised")
+	asJSON := flags.Bool("json", false, "print the full report as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		return fmt.Errorf("test takes a policy directory and at least one suite")
+	}
+
+	b, err := bundle.LoadFS(os.DirFS(flags.Arg(0)))
+	if err != nil {
+		return err
+	}
+	runner, err := policytest.NewRunner(b)
+	if err != nil {
+		return err
+	}
+
+	var suites []*policytest.Suite
+	for _, path := range flags.Args()[1:] {
+		suite, err := policytest.LoadSuite(path)
+		if err != nil {
+			return err
+		}
+		suites = append(suites, suite)
+	}
+
+	report := runner.Run(ctx, suites...)
+	if *asJSON {
+		if err := printResult(report, nil); err != nil {
+			return err
+		}
+	} else {
+		report.Write(os.Stdout, *coverage)
+	}
+
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d policy test(s) failed", failed)
+	}
+	return nil
+}
+
+func upload(ctx context.Context, policyService service.PolicyService, args []string) error {
+	flags := flag.NewFlagSet("upload", flag.ExitOnError)
+	activate := flags.Bool("activate", false, "activate the bundle after uploading it")
+	description := flags.String("description", "", "description stored with the bundle")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("upload takes exactly one directory")
+	}
+
+	b, err := bundle.LoadFS(os.DirFS(flags.Arg(0)))
+	if err != nil {
+		return err
+	}
+
+	uploaded, err := policyService.Upload(ctx, &models.UploadPolicyBundleRequest{
+		Modules:     b.Modules,
+		Data:        b.Data,
+		Description: *description,
+	}, operator())
+	if err != nil {
+		return err
+	}
+
+	if *activate {
+		return printResult(policyService.Activate(ctx, uploaded.Revision, operator()))
+	}
+	return printResult(uploaded.ToSummary(), nil)
+}
+
+func printResult(result interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// operator identifies who ran the command in the activation history.
+func operator() string {
+	if current, err := user.Current(); err == nil {
+		return "cli:" + current.Username
+	}
+	return "cli"
+}
+
+func exit(err error) {
+	if err != nil {
+		fail(err)
+	}
+	os.Exit(0)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
@@ -2,14 +2,23 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/config"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/decisionlog"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/worker"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/workflows"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/database"
+	pkgLogger "github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/storage"
 	pkgTemporal "github.com/witslab-sahil/fiber-boilerplate/pkg/temporal"
 )
 
@@ -39,13 +48,134 @@ func main() {
 	}
 	defer temporalClient.Close()
 
+	// Connect to the database for the import, erasure and data export
+	// activities; the API server runs the migrations
+	db, err := database.New(cfg.DatabaseURL)
+	if err != nil {
+		logger.Fatal("Failed to connect to database:", err)
+	}
+	appLogger := pkgLogger.New(cfg.LogLevel)
+	userRepo := repository.NewUserRepository(db)
+	importRepo := repository.NewUserImportRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	groupService := service.NewGroupService(groupRepo, userRepo, appLogger)
+	importService := service.NewUserImportService(importRepo, userRepo, nil, appLogger)
+
+	// Data exports are written to, and erasures remove files from, the
+	// export directory the API server serves downloads from
+	var exportFiles service.FileStore
+	if local, err := storage.NewLocal(cfg.ExportDir); err != nil {
+		logger.Warnf("Export storage unavailable, data exports will fail and erasures will keep export files: %v", err)
+	} else {
+		exportFiles = local
+	}
+	exportRepo := repository.NewUserExportRepository(db)
+	exportService := service.NewUserExportService(userRepo, exportRepo, exportFiles, appLogger)
+	erasureRepo := repository.NewErasureRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+
+	dataExportService := service.NewDataExportService(
+		dataExportRepo,
+		userRepo,
+		exportFiles,
+		nil,
+		cfg.PublicURL,
+		cfg.DataExportLinkTTL,
+		cfg.DataExportSigningKey,
+		appLogger,
+	)
+	dataExportService.RegisterSection(service.NewDataExportSection("profile", func(ctx context.Context, user *models.User) (interface{}, error) {
+		return user.ToResponse(), nil
+	}))
+	dataExportService.RegisterSection(service.NewDataExportSection("roles", func(ctx context.Context, user *models.User) (interface{}, error) {
+		return map[string][]string{"roles": user.Roles}, nil
+	}))
+	dataExportService.RegisterSection(service.NewDataExportSection("status_history", func(ctx context.Context, user *models.User) (interface{}, error) {
+		return userRepo.ListStatusTransitions(user.ID)
+	}))
+	dataExportService.RegisterSection(service.NewDataExportSection("groups", func(ctx context.Context, user *models.User) (interface{}, error) {
+		return groupService.UserAccess(ctx, user.ID)
+	}))
+	dataExportService.RegisterSection(service.NewDataExportSection("audit_log", func(ctx context.Context, user *models.User) (interface{}, error) {
+		return decisionlog.ForPrincipal(ctx, db, strconv.FormatUint(uint64(user.ID), 10))
+	}))
+	dataExportService.RegisterSection(service.NewDataExportSection("workflows", func(ctx context.Context, user *models.User) (interface{}, error) {
+		ids := []string{fmt.Sprintf("user-onboarding-%d", user.ID)}
+		erasures, err := erasureRepo.ListForUser(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, request := range erasures {
+			if request.WorkflowID != "" {
+				ids = append(ids, request.WorkflowID)
+			}
+		}
+		exports, err := dataExportRepo.ListForUser(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, export := range exports {
+			if export.WorkflowID != "" {
+				ids = append(ids, export.WorkflowID)
+			}
+		}
+		return workflows.DescribeWorkflows(ctx, temporalClient.GetClient(), ids)
+	}))
+	dataExportService.RegisterSection(service.NewDataExportSection("erasure_requests", func(ctx context.Context, user *models.User) (interface{}, error) {
+		return erasureRepo.ListForUser(user.ID)
+	}))
+	dataExportService.RegisterSection(service.NewDataExportSection("user_exports", func(ctx context.Context, user *models.User) (interface{}, error) {
+		return exportRepo.ListByCreator(strconv.FormatUint(uint64(user.ID), 10))
+	}))
+
+	erasureService := service.NewErasureService(
+		erasureRepo,
+		userRepo,
+		nil,
+		cfg.ErasureGracePeriod,
+		cfg.ErasureSigningKey,
+		appLogger,
+	)
+	erasureService.RegisterHook(service.NewErasureHook("user_import_rows", func(ctx context.Context, user *models.User) (int64, error) {
+		return importRepo.EraseUser(user.ID, user.Email)
+	}))
+	erasureService.RegisterHook(service.NewErasureHook("user_exports", func(ctx context.Context, user *models.User) (int64, error) {
+		return exportService.DeleteByCreator(ctx, strconv.FormatUint(uint64(user.ID), 10))
+	}))
+	erasureService.RegisterHook(service.NewErasureHook("authz_decisions", func(ctx context.Context, user *models.User) (int64, error) {
+		return decisionlog.ErasePrincipal(ctx, db, strconv.FormatUint(uint64(user.ID), 10))
+	}))
+	erasureService.RegisterHook(service.NewErasureHook("data_exports", func(ctx context.Context, user *models.User) (int64, error) {
+		return dataExportService.DeleteForUser(ctx, user.ID)
+	}))
+	erasureService.RegisterHook(service.NewErasureHook("idempotency_keys", func(ctx context.Context, user *models.User) (int64, error) {
+		return idempotencyRepo.DeleteForPrincipal(strconv.FormatUint(uint64(user.ID), 10))
+	}))
+	erasureService.RegisterHook(service.NewErasureHook("user_status_transitions", func(ctx context.Context, user *models.User) (int64, error) {
+		return userRepo.EraseStatusTransitions(user.ID)
+	}))
+	erasureService.RegisterHook(service.NewErasureHook("user_revisions", func(ctx context.Context, user *models.User) (int64, error) {
+		return userRepo.EraseRevisions(user.ID)
+	}))
+	erasureService.RegisterHook(service.NewErasureHook("group_members", func(ctx context.Context, user *models.User) (int64, error) {
+		return groupRepo.RemoveUser(user.ID)
+	}))
+	// The user row goes last, since the hooks above look it up by email
+	erasureService.RegisterHook(service.NewErasureHook("users", func(ctx context.Context, user *models.User) (int64, error) {
+		return userRepo.Anonymize(user.ID)
+	}))
+
+	// Suspensions are only ended here, so nothing is scheduled
+	statusService := service.NewUserStatusService(userRepo, nil, appLogger)
+
 	// Create worker
 	taskQueue := os.Getenv("TASK_QUEUE")
 	if taskQueue == "" {
 		taskQueue = workflows.OnboardingTaskQueue
 	}
 
-	w, err := worker.NewWorker(temporalClient.GetClient(), taskQueue, logger)
+	w, err := worker.NewWorker(temporalClient.GetClient(), taskQueue, logger, importService, erasureService, dataExportService, statusService)
 	if err != nil {
 		logger.Fatal("Failed to create worker:", err)
 	}
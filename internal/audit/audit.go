@@ -0,0 +1,30 @@
+// Package audit carries who is making a change, and in which request, from
+// the HTTP layer down to the repositories that record it.
+package audit
+
+import "context"
+
+// Actor is who a change is made by.
+type Actor struct {
+	// UserID is the ID of the authenticated user, empty for anonymous
+	// requests such as registrations.
+	UserID    string
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx, or the zero Actor if there is
+// none.
+func ActorFrom(ctx context.Context) Actor {
+	if ctx == nil {
+		return Actor{}
+	}
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
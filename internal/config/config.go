@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -25,8 +27,44 @@ type Config struct {
 	TaskQueue         string
 	
 	// OPA configuration
-	OPAEnabled bool
-	OPAURL     string
+	OPAEnabled          bool
+	OPAURL              string
+	OPATimeout          time.Duration
+	OPAMaxRetries       int
+	OPARetryBackoff     time.Duration
+	OPABreakerThreshold int
+	OPABreakerCooldown  time.Duration
+	OPAFailMode         string
+	OPAMode             string
+	OPABundlePoll       time.Duration
+	OPAShadowEnabled    bool
+
+	// Authorization decision log configuration
+	DecisionLogSink       string
+	DecisionLogFile       string
+	DecisionLogMaxSizeMB  int
+	DecisionLogMaxBackups int
+
+	// Directory background user exports are written to
+	ExportDir string
+	// Write timeout for streamed exports and export downloads, which can
+	// take far longer than other responses
+	ExportWriteTimeout time.Duration
+
+	// Data erasure configuration
+	ErasureGracePeriod time.Duration
+	ErasureSigningKey  string
+
+	// Data export configuration
+	PublicURL            string
+	DataExportLinkTTL    time.Duration
+	DataExportSigningKey string
+
+	// Reject user updates and deletes that carry no If-Match header
+	RequireIfMatch bool
+
+	// How long responses to requests with an Idempotency-Key are replayed
+	IdempotencyTTL time.Duration
 }
 
 func Load() *Config {
@@ -51,8 +89,39 @@ func Load() *Config {
 		TaskQueue:         getEnv("TASK_QUEUE", "user-onboarding"),
 		
 		// OPA configuration
-		OPAEnabled: getEnvBool("OPA_ENABLED", false),
-		OPAURL:     getEnv("OPA_URL", "http://localhost:8181"),
+		OPAEnabled:          getEnvBool("OPA_ENABLED", false),
+		OPAURL:              getEnv("OPA_URL", "http://localhost:8181"),
+		OPATimeout:          getEnvDuration("OPA_TIMEOUT", 500*time.Millisecond),
+		OPAMaxRetries:       getEnvInt("OPA_MAX_RETRIES", 2),
+		OPARetryBackoff:     getEnvDuration("OPA_RETRY_BACKOFF", 50*time.Millisecond),
+		OPABreakerThreshold: getEnvInt("OPA_BREAKER_THRESHOLD", 5),
+		OPABreakerCooldown:  getEnvDuration("OPA_BREAKER_COOLDOWN", 30*time.Second),
+		OPAFailMode:         getEnv("OPA_FAIL_MODE", "fail-closed"),
+		OPAMode:             getEnv("OPA_MODE", "remote"),
+		OPABundlePoll:       getEnvDuration("OPA_BUNDLE_POLL_INTERVAL", 10*time.Second),
+		OPAShadowEnabled:    getEnvBool("OPA_SHADOW_ENABLED", false),
+
+		// Authorization decision log configuration
+		DecisionLogSink:       getEnv("DECISION_LOG_SINK", "db"),
+		DecisionLogFile:       getEnv("DECISION_LOG_FILE", "logs/decisions.log"),
+		DecisionLogMaxSizeMB:  getEnvInt("DECISION_LOG_MAX_SIZE_MB", 100),
+		DecisionLogMaxBackups: getEnvInt("DECISION_LOG_MAX_BACKUPS", 5),
+
+		ExportDir:          getEnv("EXPORT_DIR", "data/exports"),
+		ExportWriteTimeout: getEnvDuration("EXPORT_WRITE_TIMEOUT", time.Hour),
+
+		// Data erasure configuration
+		ErasureGracePeriod: getEnvDuration("ERASURE_GRACE_PERIOD", 72*time.Hour),
+		ErasureSigningKey:  getEnv("ERASURE_SIGNING_KEY", getEnv("JWT_SECRET", "your-secret-key")),
+
+		// Data export configuration
+		PublicURL:            getEnv("PUBLIC_URL", "http://localhost:8080"),
+		DataExportLinkTTL:    getEnvDuration("DATA_EXPORT_LINK_TTL", 24*time.Hour),
+		DataExportSigningKey: getEnv("DATA_EXPORT_SIGNING_KEY", getEnv("JWT_SECRET", "your-secret-key")),
+
+		RequireIfMatch: getEnvBool("REQUIRE_IF_MATCH", false),
+
+		IdempotencyTTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
 	}
 }
 
@@ -68,4 +137,22 @@ func getEnvBool(key string, defaultValue bool) bool {
 		return value == "true" || value == "1"
 	}
 	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
 }
\ No newline at end of file
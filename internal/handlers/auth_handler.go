@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -8,54 +10,48 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/middleware"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/utils"
 )
 
+var errInvalidCredentials = problem.New(problem.CodeInvalidCredentials, "Invalid credentials")
+
 type AuthHandler struct {
-	userService service.UserService
-	logger      logger.Logger
-	jwtSecret   string
+	userService  service.UserService
+	groupService service.GroupService
+	logger       logger.Logger
+	jwtSecret    string
 }
 
-func NewAuthHandler(userService service.UserService, logger logger.Logger, jwtSecret string) *AuthHandler {
+// NewAuthHandler returns an AuthHandler. Tokens carry only the user's own
+// roles if groupService is nil.
+func NewAuthHandler(userService service.UserService, groupService service.GroupService, logger logger.Logger, jwtSecret string) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		logger:      logger,
-		jwtSecret:   jwtSecret,
+		userService:  userService,
+		groupService: groupService,
+		logger:       logger,
+		jwtSecret:    jwtSecret,
 	}
 }
 
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	var req models.CreateUserRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := bindBody(c, &req); err != nil {
+		return err
 	}
 
-	// Create user
-	user, err := h.userService.Create(c.Context(), &req)
+	// Create user; a duplicate email or username is ErrUserAlreadyExists
+	user, err := h.userService.Create(c.UserContext(), &req)
 	if err != nil {
-		h.logger.Error("Failed to create user: ", err)
-		if err.Error() == "email already exists" {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "Email already exists",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create user",
-		})
+		return err
 	}
 
 	// Generate JWT token
-	token, err := h.generateToken(user)
+	token, err := h.generateToken(c.UserContext(), user)
 	if err != nil {
-		h.logger.Error("Failed to generate token: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate token",
-		})
+		return fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -66,69 +62,61 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req models.LoginRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := bindBody(c, &req); err != nil {
+		return err
 	}
 
 	// Get user by email
 	user, err := h.userService.GetByEmail(c.Context(), req.Email)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid credentials",
-		})
+		if errors.Is(err, service.ErrUserNotFound) {
+			return errInvalidCredentials
+		}
+		return err
 	}
 
 	// Verify password
 	if !utils.CheckPassword(req.Password, user.Password) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid credentials",
-		})
+		return errInvalidCredentials
+	}
+
+	// Suspended, locked and other inactive accounts cannot sign in
+	if err := service.AccountError(user, time.Now()); err != nil {
+		return err
 	}
 
 	// Generate JWT token
-	token, err := h.generateToken(&models.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Username:  user.Username,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Roles:     user.Roles,
-		IsActive:  user.IsActive,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	})
+	response := user.ToResponse()
+	token, err := h.generateToken(c.UserContext(), response)
 	if err != nil {
-		h.logger.Error("Failed to generate token: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate token",
-		})
+		return fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	return c.JSON(fiber.Map{
-		"user": models.UserResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			Username:  user.Username,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Roles:     user.Roles,
-			IsActive:  user.IsActive,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
+		"user":  response,
 		"token": token,
 	})
 }
 
-func (h *AuthHandler) generateToken(user *models.UserResponse) (string, error) {
+// generateToken signs a token for user carrying their effective roles, their
+// own and those granted through groups, and the groups they are in.
+func (h *AuthHandler) generateToken(ctx context.Context, user *models.UserResponse) (string, error) {
+	access := &models.UserAccess{Roles: user.Roles, Groups: []string{}}
+	if h.groupService != nil {
+		var err error
+		access, err = h.groupService.UserAccess(ctx, user.ID)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	claims := jwt.MapClaims{
-		"sub":   fmt.Sprintf("%d", user.ID),
-		"email": user.Email,
-		"roles": user.Roles,
-		"exp":   time.Now().Add(time.Hour * 24).Unix(),
-		"iat":   time.Now().Unix(),
+		"sub":    fmt.Sprintf("%d", user.ID),
+		"email":  user.Email,
+		"roles":  access.Roles,
+		"groups": access.Groups,
+		"exp":    time.Now().Add(time.Hour * 24).Unix(),
+		"iat":    time.Now().Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -149,16 +137,11 @@ func (h *AuthHandler) ParseToken(tokenString string) (*middleware.User, error) {
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		roles, _ := claims["roles"].([]interface{})
-		roleStrings := make([]string, len(roles))
-		for i, r := range roles {
-			roleStrings[i] = r.(string)
-		}
-
 		return &middleware.User{
-			ID:    claims["sub"].(string),
-			Email: claims["email"].(string),
-			Roles: roleStrings,
+			ID:     claims["sub"].(string),
+			Email:  claims["email"].(string),
+			Roles:  middleware.ClaimStrings(claims, "roles"),
+			Groups: middleware.ClaimStrings(claims, "groups"),
 		}, nil
 	}
 
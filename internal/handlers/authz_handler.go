@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/middleware"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+// AuthzEvaluator evaluates authorization policy without enforcing it.
+type AuthzEvaluator interface {
+	Evaluate(ctx context.Context, input middleware.OPAInput) (*middleware.Decision, error)
+	PolicyRevision() string
+}
+
+// RouteMatrix reports the permission each registered route requires.
+type RouteMatrix interface {
+	Matrix() []middleware.RoutePermission
+	WriteMatrix(w io.Writer)
+}
+
+type AuthzHandler struct {
+	evaluator AuthzEvaluator
+	routes    RouteMatrix
+	logger    logger.Logger
+}
+
+func NewAuthzHandler(evaluator AuthzEvaluator, routes RouteMatrix, logger logger.Logger) *AuthzHandler {
+	return &AuthzHandler{
+		evaluator: evaluator,
+		routes:    routes,
+		logger:    logger,
+	}
+}
+
+// Explain evaluates a hypothetical request and reports the decision together
+// with the policy rules that matched it.
+func (h *AuthzHandler) Explain(c *fiber.Ctx) error {
+	var input middleware.OPAInput
+	if err := bindBody(c, &input); err != nil {
+		return err
+	}
+
+	if input.Action == "" || input.Resource == nil || input.Resource.Type == "" {
+		return problem.New(problem.CodeInvalidRequest, "action and resource.type are required")
+	}
+
+	decision, err := h.evaluator.Evaluate(c.UserContext(), input)
+	if err != nil {
+		return problem.Wrap(err, problem.CodeServiceUnavailable, "Authorization service unavailable")
+	}
+
+	return c.JSON(fiber.Map{
+		"input":           input,
+		"allow":           decision.Allow,
+		"matched_rules":   decision.MatchedRules,
+		"policy_revision": h.evaluator.PolicyRevision(),
+	})
+}
+
+// Routes reports the route/permission matrix, as JSON or, with
+// ?format=markdown, as a Markdown table.
+func (h *AuthzHandler) Routes(c *fiber.Ctx) error {
+	if c.Query("format") == "markdown" {
+		var buf bytes.Buffer
+		h.routes.WriteMatrix(&buf)
+		c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+		return c.Send(buf.Bytes())
+	}
+
+	return c.JSON(fiber.Map{
+		"routes": h.routes.Matrix(),
+	})
+}
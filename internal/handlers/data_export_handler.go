@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type DataExportHandler struct {
+	service service.DataExportService
+	logger  logger.Logger
+}
+
+func NewDataExportHandler(service service.DataExportService, logger logger.Logger) *DataExportHandler {
+	return &DataExportHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// errAuthenticationRequired answers a request without a numeric caller ID.
+var errAuthenticationRequired = problem.New(problem.CodeUnauthorized, "Authentication required")
+
+// dataExportResponse is a data export with its download link once it is
+// completed.
+type dataExportResponse struct {
+	*models.DataExport
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// Request starts assembling an archive of everything held about the caller.
+func (h *DataExportHandler) Request(c *fiber.Ctx) error {
+	userID, ok := callerUserID(c)
+	if !ok {
+		return errAuthenticationRequired
+	}
+
+	export, err := h.service.Request(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+
+	c.Location(c.Path() + "/" + export.ID)
+	return c.Status(fiber.StatusAccepted).JSON(dataExportResponse{DataExport: export})
+}
+
+// Get reports the progress of one of the caller's data exports.
+func (h *DataExportHandler) Get(c *fiber.Ctx) error {
+	userID, ok := callerUserID(c)
+	if !ok {
+		return errAuthenticationRequired
+	}
+
+	export, err := h.service.Get(c.UserContext(), c.Params("id"), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(dataExportResponse{
+		DataExport:  export,
+		DownloadURL: h.service.DownloadURL(export),
+	})
+}
+
+// Download serves a data export archive to anyone holding a valid signed
+// link, so that the link in the notification email works without logging
+// in.
+func (h *DataExportHandler) Download(c *fiber.Ctx) error {
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		return service.ErrInvalidDownloadLink
+	}
+
+	export, file, err := h.service.Open(c.UserContext(), c.Params("id"), expires, c.Query("signature"))
+	if err != nil {
+		return err
+	}
+
+	c.Attachment(export.FileName())
+	c.Set(fiber.HeaderContentType, "application/zip")
+	return c.SendStream(file)
+}
+
+// callerUserID returns the numeric ID of the authenticated caller.
+func callerUserID(c *fiber.Ctx) (uint, bool) {
+	id, err := strconv.ParseUint(principalID(c), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/middleware"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+)
+
+type MockDataExportService struct {
+	mock.Mock
+}
+
+func (m *MockDataExportService) Request(ctx context.Context, userID uint) (*models.DataExport, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportService) Get(ctx context.Context, id string, userID uint) (*models.DataExport, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportService) Build(ctx context.Context, id string) (*models.DataExport, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportService) Fail(ctx context.Context, id, reason string) error {
+	args := m.Called(ctx, id, reason)
+	return args.Error(0)
+}
+
+func (m *MockDataExportService) DownloadURL(export *models.DataExport) string {
+	args := m.Called(export)
+	return args.String(0)
+}
+
+func (m *MockDataExportService) Open(ctx context.Context, id string, expires int64, signature string) (*models.DataExport, io.ReadCloser, error) {
+	args := m.Called(ctx, id, expires, signature)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.DataExport), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockDataExportService) DeleteForUser(ctx context.Context, userID uint) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDataExportService) RegisterSection(section service.DataExportSection) {}
+
+func TestDataExportHandler_Request(t *testing.T) {
+	t.Run("Accepted", func(t *testing.T) {
+		mockService := new(MockDataExportService)
+		handler := NewDataExportHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Request", mock.Anything, uint(42)).
+			Return(&models.DataExport{ID: "exp", UserID: 42, Status: models.ExportStatusRunning}, nil)
+		app.Post("/users/me/data-export", func(c *fiber.Ctx) error {
+			c.Locals("user", &middleware.User{ID: "42"})
+			return c.Next()
+		}, handler.Request)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/users/me/data-export", nil))
+
+		assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "/users/me/data-export/exp", resp.Header.Get("Location"))
+	})
+
+	t.Run("Requires A Caller", func(t *testing.T) {
+		handler := NewDataExportHandler(new(MockDataExportService), new(MockLogger))
+		app := newTestApp()
+		app.Post("/users/me/data-export", handler.Request)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/users/me/data-export", nil))
+
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestDataExportHandler_Download(t *testing.T) {
+	t.Run("Serves The Archive", func(t *testing.T) {
+		mockService := new(MockDataExportService)
+		handler := NewDataExportHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Open", mock.Anything, "exp", int64(1700000000), "sig").
+			Return(&models.DataExport{ID: "exp"}, io.NopCloser(strings.NewReader("PK")), nil)
+		app.Get("/data-exports/:id/download", handler.Download)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/data-exports/exp/download?expires=1700000000&signature=sig", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), "data-export-exp.zip")
+	})
+
+	t.Run("Expired Link", func(t *testing.T) {
+		mockService := new(MockDataExportService)
+		handler := NewDataExportHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Open", mock.Anything, "exp", int64(1700000000), "sig").Return(nil, nil, service.ErrDataExportExpired)
+		app.Get("/data-exports/:id/download", handler.Download)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/data-exports/exp/download?expires=1700000000&signature=sig", nil))
+
+		assert.Equal(t, fiber.StatusGone, resp.StatusCode)
+	})
+
+	t.Run("Malformed Link", func(t *testing.T) {
+		handler := NewDataExportHandler(new(MockDataExportService), new(MockLogger))
+		app := newTestApp()
+		app.Get("/data-exports/:id/download", handler.Download)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/data-exports/exp/download", nil))
+
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+}
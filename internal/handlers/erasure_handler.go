@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type ErasureHandler struct {
+	service service.ErasureService
+	logger  logger.Logger
+}
+
+func NewErasureHandler(service service.ErasureService, logger logger.Logger) *ErasureHandler {
+	return &ErasureHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// erasureResponse is an erasure request, with whether its certificate's
+// signature checks out once it has one.
+type erasureResponse struct {
+	*models.ErasureRequest
+	CertificateValid *bool `json:"certificate_valid,omitempty"`
+}
+
+func (h *ErasureHandler) response(request *models.ErasureRequest) erasureResponse {
+	response := erasureResponse{ErasureRequest: request}
+	if request.Certificate != nil {
+		valid := h.service.VerifyCertificate(request.Certificate)
+		response.CertificateValid = &valid
+	}
+	return response
+}
+
+// IncludeErasure embeds each user's latest erasure request, or null, in
+// user responses for ?include=erasure. It is a projection.Includer.
+func (h *ErasureHandler) IncludeErasure(ctx context.Context, items []interface{}) ([]interface{}, error) {
+	requests, err := h.service.GetForUsers(ctx, includedUserIDs(items))
+	if err != nil {
+		return nil, err
+	}
+	related := make([]interface{}, len(items))
+	for i, item := range items {
+		if user, ok := item.(*models.UserResponse); ok && requests[user.ID] != nil {
+			related[i] = h.response(requests[user.ID])
+		}
+	}
+	return related, nil
+}
+
+// Request asks for the user's personal data to be erased once the grace
+// period is over.
+func (h *ErasureHandler) Request(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	var body models.ErasureRequestBody
+	if len(c.Body()) > 0 {
+		if err := bindBody(c, &body); err != nil {
+			return err
+		}
+	}
+
+	request, err := h.service.Request(c.UserContext(), id, body.Reason, principalID(c))
+	if err != nil {
+		return err
+	}
+
+	c.Location(c.Path())
+	return c.Status(fiber.StatusAccepted).JSON(h.response(request))
+}
+
+// Get reports the user's latest erasure request and, once it is completed,
+// its certificate.
+func (h *ErasureHandler) Get(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	request, err := h.service.Get(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(h.response(request))
+}
+
+// Cancel withdraws the user's erasure request during its grace period.
+func (h *ErasureHandler) Cancel(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	request, err := h.service.Cancel(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(h.response(request))
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+)
+
+type MockErasureService struct {
+	mock.Mock
+}
+
+func (m *MockErasureService) Request(ctx context.Context, userID uint, reason, requestedBy string) (*models.ErasureRequest, error) {
+	args := m.Called(ctx, userID, reason, requestedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureService) Get(ctx context.Context, userID uint) (*models.ErasureRequest, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureService) GetForUsers(ctx context.Context, userIDs []uint) (map[uint]*models.ErasureRequest, error) {
+	args := m.Called(ctx, userIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uint]*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureService) Cancel(ctx context.Context, userID uint) (*models.ErasureRequest, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureService) Execute(ctx context.Context, requestID string) (*models.ErasureRequest, error) {
+	args := m.Called(ctx, requestID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureService) VerifyCertificate(certificate *models.ErasureCertificate) bool {
+	args := m.Called(certificate)
+	return args.Bool(0)
+}
+
+func (m *MockErasureService) RegisterHook(hook service.ErasureHook) {}
+
+func TestErasureHandler_Request(t *testing.T) {
+	t.Run("Accepted", func(t *testing.T) {
+		mockService := new(MockErasureService)
+		handler := NewErasureHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Request", mock.Anything, uint(42), "leaving", "").
+			Return(&models.ErasureRequest{ID: "req", UserID: 42, Status: models.ErasureStatusPending}, nil)
+		app.Post("/users/:id/erasure", handler.Request)
+
+		req := httptest.NewRequest("POST", "/users/42/erasure", strings.NewReader(`{"reason":"leaving"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "/users/42/erasure", resp.Header.Get("Location"))
+	})
+
+	t.Run("Already In Progress", func(t *testing.T) {
+		mockService := new(MockErasureService)
+		handler := NewErasureHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Request", mock.Anything, uint(42), "", "").Return(nil, service.ErrErasureInProgress)
+		app.Post("/users/:id/erasure", handler.Request)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/users/42/erasure", nil))
+
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+}
+
+func TestErasureHandler_Get(t *testing.T) {
+	mockService := new(MockErasureService)
+	handler := NewErasureHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	certificate := &models.ErasureCertificate{ID: "cert", RequestID: "req", UserID: 42, Signature: "sig"}
+	mockService.On("Get", mock.Anything, uint(42)).
+		Return(&models.ErasureRequest{ID: "req", UserID: 42, Status: models.ErasureStatusCompleted, Certificate: certificate}, nil)
+	mockService.On("VerifyCertificate", certificate).Return(true)
+	app.Get("/users/:id/erasure", handler.Get)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/users/42/erasure", nil))
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response struct {
+		Status           string `json:"status"`
+		CertificateValid *bool  `json:"certificate_valid"`
+	}
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Equal(t, models.ErasureStatusCompleted, response.Status)
+	assert.True(t, *response.CertificateValid)
+}
+
+func TestErasureHandler_Cancel(t *testing.T) {
+	mockService := new(MockErasureService)
+	handler := NewErasureHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	mockService.On("Cancel", mock.Anything, uint(42)).Return(nil, service.ErrErasureNotCancellable)
+	app.Delete("/users/:id/erasure", handler.Cancel)
+
+	resp, _ := app.Test(httptest.NewRequest("DELETE", "/users/42/erasure", nil))
+
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+func TestErasureHandler_IncludeErasure(t *testing.T) {
+	mockService := new(MockErasureService)
+	mockUsers := new(MockUserService)
+	erasures := NewErasureHandler(mockService, new(MockLogger))
+	users := NewUserHandler(mockUsers, new(MockLogger))
+	users.Include("erasure", erasures.IncludeErasure)
+	app := newTestApp()
+
+	mockUsers.On("GetAll", mock.Anything, mock.Anything).
+		Return([]*models.UserResponse{{ID: 42, Username: "alice"}, {ID: 43, Username: "bob"}}, int64(2), nil)
+	mockService.On("GetForUsers", mock.Anything, []uint{42, 43}).Return(map[uint]*models.ErasureRequest{
+		42: {ID: "req", UserID: 42, Status: models.ErasureStatusPending},
+	}, nil).Once()
+	app.Get("/users", users.GetAll)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/users?fields=username&include=erasure", nil))
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Len(t, response.Users, 2)
+	assert.Equal(t, "pending", response.Users[0]["erasure"].(map[string]interface{})["status"])
+	assert.Nil(t, response.Users[1]["erasure"])
+	assert.NotContains(t, response.Users[0], "email")
+	mockService.AssertExpectations(t)
+}
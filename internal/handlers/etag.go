@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+)
+
+// etag formats a resource version as a strong entity tag.
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// errNoVersionMatches answers an If-Match header no version can match.
+var errNoVersionMatches = problem.New(problem.CodePreconditionFailed, "If-Match does not name a version of this resource")
+
+// ifMatch returns the version the If-Match header requires, or 0 if there is
+// no header or it is "*". ok is false if the header cannot match any version,
+// e.g. a weak tag, which If-Match never accepts.
+func ifMatch(c *fiber.Ctx) (version uint, ok bool) {
+	required, ok := ifMatchVersion(c)
+	if !ok || required == nil {
+		return 0, ok
+	}
+	if *required == 0 {
+		return 0, false
+	}
+	return *required, true
+}
+
+// ifMatchVersion returns the version the If-Match header requires, or nil if
+// there is no header or it is "*". Unlike ifMatch it accepts "0", the
+// version of a resource that has not been created yet, such as the
+// attribute schema before one is published.
+func ifMatchVersion(c *fiber.Ctx) (version *uint, ok bool) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return nil, false
+	}
+	parsed, err := strconv.ParseUint(header[1:len(header)-1], 10, 32)
+	if err != nil {
+		return nil, false
+	}
+	required := uint(parsed)
+	return &required, true
+}
+
+// noneMatch reports whether the If-None-Match header lists tag, comparing
+// weakly as RFC 9110 asks.
+func noneMatch(c *fiber.Ctx, tag string) bool {
+	header := c.Get(fiber.HeaderIfNoneMatch)
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// versionMismatch reports a version mismatch: a failed precondition if the
+// client asked for a version with If-Match, or a conflict if another request
+// changed the resource while this one was being applied.
+func versionMismatch(c *fiber.Ctx, err error) error {
+	if c.Get(fiber.HeaderIfMatch) != "" {
+		return problem.Wrap(err, problem.CodePreconditionFailed, "User has been modified since it was read")
+	}
+	return err
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type GroupHandler struct {
+	service service.GroupService
+	logger  logger.Logger
+}
+
+func NewGroupHandler(service service.GroupService, logger logger.Logger) *GroupHandler {
+	return &GroupHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *GroupHandler) Create(c *fiber.Ctx) error {
+	var req models.CreateGroupRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	group, err := h.service.Create(c.UserContext(), &req, principalID(c))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(group)
+}
+
+func (h *GroupHandler) GetAll(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	groups, total, err := h.service.GetAll(c.UserContext(), page, pageSize)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"groups": groups,
+		"pagination": fiber.Map{
+			"page":        page,
+			"page_size":   pageSize,
+			"total":       total,
+			"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+func (h *GroupHandler) GetByID(c *fiber.Ctx) error {
+	id, err := idParam(c, "id", "group")
+	if err != nil {
+		return err
+	}
+
+	group, err := h.service.GetByID(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(group)
+}
+
+func (h *GroupHandler) Update(c *fiber.Ctx) error {
+	id, err := idParam(c, "id", "group")
+	if err != nil {
+		return err
+	}
+
+	var req models.UpdateGroupRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	group, err := h.service.Update(c.UserContext(), id, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(group)
+}
+
+func (h *GroupHandler) Delete(c *fiber.Ctx) error {
+	id, err := idParam(c, "id", "group")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.Delete(c.UserContext(), id); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListMembers returns the direct members of a group; members of nested
+// groups are listed under those groups.
+func (h *GroupHandler) ListMembers(c *fiber.Ctx) error {
+	id, err := idParam(c, "id", "group")
+	if err != nil {
+		return err
+	}
+
+	members, err := h.service.ListMembers(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{"members": members})
+}
+
+func (h *GroupHandler) AddMember(c *fiber.Ctx) error {
+	id, err := idParam(c, "id", "group")
+	if err != nil {
+		return err
+	}
+
+	var req models.AddGroupMemberRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	member, added, err := h.service.AddMember(c.UserContext(), id, &req, principalID(c))
+	if err != nil {
+		return err
+	}
+
+	// Adding an existing member again changes nothing and returns it as is
+	status := fiber.StatusOK
+	if added {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(member)
+}
+
+func (h *GroupHandler) RemoveUser(c *fiber.Ctx) error {
+	return h.removeMember(c, models.GroupMemberUser, "user")
+}
+
+func (h *GroupHandler) RemoveGroup(c *fiber.Ctx) error {
+	return h.removeMember(c, models.GroupMemberGroup, "group")
+}
+
+// UserAccess returns the groups a user is in and the roles they hold, their
+// own and those granted through groups.
+func (h *GroupHandler) UserAccess(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	access, err := h.service.UserAccess(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(access)
+}
+
+// IncludeGroups embeds the names of the groups each user is in, directly or
+// through nested groups, in user responses for ?include=groups. It is a
+// projection.Includer.
+func (h *GroupHandler) IncludeGroups(ctx context.Context, items []interface{}) ([]interface{}, error) {
+	return h.includeAccess(ctx, items, func(access *models.UserAccess) interface{} {
+		return access.Groups
+	})
+}
+
+// IncludeEffectiveRoles embeds every role each user holds, their own and
+// those granted through groups, in user responses for
+// ?include=effective_roles. It is a projection.Includer.
+func (h *GroupHandler) IncludeEffectiveRoles(ctx context.Context, items []interface{}) ([]interface{}, error) {
+	return h.includeAccess(ctx, items, func(access *models.UserAccess) interface{} {
+		return access.Roles
+	})
+}
+
+// includeAccess renders part of each user's access; users that cannot be
+// resolved, e.g. because they are soft-deleted, get null.
+func (h *GroupHandler) includeAccess(ctx context.Context, items []interface{}, part func(*models.UserAccess) interface{}) ([]interface{}, error) {
+	access, err := h.service.UsersAccess(ctx, includedUserIDs(items))
+	if err != nil {
+		return nil, err
+	}
+	related := make([]interface{}, len(items))
+	for i, item := range items {
+		if user, ok := item.(*models.UserResponse); ok && access[user.ID] != nil {
+			related[i] = part(access[user.ID])
+		}
+	}
+	return related, nil
+}
+
+func (h *GroupHandler) removeMember(c *fiber.Ctx, memberType, resource string) error {
+	id, err := idParam(c, "id", "group")
+	if err != nil {
+		return err
+	}
+	memberID, err := idParam(c, "memberId", resource)
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.RemoveMember(c.UserContext(), id, memberType, memberID); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+)
+
+type MockGroupService struct {
+	mock.Mock
+}
+
+func (m *MockGroupService) Create(ctx context.Context, req *models.CreateGroupRequest, by string) (*models.Group, error) {
+	args := m.Called(ctx, req, by)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Group), args.Error(1)
+}
+
+func (m *MockGroupService) GetByID(ctx context.Context, id uint) (*models.Group, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Group), args.Error(1)
+}
+
+func (m *MockGroupService) GetAll(ctx context.Context, page, pageSize int) ([]*models.Group, int64, error) {
+	args := m.Called(ctx, page, pageSize)
+	return args.Get(0).([]*models.Group), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockGroupService) Update(ctx context.Context, id uint, req *models.UpdateGroupRequest) (*models.Group, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Group), args.Error(1)
+}
+
+func (m *MockGroupService) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockGroupService) AddMember(ctx context.Context, groupID uint, req *models.AddGroupMemberRequest, by string) (*models.GroupMember, bool, error) {
+	args := m.Called(ctx, groupID, req, by)
+	if args.Get(0) == nil {
+		return nil, false, args.Error(2)
+	}
+	return args.Get(0).(*models.GroupMember), args.Bool(1), args.Error(2)
+}
+
+func (m *MockGroupService) RemoveMember(ctx context.Context, groupID uint, memberType string, memberID uint) error {
+	args := m.Called(ctx, groupID, memberType, memberID)
+	return args.Error(0)
+}
+
+func (m *MockGroupService) ListMembers(ctx context.Context, groupID uint) ([]*models.GroupMember, error) {
+	args := m.Called(ctx, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.GroupMember), args.Error(1)
+}
+
+func (m *MockGroupService) UserAccess(ctx context.Context, userID uint) (*models.UserAccess, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserAccess), args.Error(1)
+}
+
+func (m *MockGroupService) UsersAccess(ctx context.Context, userIDs []uint) (map[uint]*models.UserAccess, error) {
+	args := m.Called(ctx, userIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uint]*models.UserAccess), args.Error(1)
+}
+
+func TestGroupHandler_Create(t *testing.T) {
+	t.Run("Created", func(t *testing.T) {
+		mockService := new(MockGroupService)
+		handler := NewGroupHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Create", mock.Anything, mock.MatchedBy(func(req *models.CreateGroupRequest) bool {
+			return req.Name == "engineering" && len(req.Roles) == 1 && req.Roles[0] == "deployer"
+		}), "").Return(&models.Group{ID: 1, Name: "engineering", Roles: []string{"deployer"}}, nil)
+		app.Post("/groups", handler.Create)
+
+		req := httptest.NewRequest("POST", "/groups", strings.NewReader(`{"name":"engineering","roles":["deployer"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("Name Required", func(t *testing.T) {
+		handler := NewGroupHandler(new(MockGroupService), new(MockLogger))
+		app := newTestApp()
+		app.Post("/groups", handler.Create)
+
+		req := httptest.NewRequest("POST", "/groups", strings.NewReader(`{"roles":["deployer"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("Name Taken", func(t *testing.T) {
+		mockService := new(MockGroupService)
+		handler := NewGroupHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Create", mock.Anything, mock.Anything, "").Return(nil, service.ErrGroupAlreadyExists)
+		app.Post("/groups", handler.Create)
+
+		req := httptest.NewRequest("POST", "/groups", strings.NewReader(`{"name":"engineering"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+}
+
+func TestGroupHandler_AddMember(t *testing.T) {
+	t.Run("Adds Group", func(t *testing.T) {
+		mockService := new(MockGroupService)
+		handler := NewGroupHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("AddMember", mock.Anything, uint(1), &models.AddGroupMemberRequest{GroupID: 2}, "").
+			Return(&models.GroupMember{GroupID: 1, MemberType: models.GroupMemberGroup, MemberID: 2}, true, nil)
+		app.Post("/groups/:id/members", handler.AddMember)
+
+		req := httptest.NewRequest("POST", "/groups/1/members", strings.NewReader(`{"group_id":2}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("Already A Member", func(t *testing.T) {
+		mockService := new(MockGroupService)
+		handler := NewGroupHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("AddMember", mock.Anything, uint(1), &models.AddGroupMemberRequest{UserID: 42}, "").
+			Return(&models.GroupMember{GroupID: 1, MemberType: models.GroupMemberUser, MemberID: 42, AddedBy: "7"}, false, nil)
+		app.Post("/groups/:id/members", handler.AddMember)
+
+		req := httptest.NewRequest("POST", "/groups/1/members", strings.NewReader(`{"user_id":42}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var member models.GroupMember
+		json.NewDecoder(resp.Body).Decode(&member)
+		assert.Equal(t, "7", member.AddedBy)
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		mockService := new(MockGroupService)
+		handler := NewGroupHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("AddMember", mock.Anything, uint(2), mock.Anything, "").Return(nil, false, service.ErrGroupCycle)
+		app.Post("/groups/:id/members", handler.AddMember)
+
+		req := httptest.NewRequest("POST", "/groups/2/members", strings.NewReader(`{"group_id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+}
+
+func TestGroupHandler_RemoveMember(t *testing.T) {
+	mockService := new(MockGroupService)
+	handler := NewGroupHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	mockService.On("RemoveMember", mock.Anything, uint(1), models.GroupMemberUser, uint(42)).Return(nil)
+	mockService.On("RemoveMember", mock.Anything, uint(1), models.GroupMemberGroup, uint(2)).Return(service.ErrGroupMemberNotFound)
+	app.Delete("/groups/:id/members/users/:memberId", handler.RemoveUser)
+	app.Delete("/groups/:id/members/groups/:memberId", handler.RemoveGroup)
+
+	resp, _ := app.Test(httptest.NewRequest("DELETE", "/groups/1/members/users/42", nil))
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+	resp, _ = app.Test(httptest.NewRequest("DELETE", "/groups/1/members/groups/2", nil))
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, _ = app.Test(httptest.NewRequest("DELETE", "/groups/1/members/users/abc", nil))
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestGroupHandler_UserAccess(t *testing.T) {
+	mockService := new(MockGroupService)
+	handler := NewGroupHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	mockService.On("UserAccess", mock.Anything, uint(42)).Return(&models.UserAccess{UserID: 42, Groups: []string{"engineering"}, Roles: []string{"deployer", "user"}}, nil)
+	app.Get("/users/:id/access", handler.UserAccess)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/users/42/access", nil))
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var access models.UserAccess
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&access))
+	assert.Equal(t, []string{"engineering"}, access.Groups)
+	assert.Equal(t, []string{"deployer", "user"}, access.Roles)
+}
+
+func TestGroupHandler_Include(t *testing.T) {
+	mockService := new(MockGroupService)
+	mockUsers := new(MockUserService)
+	groups := NewGroupHandler(mockService, new(MockLogger))
+	users := NewUserHandler(mockUsers, new(MockLogger))
+	users.Include("groups", groups.IncludeGroups)
+	users.Include("effective_roles", groups.IncludeEffectiveRoles)
+	app := newTestApp()
+
+	mockUsers.On("GetAll", mock.Anything, mock.Anything).
+		Return([]*models.UserResponse{{ID: 42, Username: "alice", Roles: []string{"user"}}, {ID: 43, Username: "bob"}}, int64(2), nil)
+	// Each relation is loaded once for the whole page
+	mockService.On("UsersAccess", mock.Anything, []uint{42, 43}).Return(map[uint]*models.UserAccess{
+		42: {UserID: 42, Groups: []string{"engineering"}, Roles: []string{"deployer", "user"}},
+	}, nil).Twice()
+	app.Get("/users", users.GetAll)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/users?fields=username&include=groups,effective_roles", nil))
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Len(t, response.Users, 2)
+	assert.Equal(t, []interface{}{"engineering"}, response.Users[0]["groups"])
+	assert.Equal(t, []interface{}{"deployer", "user"}, response.Users[0]["effective_roles"])
+	assert.Nil(t, response.Users[1]["groups"])
+	assert.NotContains(t, response.Users[0], "email")
+	mockService.AssertExpectations(t)
+}
@@ -4,9 +4,25 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func HealthCheck(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
+type HealthHandler struct {
+	policyRevision func() string
+}
+
+// NewHealthHandler creates the health handler. policyRevision may be nil when
+// authorization is disabled.
+func NewHealthHandler(policyRevision func() string) *HealthHandler {
+	return &HealthHandler{
+		policyRevision: policyRevision,
+	}
+}
+
+func (h *HealthHandler) Check(c *fiber.Ctx) error {
+	response := fiber.Map{
 		"status": "healthy",
 		"service": "fiber-boilerplate",
-	})
+	}
+	if h.policyRevision != nil {
+		response["policy_revision"] = h.policyRevision()
+	}
+	return c.JSON(response)
 }
\ No newline at end of file
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/middleware"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type PolicyHandler struct {
+	service service.PolicyService
+	logger  logger.Logger
+}
+
+func NewPolicyHandler(service service.PolicyService, logger logger.Logger) *PolicyHandler {
+	return &PolicyHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *PolicyHandler) Validate(c *fiber.Ctx) error {
+	var req models.UploadPolicyBundleRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	revision, err := h.service.Validate(c.UserContext(), &req)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"valid": false,
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"valid":    true,
+		"revision": revision,
+	})
+}
+
+func (h *PolicyHandler) Upload(c *fiber.Ctx) error {
+	var req models.UploadPolicyBundleRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	bundle, err := h.service.Upload(c.UserContext(), &req, principalID(c))
+	if err != nil {
+		return err
+	}
+
+	if c.QueryBool("activate") {
+		return h.activate(c, bundle.Revision)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(bundle.ToSummary())
+}
+
+func (h *PolicyHandler) List(c *fiber.Ctx) error {
+	bundles, err := h.service.List(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"bundles":         bundles,
+		"active_revision": h.service.ActiveRevision(),
+	})
+}
+
+func (h *PolicyHandler) Get(c *fiber.Ctx) error {
+	bundle, err := h.service.Get(c.UserContext(), c.Params("revision"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(bundle)
+}
+
+func (h *PolicyHandler) Activate(c *fiber.Ctx) error {
+	return h.activate(c, c.Params("revision"))
+}
+
+func (h *PolicyHandler) Rollback(c *fiber.Ctx) error {
+	bundle, err := h.service.Rollback(c.UserContext(), principalID(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(bundle)
+}
+
+func (h *PolicyHandler) activate(c *fiber.Ctx, revision string) error {
+	bundle, err := h.service.Activate(c.UserContext(), revision, principalID(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(bundle)
+}
+
+// principalID returns the ID of the authenticated user, if any.
+func principalID(c *fiber.Ctx) string {
+	return middleware.UserID(c)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type UserAttributeHandler struct {
+	service service.UserAttributeService
+	logger  logger.Logger
+}
+
+func NewUserAttributeHandler(service service.UserAttributeService, logger logger.Logger) *UserAttributeHandler {
+	return &UserAttributeHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetSchema returns the attribute schema, tagged with its version.
+func (h *UserAttributeHandler) GetSchema(c *fiber.Ctx) error {
+	schema, err := h.service.GetSchema(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	tag := etag(schema.Version)
+	c.Set(fiber.HeaderETag, tag)
+	if noneMatch(c, tag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(schema)
+}
+
+// PutSchema publishes a new attribute schema. With If-Match, it is only
+// published if the current schema is still the version named; "0", the
+// ETag of the default schema, requires that none has been published.
+func (h *UserAttributeHandler) PutSchema(c *fiber.Ctx) error {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return errNoVersionMatches
+	}
+
+	var req models.PutUserAttributeSchemaRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	schema, err := h.service.PutSchema(c.UserContext(), version, req.Schema, principalID(c))
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(schema.Version))
+	return c.JSON(schema)
+}
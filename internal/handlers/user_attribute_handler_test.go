@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type MockUserAttributeService struct {
+	mock.Mock
+}
+
+func (m *MockUserAttributeService) GetSchema(ctx context.Context) (*models.UserAttributeSchema, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserAttributeSchema), args.Error(1)
+}
+
+func (m *MockUserAttributeService) PutSchema(ctx context.Context, version *uint, schema map[string]interface{}, by string) (*models.UserAttributeSchema, error) {
+	args := m.Called(ctx, version, schema, by)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserAttributeSchema), args.Error(1)
+}
+
+func (m *MockUserAttributeService) Validate(ctx context.Context, attributes map[string]interface{}) error {
+	args := m.Called(ctx, attributes)
+	return args.Error(0)
+}
+
+func TestUserAttributeHandler_GetSchema(t *testing.T) {
+	mockService := new(MockUserAttributeService)
+	handler := NewUserAttributeHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	mockService.On("GetSchema", mock.Anything).Return(&models.UserAttributeSchema{Version: 2, Schema: map[string]interface{}{"type": "object"}}, nil)
+	app.Get("/users/attributes/schema", handler.GetSchema)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/users/attributes/schema", nil))
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+	req := httptest.NewRequest("GET", "/users/attributes/schema", nil)
+	req.Header.Set("If-None-Match", `"2"`)
+	resp, _ = app.Test(req)
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+}
+
+func TestUserAttributeHandler_PutSchema(t *testing.T) {
+	t.Run("Published", func(t *testing.T) {
+		mockService := new(MockUserAttributeService)
+		handler := NewUserAttributeHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		version := uint(2)
+		mockService.On("PutSchema", mock.Anything, &version, mock.MatchedBy(func(schema map[string]interface{}) bool {
+			return schema["type"] == "object"
+		}), "").Return(&models.UserAttributeSchema{Version: 3}, nil)
+		app.Put("/users/attributes/schema", handler.PutSchema)
+
+		req := httptest.NewRequest("PUT", "/users/attributes/schema", strings.NewReader(`{"schema":{"type":"object"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"2"`)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"3"`, resp.Header.Get("ETag"))
+	})
+
+	t.Run("Schema Required", func(t *testing.T) {
+		handler := NewUserAttributeHandler(new(MockUserAttributeService), new(MockLogger))
+		app := newTestApp()
+		app.Put("/users/attributes/schema", handler.PutSchema)
+
+		req := httptest.NewRequest("PUT", "/users/attributes/schema", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("Stale Version", func(t *testing.T) {
+		mockService := new(MockUserAttributeService)
+		handler := NewUserAttributeHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		version := uint(1)
+		mockService.On("PutSchema", mock.Anything, &version, mock.Anything, "").Return(nil, service.ErrAttributeSchemaChanged)
+		app.Put("/users/attributes/schema", handler.PutSchema)
+
+		req := httptest.NewRequest("PUT", "/users/attributes/schema", strings.NewReader(`{"schema":{"type":"object"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"1"`)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+	})
+
+	t.Run("Invalid Schema", func(t *testing.T) {
+		mockService := new(MockUserAttributeService)
+		handler := NewUserAttributeHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("PutSchema", mock.Anything, (*uint)(nil), mock.Anything, "").Return(nil, service.ErrInvalidAttributeSchema)
+		app.Put("/users/attributes/schema", handler.PutSchema)
+
+		req := httptest.NewRequest("PUT", "/users/attributes/schema", strings.NewReader(`{"schema":{"type":"array"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	})
+}
+
+func TestUserAttributeHandler_SchemaRoundTrip(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.UserAttributeSchema{}))
+	attributes := service.NewUserAttributeService(repository.NewUserAttributeSchemaRepository(db), logger.New("error"))
+	handler := NewUserAttributeHandler(attributes, new(MockLogger))
+	app := newTestApp()
+	app.Get("/users/attributes/schema", handler.GetSchema)
+	app.Put("/users/attributes/schema", handler.PutSchema)
+
+	put := func(tag string) *http.Response {
+		req := httptest.NewRequest("PUT", "/users/attributes/schema", strings.NewReader(`{"schema":{"type":"object"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", tag)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// The default schema's ETag can be sent back to publish the first one
+	resp, err := app.Test(httptest.NewRequest("GET", "/users/attributes/schema", nil))
+	require.NoError(t, err)
+	tag := resp.Header.Get("ETag")
+	assert.Equal(t, `"0"`, tag)
+
+	resp = put(tag)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+
+	// Once one is published, "0" is stale
+	resp = put(tag)
+	assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var exportContentTypes = map[string]string{
+	models.ExportFormatCSV:    "text/csv; charset=utf-8",
+	models.ExportFormatNDJSON: "application/x-ndjson",
+	models.ExportFormatJSON:   fiber.MIMEApplicationJSON,
+}
+
+type UserExportHandler struct {
+	service       service.UserExportService
+	logger        logger.Logger
+	tracer        trace.Tracer
+	streamTimeout time.Duration
+}
+
+// NewUserExportHandler returns the export handler; streamTimeout bounds how
+// long a streamed export may keep writing after the handler returns.
+func NewUserExportHandler(service service.UserExportService, logger logger.Logger, streamTimeout time.Duration) *UserExportHandler {
+	return &UserExportHandler{
+		service:       service,
+		logger:        logger,
+		tracer:        otel.Tracer("user-export-handler"),
+		streamTimeout: streamTimeout,
+	}
+}
+
+// Export streams every user matching the listing filters, reading them from
+// a database cursor as the response is written. Rows and fields are limited
+// by the OPA row and field filters.
+func (h *UserExportHandler) Export(c *fiber.Ctx) error {
+	format, query, fields, err := parseExportRequest(c)
+	if err != nil {
+		return err
+	}
+
+	c.Attachment("users." + format)
+	c.Set(fiber.HeaderContentType, exportContentTypes[format])
+
+	// The body is written after the handler returns, when the request span
+	// has ended, so the stream keeps the request's values but gets its own
+	// span and deadline. A failure part way through can only be logged; the
+	// response ends early
+	parent := context.WithoutCancel(c.UserContext())
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(parent, h.streamTimeout)
+		defer cancel()
+		ctx, span := h.tracer.Start(ctx, "UserExportHandler.Stream")
+		defer span.End()
+
+		if _, err := h.service.Write(ctx, query, format, fields, w); err != nil {
+			span.RecordError(err)
+			h.logger.Error("Failed to stream user export: ", err)
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// Start writes the export to file storage in the background and returns the
+// job; poll Get until it completes, then download it.
+func (h *UserExportHandler) Start(c *fiber.Ctx) error {
+	format, query, fields, err := parseExportRequest(c)
+	if err != nil {
+		return err
+	}
+
+	export, err := h.service.Start(c.UserContext(), query, format, fields, principalID(c))
+	if err != nil {
+		return err
+	}
+
+	c.Location(c.Path() + "/" + export.ID)
+	return c.Status(fiber.StatusAccepted).JSON(h.exportResponse(c, export))
+}
+
+func (h *UserExportHandler) Get(c *fiber.Ctx) error {
+	export, err := h.service.Get(c.UserContext(), c.Params("id"), principalID(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(h.exportResponse(c, export))
+}
+
+func (h *UserExportHandler) Download(c *fiber.Ctx) error {
+	export, file, err := h.service.Open(c.UserContext(), c.Params("id"), principalID(c))
+	if err != nil {
+		return err
+	}
+
+	c.Attachment(export.FileName())
+	c.Set(fiber.HeaderContentType, exportContentTypes[export.Format])
+	return c.SendStream(file)
+}
+
+// exportResponse adds the download link to completed exports.
+func (h *UserExportHandler) exportResponse(c *fiber.Ctx, export *models.UserExport) fiber.Map {
+	response := fiber.Map{"export": export}
+	if export.Status == models.ExportStatusCompleted {
+		response["download_url"] = fmt.Sprintf("%s/api/v1/users/export/%s/download", c.BaseURL(), export.ID)
+	}
+	return response
+}
+
+// parseExportRequest reads the format and listing filters of an export and
+// the caller's row and field filters. Pagination parameters are ignored.
+func parseExportRequest(c *fiber.Ctx) (string, *models.UserListQuery, []string, error) {
+	format := c.Query("format", models.ExportFormatCSV)
+	if _, ok := exportContentTypes[format]; !ok {
+		return "", nil, nil, problem.New(problem.CodeInvalidRequest, fmt.Sprintf("format must be %s, %s or %s", models.ExportFormatCSV, models.ExportFormatNDJSON, models.ExportFormatJSON))
+	}
+
+	query, err := parseUserListQuery(c)
+	if err != nil {
+		return "", nil, nil, problem.Wrap(err, problem.CodeInvalidRequest, err.Error())
+	}
+	query.RowFilter, _ = c.Locals("row_filter").(*models.RowFilter)
+
+	fields, _ := c.Locals("field_filter").([]string)
+	if fields != nil && !service.CanExport(fields) {
+		return "", nil, nil, service.ErrNoExportableFields
+	}
+	return format, query, fields, nil
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+)
+
+type MockUserExportService struct {
+	mock.Mock
+}
+
+func (m *MockUserExportService) Write(ctx context.Context, query *models.UserListQuery, format string, fields []string, w io.Writer) (int, error) {
+	args := m.Called(ctx, query, format, fields, w)
+	io.WriteString(w, args.String(0))
+	return args.Int(1), args.Error(2)
+}
+
+func (m *MockUserExportService) Start(ctx context.Context, query *models.UserListQuery, format string, fields []string, createdBy string) (*models.UserExport, error) {
+	args := m.Called(ctx, query, format, fields, createdBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserExport), args.Error(1)
+}
+
+func (m *MockUserExportService) Get(ctx context.Context, id, requestedBy string) (*models.UserExport, error) {
+	args := m.Called(ctx, id, requestedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserExport), args.Error(1)
+}
+
+func (m *MockUserExportService) Open(ctx context.Context, id, requestedBy string) (*models.UserExport, io.ReadCloser, error) {
+	args := m.Called(ctx, id, requestedBy)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.UserExport), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockUserExportService) DeleteByCreator(ctx context.Context, createdBy string) (int64, error) {
+	args := m.Called(ctx, createdBy)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserExportService) Wait() {}
+
+func TestUserExportHandler_Export(t *testing.T) {
+	t.Run("Streams With The Caller's Fields", func(t *testing.T) {
+		mockService := new(MockUserExportService)
+		handler := NewUserExportHandler(mockService, new(MockLogger), time.Hour)
+		app := newTestApp()
+
+		mockService.On("Write", mock.Anything, mock.MatchedBy(func(q *models.UserListQuery) bool {
+			return q.Role == "admin" && q.Limit == 0
+		}), models.ExportFormatNDJSON, []string{"id", "username"}, mock.Anything).
+			Return("{\"id\":1,\"username\":\"alice\"}\n", 1, nil)
+
+		app.Get("/users/export", func(c *fiber.Ctx) error {
+			c.Locals("field_filter", []string{"id", "username"})
+			return c.Next()
+		}, handler.Export)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/export?format=ndjson&role=admin", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), "users.ndjson")
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "{\"id\":1,\"username\":\"alice\"}\n", string(body))
+	})
+
+	t.Run("Streams Under Its Own Deadline", func(t *testing.T) {
+		mockService := new(MockUserExportService)
+		handler := NewUserExportHandler(mockService, new(MockLogger), time.Minute)
+		app := newTestApp()
+
+		// The request's context is done by the time the body is written
+		mockService.On("Write", mock.MatchedBy(func(ctx context.Context) bool {
+			deadline, ok := ctx.Deadline()
+			return ok && ctx.Err() == nil && time.Until(deadline) <= time.Minute
+		}), mock.Anything, models.ExportFormatCSV, mock.Anything, mock.Anything).Return("id\n", 0, nil)
+
+		app.Get("/users/export", func(c *fiber.Ctx) error {
+			ctx, cancel := context.WithCancel(c.UserContext())
+			defer cancel()
+			c.SetUserContext(ctx)
+			return c.Next()
+		}, handler.Export)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/export", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "id\n", string(body))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Rejects Unknown Format", func(t *testing.T) {
+		handler := NewUserExportHandler(new(MockUserExportService), new(MockLogger), time.Hour)
+		app := newTestApp()
+		app.Get("/users/export", handler.Export)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/export?format=xml", nil))
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Rejects An Empty Field Filter", func(t *testing.T) {
+		handler := NewUserExportHandler(new(MockUserExportService), new(MockLogger), time.Hour)
+		app := newTestApp()
+		app.Get("/users/export", func(c *fiber.Ctx) error {
+			c.Locals("field_filter", []string{"password"})
+			return c.Next()
+		}, handler.Export)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/export", nil))
+
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestUserExportHandler_Get(t *testing.T) {
+	t.Run("Links Completed Exports", func(t *testing.T) {
+		mockService := new(MockUserExportService)
+		handler := NewUserExportHandler(mockService, new(MockLogger), time.Hour)
+		app := newTestApp()
+
+		mockService.On("Get", mock.Anything, "job", "").
+			Return(&models.UserExport{ID: "job", Format: models.ExportFormatCSV, Status: models.ExportStatusCompleted}, nil)
+		app.Get("/users/export/:id", handler.Get)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "http://api.example.com/users/export/job", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var response struct {
+			DownloadURL string `json:"download_url"`
+		}
+		json.NewDecoder(resp.Body).Decode(&response)
+		assert.Equal(t, "http://api.example.com/api/v1/users/export/job/download", response.DownloadURL)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockService := new(MockUserExportService)
+		handler := NewUserExportHandler(mockService, new(MockLogger), time.Hour)
+		app := newTestApp()
+
+		mockService.On("Get", mock.Anything, "job", "").Return(nil, service.ErrExportNotFound)
+		app.Get("/users/export/:id", handler.Get)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/export/job", nil))
+
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Download Not Ready", func(t *testing.T) {
+		mockService := new(MockUserExportService)
+		handler := NewUserExportHandler(mockService, new(MockLogger), time.Hour)
+		app := newTestApp()
+
+		mockService.On("Open", mock.Anything, "job", "").Return(nil, nil, service.ErrExportNotReady)
+		app.Get("/users/export/:id/download", handler.Download)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/export/job/download", nil))
+
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+}
@@ -1,153 +1,569 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/middleware"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/projection"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
 )
 
+// userFields are the fields of a user response that ?fields= can select.
+var userFields = []string{
+	"id", "email", "username", "first_name", "last_name", "roles", "status", "status_reason", "suspended_until", "attributes", "is_active", "version", "created_at", "updated_at", "deleted_at",
+}
+
 type UserHandler struct {
-	service service.UserService
-	logger  logger.Logger
+	service    service.UserService
+	logger     logger.Logger
+	projection *projection.Resource
 }
 
 func NewUserHandler(service service.UserService, logger logger.Logger) *UserHandler {
 	return &UserHandler{
-		service: service,
-		logger:  logger,
+		service:    service,
+		logger:     logger,
+		projection: projection.NewResource(userFields, "id"),
+	}
+}
+
+// Include offers a relation that user responses embed with ?include=name.
+func (h *UserHandler) Include(name string, includer projection.Includer) {
+	h.projection.Include(name, includer)
+}
+
+// includedUserIDs returns the IDs of the user responses among the items an
+// Includer is given, so it can load its relation for all of them at once.
+func includedUserIDs(items []interface{}) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		if user, ok := item.(*models.UserResponse); ok {
+			ids = append(ids, user.ID)
+		}
 	}
+	return ids
 }
 
 func (h *UserHandler) Create(c *fiber.Ctx) error {
 	var req models.CreateUserRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := bindBody(c, &req); err != nil {
+		return err
 	}
 
-	user, err := h.service.Create(c.Context(), &req)
+	user, err := h.service.Create(c.UserContext(), &req)
 	if err != nil {
-		h.logger.Error("Failed to create user: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create user",
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(user)
 }
 
 func (h *UserHandler) GetAll(c *fiber.Ctx) error {
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	pageSize, _ := strconv.Atoi(c.Query("page_size", "10"))
+	query, err := parseUserListQuery(c)
+	if err != nil {
+		return problem.Wrap(err, problem.CodeInvalidRequest, err.Error())
+	}
 
-	if page < 1 {
-		page = 1
+	// Set by the OPA row filter middleware; nil means no restriction
+	query.RowFilter, _ = c.Locals("row_filter").(*models.RowFilter)
+
+	view, err := h.projection.Parse(c)
+	if err != nil {
+		return err
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+
+	if len(query.IDs) > 0 {
+		return h.getBatch(c, query, view)
+	}
+	if query.Limit > 0 {
+		return h.getPage(c, query, view)
 	}
 
-	users, total, err := h.service.GetAll(c.Context(), page, pageSize)
+	users, total, err := h.service.GetAll(c.UserContext(), query)
 	if err != nil {
-		h.logger.Error("Failed to get users: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get users",
-		})
+		return err
+	}
+	rendered, err := view.RenderList(c.UserContext(), users)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(fiber.Map{
-		"users": users,
+		"users": rendered,
 		"pagination": fiber.Map{
-			"page":       page,
-			"page_size":  pageSize,
+			"page":       query.Page,
+			"page_size":  query.PageSize,
 			"total":      total,
-			"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+			"total_pages": (total + int64(query.PageSize) - 1) / int64(query.PageSize),
 		},
 	})
 }
 
+// getBatch serves a batch get, selected by the ids parameter.
+func (h *UserHandler) getBatch(c *fiber.Ctx, query *models.UserListQuery, view *projection.Projection) error {
+	batch, err := h.service.GetBatch(c.UserContext(), query)
+	if err != nil {
+		return err
+	}
+	rendered, err := view.RenderList(c.UserContext(), batch.Users)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"users":   rendered,
+		"missing": batch.Missing,
+	})
+}
+
+// getPage serves keyset pagination, selected by the cursor or limit
+// parameters.
+func (h *UserHandler) getPage(c *fiber.Ctx, query *models.UserListQuery, view *projection.Projection) error {
+	page, err := h.service.GetPage(c.UserContext(), query)
+	if err != nil {
+		return err
+	}
+	rendered, err := view.RenderList(c.UserContext(), page.Users)
+	if err != nil {
+		return err
+	}
+
+	pagination := fiber.Map{
+		"limit":       query.Limit,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+	}
+	if page.Total != nil {
+		pagination["total"] = *page.Total
+	}
+
+	return c.JSON(fiber.Map{
+		"users":      rendered,
+		"pagination": pagination,
+	})
+}
+
 func (h *UserHandler) GetByID(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	id, err := userIDParam(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
 
-	user, err := h.service.GetByID(c.Context(), uint(id))
+	view, err := h.projection.Parse(c)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
+		return err
+	}
+
+	// A past version of the user; its ETag would not match the user now
+	if value := c.Query("as_of"); value != "" {
+		at, err := parseTime(value)
+		if err != nil {
+			return problem.New(problem.CodeInvalidRequest, "as_of must be a date (2006-01-02) or an RFC 3339 timestamp")
 		}
-		h.logger.Error("Failed to get user: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get user",
-		})
+		user, err := h.service.GetAsOf(c.UserContext(), id, at)
+		if err != nil {
+			return err
+		}
+		rendered, err := view.Render(c.UserContext(), user)
+		if err != nil {
+			return err
+		}
+		return c.JSON(rendered)
 	}
 
-	return c.JSON(user)
+	user, err := h.service.GetByID(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	if noneMatch(c, etag(user.Version)) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	rendered, err := view.Render(c.UserContext(), user)
+	if err != nil {
+		return err
+	}
+	return c.JSON(rendered)
+}
+
+// History lists the user's revisions, oldest first: a snapshot of the user
+// after each change, with who made it and in which request.
+func (h *UserHandler) History(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := h.service.History(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"revisions": revisions,
+	})
 }
 
 func (h *UserHandler) Update(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	id, err := userIDParam(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return errNoVersionMatches
 	}
 
 	var req models.UpdateUserRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := bindBody(c, &req); err != nil {
+		return err
 	}
 
-	user, err := h.service.Update(c.Context(), uint(id), &req)
+	user, err := h.service.Update(c.UserContext(), id, version, &req)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
+		if errors.Is(err, service.ErrVersionMismatch) {
+			return versionMismatch(c, err)
 		}
-		h.logger.Error("Failed to update user: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update user",
-		})
+		return err
 	}
 
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	return c.JSON(user)
+}
+
+// acceptPatch lists the patch formats Patch understands.
+const acceptPatch = models.PatchFormatMerge + ", " + models.PatchFormatJSON
+
+// Patch applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) to a
+// user, picked by the request's Content-Type.
+func (h *UserHandler) Patch(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return errNoVersionMatches
+	}
+
+	format := strings.ToLower(strings.TrimSpace(strings.Split(c.Get(fiber.HeaderContentType), ";")[0]))
+	if format != models.PatchFormatMerge && format != models.PatchFormatJSON {
+		c.Set("Accept-Patch", acceptPatch)
+		return problem.New(problem.CodeUnsupportedMediaType, "Content-Type must be one of "+acceptPatch)
+	}
+
+	user, err := h.service.Patch(c.UserContext(), id, version, format, c.Body())
+	if err != nil {
+		if errors.Is(err, service.ErrVersionMismatch) {
+			return versionMismatch(c, err)
+		}
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
 	return c.JSON(user)
 }
 
 func (h *UserHandler) Delete(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	// purge=true removes the row for good instead of soft-deleting it
+	if c.QueryBool("purge") {
+		return h.purge(c, id)
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return errNoVersionMatches
+	}
+
+	if err := h.service.Delete(c.UserContext(), id, version); err != nil {
+		if errors.Is(err, service.ErrVersionMismatch) {
+			return versionMismatch(c, err)
+		}
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User deleted successfully",
+	})
+}
+
+func (h *UserHandler) purge(c *fiber.Ctx, id uint) error {
+	if err := h.service.Purge(c.UserContext(), id); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User permanently deleted",
+	})
+}
+
+// Restore undoes a soft delete.
+func (h *UserHandler) Restore(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
 
-	err = h.service.Delete(c.Context(), uint(id))
+	user, err := h.service.Restore(c.UserContext(), id)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
+		return err
+	}
+
+	return c.JSON(user)
+}
+
+// batchUpdateItem is one entry of a batch update response: the updated user
+// or, for an update that failed, its problem document.
+type batchUpdateItem struct {
+	ID    uint                 `json:"id"`
+	User  *models.UserResponse `json:"user,omitempty"`
+	Error *problem.Document    `json:"error,omitempty"`
+}
+
+// BatchUpdate changes the status or roles of several users in one
+// transaction. An atomic batch with failed updates is rejected as a
+// whole, listing them; a partial batch reports each update's outcome.
+func (h *UserHandler) BatchUpdate(c *fiber.Ctx) error {
+	var req models.BatchUpdateUsersRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	result, err := h.service.BatchUpdate(c.UserContext(), &req, principalID(c))
+	if errors.Is(err, service.ErrBatchFailed) {
+		appErr := middleware.ToProblem(err)
+		for i, item := range result.Items {
+			if item.Error == nil {
+				continue
+			}
+			itemErr := middleware.ToProblem(item.Error)
+			appErr.Fields = append(appErr.Fields, validation.FieldError{
+				Field:   fmt.Sprintf("updates[%d]", i),
+				Rule:    string(itemErr.Code),
+				Message: itemErr.Detail,
 			})
 		}
-		h.logger.Error("Failed to delete user: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete user",
-		})
+		return appErr
+	}
+	if err != nil {
+		return err
+	}
+
+	requestID, _ := c.Locals("request_id").(string)
+	items := make([]batchUpdateItem, len(result.Items))
+	for i, item := range result.Items {
+		items[i] = batchUpdateItem{ID: item.ID, User: item.User}
+		if item.Error != nil {
+			document := middleware.ToProblem(item.Error).Document(c.Path(), requestID)
+			items[i].Error = &document
+		}
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "User deleted successfully",
+		"mode":    result.Mode,
+		"applied": result.Applied,
+		"failed":  result.Failed(),
+		"items":   items,
 	})
-}
\ No newline at end of file
+}
+
+// parseUserListQuery reads pagination, filters, sort and search from the
+// query string. Unknown sort columns and malformed values are rejected rather
+// than ignored, so a typo does not silently return the wrong rows.
+func parseUserListQuery(c *fiber.Ctx) (*models.UserListQuery, error) {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	query := &models.UserListQuery{
+		Page:        page,
+		PageSize:    pageSize,
+		Role:        strings.TrimSpace(c.Query("role")),
+		EmailDomain: strings.TrimPrefix(strings.TrimSpace(c.Query("email_domain")), "@"),
+		Search:      strings.TrimSpace(c.Query("q")),
+	}
+
+	switch deleted := c.Query("deleted"); deleted {
+	case "", models.DeletedInclude, models.DeletedOnly:
+		query.Deleted = deleted
+	default:
+		return nil, fmt.Errorf("deleted must be %s or %s", models.DeletedOnly, models.DeletedInclude)
+	}
+
+	if status := c.Query("status"); status != "" {
+		if !models.IsUserStatus(status) {
+			return nil, fmt.Errorf("status must be one of %s", strings.Join(models.UserStatuses, ", "))
+		}
+		query.Status = status
+	}
+
+	if value := c.Query("is_active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("is_active must be true or false")
+		}
+		query.IsActive = &active
+	}
+
+	attributes, err := parseAttributeFilters(c)
+	if err != nil {
+		return nil, err
+	}
+	query.Attributes = attributes
+
+	for param, target := range map[string]**time.Time{
+		"created_after":  &query.CreatedAfter,
+		"created_before": &query.CreatedBefore,
+	} {
+		if value := c.Query(param); value != "" {
+			t, err := parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a date (2006-01-02) or an RFC 3339 timestamp", param)
+			}
+			*target = &t
+		}
+	}
+
+	if value := c.Query("ids"); value != "" {
+		ids, err := parseIDs(value)
+		if err != nil {
+			return nil, err
+		}
+		query.IDs = ids
+		return query, nil
+	}
+
+	if c.Query("cursor") != "" || c.Query("limit") != "" {
+		return parseKeysetQuery(c, query)
+	}
+
+	if value := c.Query("sort"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			desc := strings.HasPrefix(field, "-")
+			column := strings.TrimPrefix(strings.TrimPrefix(field, "-"), "+")
+			if !models.UserSortColumns[column] {
+				return nil, fmt.Errorf("cannot sort by %q", column)
+			}
+			query.Sort = append(query.Sort, models.SortField{Column: column, Desc: desc})
+		}
+	}
+
+	return query, nil
+}
+
+// parseAttributeFilters reads attributes.<key>=value filters. A value that is
+// a JSON number, boolean or null matches that value; anything else matches
+// as a string, so attributes.plan=pro and attributes.plan="pro" agree.
+func parseAttributeFilters(c *fiber.Ctx) (map[string]interface{}, error) {
+	var filters map[string]interface{}
+	var err error
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		name, ok := strings.CutPrefix(string(key), "attributes.")
+		if !ok || err != nil {
+			return
+		}
+		if name == "" {
+			err = fmt.Errorf("attribute filters must name an attribute, as in attributes.<key>=value")
+			return
+		}
+		if filters == nil {
+			filters = map[string]interface{}{}
+		}
+		var decoded interface{}
+		if json.Unmarshal(value, &decoded) == nil {
+			switch decoded.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				filters[name] = decoded
+				return
+			}
+		}
+		filters[name] = string(value)
+	})
+	return filters, err
+}
+
+// parseKeysetQuery completes query for keyset pagination, which always
+// orders by (created_at, id): sort may only choose the direction.
+func parseKeysetQuery(c *fiber.Ctx, query *models.UserListQuery) (*models.UserListQuery, error) {
+	query.Limit = 20
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 100 {
+			return nil, fmt.Errorf("limit must be between 1 and 100")
+		}
+		query.Limit = limit
+	}
+
+	switch c.Query("sort") {
+	case "", "created_at":
+	case "-created_at":
+		query.Desc = true
+	default:
+		return nil, fmt.Errorf("cursor pagination only supports sort=created_at or sort=-created_at")
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := models.DecodeCursor(value)
+		if err != nil || cursor.Desc != query.Desc {
+			return nil, models.ErrInvalidCursor
+		}
+		query.Cursor = cursor
+	}
+
+	query.IncludeTotal = c.QueryBool("include_total")
+	return query, nil
+}
+
+// parseIDs reads the comma-separated user IDs of a batch get, dropping
+// repeats.
+func parseIDs(value string) ([]uint, error) {
+	var ids []uint
+	seen := map[uint]bool{}
+	for _, field := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("ids must be a comma-separated list of user IDs")
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) > models.MaxUserBatchSize {
+		return nil, fmt.Errorf("at most %d ids can be requested at once", models.MaxUserBatchSize)
+	}
+	return ids, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
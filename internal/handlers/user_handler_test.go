@@ -6,17 +6,30 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/middleware"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
 )
 
+// newTestApp returns an app that renders handler errors the way the server
+// does.
+func newTestApp() *fiber.App {
+	return fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler(logger.New("error"))})
+}
+
 type MockUserService struct {
 	mock.Mock
 }
@@ -29,14 +42,22 @@ func (m *MockUserService) Create(ctx context.Context, req *models.CreateUserRequ
 	return args.Get(0).(*models.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) GetAll(ctx context.Context, page, pageSize int) ([]*models.UserResponse, int64, error) {
-	args := m.Called(ctx, page, pageSize)
+func (m *MockUserService) GetAll(ctx context.Context, query *models.UserListQuery) ([]*models.UserResponse, int64, error) {
+	args := m.Called(ctx, query)
 	if args.Get(0) == nil {
 		return nil, args.Get(1).(int64), args.Error(2)
 	}
 	return args.Get(0).([]*models.UserResponse), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockUserService) GetPage(ctx context.Context, query *models.UserListQuery) (*models.UserPage, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPage), args.Error(1)
+}
+
 func (m *MockUserService) GetByID(ctx context.Context, id uint) (*models.UserResponse, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -45,6 +66,22 @@ func (m *MockUserService) GetByID(ctx context.Context, id uint) (*models.UserRes
 	return args.Get(0).(*models.UserResponse), args.Error(1)
 }
 
+func (m *MockUserService) GetAsOf(ctx context.Context, id uint, at time.Time) (*models.UserResponse, error) {
+	args := m.Called(ctx, id, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) History(ctx context.Context, id uint) ([]*models.UserRevision, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.UserRevision), args.Error(1)
+}
+
 func (m *MockUserService) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -53,19 +90,56 @@ func (m *MockUserService) GetByEmail(ctx context.Context, email string) (*models
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserService) Update(ctx context.Context, id uint, req *models.UpdateUserRequest) (*models.UserResponse, error) {
-	args := m.Called(ctx, id, req)
+func (m *MockUserService) Update(ctx context.Context, id uint, version uint, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+	args := m.Called(ctx, id, version, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) Patch(ctx context.Context, id uint, version uint, format string, patch []byte) (*models.UserResponse, error) {
+	args := m.Called(ctx, id, version, format, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) Delete(ctx context.Context, id uint, version uint) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *MockUserService) Restore(ctx context.Context, id uint) (*models.UserResponse, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) Delete(ctx context.Context, id uint) error {
+func (m *MockUserService) Purge(ctx context.Context, id uint) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockUserService) GetBatch(ctx context.Context, query *models.UserListQuery) (*models.UserBatch, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserBatch), args.Error(1)
+}
+
+func (m *MockUserService) BatchUpdate(ctx context.Context, req *models.BatchUpdateUsersRequest, by string) (*models.BatchUpdateResult, error) {
+	args := m.Called(ctx, req, by)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BatchUpdateResult), args.Error(1)
+}
+
 func (m *MockUserService) CreateUser(user *models.User) (*models.User, error) {
 	args := m.Called(user)
 	if args.Get(0) == nil {
@@ -136,7 +210,7 @@ func TestUserHandler_Create(t *testing.T) {
 		mockService := new(MockUserService)
 		mockLogger := new(MockLogger)
 		handler := NewUserHandler(mockService, mockLogger)
-		app := fiber.New()
+		app := newTestApp()
 		
 		req := &models.CreateUserRequest{
 			Email:     "test@example.com",
@@ -179,7 +253,7 @@ func TestUserHandler_Create(t *testing.T) {
 		mockService := new(MockUserService)
 		mockLogger := new(MockLogger)
 		handler := NewUserHandler(mockService, mockLogger)
-		app := fiber.New()
+		app := newTestApp()
 		app.Post("/users", handler.Create)
 
 		request := httptest.NewRequest("POST", "/users", bytes.NewReader([]byte("invalid json")))
@@ -194,15 +268,15 @@ func TestUserHandler_Create(t *testing.T) {
 		mockService := new(MockUserService)
 		mockLogger := new(MockLogger)
 		handler := NewUserHandler(mockService, mockLogger)
-		app := fiber.New()
+		app := newTestApp()
 		
 		req := &models.CreateUserRequest{
 			Email:    "test@example.com",
 			Username: "testuser",
+			Password: "password123",
 		}
 
 		mockService.On("Create", mock.Anything, req).Return(nil, errors.New("service error"))
-		mockLogger.On("Error", "Failed to create user: ", mock.Anything).Return()
 
 		app.Post("/users", handler.Create)
 
@@ -215,6 +289,71 @@ func TestUserHandler_Create(t *testing.T) {
 		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		req := &models.CreateUserRequest{
+			Email:    "test@example.com",
+			Username: "testuser",
+			Password: "password123",
+		}
+
+		mockService.On("Create", mock.Anything, req).Return(nil, service.ErrUserAlreadyExists)
+
+		app.Post("/users", handler.Create)
+
+		reqBody, _ := json.Marshal(req)
+		request := httptest.NewRequest("POST", "/users", bytes.NewReader(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+		assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+		var body problem.Document
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Equal(t, problem.CodeUserAlreadyExists, body.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestUserHandler_CreateValidation(t *testing.T) {
+	cases := map[string]struct {
+		body  string
+		field string
+		rule  string
+	}{
+		"missing email":     {`{"username":"alice","password":"secret1"}`, "email", "required"},
+		"invalid email":     {`{"email":"alice","username":"alice","password":"secret1"}`, "email", "email"},
+		"short password":    {`{"email":"alice@example.com","username":"alice","password":"x"}`, "password", "min"},
+		"username charset":  {`{"email":"alice@example.com","username":"al ice","password":"secret1"}`, "username", "username"},
+		"reserved username": {`{"email":"alice@example.com","username":"Admin","password":"secret1"}`, "username", "unreserved"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			handler := NewUserHandler(mockService, new(MockLogger))
+			app := newTestApp()
+			app.Post("/users", handler.Create)
+
+			request := httptest.NewRequest("POST", "/users", bytes.NewReader([]byte(tc.body)))
+			request.Header.Set("Content-Type", "application/json")
+			resp, _ := app.Test(request)
+
+			assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+			var body struct {
+				Errors []validation.FieldError `json:"errors"`
+			}
+			json.NewDecoder(resp.Body).Decode(&body)
+			assert.Len(t, body.Errors, 1)
+			assert.Equal(t, tc.field, body.Errors[0].Field)
+			assert.Equal(t, tc.rule, body.Errors[0].Rule)
+			mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
 }
 
 func TestUserHandler_GetByID(t *testing.T) {
@@ -222,7 +361,7 @@ func TestUserHandler_GetByID(t *testing.T) {
 		mockService := new(MockUserService)
 		mockLogger := new(MockLogger)
 		handler := NewUserHandler(mockService, mockLogger)
-		app := fiber.New()
+		app := newTestApp()
 		
 		expectedUser := &models.UserResponse{
 			ID:       1,
@@ -251,7 +390,7 @@ func TestUserHandler_GetByID(t *testing.T) {
 		mockService := new(MockUserService)
 		mockLogger := new(MockLogger)
 		handler := NewUserHandler(mockService, mockLogger)
-		app := fiber.New()
+		app := newTestApp()
 		
 		mockService.On("GetByID", mock.Anything, uint(999)).Return(nil, service.ErrUserNotFound)
 
@@ -263,4 +402,650 @@ func TestUserHandler_GetByID(t *testing.T) {
 		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
 		mockService.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+
+func TestUserHandler_GetAsOf(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := NewUserHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockService.On("GetAsOf", mock.Anything, uint(1), at).Return(&models.UserResponse{ID: 1, Username: "before", Version: 2}, nil)
+	app.Get("/users/:id", handler.GetByID)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/users/1?as_of=2026-03-01T12:00:00Z", nil))
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("ETag"))
+	var response models.UserResponse
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Equal(t, "before", response.Username)
+	mockService.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+
+	resp, _ = app.Test(httptest.NewRequest("GET", "/users/1?as_of=yesterday", nil))
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestUserHandler_History(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := NewUserHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	mockService.On("History", mock.Anything, uint(1)).Return([]*models.UserRevision{
+		{ID: 1, UserID: 1, Version: 1, Operation: models.RevisionCreate, User: &models.UserResponse{ID: 1, Username: "before"}},
+		{ID: 2, UserID: 1, Version: 2, Operation: models.RevisionUpdate, User: &models.UserResponse{ID: 1, Username: "after"}, ChangedBy: "9", RequestID: "req-1"},
+	}, nil)
+	mockService.On("History", mock.Anything, uint(2)).Return(nil, service.ErrUserNotFound)
+	app.Get("/users/:id/history", handler.History)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/users/1/history", nil))
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response struct {
+		Revisions []models.UserRevision `json:"revisions"`
+	}
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Len(t, response.Revisions, 2)
+	assert.Equal(t, "after", response.Revisions[1].User.Username)
+	assert.Equal(t, "req-1", response.Revisions[1].RequestID)
+
+	resp, _ = app.Test(httptest.NewRequest("GET", "/users/2/history", nil))
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestUserHandler_GetAll(t *testing.T) {
+	t.Run("Filters Sort And Search", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockLogger := new(MockLogger)
+		handler := NewUserHandler(mockService, mockLogger)
+		app := newTestApp()
+
+		mockService.On("GetAll", mock.Anything, mock.MatchedBy(func(q *models.UserListQuery) bool {
+			return q.Page == 2 && q.PageSize == 20 &&
+				q.IsActive != nil && *q.IsActive &&
+				q.Status == models.UserStatusActive &&
+				q.Role == "admin" &&
+				q.EmailDomain == "example.com" &&
+				q.CreatedAfter != nil && q.CreatedAfter.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) &&
+				q.Search == "jo" &&
+				len(q.Sort) == 2 &&
+				q.Sort[0] == models.SortField{Column: "created_at", Desc: true} &&
+				q.Sort[1] == models.SortField{Column: "username"}
+		})).Return([]*models.UserResponse{{ID: 1}}, int64(21), nil)
+
+		app.Get("/users", handler.GetAll)
+
+		request := httptest.NewRequest("GET", "/users?page=2&page_size=20&is_active=true&status=active&role=admin&email_domain=@example.com&created_after=2024-01-01&q=jo&sort=-created_at,username", nil)
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var response struct {
+			Pagination struct {
+				TotalPages int64 `json:"total_pages"`
+			} `json:"pagination"`
+		}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &response)
+
+		assert.Equal(t, int64(2), response.Pagination.TotalPages)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Rejects Unknown Sort Column", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockLogger := new(MockLogger)
+		handler := NewUserHandler(mockService, mockLogger)
+		app := newTestApp()
+		app.Get("/users", handler.GetAll)
+
+		request := httptest.NewRequest("GET", "/users?sort=password", nil)
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects Malformed Dates", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockLogger := new(MockLogger)
+		handler := NewUserHandler(mockService, mockLogger)
+		app := newTestApp()
+		app.Get("/users", handler.GetAll)
+
+		request := httptest.NewRequest("GET", "/users?created_before=yesterday", nil)
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+	t.Run("Cursor Pagination", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockLogger := new(MockLogger)
+		handler := NewUserHandler(mockService, mockLogger)
+		app := newTestApp()
+
+		cursor := models.Cursor{ID: 7, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Desc: true}
+		total := int64(42)
+		mockService.On("GetPage", mock.Anything, mock.MatchedBy(func(q *models.UserListQuery) bool {
+			return q.Limit == 5 && q.Desc && q.IncludeTotal && q.Cursor != nil && q.Cursor.ID == 7
+		})).Return(&models.UserPage{Users: []*models.UserResponse{{ID: 6}}, NextCursor: "next", Total: &total}, nil)
+
+		app.Get("/users", handler.GetAll)
+
+		request := httptest.NewRequest("GET", "/users?limit=5&sort=-created_at&include_total=true&cursor="+cursor.Encode(), nil)
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var response struct {
+			Pagination map[string]interface{} `json:"pagination"`
+		}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &response)
+
+		assert.Equal(t, "next", response.Pagination["next_cursor"])
+		assert.Equal(t, float64(42), response.Pagination["total"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Rejects Cursor For Other Order", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockLogger := new(MockLogger)
+		handler := NewUserHandler(mockService, mockLogger)
+		app := newTestApp()
+		app.Get("/users", handler.GetAll)
+
+		cursor := models.Cursor{ID: 7, CreatedAt: time.Now(), Desc: true}
+		request := httptest.NewRequest("GET", "/users?cursor="+cursor.Encode(), nil)
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "GetPage", mock.Anything, mock.Anything)
+	})
+}
+func TestUserHandler_Preconditions(t *testing.T) {
+	t.Run("ETag And Not Modified", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(&models.UserResponse{ID: 1, Version: 4}, nil)
+		app.Get("/users/:id", handler.GetByID)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/1", nil))
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"4"`, resp.Header.Get("ETag"))
+
+		req := httptest.NewRequest("GET", "/users/1", nil)
+		req.Header.Set("If-None-Match", `"3", W/"4"`)
+		resp, _ = app.Test(req)
+		assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	})
+
+	t.Run("If-Match Is Passed On", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Update", mock.Anything, uint(1), uint(4), mock.Anything).Return(&models.UserResponse{ID: 1, Version: 5}, nil)
+		app.Put("/users/:id", handler.Update)
+
+		req := httptest.NewRequest("PUT", "/users/1", bytes.NewReader([]byte(`{"first_name":"Jane"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"4"`)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"5"`, resp.Header.Get("ETag"))
+	})
+
+	t.Run("Stale If-Match", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Delete", mock.Anything, uint(1), uint(3)).Return(service.ErrVersionMismatch)
+		app.Delete("/users/:id", handler.Delete)
+
+		req := httptest.NewRequest("DELETE", "/users/1", nil)
+		req.Header.Set("If-Match", `"3"`)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+	})
+
+	t.Run("Weak If-Match Never Matches", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+		app.Delete("/users/:id", handler.Delete)
+
+		req := httptest.NewRequest("DELETE", "/users/1", nil)
+		req.Header.Set("If-Match", `W/"3"`)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Concurrent Change Without If-Match", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Delete", mock.Anything, uint(1), uint(0)).Return(service.ErrVersionMismatch)
+		app.Delete("/users/:id", handler.Delete)
+
+		resp, _ := app.Test(httptest.NewRequest("DELETE", "/users/1", nil))
+
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("If-Match Required", func(t *testing.T) {
+		handler := NewUserHandler(new(MockUserService), new(MockLogger))
+		app := newTestApp()
+		app.Put("/users/:id", middleware.RequireIfMatch(nil), handler.Update)
+
+		resp, _ := app.Test(httptest.NewRequest("PUT", "/users/1", nil))
+
+		assert.Equal(t, fiber.StatusPreconditionRequired, resp.StatusCode)
+	})
+}
+
+func TestUserHandler_Patch(t *testing.T) {
+	t.Run("Merge Patch", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		patch := []byte(`{"last_name":null}`)
+		mockService.On("Patch", mock.Anything, uint(1), uint(0), models.PatchFormatMerge, patch).Return(&models.UserResponse{ID: 1}, nil)
+		app.Patch("/users/:id", handler.Patch)
+
+		req := httptest.NewRequest("PATCH", "/users/1", bytes.NewReader(patch))
+		req.Header.Set("Content-Type", "application/merge-patch+json; charset=utf-8")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Unsupported Media Type", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+		app.Patch("/users/:id", handler.Patch)
+
+		req := httptest.NewRequest("PATCH", "/users/1", bytes.NewReader([]byte(`{}`)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusUnsupportedMediaType, resp.StatusCode)
+		assert.Equal(t, "application/merge-patch+json, application/json-patch+json", resp.Header.Get("Accept-Patch"))
+		mockService.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	statuses := map[error]int{
+		service.ErrInvalidPatch:       fiber.StatusBadRequest,
+		service.ErrUnprocessablePatch: fiber.StatusUnprocessableEntity,
+		service.ErrPatchTestFailed:    fiber.StatusConflict,
+		service.ErrUserNotFound:       fiber.StatusNotFound,
+	}
+	for err, status := range statuses {
+		t.Run(err.Error(), func(t *testing.T) {
+			mockService := new(MockUserService)
+			handler := NewUserHandler(mockService, new(MockLogger))
+			app := newTestApp()
+
+			mockService.On("Patch", mock.Anything, uint(1), uint(0), models.PatchFormatJSON, mock.Anything).Return(nil, err)
+			app.Patch("/users/:id", handler.Patch)
+
+			req := httptest.NewRequest("PATCH", "/users/1", bytes.NewReader([]byte(`[]`)))
+			req.Header.Set("Content-Type", "application/json-patch+json")
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, status, resp.StatusCode)
+		})
+	}
+}
+
+func TestUserHandler_Delete(t *testing.T) {
+	t.Run("Soft Deletes", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Delete", mock.Anything, uint(1), uint(0)).Return(nil)
+		app.Delete("/users/:id", handler.Delete)
+
+		resp, _ := app.Test(httptest.NewRequest("DELETE", "/users/1", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Purges", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Purge", mock.Anything, uint(1)).Return(nil)
+		app.Delete("/users/:id", handler.Delete)
+
+		resp, _ := app.Test(httptest.NewRequest("DELETE", "/users/1?purge=true", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserHandler_Restore(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Restore", mock.Anything, uint(1)).Return(&models.UserResponse{ID: 1}, nil)
+		app.Post("/users/:id/restore", handler.Restore)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/users/1/restore", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Email Taken", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Restore", mock.Anything, uint(1)).Return(nil, service.ErrUserAlreadyExists)
+		app.Post("/users/:id/restore", handler.Restore)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/users/1/restore", nil))
+
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+}
+
+func TestUserHandler_GetAllDeleted(t *testing.T) {
+	t.Run("Lists Deleted Users", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("GetAll", mock.Anything, mock.MatchedBy(func(q *models.UserListQuery) bool {
+			return q.Deleted == models.DeletedOnly
+		})).Return([]*models.UserResponse{}, int64(0), nil)
+		app.Get("/users", handler.GetAll)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users?deleted=only", nil))
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		resp, _ = app.Test(httptest.NewRequest("GET", "/users?deleted=yes", nil))
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestUserHandler_GetAllByAttributes(t *testing.T) {
+	t.Run("Filters By Attribute Values", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("GetAll", mock.Anything, mock.MatchedBy(func(q *models.UserListQuery) bool {
+			return len(q.Attributes) == 3 &&
+				q.Attributes["plan"] == "pro" &&
+				q.Attributes["seats"] == float64(5) &&
+				q.Attributes["beta"] == true
+		})).Return([]*models.UserResponse{}, int64(0), nil)
+		app.Get("/users", handler.GetAll)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users?attributes.plan=pro&attributes.seats=5&attributes.beta=true", nil))
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Attribute Name Required", func(t *testing.T) {
+		handler := NewUserHandler(new(MockUserService), new(MockLogger))
+		app := newTestApp()
+		app.Get("/users", handler.GetAll)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users?attributes.=pro", nil))
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestUserHandler_GetBatch(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockLogger := new(MockLogger)
+		handler := NewUserHandler(mockService, mockLogger)
+		app := newTestApp()
+
+		mockService.On("GetBatch", mock.Anything, mock.MatchedBy(func(q *models.UserListQuery) bool {
+			return len(q.IDs) == 2 && q.IDs[0] == 3 && q.IDs[1] == 1
+		})).Return(&models.UserBatch{Users: []*models.UserResponse{{ID: 3}}, Missing: []uint{1}}, nil)
+
+		app.Get("/users", handler.GetAll)
+
+		request := httptest.NewRequest("GET", "/users?ids=3,1,3", nil)
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var response models.UserBatch
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &response)
+
+		assert.Len(t, response.Users, 1)
+		assert.Equal(t, []uint{1}, response.Missing)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Rejects Malformed IDs", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockLogger := new(MockLogger)
+		handler := NewUserHandler(mockService, mockLogger)
+		app := newTestApp()
+		app.Get("/users", handler.GetAll)
+
+		request := httptest.NewRequest("GET", "/users?ids=1,abc", nil)
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "GetBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects Too Many IDs", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockLogger := new(MockLogger)
+		handler := NewUserHandler(mockService, mockLogger)
+		app := newTestApp()
+		app.Get("/users", handler.GetAll)
+
+		ids := make([]string, models.MaxUserBatchSize+1)
+		for i := range ids {
+			ids[i] = strconv.Itoa(i + 1)
+		}
+		request := httptest.NewRequest("GET", "/users?ids="+strings.Join(ids, ","), nil)
+		resp, _ := app.Test(request)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestUserHandler_BatchUpdate(t *testing.T) {
+	newApp := func(mockService *MockUserService) *fiber.App {
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+		app.Post("/users\\:batchUpdate", handler.BatchUpdate)
+		return app
+	}
+	post := func(app *fiber.App, body string) *http.Response {
+		request := httptest.NewRequest("POST", "/users:batchUpdate", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(request)
+		return resp
+	}
+
+	t.Run("Partial", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockService.On("BatchUpdate", mock.Anything, mock.MatchedBy(func(req *models.BatchUpdateUsersRequest) bool {
+			return req.Mode == models.BatchModePartial && len(req.Updates) == 2 && req.Updates[0].Status == models.UserStatusLocked
+		}), "").Return(&models.BatchUpdateResult{
+			Mode:    models.BatchModePartial,
+			Applied: true,
+			Items: []*models.BatchUpdateItem{
+				{ID: 1, User: &models.UserResponse{ID: 1}},
+				{ID: 2, Error: service.ErrUserNotFound},
+			},
+		}, nil)
+
+		resp := post(newApp(mockService), `{"mode":"partial","updates":[{"id":1,"status":"locked","reason":"fraud"},{"id":2,"roles":["admin"]}]}`)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var response struct {
+			Failed int `json:"failed"`
+			Items  []struct {
+				ID    uint                 `json:"id"`
+				User  *models.UserResponse `json:"user"`
+				Error *problem.Document    `json:"error"`
+			} `json:"items"`
+		}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &response)
+
+		assert.Equal(t, 1, response.Failed)
+		assert.NotNil(t, response.Items[0].User)
+		assert.Nil(t, response.Items[0].Error)
+		assert.Equal(t, problem.CodeUserNotFound, response.Items[1].Error.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Atomic Failure", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockService.On("BatchUpdate", mock.Anything, mock.Anything, "").Return(&models.BatchUpdateResult{
+			Mode: models.BatchModeAtomic,
+			Items: []*models.BatchUpdateItem{
+				{ID: 1},
+				{ID: 2, Error: service.ErrVersionMismatch},
+			},
+		}, service.ErrBatchFailed)
+
+		resp := post(newApp(mockService), `{"updates":[{"id":1,"status":"active"},{"id":2,"version":3,"status":"active"}]}`)
+
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+		var document problem.Document
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &document)
+
+		assert.Equal(t, problem.CodeBatchUpdateFailed, document.Code)
+		assert.Equal(t, []validation.FieldError{{
+			Field:   "updates[1]",
+			Rule:    string(problem.CodeConcurrentModification),
+			Message: "User was modified concurrently, please retry",
+		}}, document.Errors)
+	})
+
+	t.Run("Validates Updates", func(t *testing.T) {
+		mockService := new(MockUserService)
+
+		resp := post(newApp(mockService), `{"mode":"all","updates":[{"status":"suspended"}]}`)
+
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+		var document problem.Document
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &document)
+
+		fields := map[string]string{}
+		for _, field := range document.Errors {
+			fields[field.Field] = field.Rule
+		}
+		assert.Equal(t, map[string]string{"mode": "oneof", "updates[0].id": "required", "updates[0].status": "oneof"}, fields)
+		mockService.AssertNotCalled(t, "BatchUpdate", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserHandler_Projection(t *testing.T) {
+	user := &models.UserResponse{ID: 1, Email: "test@example.com", Username: "testuser", Version: 2}
+
+	t.Run("Selects Fields", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(user, nil)
+		app.Get("/users/:id", handler.GetByID)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/1?fields=username,email", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"2"`, resp.Header.Get(fiber.HeaderETag))
+		body, _ := io.ReadAll(resp.Body)
+		assert.JSONEq(t, `{"id":1,"username":"testuser","email":"test@example.com"}`, string(body))
+	})
+
+	t.Run("Rejects Unknown Field", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+		app.Get("/users", handler.GetAll)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users?fields=password", nil))
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects Unknown Include", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		handler.Include("groups", NewGroupHandler(new(MockGroupService), new(MockLogger)).IncludeGroups)
+		app := newTestApp()
+		app.Get("/users/:id", handler.GetByID)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users/1?include=sessions", nil))
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		var doc problem.Document
+		json.NewDecoder(resp.Body).Decode(&doc)
+		assert.Equal(t, `Unknown include "sessions"; includes are groups`, doc.Detail)
+	})
+
+	t.Run("Rejects Fields The Policy Hides", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+		app.Get("/users", func(c *fiber.Ctx) error {
+			c.Locals("field_filter", []string{"id", "username"})
+			return c.Next()
+		}, handler.GetAll)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users?fields=username,roles", nil))
+
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Cursor Pages", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("GetPage", mock.Anything, mock.Anything).
+			Return(&models.UserPage{Users: []*models.UserResponse{user}, NextCursor: "next"}, nil)
+		app.Get("/users", handler.GetAll)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/users?limit=1&fields=username", nil))
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var response struct {
+			Users []map[string]interface{} `json:"users"`
+		}
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &response)
+		assert.Equal(t, []map[string]interface{}{{"id": float64(1), "username": "testuser"}}, response.Users)
+	})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type UserImportHandler struct {
+	service service.UserImportService
+	logger  logger.Logger
+}
+
+func NewUserImportHandler(service service.UserImportService, logger logger.Logger) *UserImportHandler {
+	return &UserImportHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Start accepts a CSV or NDJSON file, either as the request body or as the
+// "file" field of a multipart form, and starts importing it in the
+// background. The format comes from ?format=, else from the content type or
+// file extension.
+func (h *UserImportHandler) Start(c *fiber.Ctx) error {
+	req := &models.StartUserImportRequest{
+		Format:  c.Query("format"),
+		Mode:    c.Query("mode", models.ImportModeCreate),
+		DryRun:  c.QueryBool("dry_run"),
+		Onboard: c.QueryBool("onboard"),
+	}
+
+	var file io.Reader
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return problem.Wrap(err, problem.CodeInvalidImport, "Missing file field")
+		}
+		opened, err := header.Open()
+		if err != nil {
+			return problem.Wrap(err, problem.CodeInvalidImport, "Invalid file")
+		}
+		defer opened.Close()
+		file = opened
+		if req.Format == "" {
+			req.Format = importFormat(header.Header.Get(fiber.HeaderContentType), header.Filename)
+		}
+	} else {
+		file = bytes.NewReader(c.Body())
+		if req.Format == "" {
+			req.Format = importFormat(c.Get(fiber.HeaderContentType), "")
+		}
+	}
+
+	job, err := h.service.Start(c.UserContext(), req, file, principalID(c))
+	if err != nil {
+		return err
+	}
+
+	c.Location(c.Path() + "/" + job.ID)
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// Get reports an import's progress and the rows that failed.
+func (h *UserImportHandler) Get(c *fiber.Ctx) error {
+	report, err := h.service.Get(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(report)
+}
+
+// importFormat infers the import format from a content type or file name.
+func importFormat(contentType, filename string) string {
+	contentType = strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	switch contentType {
+	case "text/csv":
+		return models.ImportFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return models.ImportFormatNDJSON
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return models.ImportFormatCSV
+	case ".ndjson", ".jsonl":
+		return models.ImportFormatNDJSON
+	}
+	return ""
+}
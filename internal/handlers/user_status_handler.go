@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type UserStatusHandler struct {
+	service service.UserStatusService
+	logger  logger.Logger
+}
+
+func NewUserStatusHandler(service service.UserStatusService, logger logger.Logger) *UserStatusHandler {
+	return &UserStatusHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Suspend suspends a user, until a given time or until it is reactivated.
+func (h *UserStatusHandler) Suspend(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return errNoVersionMatches
+	}
+
+	var req models.SuspendUserRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	user, err := h.service.Suspend(c.UserContext(), id, version, &req, principalID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrVersionMismatch) {
+			return versionMismatch(c, err)
+		}
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	return c.JSON(user)
+}
+
+// Reactivate makes a suspended, locked, deactivated or unverified user
+// active.
+func (h *UserStatusHandler) Reactivate(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return errNoVersionMatches
+	}
+
+	var req models.ReactivateUserRequest
+	if len(c.Body()) > 0 {
+		if err := bindBody(c, &req); err != nil {
+			return err
+		}
+	}
+
+	user, err := h.service.Reactivate(c.UserContext(), id, version, &req, principalID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrVersionMismatch) {
+			return versionMismatch(c, err)
+		}
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	return c.JSON(user)
+}
+
+// History lists a user's status changes, oldest first.
+func (h *UserStatusHandler) History(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	transitions, err := h.service.History(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"transitions": transitions,
+	})
+}
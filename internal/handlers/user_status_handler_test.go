@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+)
+
+type MockUserStatusService struct {
+	mock.Mock
+}
+
+func (m *MockUserStatusService) Suspend(ctx context.Context, id uint, version uint, req *models.SuspendUserRequest, by string) (*models.UserResponse, error) {
+	args := m.Called(ctx, id, version, req, by)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserResponse), args.Error(1)
+}
+
+func (m *MockUserStatusService) Reactivate(ctx context.Context, id uint, version uint, req *models.ReactivateUserRequest, by string) (*models.UserResponse, error) {
+	args := m.Called(ctx, id, version, req, by)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserResponse), args.Error(1)
+}
+
+func (m *MockUserStatusService) EndSuspension(ctx context.Context, id uint, until time.Time) error {
+	args := m.Called(ctx, id, until)
+	return args.Error(0)
+}
+
+func (m *MockUserStatusService) History(ctx context.Context, id uint) ([]*models.UserStatusTransition, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.UserStatusTransition), args.Error(1)
+}
+
+func (m *MockUserStatusService) CheckAccount(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestUserStatusHandler_Suspend(t *testing.T) {
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Suspended", func(t *testing.T) {
+		mockService := new(MockUserStatusService)
+		handler := NewUserStatusHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Suspend", mock.Anything, uint(42), uint(3), mock.MatchedBy(func(req *models.SuspendUserRequest) bool {
+			return req.Reason == "spam" && req.Until.Equal(until)
+		}), "").Return(&models.UserResponse{ID: 42, Status: models.UserStatusSuspended, SuspendedUntil: &until, Version: 4}, nil)
+		app.Post("/users/:id/suspend", handler.Suspend)
+
+		req := httptest.NewRequest("POST", "/users/42/suspend", strings.NewReader(`{"reason":"spam","until":"2030-01-01T00:00:00Z"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"3"`)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"4"`, resp.Header.Get("ETag"))
+	})
+
+	t.Run("Reason Required", func(t *testing.T) {
+		mockService := new(MockUserStatusService)
+		handler := NewUserStatusHandler(mockService, new(MockLogger))
+		app := newTestApp()
+		app.Post("/users/:id/suspend", handler.Suspend)
+
+		req := httptest.NewRequest("POST", "/users/42/suspend", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Suspend", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid Transition", func(t *testing.T) {
+		mockService := new(MockUserStatusService)
+		handler := NewUserStatusHandler(mockService, new(MockLogger))
+		app := newTestApp()
+
+		mockService.On("Suspend", mock.Anything, uint(42), uint(0), mock.Anything, "").
+			Return(nil, fmt.Errorf("%w: a deactivated user cannot become suspended", service.ErrInvalidStatusTransition))
+		app.Post("/users/:id/suspend", handler.Suspend)
+
+		req := httptest.NewRequest("POST", "/users/42/suspend", strings.NewReader(`{"reason":"spam"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+		var document problem.Document
+		json.NewDecoder(resp.Body).Decode(&document)
+		assert.Equal(t, problem.CodeInvalidStatusTransition, document.Code)
+	})
+}
+
+func TestUserStatusHandler_Reactivate(t *testing.T) {
+	mockService := new(MockUserStatusService)
+	handler := NewUserStatusHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	// The body is optional
+	mockService.On("Reactivate", mock.Anything, uint(42), uint(0), &models.ReactivateUserRequest{}, "").
+		Return(&models.UserResponse{ID: 42, Status: models.UserStatusActive, IsActive: true, Version: 5}, nil)
+	app.Post("/users/:id/reactivate", handler.Reactivate)
+
+	resp, _ := app.Test(httptest.NewRequest("POST", "/users/42/reactivate", nil))
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"5"`, resp.Header.Get("ETag"))
+}
+
+func TestUserStatusHandler_History(t *testing.T) {
+	mockService := new(MockUserStatusService)
+	handler := NewUserStatusHandler(mockService, new(MockLogger))
+	app := newTestApp()
+
+	mockService.On("History", mock.Anything, uint(42)).Return([]*models.UserStatusTransition{
+		{ID: 1, UserID: 42, From: models.UserStatusActive, To: models.UserStatusLocked, ChangedBy: "1"},
+	}, nil)
+	mockService.On("History", mock.Anything, uint(43)).Return(nil, service.ErrUserNotFound)
+	app.Get("/users/:id/status-history", handler.History)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/users/42/status-history", nil))
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response struct {
+		Transitions []models.UserStatusTransition `json:"transitions"`
+	}
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Len(t, response.Transitions, 1)
+	assert.Equal(t, models.UserStatusLocked, response.Transitions[0].To)
+
+	resp, _ = app.Test(httptest.NewRequest("GET", "/users/43/status-history", nil))
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
+)
+
+// bindBody decodes the request body into out and checks it against the
+// rules in its struct tags.
+func bindBody(c *fiber.Ctx, out interface{}) error {
+	if err := c.BodyParser(out); err != nil {
+		return problem.Wrap(err, problem.CodeInvalidRequest, "Invalid request body")
+	}
+	if err := validation.Validate(out); err != nil {
+		var fieldErrs validation.Errors
+		errors.As(err, &fieldErrs)
+		return &problem.Error{Code: problem.CodeValidationFailed, Detail: "Request body failed validation", Fields: fieldErrs, Err: err}
+	}
+	return nil
+}
+
+// userIDParam reads the user ID from the :id route parameter.
+func userIDParam(c *fiber.Ctx) (uint, error) {
+	return idParam(c, "id", "user")
+}
+
+// idParam reads the ID of a resource, e.g. a "group", from a route
+// parameter.
+func idParam(c *fiber.Ctx, param, resource string) (uint, error) {
+	id, err := strconv.ParseUint(c.Params(param), 10, 32)
+	if err != nil {
+		return 0, problem.New(problem.CodeInvalidRequest, "Invalid "+resource+" ID")
+	}
+	return uint(id), nil
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"fmt"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/workflows"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/temporal"
@@ -9,6 +12,10 @@ import (
 	"go.temporal.io/api/workflowservice/v1"
 )
 
+// errWorkflowUnavailable answers workflow requests when Temporal is not
+// configured.
+var errWorkflowUnavailable = problem.New(problem.CodeServiceUnavailable, "Workflow service unavailable")
+
 type WorkflowHandler struct {
 	temporalClient *temporal.Client
 	logger         logger.Logger
@@ -28,16 +35,12 @@ type StartWorkflowRequest struct {
 
 func (h *WorkflowHandler) StartUserOnboarding(c *fiber.Ctx) error {
 	if h.temporalClient == nil {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": "Workflow service unavailable",
-		})
+		return errWorkflowUnavailable
 	}
 
 	var req StartWorkflowRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := bindBody(c, &req); err != nil {
+		return err
 	}
 
 	// Start workflow
@@ -48,10 +51,7 @@ func (h *WorkflowHandler) StartUserOnboarding(c *fiber.Ctx) error {
 
 	we, err := h.temporalClient.GetClient().ExecuteWorkflow(c.Context(), options, workflows.UserOnboardingWorkflow, req.Input)
 	if err != nil {
-		h.logger.Error("Failed to start workflow: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to start workflow",
-		})
+		return fmt.Errorf("failed to start workflow: %w", err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -62,25 +62,18 @@ func (h *WorkflowHandler) StartUserOnboarding(c *fiber.Ctx) error {
 
 func (h *WorkflowHandler) GetWorkflowStatus(c *fiber.Ctx) error {
 	if h.temporalClient == nil {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": "Workflow service unavailable",
-		})
+		return errWorkflowUnavailable
 	}
 
 	workflowID := c.Params("id")
 	if workflowID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Workflow ID is required",
-		})
+		return problem.New(problem.CodeInvalidRequest, "Workflow ID is required")
 	}
 
 	// Get workflow execution
 	resp, err := h.temporalClient.GetClient().DescribeWorkflowExecution(c.Context(), workflowID, "")
 	if err != nil {
-		h.logger.Error("Failed to describe workflow: ", err)
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Workflow not found",
-		})
+		return problem.Wrap(err, problem.CodeWorkflowNotFound, "Workflow not found")
 	}
 
 	return c.JSON(fiber.Map{
@@ -93,9 +86,7 @@ func (h *WorkflowHandler) GetWorkflowStatus(c *fiber.Ctx) error {
 
 func (h *WorkflowHandler) ListWorkflows(c *fiber.Ctx) error {
 	if h.temporalClient == nil {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": "Workflow service unavailable",
-		})
+		return errWorkflowUnavailable
 	}
 
 	// List workflow executions
@@ -108,10 +99,7 @@ func (h *WorkflowHandler) ListWorkflows(c *fiber.Ctx) error {
 
 	response, err := h.temporalClient.GetClient().ListWorkflow(c.Context(), request)
 	if err != nil {
-		h.logger.Error("Failed to list workflows: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to list workflows",
-		})
+		return fmt.Errorf("failed to list workflows: %w", err)
 	}
 
 	for _, execution := range response.Executions {
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/audit"
+)
+
+// Actor stores who is making the request, and its request ID, on the user
+// context, so the changes it makes are recorded against them. It must run
+// after RequestID and after authentication; userID returns the
+// authenticated user's ID, or "" for anonymous requests.
+func Actor(userID func(c *fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID, _ := c.Locals("request_id").(string)
+		c.SetUserContext(audit.WithActor(c.UserContext(), audit.Actor{UserID: userID(c), RequestID: requestID}))
+		return c.Next()
+	}
+}
@@ -4,39 +4,145 @@ import (
 	"errors"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
 )
 
-// ErrorHandler is the custom error handler for Fiber
-func ErrorHandler(c *fiber.Ctx, err error) error {
-	// Default to 500 server error
-	code := fiber.StatusInternalServerError
-	message := "Internal Server Error"
+// serviceErrors maps the service layer's sentinel errors to error codes.
+// An empty detail shows the error's own message, for errors that explain
+// what was wrong with the request.
+var serviceErrors = []struct {
+	err    error
+	code   problem.Code
+	detail string
+}{
+	{service.ErrUserNotFound, problem.CodeUserNotFound, "User not found"},
+	{service.ErrUserAlreadyExists, problem.CodeUserAlreadyExists, "A user with this email or username already exists"},
+	{service.ErrUserNotDeleted, problem.CodeUserNotDeleted, "User is not deleted"},
+	{service.ErrVersionMismatch, problem.CodeConcurrentModification, "User was modified concurrently, please retry"},
+	{service.ErrInvalidPatch, problem.CodeInvalidPatch, ""},
+	{service.ErrUnprocessablePatch, problem.CodeUnprocessablePatch, ""},
+	{service.ErrPatchTestFailed, problem.CodePatchTestFailed, ""},
+	{service.ErrInvalidRole, problem.CodeValidationFailed, ""},
+	{service.ErrBatchFailed, problem.CodeBatchUpdateFailed, "No updates were applied because some of them failed"},
+	{service.ErrInvalidStatusTransition, problem.CodeInvalidStatusTransition, ""},
+	{service.ErrInvalidSuspension, problem.CodeValidationFailed, ""},
+	{service.ErrInvalidAttributes, problem.CodeValidationFailed, ""},
+	{service.ErrInvalidAttributeSchema, problem.CodeInvalidAttributeSchema, ""},
+	{service.ErrAttributeSchemaChanged, problem.CodePreconditionFailed, "Attribute schema was changed since it was read, please retry"},
+	{service.ErrAccountPendingVerification, problem.CodeAccountPendingVerification, "Account is pending verification"},
+	{service.ErrAccountSuspended, problem.CodeAccountSuspended, ""},
+	{service.ErrAccountLocked, problem.CodeAccountLocked, "Account is locked"},
+	{service.ErrAccountDeactivated, problem.CodeAccountDeactivated, "Account is deactivated"},
 
-	// Check if it's a Fiber error
-	var fiberErr *fiber.Error
-	if errors.As(err, &fiberErr) {
-		code = fiberErr.Code
-		message = fiberErr.Message
+	{service.ErrGroupNotFound, problem.CodeGroupNotFound, "Group not found"},
+	{service.ErrGroupAlreadyExists, problem.CodeGroupAlreadyExists, "A group with this name already exists"},
+	{service.ErrGroupMemberNotFound, problem.CodeGroupMemberNotFound, "Not a direct member of this group"},
+	{service.ErrGroupCycle, problem.CodeGroupCycle, "A group cannot be nested within itself"},
+	{service.ErrInvalidGroupMember, problem.CodeValidationFailed, ""},
+
+	{service.ErrInvalidImport, problem.CodeInvalidImport, ""},
+	{service.ErrImportNotFound, problem.CodeImportNotFound, "User import not found"},
+	{service.ErrImportUnavailable, problem.CodeServiceUnavailable, "Workflow service unavailable"},
+	{service.ErrInvalidExport, problem.CodeInvalidRequest, ""},
+	{service.ErrNoExportableFields, problem.CodeForbidden, "No user fields may be exported"},
+	{service.ErrExportNotFound, problem.CodeExportNotFound, "User export not found"},
+	{service.ErrExportNotReady, problem.CodeExportNotReady, "User export is not ready"},
+	{service.ErrExportUnavailable, problem.CodeServiceUnavailable, "Export storage unavailable"},
+	{service.ErrDataExportNotFound, problem.CodeDataExportNotFound, "Data export not found"},
+	{service.ErrDataExportInProgress, problem.CodeDataExportInProgress, "A data export is already in progress"},
+	{service.ErrDataExportNotReady, problem.CodeDataExportNotReady, "Data export is not ready"},
+	{service.ErrDataExportExpired, problem.CodeDataExportExpired, "Download link has expired"},
+	{service.ErrInvalidDownloadLink, problem.CodeInvalidDownloadLink, "Invalid download link"},
+	{service.ErrDataExportUnavailable, problem.CodeServiceUnavailable, "Data exports are unavailable"},
+
+	{service.ErrErasureNotFound, problem.CodeErasureNotFound, "Erasure request not found"},
+	{service.ErrErasureInProgress, problem.CodeErasureInProgress, "An erasure request is already in progress"},
+	{service.ErrErasureNotCancellable, problem.CodeErasureNotCancellable, "Erasure request can no longer be cancelled"},
+	{service.ErrErasureUnavailable, problem.CodeServiceUnavailable, "Workflow service unavailable"},
+
+	{service.ErrBundleNotFound, problem.CodePolicyBundleNotFound, "Policy bundle not found"},
+	{service.ErrInvalidBundle, problem.CodeInvalidPolicyBundle, ""},
+	{service.ErrNoPreviousRevision, problem.CodeNoPreviousRevision, "No previous policy revision to roll back to"},
+}
+
+// fiberCodes maps the statuses of fiber's own errors, e.g. for unknown
+// routes, to error codes.
+var fiberCodes = map[int]problem.Code{
+	fiber.StatusBadRequest:           problem.CodeInvalidRequest,
+	fiber.StatusUnauthorized:         problem.CodeUnauthorized,
+	fiber.StatusForbidden:            problem.CodeForbidden,
+	fiber.StatusNotFound:             problem.CodeNotFound,
+	fiber.StatusMethodNotAllowed:     problem.CodeMethodNotAllowed,
+	fiber.StatusUnsupportedMediaType: problem.CodeUnsupportedMediaType,
+	fiber.StatusUnprocessableEntity:  problem.CodeValidationFailed,
+	fiber.StatusServiceUnavailable:   problem.CodeServiceUnavailable,
+}
+
+// ErrorHandler returns the custom error handler for Fiber. It renders every
+// error returned by a handler as an RFC 7807 problem document. Internal
+// errors hide their cause from the client, so it is logged with the request
+// ID the document carries.
+func ErrorHandler(log logger.Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		appErr := ToProblem(err)
+
+		requestID, _ := c.Locals("request_id").(string)
+		if appErr.Code == problem.CodeInternal {
+			cause := appErr.Err
+			if cause == nil {
+				cause = appErr
+			}
+			log.WithFields(map[string]interface{}{
+				"request_id": requestID,
+				"method":     c.Method(),
+				"path":       c.Path(),
+			}).Error("Internal error: ", cause)
+		}
+
+		document := appErr.Document(c.Path(), requestID)
+		return c.Status(document.Status).JSON(document, problem.ContentType)
 	}
+}
 
-	// Custom error types can be handled here
-	switch {
-	case errors.Is(err, fiber.ErrUnauthorized):
-		code = fiber.StatusUnauthorized
-		message = "Unauthorized"
-	case errors.Is(err, fiber.ErrForbidden):
-		code = fiber.StatusForbidden
-		message = "Forbidden"
-	case errors.Is(err, fiber.ErrNotFound):
-		code = fiber.StatusNotFound
-		message = "Not Found"
-	case errors.Is(err, fiber.ErrBadRequest):
-		code = fiber.StatusBadRequest
-		message = "Bad Request"
+// ToProblem turns any error into an application error. Errors it does not
+// recognise become internal errors, whose message is not shown.
+func ToProblem(err error) *problem.Error {
+	var appErr *problem.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var fieldErrs validation.Errors
+	errors.As(err, &fieldErrs)
+
+	for _, mapping := range serviceErrors {
+		if errors.Is(err, mapping.err) {
+			detail := mapping.detail
+			if detail == "" {
+				detail = err.Error()
+			}
+			return &problem.Error{Code: mapping.code, Detail: detail, Fields: fieldErrs, Err: err}
+		}
+	}
+
+	if fieldErrs != nil {
+		return &problem.Error{Code: problem.CodeValidationFailed, Detail: "Request failed validation", Fields: fieldErrs, Err: err}
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code, ok := fiberCodes[fiberErr.Code]
+		if !ok {
+			code = problem.CodeInternal
+			if fiberErr.Code < fiber.StatusInternalServerError {
+				code = problem.CodeInvalidRequest
+			}
+		}
+		return &problem.Error{Code: code, Detail: fiberErr.Message, Err: err}
 	}
 
-	// Return JSON error response
-	return c.Status(code).JSON(fiber.Map{
-		"error": message,
-	})
-}
\ No newline at end of file
+	return problem.Wrap(err, problem.CodeInternal, "An unexpected error occurred")
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
+)
+
+// recordingLogger remembers what is logged at error level.
+type recordingLogger struct {
+	logger.Logger
+	fields map[string]interface{}
+	errors []string
+}
+
+func (l *recordingLogger) WithFields(fields map[string]interface{}) logger.Logger {
+	l.fields = fields
+	return l
+}
+
+func (l *recordingLogger) Error(args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprint(args...))
+}
+
+// serveError answers a request with err and decodes the problem document.
+func serveError(t *testing.T, err error) (int, string, problem.Document) {
+	return serveErrorLogged(t, &recordingLogger{Logger: logger.New("error")}, err)
+}
+
+func serveErrorLogged(t *testing.T, log logger.Logger, err error) (int, string, problem.Document) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(log)})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("request_id", "req-1")
+		return c.Next()
+	})
+	app.Get("/users", func(c *fiber.Ctx) error {
+		return err
+	})
+
+	resp, testErr := app.Test(httptest.NewRequest("GET", "/users", nil))
+	require.NoError(t, testErr)
+
+	var doc problem.Document
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), doc
+}
+
+func TestErrorHandler(t *testing.T) {
+	t.Run("Maps Service Errors", func(t *testing.T) {
+		status, contentType, doc := serveError(t, fmt.Errorf("create: %w", service.ErrUserAlreadyExists))
+
+		assert.Equal(t, fiber.StatusConflict, status)
+		assert.Equal(t, problem.ContentType, contentType)
+		assert.Equal(t, problem.CodeUserAlreadyExists, doc.Code)
+		assert.Equal(t, problem.TypeBase+"user_already_exists", doc.Type)
+		assert.Equal(t, "/users", doc.Instance)
+		assert.Equal(t, "req-1", doc.RequestID)
+	})
+
+	t.Run("Shows The Message Of Request Errors", func(t *testing.T) {
+		status, _, doc := serveError(t, fmt.Errorf("%w: the file has no records", service.ErrInvalidImport))
+
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, problem.CodeInvalidImport, doc.Code)
+		assert.Equal(t, "invalid user import: the file has no records", doc.Detail)
+	})
+
+	t.Run("Keeps Field Errors", func(t *testing.T) {
+		fieldErrs := validation.Errors{{Field: "email", Rule: "email", Message: "email must be a valid email address"}}
+		status, _, doc := serveError(t, fmt.Errorf("%w: %w", service.ErrUnprocessablePatch, fieldErrs))
+
+		assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+		assert.Equal(t, problem.CodeUnprocessablePatch, doc.Code)
+		assert.Equal(t, []validation.FieldError(fieldErrs), doc.Errors)
+	})
+
+	t.Run("Passes Application Errors Through", func(t *testing.T) {
+		status, _, doc := serveError(t, problem.New(problem.CodePreconditionRequired, "If-Match header is required"))
+
+		assert.Equal(t, fiber.StatusPreconditionRequired, status)
+		assert.Equal(t, problem.CodePreconditionRequired, doc.Code)
+		assert.Equal(t, "If-Match header is required", doc.Detail)
+	})
+
+	t.Run("Maps Fiber Errors", func(t *testing.T) {
+		status, _, doc := serveError(t, fiber.ErrMethodNotAllowed)
+
+		assert.Equal(t, fiber.StatusMethodNotAllowed, status)
+		assert.Equal(t, problem.CodeMethodNotAllowed, doc.Code)
+	})
+
+	t.Run("Hides Unknown Errors", func(t *testing.T) {
+		status, _, doc := serveError(t, errors.New("pq: connection refused"))
+
+		assert.Equal(t, fiber.StatusInternalServerError, status)
+		assert.Equal(t, problem.CodeInternal, doc.Code)
+		assert.NotContains(t, doc.Detail, "connection refused")
+	})
+
+	t.Run("Logs The Cause Of Internal Errors", func(t *testing.T) {
+		log := &recordingLogger{Logger: logger.New("error")}
+		_, _, doc := serveErrorLogged(t, log, fmt.Errorf("failed to get user: %w", errors.New("pq: connection refused")))
+
+		assert.Equal(t, problem.CodeInternal, doc.Code)
+		assert.Equal(t, []string{"Internal error: failed to get user: pq: connection refused"}, log.errors)
+		assert.Equal(t, "req-1", log.fields["request_id"])
+		assert.Equal(t, "/users", log.fields["path"])
+	})
+
+	t.Run("Does Not Log Request Errors", func(t *testing.T) {
+		log := &recordingLogger{Logger: logger.New("error")}
+		serveErrorLogged(t, log, service.ErrUserNotFound)
+
+		assert.Empty(t, log.errors)
+	})
+}
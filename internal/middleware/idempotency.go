@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+const (
+	// HeaderIdempotencyKey carries the client's key for a request.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed marks a response replayed for a repeat.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyLockTimeout is how long a request holds its key before it is
+	// answered. A key held by a server that died is free again after it.
+	idempotencyLockTimeout = time.Minute
+)
+
+// replayedHeaders are the response headers stored with a response and
+// replayed with it.
+var replayedHeaders = []string{fiber.HeaderContentType, fiber.HeaderLocation, fiber.HeaderETag}
+
+// IdempotencyStore keeps the responses of requests made with an
+// Idempotency-Key. It is implemented by repository.IdempotencyRepository.
+type IdempotencyStore interface {
+	Reserve(record *models.IdempotencyKey) (*models.IdempotencyKey, error)
+	Complete(record *models.IdempotencyKey) error
+	Release(record *models.IdempotencyKey) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// Idempotency makes POST requests safe to retry. A request with an
+// Idempotency-Key header is processed once per key, caller and route; its
+// response is stored for ttl and replayed for repeats of the request.
+type Idempotency struct {
+	store     IdempotencyStore
+	ttl       time.Duration
+	principal func(c *fiber.Ctx) string
+	logger    logger.Logger
+}
+
+// NewIdempotency returns the middleware. principal names the caller a key
+// belongs to, or "" for anonymous requests.
+func NewIdempotency(store IdempotencyStore, ttl time.Duration, principal func(c *fiber.Ctx) string, logger logger.Logger) *Idempotency {
+	return &Idempotency{
+		store:     store,
+		ttl:       ttl,
+		principal: principal,
+		logger:    logger,
+	}
+}
+
+// Handler processes the first request with a key and replays its response
+// for repeats. A repeat with a different method, query or body is rejected
+// with 422, and one that arrives while the first is still being processed
+// with 409. Requests that fail, with an error or a 5xx, are not stored, so
+// their key can be retried.
+func (m *Idempotency) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return problem.New(problem.CodeInvalidRequest, "Idempotency-Key must be at most 255 characters")
+		}
+
+		now := time.Now()
+		record := &models.IdempotencyKey{
+			Key:         key,
+			Principal:   m.principal(c),
+			Route:       c.Path(),
+			RequestHash: requestHash(c),
+			Status:      models.IdempotencyStatusInProgress,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyLockTimeout),
+		}
+
+		existing, err := m.store.Reserve(record)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return replay(c, existing, record.RequestHash)
+		}
+
+		if err := c.Next(); err != nil {
+			m.release(record)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			m.release(record)
+			return nil
+		}
+
+		record.ResponseStatus = status
+		record.ResponseHeaders = map[string]string{}
+		for _, header := range replayedHeaders {
+			if value := c.GetRespHeader(header); value != "" {
+				record.ResponseHeaders[header] = value
+			}
+		}
+		record.ResponseBody = append([]byte(nil), c.Response().Body()...)
+		record.ExpiresAt = time.Now().Add(m.ttl)
+		if err := m.store.Complete(record); err != nil {
+			// The response was still produced; only repeats will not see it
+			m.logger.Error("Failed to store idempotent response: ", err)
+			m.release(record)
+		}
+		return nil
+	}
+}
+
+// Sweep deletes expired keys every interval until ctx is done.
+func (m *Idempotency) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.store.DeleteExpired(time.Now()); err != nil {
+				m.logger.Error("Failed to delete expired idempotency keys: ", err)
+			}
+		}
+	}
+}
+
+func (m *Idempotency) release(record *models.IdempotencyKey) {
+	if err := m.store.Release(record); err != nil {
+		m.logger.Error("Failed to release idempotency key: ", err)
+	}
+}
+
+// replay answers a repeat of the request that holds existing.
+func replay(c *fiber.Ctx, existing *models.IdempotencyKey, hash string) error {
+	if existing.RequestHash != hash {
+		return problem.New(problem.CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request")
+	}
+	if existing.Status != models.IdempotencyStatusCompleted {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return problem.New(problem.CodeIdempotencyKeyInProgress, "A request with this Idempotency-Key is still being processed")
+	}
+
+	for header, value := range existing.ResponseHeaders {
+		c.Set(header, value)
+	}
+	c.Set(HeaderIdempotentReplayed, "true")
+	return c.Status(existing.ResponseStatus).Send(existing.ResponseBody)
+}
+
+// requestHash fingerprints what a repeat of the request must match: its
+// method, query string and body.
+func requestHash(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method()))
+	hash.Write([]byte{0})
+	hash.Write(c.Request().URI().QueryString())
+	hash.Write([]byte{0})
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
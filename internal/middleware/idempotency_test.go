@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// idempotentApp serves POST /users through the idempotency middleware,
+// creating a user with the next ID for every request that reaches it.
+type idempotentApp struct {
+	*fiber.App
+	calls   atomic.Int32
+	fail    error
+	release chan struct{}
+}
+
+func newIdempotentApp(t *testing.T) *idempotentApp {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared&_busy_timeout=5000"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.IdempotencyKey{}))
+	t.Cleanup(func() { db.Exec("DELETE FROM idempotency_keys") })
+
+	principal := func(c *fiber.Ctx) string { return c.Get("X-User") }
+	idempotency := NewIdempotency(repository.NewIdempotencyRepository(db), time.Hour, principal, logger.New("error"))
+
+	app := &idempotentApp{App: fiber.New(fiber.Config{ErrorHandler: ErrorHandler(logger.New("error"))})}
+	app.Post("/users", idempotency.Handler(), func(c *fiber.Ctx) error {
+		if app.release != nil {
+			<-app.release
+		}
+		if app.fail != nil {
+			return app.fail
+		}
+		id := app.calls.Add(1)
+		c.Location("/users/" + strconv.Itoa(int(id)))
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": id})
+	})
+	return app
+}
+
+func (app *idempotentApp) post(t *testing.T, key, user, body string) (*http.Response, string) {
+	request := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	request.Header.Set("X-User", user)
+	if key != "" {
+		request.Header.Set(HeaderIdempotencyKey, key)
+	}
+	resp, err := app.Test(request, -1)
+	require.NoError(t, err)
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp, string(respBody)
+}
+
+func problemCode(t *testing.T, body string) problem.Code {
+	var doc problem.Document
+	require.NoError(t, json.Unmarshal([]byte(body), &doc))
+	return doc.Code
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Run("Replays The Response", func(t *testing.T) {
+		app := newIdempotentApp(t)
+
+		first, firstBody := app.post(t, "k1", "42", `{"email":"a@example.com"}`)
+		assert.Equal(t, fiber.StatusCreated, first.StatusCode)
+
+		repeat, repeatBody := app.post(t, "k1", "42", `{"email":"a@example.com"}`)
+		assert.Equal(t, fiber.StatusCreated, repeat.StatusCode)
+		assert.Equal(t, firstBody, repeatBody)
+		assert.Equal(t, "/users/1", repeat.Header.Get(fiber.HeaderLocation))
+		assert.Equal(t, "true", repeat.Header.Get(HeaderIdempotentReplayed))
+		assert.Equal(t, int32(1), app.calls.Load())
+	})
+
+	t.Run("Scopes Keys To The Caller", func(t *testing.T) {
+		app := newIdempotentApp(t)
+
+		app.post(t, "k1", "42", `{}`)
+		resp, _ := app.post(t, "k1", "7", `{}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(HeaderIdempotentReplayed))
+		assert.Equal(t, int32(2), app.calls.Load())
+	})
+
+	t.Run("Rejects A Reused Key", func(t *testing.T) {
+		app := newIdempotentApp(t)
+
+		app.post(t, "k1", "42", `{"email":"a@example.com"}`)
+		resp, body := app.post(t, "k1", "42", `{"email":"b@example.com"}`)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, problem.CodeIdempotencyKeyReused, problemCode(t, body))
+		assert.Equal(t, int32(1), app.calls.Load())
+	})
+
+	t.Run("Rejects A Repeat In Flight", func(t *testing.T) {
+		app := newIdempotentApp(t)
+		app.release = make(chan struct{})
+
+		done := make(chan *http.Response)
+		go func() {
+			resp, _ := app.post(t, "k1", "42", `{}`)
+			done <- resp
+		}()
+
+		// Wait for the first request to hold the key
+		var resp *http.Response
+		var body string
+		require.Eventually(t, func() bool {
+			resp, body = app.post(t, "k1", "42", `{}`)
+			return resp.StatusCode == fiber.StatusConflict
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, problem.CodeIdempotencyKeyInProgress, problemCode(t, body))
+		assert.Equal(t, "1", resp.Header.Get(fiber.HeaderRetryAfter))
+
+		close(app.release)
+		assert.Equal(t, fiber.StatusCreated, (<-done).StatusCode)
+	})
+
+	t.Run("Does Not Store Failures", func(t *testing.T) {
+		app := newIdempotentApp(t)
+		app.fail = errors.New("database unavailable")
+
+		resp, _ := app.post(t, "k1", "42", `{}`)
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+		app.fail = nil
+		resp, _ = app.post(t, "k1", "42", `{}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(HeaderIdempotentReplayed))
+	})
+
+	t.Run("Passes Requests Without A Key", func(t *testing.T) {
+		app := newIdempotentApp(t)
+
+		app.post(t, "", "42", `{}`)
+		app.post(t, "", "42", `{}`)
+		assert.Equal(t, int32(2), app.calls.Load())
+	})
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+)
+
+// RequireIfMatch rejects requests without an If-Match header with 428
+// Precondition Required, so that clients have to say which version of a
+// resource they are changing. Requests for which skip returns true are let
+// through; skip may be nil.
+func RequireIfMatch(skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get(fiber.HeaderIfMatch) == "" && (skip == nil || !skip(c)) {
+			return problem.New(problem.CodePreconditionRequired, "If-Match header is required")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a listing ordered by (created_at, id). Clients only
+// ever see its opaque encoded form.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"i"`
+	// Desc records the order the cursor was issued for, so it cannot be
+	// replayed against the opposite order.
+	Desc bool `json:"d,omitempty"`
+	// Backward fetches the page before the position instead of after it.
+	Backward bool `json:"b,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	body, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	body, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(body, &c); err != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// UserPage is one page of a cursor-paginated user listing. Total is only
+// set when the caller asked for it.
+type UserPage struct {
+	Users      []*UserResponse
+	NextCursor string
+	PrevCursor string
+	Total      *int64
+}
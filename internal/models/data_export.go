@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// DataExport is an archive of everything held about a user, assembled at
+// their request. It can be downloaded through a signed link until ExpiresAt.
+// Its statuses are the export job statuses.
+type DataExport struct {
+	ID          string     `json:"id" gorm:"primaryKey;size:36"`
+	UserID      uint       `json:"user_id" gorm:"index;not null"`
+	Status      string     `json:"status" gorm:"index;not null"`
+	WorkflowID  string     `json:"workflow_id,omitempty"`
+	Sections    []string   `json:"sections,omitempty" gorm:"serializer:json"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// FileName is the name the archive is stored and downloaded under.
+func (e *DataExport) FileName() string {
+	return "data-export-" + e.ID + ".zip"
+}
+
+// DataExportManifest describes the contents of a data export archive.
+type DataExportManifest struct {
+	ExportID    string    `json:"export_id"`
+	UserID      uint      `json:"user_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Sections    []string  `json:"sections"`
+}
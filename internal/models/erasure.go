@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Erasure request statuses
+const (
+	ErasureStatusPending   = "pending"
+	ErasureStatusRunning   = "running"
+	ErasureStatusCompleted = "completed"
+	ErasureStatusCancelled = "cancelled"
+	ErasureStatusFailed    = "failed"
+)
+
+// ErasureRequest is a request to erase a user's personal data. It waits in
+// pending until GraceUntil, during which it can be cancelled, and is then
+// carried out by the erasure hooks.
+type ErasureRequest struct {
+	ID          string              `json:"id" gorm:"primaryKey;size:36"`
+	UserID      uint                `json:"user_id" gorm:"index;not null"`
+	Status      string              `json:"status" gorm:"index;not null"`
+	Reason      string              `json:"reason,omitempty"`
+	RequestedBy string              `json:"requested_by"`
+	WorkflowID  string              `json:"workflow_id,omitempty"`
+	GraceUntil  time.Time           `json:"grace_until"`
+	Error       string              `json:"error,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+	Certificate *ErasureCertificate `json:"certificate,omitempty" gorm:"foreignKey:RequestID"`
+}
+
+type ErasureRequestBody struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ErasureStep records what one erasure hook did.
+type ErasureStep struct {
+	Hook    string `json:"hook"`
+	Records int64  `json:"records"`
+}
+
+// ErasureCertificate attests that a user's data was erased. It holds no
+// personal data, only the user ID and what each hook erased, and is signed
+// so that it cannot be altered after the fact.
+type ErasureCertificate struct {
+	ID          string        `json:"id" gorm:"primaryKey;size:36"`
+	RequestID   string        `json:"request_id" gorm:"uniqueIndex;size:36;not null"`
+	UserID      uint          `json:"user_id" gorm:"not null"`
+	Steps       []ErasureStep `json:"steps" gorm:"serializer:json"`
+	RequestedAt time.Time     `json:"requested_at"`
+	ErasedAt    time.Time     `json:"erased_at"`
+	Signature   string        `json:"signature" gorm:"not null"`
+}
+
+// SigningPayload is the canonical encoding of the certificate that
+// Signature covers. Times are in UTC to the second, so they survive a round
+// trip through the database unchanged.
+func (c *ErasureCertificate) SigningPayload() []byte {
+	payload, _ := json.Marshal(struct {
+		ID          string        `json:"id"`
+		RequestID   string        `json:"request_id"`
+		UserID      uint          `json:"user_id"`
+		Steps       []ErasureStep `json:"steps"`
+		RequestedAt string        `json:"requested_at"`
+		ErasedAt    string        `json:"erased_at"`
+	}{
+		ID:          c.ID,
+		RequestID:   c.RequestID,
+		UserID:      c.UserID,
+		Steps:       c.Steps,
+		RequestedAt: c.RequestedAt.UTC().Format(time.RFC3339),
+		ErasedAt:    c.ErasedAt.UTC().Format(time.RFC3339),
+	})
+	return payload
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Group member types
+const (
+	GroupMemberUser  = "user"
+	GroupMemberGroup = "group"
+)
+
+// Group grants its Roles to its members, including the members of groups
+// nested in it.
+type Group struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description string    `json:"description"`
+	Roles       []string  `json:"roles" gorm:"serializer:json"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GroupMember puts a user, or another group, in a group. MemberType is one
+// of the GroupMember constants.
+type GroupMember struct {
+	GroupID    uint      `json:"group_id" gorm:"primaryKey"`
+	MemberType string    `json:"member_type" gorm:"primaryKey;size:16;index:idx_group_members_member,priority:1"`
+	MemberID   uint      `json:"member_id" gorm:"primaryKey;index:idx_group_members_member,priority:2"`
+	AddedBy    string    `json:"added_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CreateGroupRequest struct {
+	Name        string   `json:"name" binding:"required,max=100"`
+	Description string   `json:"description" binding:"max=500"`
+	Roles       []string `json:"roles"`
+}
+
+type UpdateGroupRequest struct {
+	Name        string  `json:"name" binding:"omitempty,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	// Roles, if set, replace all of the group's roles
+	Roles []string `json:"roles"`
+}
+
+// AddGroupMemberRequest names the user or the group to add; exactly one of
+// UserID and GroupID is set.
+type AddGroupMemberRequest struct {
+	UserID  uint `json:"user_id"`
+	GroupID uint `json:"group_id"`
+}
+
+// UserAccess is what a user is granted: the groups they are in, directly or
+// through nested groups, and their own roles together with those the groups
+// grant.
+type UserAccess struct {
+	UserID uint     `json:"user_id"`
+	Groups []string `json:"groups"`
+	Roles  []string `json:"roles"`
+}
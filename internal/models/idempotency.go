@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Idempotency key statuses
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyKey records a request made with an Idempotency-Key header and,
+// once it has been answered, the response to replay for repeats of it. Keys
+// are scoped to the caller and the route they were used on.
+type IdempotencyKey struct {
+	Key             string `gorm:"primaryKey;size:255"`
+	Principal       string `gorm:"primaryKey;size:255"`
+	Route           string `gorm:"primaryKey;size:255"`
+	RequestHash     string `gorm:"size:64;not null"`
+	Status          string `gorm:"size:16;not null"`
+	ResponseStatus  int
+	ResponseHeaders map[string]string `gorm:"serializer:json"`
+	ResponseBody    []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time `gorm:"index;not null"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// PolicyBundle is an uploaded set of Rego modules and data, identified by a
+// content-derived revision.
+type PolicyBundle struct {
+	ID          uint                   `json:"id" gorm:"primaryKey"`
+	Revision    string                 `json:"revision" gorm:"uniqueIndex;not null"`
+	Modules     map[string]string      `json:"modules" gorm:"serializer:json;not null"`
+	Data        map[string]interface{} `json:"data" gorm:"serializer:json"`
+	Description string                 `json:"description"`
+	Active      bool                   `json:"active" gorm:"index;default:false"`
+	CreatedBy   string                 `json:"created_by"`
+	CreatedAt   time.Time              `json:"created_at"`
+	ActivatedAt *time.Time             `json:"activated_at,omitempty"`
+}
+
+// PolicyActivation records each time a bundle was made active, newest last,
+// so that rollbacks know what was active before.
+type PolicyActivation struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Revision    string    `json:"revision" gorm:"index;not null"`
+	ActivatedBy string    `json:"activated_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type UploadPolicyBundleRequest struct {
+	Modules     map[string]string      `json:"modules"`
+	Data        map[string]interface{} `json:"data"`
+	Description string                 `json:"description"`
+}
+
+type PolicyBundleSummary struct {
+	Revision    string     `json:"revision"`
+	Modules     []string   `json:"modules"`
+	Description string     `json:"description"`
+	Active      bool       `json:"active"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+}
+
+func (b *PolicyBundle) ToSummary() *PolicyBundleSummary {
+	modules := make([]string, 0, len(b.Modules))
+	for name := range b.Modules {
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+
+	return &PolicyBundleSummary{
+		Revision:    b.Revision,
+		Modules:     modules,
+		Description: b.Description,
+		Active:      b.Active,
+		CreatedBy:   b.CreatedBy,
+		CreatedAt:   b.CreatedAt,
+		ActivatedAt: b.ActivatedAt,
+	}
+}
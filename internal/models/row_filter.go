@@ -0,0 +1,16 @@
+package models
+
+// RowFilter restricts a listing to the rows a caller is allowed to see. The
+// clauses are OR-ed together and the conditions within a clause are AND-ed,
+// so a filter with no clauses matches nothing unless Unrestricted is set.
+type RowFilter struct {
+	Unrestricted bool
+	Clauses      [][]RowCondition
+}
+
+// RowCondition compares a whitelisted column with a constant.
+type RowCondition struct {
+	Column   string
+	Operator string // one of =, <>, <, <=, >, >=
+	Value    interface{}
+}
@@ -6,47 +6,65 @@ import (
 	"gorm.io/gorm"
 )
 
+// User is an account. Status is one of the UserStatus constants and changes
+// only as CanTransition allows; SuspendedUntil is when a suspension ends by
+// itself, if it does. Attributes are the custom fields described by the
+// current UserAttributeSchema.
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"`
-	FirstName string         `json:"first_name"`
-	LastName  string         `json:"last_name"`
-	Roles     []string       `json:"roles" gorm:"type:text[]"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ID             uint                   `json:"id" gorm:"primaryKey"`
+	Email          string                 `json:"email" gorm:"uniqueIndex:idx_users_email_live,where:deleted_at IS NULL;not null"`
+	Username       string                 `json:"username" gorm:"uniqueIndex:idx_users_username_live,where:deleted_at IS NULL;not null"`
+	Password       string                 `json:"-" gorm:"not null"`
+	FirstName      string                 `json:"first_name"`
+	LastName       string                 `json:"last_name"`
+	Roles          []string               `json:"roles" gorm:"type:text[]"`
+	Status         string                 `json:"status" gorm:"index;not null;default:active"`
+	StatusReason   string                 `json:"status_reason"`
+	SuspendedUntil *time.Time             `json:"suspended_until"`
+	Attributes     map[string]interface{} `json:"attributes" gorm:"type:jsonb;serializer:json"`
+	Version        uint                   `json:"version" gorm:"not null;default:1"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt         `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 type CreateUserRequest struct {
-	Email     string   `json:"email" binding:"required,email"`
-	Username  string   `json:"username" binding:"required,min=3,max=50"`
-	Password  string   `json:"password" binding:"required,min=6"`
-	FirstName string   `json:"first_name" binding:"max=100"`
-	LastName  string   `json:"last_name" binding:"max=100"`
-	Roles     []string `json:"roles"`
+	Email      string                 `json:"email" binding:"required,email"`
+	Username   string                 `json:"username" binding:"required,min=3,max=50,username,unreserved"`
+	Password   string                 `json:"password" binding:"required,min=6"`
+	FirstName  string                 `json:"first_name" binding:"max=100"`
+	LastName   string                 `json:"last_name" binding:"max=100"`
+	Roles      []string               `json:"roles"`
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
 type UpdateUserRequest struct {
 	Email     string `json:"email" binding:"omitempty,email"`
-	Username  string `json:"username" binding:"omitempty,min=3,max=50"`
+	Username  string `json:"username" binding:"omitempty,min=3,max=50,username,unreserved"`
 	FirstName string `json:"first_name" binding:"omitempty,max=100"`
 	LastName  string `json:"last_name" binding:"omitempty,max=100"`
-	IsActive  *bool  `json:"is_active" binding:"omitempty"`
+	// Attributes, if set, replace all of the user's attributes
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
+// UserResponse is a user as the API returns it. IsActive is kept for clients
+// that predate Status and is true only for active users.
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Roles     []string  `json:"roles"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint                   `json:"id"`
+	Email          string                 `json:"email"`
+	Username       string                 `json:"username"`
+	FirstName      string                 `json:"first_name"`
+	LastName       string                 `json:"last_name"`
+	Roles          []string               `json:"roles"`
+	Status         string                 `json:"status"`
+	StatusReason   string                 `json:"status_reason,omitempty"`
+	SuspendedUntil *time.Time             `json:"suspended_until,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+	IsActive       bool                   `json:"is_active"`
+	Version        uint                   `json:"version"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	DeletedAt      *time.Time             `json:"deleted_at,omitempty"`
 }
 
 type LoginRequest struct {
@@ -55,15 +73,33 @@ type LoginRequest struct {
 }
 
 func (u *User) ToResponse() *UserResponse {
-	return &UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Username:  u.Username,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Roles:     u.Roles,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+	response := &UserResponse{
+		ID:             u.ID,
+		Email:          u.Email,
+		Username:       u.Username,
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		Roles:          u.Roles,
+		Status:         u.Status,
+		StatusReason:   u.StatusReason,
+		SuspendedUntil: u.SuspendedUntil,
+		Attributes:     u.Attributes,
+		IsActive:       u.Status == UserStatusActive,
+		Version:        u.Version,
+		CreatedAt:      u.CreatedAt,
+		UpdatedAt:      u.UpdatedAt,
 	}
-}
\ No newline at end of file
+	if u.DeletedAt.Valid {
+		response.DeletedAt = &u.DeletedAt.Time
+	}
+	return response
+}
+
+// EffectiveStatus is the user's status at now: a suspension that ended
+// before its timer ran counts as active.
+func (u *User) EffectiveStatus(now time.Time) string {
+	if u.Status == UserStatusSuspended && u.SuspendedUntil != nil && !now.Before(*u.SuspendedUntil) {
+		return UserStatusActive
+	}
+	return u.Status
+}
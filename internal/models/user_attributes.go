@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// UserAttributeSchema is a version of the JSON Schema that users' custom
+// attributes must match. Admins publish new versions; the latest applies to
+// writes from then on.
+type UserAttributeSchema struct {
+	ID        uint                   `json:"-" gorm:"primaryKey"`
+	Version   uint                   `json:"version" gorm:"uniqueIndex;not null"`
+	Schema    map[string]interface{} `json:"schema" gorm:"serializer:json;not null"`
+	CreatedBy string                 `json:"created_by,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// DefaultUserAttributeSchema applies until a schema is published. It allows
+// any object.
+func DefaultUserAttributeSchema() *UserAttributeSchema {
+	return &UserAttributeSchema{Schema: map[string]interface{}{"type": "object"}}
+}
+
+type PutUserAttributeSchemaRequest struct {
+	Schema map[string]interface{} `json:"schema" binding:"required"`
+}
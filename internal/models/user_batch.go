@@ -0,0 +1,70 @@
+package models
+
+// MaxUserBatchSize bounds the number of users one batch request can read or
+// update.
+const MaxUserBatchSize = 100
+
+// Batch update modes
+const (
+	// BatchModeAtomic applies every update or, if any of them fails, none.
+	BatchModeAtomic = "atomic"
+	// BatchModePartial applies the updates that succeed and reports the
+	// others.
+	BatchModePartial = "partial"
+)
+
+// UserBatch is the answer to a batch get: the users found, in the order
+// their IDs were asked for, and the IDs that were not.
+type UserBatch struct {
+	Users   []*UserResponse `json:"users"`
+	Missing []uint          `json:"missing"`
+}
+
+// BatchUpdateUsersRequest changes the status or roles of several users in
+// one transaction.
+type BatchUpdateUsersRequest struct {
+	Mode    string            `json:"mode" binding:"omitempty,oneof=atomic partial"`
+	Updates []BatchUserUpdate `json:"updates" binding:"required,min=1,max=100"`
+}
+
+// BatchUserUpdate changes one user. Roles replaces the user's roles;
+// AddRoles and RemoveRoles are applied after it. A non-zero Version must be
+// the user's current version, as with If-Match. Status changes the user's
+// status, recording Reason, as long as the transition is allowed; suspending
+// has its own endpoint.
+type BatchUserUpdate struct {
+	ID          uint     `json:"id" binding:"required"`
+	Version     uint     `json:"version"`
+	Status      string   `json:"status" binding:"omitempty,oneof=active locked deactivated"`
+	Reason      string   `json:"reason" binding:"max=500"`
+	Roles       []string `json:"roles"`
+	AddRoles    []string `json:"add_roles"`
+	RemoveRoles []string `json:"remove_roles"`
+}
+
+// BatchUpdateItem is the outcome of one update of a batch: the updated
+// user, or the error that stopped it.
+type BatchUpdateItem struct {
+	ID    uint          `json:"id"`
+	User  *UserResponse `json:"user,omitempty"`
+	Error error         `json:"-"`
+}
+
+// BatchUpdateResult reports every update of a batch, in request order.
+// Applied is false if an atomic batch was rolled back.
+type BatchUpdateResult struct {
+	Mode    string             `json:"mode"`
+	Applied bool               `json:"applied"`
+	Items   []*BatchUpdateItem `json:"items"`
+}
+
+// Failed counts the updates that did not succeed.
+func (r *BatchUpdateResult) Failed() int {
+	failed := 0
+	for _, item := range r.Items {
+		if item.Error != nil {
+			failed++
+		}
+	}
+	return failed
+}
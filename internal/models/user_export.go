@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Export formats
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatJSON   = "json"
+)
+
+// Export job statuses
+const (
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+)
+
+// UserExportColumns are the user fields an export can contain, in the order
+// they are written.
+var UserExportColumns = []string{
+	"id", "email", "username", "first_name", "last_name", "roles", "status", "is_active", "created_at", "updated_at",
+}
+
+// UserExport is an export written to file storage in the background, for
+// listings too large to stream in one request.
+type UserExport struct {
+	ID          string        `json:"id" gorm:"primaryKey;size:36"`
+	Format      string        `json:"format" gorm:"not null"`
+	Status      string        `json:"status" gorm:"index;not null"`
+	Query       UserListQuery `json:"-" gorm:"serializer:json"`
+	Fields      []string      `json:"fields" gorm:"serializer:json"`
+	RowCount    int           `json:"row_count"`
+	Error       string        `json:"error,omitempty"`
+	CreatedBy   string        `json:"created_by" gorm:"index"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+}
+
+// FileName is the name the export is stored and downloaded under.
+func (e *UserExport) FileName() string {
+	return "users-" + e.ID + "." + e.Format
+}
@@ -0,0 +1,134 @@
+package models
+
+import "time"
+
+// Import formats
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// Import modes: create fails rows whose email is already registered, upsert
+// updates them instead.
+const (
+	ImportModeCreate = "create"
+	ImportModeUpsert = "upsert"
+)
+
+// Import job statuses
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// Import row statuses. In a dry run, created and updated mean the row would
+// have been.
+const (
+	ImportRowPending = "pending"
+	ImportRowCreated = "created"
+	ImportRowUpdated = "updated"
+	ImportRowFailed  = "failed"
+)
+
+// UserImport is a bulk user import job. The counters are updated as the
+// background workflow works through its rows.
+type UserImport struct {
+	ID          string     `json:"id" gorm:"primaryKey;size:36"`
+	Format      string     `json:"format" gorm:"not null"`
+	Mode        string     `json:"mode" gorm:"not null"`
+	DryRun      bool       `json:"dry_run"`
+	Onboard     bool       `json:"onboard"`
+	Status      string     `json:"status" gorm:"index;not null"`
+	WorkflowID  string     `json:"workflow_id,omitempty"`
+	TotalRows   int        `json:"total_rows"`
+	Processed   int        `json:"processed"`
+	Created     int        `json:"created"`
+	Updated     int        `json:"updated"`
+	Failed      int        `json:"failed"`
+	Error       string     `json:"error,omitempty"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// UserImportRow is one record of an import and its outcome. Line is where it
+// appeared in the uploaded file.
+type UserImportRow struct {
+	ID       uint         `json:"-" gorm:"primaryKey"`
+	ImportID string       `json:"-" gorm:"size:36;not null;index:idx_user_import_rows_import_line,priority:1"`
+	Line     int          `json:"line" gorm:"not null;index:idx_user_import_rows_import_line,priority:2"`
+	Record   ImportRecord `json:"record" gorm:"serializer:json"`
+	Status   string       `json:"status" gorm:"not null"`
+	Error    string       `json:"error,omitempty"`
+	UserID   *uint        `json:"user_id,omitempty"`
+}
+
+// ImportRecord is a user as read from an import file. Passwords cannot be
+// imported; imported users set theirs through onboarding. Its fields are
+// validated with the same rules as CreateUserRequest, except that the
+// username may be left out of upserts.
+type ImportRecord struct {
+	Email     string   `json:"email" binding:"required,email"`
+	Username  string   `json:"username" binding:"omitempty,min=3,max=50,username,unreserved"`
+	FirstName string   `json:"first_name" binding:"max=100"`
+	LastName  string   `json:"last_name" binding:"max=100"`
+	Roles     []string `json:"roles"`
+	IsActive  *bool    `json:"is_active"`
+}
+
+type StartUserImportRequest struct {
+	Format  string
+	Mode    string
+	DryRun  bool
+	Onboard bool
+}
+
+// ImportRowError reports why a row was not imported.
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error"`
+}
+
+// UserImportReport is an import job with its progress and per-row errors.
+type UserImportReport struct {
+	*UserImport
+	Progress float64          `json:"progress"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportedUser identifies a user created by an import, for onboarding.
+type ImportedUser struct {
+	ID       uint   `json:"id"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// ImportBatchResult is the outcome of processing one batch of rows.
+type ImportBatchResult struct {
+	Processed int  `json:"processed"`
+	LastLine  int  `json:"last_line"`
+	Done      bool `json:"done"`
+	// Created lists the users created in the batch when the import asked
+	// for onboarding.
+	Created []ImportedUser `json:"created,omitempty"`
+}
+
+func (i *UserImport) ToReport(failed []*UserImportRow) *UserImportReport {
+	report := &UserImportReport{
+		UserImport: i,
+		Errors:     make([]ImportRowError, len(failed)),
+	}
+	if i.TotalRows > 0 {
+		report.Progress = float64(i.Processed) / float64(i.TotalRows)
+	} else if i.Status == ImportStatusCompleted {
+		report.Progress = 1
+	}
+	for j, row := range failed {
+		report.Errors[j] = ImportRowError{Line: row.Line, Email: row.Record.Email, Error: row.Error}
+	}
+	return report
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Media types accepted by PATCH /users/:id.
+const (
+	PatchFormatMerge = "application/merge-patch+json"
+	PatchFormatJSON  = "application/json-patch+json"
+)
+
+// PatchableUser is the document user patches are applied to. Only these
+// fields can be patched; a nil field has been cleared.
+type PatchableUser struct {
+	Email     *string `json:"email" binding:"required,email"`
+	Username  *string `json:"username" binding:"required,min=3,max=50,username,unreserved"`
+	FirstName *string `json:"first_name" binding:"omitempty,max=100"`
+	LastName  *string `json:"last_name" binding:"omitempty,max=100"`
+	// Attributes are patched key by key, and checked against the attribute
+	// schema rather than here
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+func NewPatchableUser(u *User) *PatchableUser {
+	return &PatchableUser{
+		Email:      &u.Email,
+		Username:   &u.Username,
+		FirstName:  &u.FirstName,
+		LastName:   &u.LastName,
+		Attributes: u.Attributes,
+	}
+}
+
+// Document returns the fields as a JSON object for a patch to apply to.
+func (p *PatchableUser) Document() map[string]interface{} {
+	doc := make(map[string]interface{})
+	data, _ := json.Marshal(p)
+	json.Unmarshal(data, &doc)
+	return doc
+}
+
+// DecodePatchableUser reads the fields back out of a patched document,
+// rejecting anything that is not patchable.
+func DecodePatchableUser(doc map[string]interface{}) (*PatchableUser, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var p PatchableUser
+	if err := decoder.Decode(&p); err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			return nil, fmt.Errorf("%s must be a %s", typeErr.Field, typeErr.Type)
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ApplyTo copies the fields onto u, clearing those that are nil.
+func (p *PatchableUser) ApplyTo(u *User) {
+	u.Email, u.Username = deref(p.Email), deref(p.Username)
+	u.FirstName, u.LastName = deref(p.FirstName), deref(p.LastName)
+	u.Attributes = p.Attributes
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
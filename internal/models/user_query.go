@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// UserSortColumns are the columns GET /api/v1/users can be sorted by.
+var UserSortColumns = map[string]bool{
+	"id":         true,
+	"username":   true,
+	"email":      true,
+	"first_name": true,
+	"last_name":  true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Values of UserListQuery.Deleted
+const (
+	DeletedInclude = "include"
+	DeletedOnly    = "only"
+)
+
+// SortField orders a listing by Column, descending if Desc is set.
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// UserListQuery selects a page of users. Zero-valued filters are not applied.
+// Offset pagination uses Page and PageSize; keyset pagination uses Limit and
+// Cursor and orders by (created_at, id).
+type UserListQuery struct {
+	Page     int
+	PageSize int
+
+	Limit        int
+	Cursor       *Cursor
+	Desc         bool
+	IncludeTotal bool
+
+	// IDs limits the listing to these users, for batch gets.
+	IDs []uint
+
+	// IsActive lists active users when true and all others when false; it
+	// predates Status.
+	IsActive      *bool
+	Status        string
+	Role          string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	EmailDomain   string
+
+	// Attributes lists users whose attributes contain these values.
+	Attributes map[string]interface{}
+
+	// Search matches username, email, first and last name case-insensitively
+	// by substring; prefix matches are listed first unless Sort is set.
+	Search string
+	Sort   []SortField
+
+	// Deleted lists soft-deleted users as well (DeletedInclude) or alone
+	// (DeletedOnly). By default they are left out.
+	Deleted string
+
+	// RowFilter restricts the listing to the rows the caller may see.
+	RowFilter *RowFilter
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// User revision operations
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	// RevisionBaseline is the state of users that existed before their
+	// history was kept, recorded by the migration that added it.
+	RevisionBaseline = "baseline"
+)
+
+// UserRevision is a snapshot of a user taken after each change to it, for
+// its change history and point-in-time reads. The snapshot is the user as
+// the API returns it, so the password hash is never recorded.
+type UserRevision struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`
+	UserID    uint          `json:"user_id" gorm:"not null;index:idx_user_revisions_user_created,priority:1"`
+	Version   uint          `json:"version" gorm:"not null"`
+	Operation string        `json:"operation" gorm:"not null"`
+	User      *UserResponse `json:"user" gorm:"column:snapshot;serializer:json"`
+	// ChangedBy is the ID of the user who made the change, empty for
+	// anonymous requests, or StatusChangedBySystem for automatic changes
+	ChangedBy string    `json:"changed_by"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_user_revisions_user_created,priority:2"`
+}
+
+// NewUserRevision snapshots user after operation.
+func NewUserRevision(user *User, operation, changedBy, requestID string) *UserRevision {
+	return &UserRevision{
+		UserID:    user.ID,
+		Version:   user.Version,
+		Operation: operation,
+		User:      user.ToResponse(),
+		ChangedBy: changedBy,
+		RequestID: requestID,
+	}
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// User account statuses
+const (
+	// UserStatusPendingVerification is an account that has not been verified
+	// yet and cannot sign in.
+	UserStatusPendingVerification = "pending_verification"
+	UserStatusActive              = "active"
+	// UserStatusSuspended is an account barred from signing in for a while,
+	// until SuspendedUntil if set or until it is reactivated.
+	UserStatusSuspended = "suspended"
+	// UserStatusLocked is an account barred from signing in until an admin
+	// unlocks it, e.g. after suspicious activity.
+	UserStatusLocked      = "locked"
+	UserStatusDeactivated = "deactivated"
+)
+
+// UserStatuses lists every user status.
+var UserStatuses = []string{
+	UserStatusPendingVerification, UserStatusActive, UserStatusSuspended, UserStatusLocked, UserStatusDeactivated,
+}
+
+// userStatusTransitions lists the statuses each status can change to.
+// Suspending a suspended user changes the reason or end of the suspension.
+var userStatusTransitions = map[string][]string{
+	UserStatusPendingVerification: {UserStatusActive, UserStatusDeactivated},
+	UserStatusActive:              {UserStatusSuspended, UserStatusLocked, UserStatusDeactivated},
+	UserStatusSuspended:           {UserStatusActive, UserStatusSuspended, UserStatusLocked, UserStatusDeactivated},
+	UserStatusLocked:              {UserStatusActive, UserStatusDeactivated},
+	UserStatusDeactivated:         {UserStatusActive},
+}
+
+// CanTransition reports whether a user's status may change from from to to.
+func CanTransition(from, to string) bool {
+	for _, next := range userStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUserStatus reports whether status is one of UserStatuses.
+func IsUserStatus(status string) bool {
+	_, ok := userStatusTransitions[status]
+	return ok
+}
+
+// UserStatusTransition records a change of a user's status, for the audit
+// trail.
+type UserStatusTransition struct {
+	ID     uint       `json:"id" gorm:"primaryKey"`
+	UserID uint       `json:"user_id" gorm:"index;not null"`
+	From   string     `json:"from" gorm:"column:from_status;not null"`
+	To     string     `json:"to" gorm:"column:to_status;not null"`
+	Reason string     `json:"reason,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+	// ChangedBy is the ID of the user who made the change, or
+	// StatusChangedBySystem for automatic changes
+	ChangedBy string    `json:"changed_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StatusChangedBySystem is the ChangedBy of automatic status changes, such
+// as the end of a suspension.
+const StatusChangedBySystem = "system"
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+	// Until ends the suspension automatically; without it the user stays
+	// suspended until reactivated.
+	Until *time.Time `json:"until"`
+}
+
+type ReactivateUserRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
+)
+
+// ReservedUsernames cannot be taken by users, since they would be confused
+// with routes such as /users/me or with staff accounts.
+var ReservedUsernames = []string{
+	"admin", "administrator", "root", "system", "support", "me", "api", "null", "undefined",
+}
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+func init() {
+	validation.RegisterRule("username", func(value reflect.Value, _ string) bool {
+		return value.Kind() == reflect.String && usernamePattern.MatchString(value.String())
+	}, "may only contain letters, digits, dots, dashes and underscores, and must start with a letter or digit")
+
+	validation.RegisterRule("unreserved", func(value reflect.Value, _ string) bool {
+		for _, reserved := range ReservedUsernames {
+			if strings.EqualFold(value.String(), reserved) {
+				return false
+			}
+		}
+		return true
+	}, "is reserved")
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
+)
+
+func TestUsernameRules(t *testing.T) {
+	tests := []struct {
+		username string
+		rule     string // the rule broken, or "" if valid
+	}{
+		{"alice", ""},
+		{"alice.smith", ""},
+		{"alice_smith-2", ""},
+		{"2alice", ""},
+		{"ALICE", ""},
+		{".alice", "username"},
+		{"-alice", "username"},
+		{"_alice", "username"},
+		{"alice smith", "username"},
+		{"alice@example", "username"},
+		{"alicé", "username"},
+		{"admin", "unreserved"},
+		{"Admin", "unreserved"},
+		{"ROOT", "unreserved"},
+		{"me", "min"},
+		{"admins", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			req := &CreateUserRequest{Email: "alice@example.com", Username: tt.username, Password: "secret123"}
+
+			err := validation.Validate(req)
+
+			if tt.rule == "" {
+				assert.NoError(t, err)
+				return
+			}
+			errs, ok := err.(validation.Errors)
+			if assert.True(t, ok, "got %v", err) && assert.Len(t, errs, 1) {
+				assert.Equal(t, "username", errs[0].Field)
+				assert.Equal(t, tt.rule, errs[0].Rule)
+			}
+		})
+	}
+}
+
+func TestUsernameRules_Optional(t *testing.T) {
+	assert.NoError(t, validation.Validate(&UpdateUserRequest{}))
+
+	err := validation.Validate(&UpdateUserRequest{Username: "system"})
+	if errs, ok := err.(validation.Errors); assert.True(t, ok) {
+		assert.Equal(t, "username is reserved", errs[0].Message)
+	}
+}
@@ -0,0 +1,122 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+)
+
+// DataFile is the optional JSON document loaded alongside the Rego modules.
+const DataFile = "data.json"
+
+// Entrypoints are the rules the service queries. A bundle that does not
+// define them would deny every request, so validation rejects it.
+var Entrypoints = []string{
+	"data.authz.allow",
+	"data.authz.decision",
+}
+
+var ErrNoModules = errors.New("bundle contains no rego modules")
+
+// LoadFS reads every .rego file and an optional data.json from the root of
+// fsys into a bundle.
+func LoadFS(fsys fs.FS) (*models.PolicyBundle, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy directory: %w", err)
+	}
+
+	bundle := &models.PolicyBundle{
+		Modules: map[string]string{},
+		Data:    map[string]interface{}{},
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		switch {
+		case strings.HasSuffix(name, ".rego") && !strings.HasSuffix(name, "_test.rego"):
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			bundle.Modules[name] = string(content)
+		case name == DataFile:
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			if err := json.Unmarshal(content, &bundle.Data); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	bundle.Revision = Revision(bundle)
+	return bundle, nil
+}
+
+// Revision derives a stable revision ID from the bundle contents, so that
+// uploading the same policies twice yields the same revision.
+func Revision(bundle *models.PolicyBundle) string {
+	names := make([]string, 0, len(bundle.Modules))
+	for name := range bundle.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, bundle.Modules[name])
+	}
+	// encoding/json sorts map keys, so this is deterministic.
+	data, _ := json.Marshal(bundle.Data)
+	hash.Write(data)
+
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// Compile parses and compiles the bundle's modules, returning every error
+// found. It also checks that the entrypoints the service relies on exist.
+func Compile(bundle *models.PolicyBundle) (*ast.Compiler, error) {
+	if len(bundle.Modules) == 0 {
+		return nil, ErrNoModules
+	}
+
+	parsed := make(map[string]*ast.Module, len(bundle.Modules))
+	for name, content := range bundle.Modules {
+		if path.Ext(name) != ".rego" {
+			return nil, fmt.Errorf("module %q must have a .rego extension", name)
+		}
+		module, err := ast.ParseModule(name, content)
+		if err != nil {
+			return nil, err
+		}
+		parsed[name] = module
+	}
+
+	compiler := ast.NewCompiler()
+	compiler.Compile(parsed)
+	if compiler.Failed() {
+		return nil, compiler.Errors
+	}
+
+	for _, entrypoint := range Entrypoints {
+		if len(compiler.GetRulesExact(ast.MustParseRef(entrypoint))) == 0 {
+			return nil, fmt.Errorf("bundle does not define required rule %s", entrypoint)
+		}
+	}
+
+	return compiler, nil
+}
@@ -0,0 +1,288 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrCircuitOpen     = errors.New("opa circuit breaker is open")
+	ErrUndefinedResult = errors.New("opa returned an undefined result")
+)
+
+// StatusError is returned when OPA answers with a non-2xx status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("opa returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// Config controls timeouts, retries and circuit breaking for OPA calls.
+type Config struct {
+	URL              string
+	Timeout          time.Duration // per attempt
+	MaxRetries       int
+	RetryBackoff     time.Duration
+	BreakerThreshold int // consecutive failures before opening; 0 disables
+	BreakerCooldown  time.Duration
+}
+
+// Client talks to the OPA REST API.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+	breaker    *breaker
+	tracer     trace.Tracer
+}
+
+func New(cfg Config) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 500 * time.Millisecond
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.Timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &Client{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+		},
+		breaker: newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		tracer:  otel.Tracer("opa-client"),
+	}
+}
+
+// Query evaluates the document at path (e.g. "authz/allow") with the given
+// input and decodes the result into out.
+func (c *Client) Query(ctx context.Context, path string, input interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return fmt.Errorf("failed to marshal opa input: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/data/%s", strings.TrimRight(c.cfg.URL, "/"), strings.TrimLeft(path, "/"))
+
+	ctx, span := c.tracer.Start(ctx, "OPA.Query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("opa.path", path),
+			attribute.String("http.method", http.MethodPost),
+			attribute.String("http.url", url),
+		),
+	)
+	defer span.End()
+
+	raw, err := c.call(ctx, url, body)
+	if err != nil {
+		return err
+	}
+
+	if len(raw) == 0 {
+		return ErrUndefinedResult
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode opa result: %w", err)
+	}
+	return nil
+}
+
+// Partial partially evaluates query through the Compile API with the given
+// references left unknown and returns the residual queries.
+func (c *Client) Partial(ctx context.Context, query string, input interface{}, unknowns []string) ([]ast.Body, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":    query,
+		"input":    input,
+		"unknowns": unknowns,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal opa input: %w", err)
+	}
+
+	url := strings.TrimRight(c.cfg.URL, "/") + "/v1/compile"
+
+	ctx, span := c.tracer.Start(ctx, "OPA.Partial",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("opa.query", query),
+			attribute.String("http.method", http.MethodPost),
+			attribute.String("http.url", url),
+		),
+	)
+	defer span.End()
+
+	raw, err := c.call(ctx, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Queries []ast.Body        `json:"queries"`
+		Support []json.RawMessage `json:"support"`
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode opa compile result: %w", err)
+		}
+	}
+	if len(result.Support) > 0 {
+		return nil, fmt.Errorf("partial evaluation of %s produced support rules", query)
+	}
+	return result.Queries, nil
+}
+
+// call posts body to url, retrying transient failures and keeping the
+// circuit breaker up to date, and returns the "result" member of the reply.
+func (c *Client) call(ctx context.Context, url string, body []byte) (json.RawMessage, error) {
+	span := trace.SpanFromContext(ctx)
+
+	if !c.breaker.allow() {
+		span.SetStatus(codes.Error, ErrCircuitOpen.Error())
+		return nil, ErrCircuitOpen
+	}
+
+	var raw json.RawMessage
+	var err error
+	for attempt := 0; ; attempt++ {
+		raw, err = c.do(ctx, url, body)
+		if err == nil || attempt >= c.cfg.MaxRetries || !retryable(err) {
+			break
+		}
+
+		wait := c.cfg.RetryBackoff << attempt
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(wait):
+			continue
+		}
+		break
+	}
+
+	switch {
+	case err == nil:
+		c.breaker.success()
+	case errors.Is(err, context.Canceled):
+		// The caller gave up; that says nothing about OPA's health.
+		c.breaker.release()
+	case retryable(err):
+		c.breaker.failure()
+	default:
+		// OPA answered, just not with something we could use.
+		c.breaker.success()
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return raw, nil
+}
+
+// Allow evaluates the boolean rule at path.
+func (c *Client) Allow(ctx context.Context, path string, input interface{}) (bool, error) {
+	var allowed bool
+	if err := c.Query(ctx, path, input, &allowed); err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+// BreakerState reports the circuit breaker state ("closed", "open" or "half-open").
+func (c *Client) BreakerState() string {
+	return c.breaker.currentState().String()
+}
+
+func (c *Client) do(ctx context.Context, url string, body []byte) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(payload)}
+	}
+
+	var decoded struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode opa response: %w", err)
+	}
+	return decoded.Result, nil
+}
+
+// Unavailable reports whether err means OPA could not be consulted: a
+// transport failure, a timeout, a 5xx response or an open circuit. Any
+// other error means OPA answered, e.g. with a 4xx for a missing or broken
+// policy, or with an undefined result.
+func Unavailable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryable reports whether err is worth another attempt: transport
+// failures, timeouts, 429 and 5xx responses.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var syntaxErr *json.SyntaxError
+	return !errors.As(err, &syntaxErr)
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(url string) *Client {
+	return New(Config{
+		URL:              url,
+		Timeout:          100 * time.Millisecond,
+		MaxRetries:       2,
+		RetryBackoff:     time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	})
+}
+
+func TestClient_Allow(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/data/authz/allow", r.URL.Path)
+			w.Write([]byte(`{"result": true}`))
+		}))
+		defer server.Close()
+
+		allowed, err := newTestClient(server.URL).Allow(context.Background(), "authz/allow", map[string]string{})
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("Error Status Is Not A Decision", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code": "invalid_parameter"}`))
+		}))
+		defer server.Close()
+
+		_, err := newTestClient(server.URL).Allow(context.Background(), "authz/allow", nil)
+		var statusErr *StatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	})
+
+	t.Run("Undefined Result", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		_, err := newTestClient(server.URL).Allow(context.Background(), "authz/allow", nil)
+		assert.ErrorIs(t, err, ErrUndefinedResult)
+	})
+
+	t.Run("Retries Server Errors", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"result": true}`))
+		}))
+		defer server.Close()
+
+		allowed, err := newTestClient(server.URL).Allow(context.Background(), "authz/allow", nil)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Times Out Hung Server", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		c := newTestClient(server.URL)
+		c.cfg.MaxRetries = 0
+
+		start := time.Now()
+		_, err := c.Allow(context.Background(), "authz/allow", nil)
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestClient_Partial(t *testing.T) {
+	t.Run("Residual Queries", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/compile", r.URL.Path)
+			w.Write([]byte(`{"result": {"queries": [[{"index": 0, "terms": [
+				{"type": "ref", "value": [{"type": "var", "value": "eq"}]},
+				{"type": "ref", "value": [{"type": "var", "value": "input"}, {"type": "string", "value": "row"}, {"type": "string", "value": "id"}]},
+				{"type": "number", "value": 7}
+			]}]]}}`))
+		}))
+		defer server.Close()
+
+		queries, err := newTestClient(server.URL).Partial(context.Background(), "data.authz.filters.users == true", map[string]string{}, []string{"input.row"})
+		assert.NoError(t, err)
+		if assert.Len(t, queries, 1) {
+			assert.Equal(t, "input.row.id = 7", queries[0].String())
+		}
+	})
+
+	t.Run("Never True", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"result": {}}`))
+		}))
+		defer server.Close()
+
+		queries, err := newTestClient(server.URL).Partial(context.Background(), "data.authz.filters.users == true", map[string]string{}, []string{"input.row"})
+		assert.NoError(t, err)
+		assert.Empty(t, queries)
+	})
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+	c.cfg.MaxRetries = 0
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Allow(context.Background(), "authz/allow", nil)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, "open", c.BreakerState())
+
+	_, err := c.Allow(context.Background(), "authz/allow", nil)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	assert.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	assert.False(t, b.allow(), "only one probe while half-open")
+
+	b.success()
+	assert.Equal(t, stateClosed, b.currentState())
+	assert.True(t, b.allow())
+}
+
+func TestClient_CanceledProbe(t *testing.T) {
+	var fail int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"result": true}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	c := newTestClient(server.URL)
+	c.cfg.MaxRetries = 0
+	c.breaker.threshold = 1
+	c.breaker.now = func() time.Time { return now }
+
+	_, err := c.Allow(context.Background(), "authz/allow", nil)
+	assert.Error(t, err)
+	assert.Equal(t, "open", c.BreakerState())
+
+	// The probe's caller gives up before OPA answers
+	now = now.Add(2 * time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Allow(ctx, "authz/allow", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The next call gets to probe, and closes the circuit
+	atomic.StoreInt32(&fail, 0)
+	allowed, err := c.Allow(context.Background(), "authz/allow", nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, "closed", c.BreakerState())
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// policyIDPrefix namespaces the policies this service manages so that stale
+// ones can be removed without touching policies loaded by other means.
+const policyIDPrefix = "bundle/"
+
+// Load pushes a bundle to OPA through the Policy and Data APIs: every module
+// is upserted, modules from earlier bundles are deleted and the data document
+// is replaced. Each call is atomic on its own, but the bundle as a whole is
+// not switched in a single step.
+func (c *Client) Load(ctx context.Context, bundle *models.PolicyBundle) error {
+	ctx, span := c.tracer.Start(ctx, "OPA.Load")
+	defer span.End()
+
+	for name, module := range bundle.Modules {
+		if err := c.send(ctx, http.MethodPut, "/v1/policies/"+policyIDPrefix+name, "text/plain", []byte(module), nil); err != nil {
+			span.RecordError(err)
+			return fmt.Errorf("failed to push module %s: %w", name, err)
+		}
+	}
+
+	var listed struct {
+		Result []struct {
+			ID string `json:"id"`
+		} `json:"result"`
+	}
+	if err := c.send(ctx, http.MethodGet, "/v1/policies", "", nil, &listed); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to list policies: %w", err)
+	}
+	for _, policy := range listed.Result {
+		name := strings.TrimPrefix(policy.ID, policyIDPrefix)
+		if name == policy.ID {
+			continue
+		}
+		if _, ok := bundle.Modules[name]; ok {
+			continue
+		}
+		if err := c.send(ctx, http.MethodDelete, "/v1/policies/"+policy.ID, "", nil, nil); err != nil {
+			span.RecordError(err)
+			return fmt.Errorf("failed to delete stale module %s: %w", policy.ID, err)
+		}
+	}
+
+	data := bundle.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle data: %w", err)
+	}
+	if err := c.send(ctx, http.MethodPut, "/v1/data", "application/json", body, nil); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to push data: %w", err)
+	}
+
+	return nil
+}
+
+// send performs a single management API call. Management calls are rare and
+// operator-driven, so they bypass the retry loop and circuit breaker.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	url := strings.TrimRight(c.cfg.URL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(payload)}
+	}
+	if out != nil {
+		return json.Unmarshal(payload, out)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package decisionlog
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// DBSink stores decisions in the authz_decisions table.
+type DBSink struct {
+	db *gorm.DB
+}
+
+func NewDBSink(db *gorm.DB) *DBSink {
+	return &DBSink{db: db}
+}
+
+func (s *DBSink) Write(ctx context.Context, entries []*Entry) error {
+	return s.db.WithContext(ctx).CreateInBatches(entries, len(entries)).Error
+}
+
+func (s *DBSink) Close() error {
+	return nil
+}
+
+// ErasePrincipal replaces principal in every stored decision, for data
+// erasure requests, and returns the number of decisions changed. Decisions
+// already written by the file sink are not rewritten.
+func ErasePrincipal(ctx context.Context, db *gorm.DB, principal string) (int64, error) {
+	result := db.WithContext(ctx).Model(&Entry{}).Where("principal = ?", principal).Update("principal", ErasedPrincipal)
+	return result.RowsAffected, result.Error
+}
+
+// ForPrincipal returns every stored decision made for principal, oldest
+// first, for data access requests.
+func ForPrincipal(ctx context.Context, db *gorm.DB, principal string) ([]*Entry, error) {
+	var entries []*Entry
+	err := db.WithContext(ctx).Where("principal = ?", principal).Order("timestamp").Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,153 @@
+package decisionlog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+// Entry is a single authorization decision.
+type Entry struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Timestamp      time.Time `json:"timestamp" gorm:"index;not null"`
+	RequestID      string    `json:"request_id" gorm:"index"`
+	InputHash      string    `json:"input_hash" gorm:"index;not null"`
+	Principal      string    `json:"principal" gorm:"index"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	Action         string    `json:"action" gorm:"index"`
+	Resource       string    `json:"resource"`
+	Allowed        bool      `json:"allowed"`
+	MatchedRules   []string  `json:"matched_rules" gorm:"serializer:json"`
+	PolicyRevision string    `json:"policy_revision"`
+	LatencyMS      float64   `json:"latency_ms"`
+	Error          string    `json:"error,omitempty"`
+}
+
+func (Entry) TableName() string {
+	return "authz_decisions"
+}
+
+// ErasedPrincipal replaces the principal of decisions made for a user whose
+// data has been erased.
+const ErasedPrincipal = "erased"
+
+// Sink persists decision log entries.
+type Sink interface {
+	Write(ctx context.Context, entries []*Entry) error
+	Close() error
+}
+
+// HashInput returns a stable fingerprint of an OPA input document so that
+// identical requests can be correlated without storing the whole input.
+func HashInput(input interface{}) string {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Recorder buffers entries and writes them to a Sink in the background so
+// that logging never adds latency to the request being authorized. When the
+// buffer is full new entries are dropped and a warning is logged. Entries
+// recorded after Close are dropped too.
+type Recorder struct {
+	sink      Sink
+	logger    logger.Logger
+	entries   chan *Entry
+	batchSize int
+	interval  time.Duration
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	// mu guards closed, so that no entry is sent once entries is closed
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewRecorder(sink Sink, logger logger.Logger, bufferSize int) *Recorder {
+	if bufferSize <= 0 {
+		bufferSize = 1024
+	}
+	r := &Recorder{
+		sink:      sink,
+		logger:    logger,
+		entries:   make(chan *Entry, bufferSize),
+		batchSize: 100,
+		interval:  time.Second,
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+func (r *Recorder) Record(entry *Entry) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.entries <- entry:
+	default:
+		r.logger.Warn("Decision log buffer full, dropping entry for request ", entry.RequestID)
+	}
+}
+
+// Close flushes buffered entries and closes the sink.
+func (r *Recorder) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		r.mu.Lock()
+		r.closed = true
+		close(r.entries)
+		r.mu.Unlock()
+		r.wg.Wait()
+		err = r.sink.Close()
+	})
+	return err
+}
+
+func (r *Recorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	batch := make([]*Entry, 0, r.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.sink.Write(context.Background(), batch); err != nil {
+			r.logger.Error("Failed to write decision log: ", err)
+		}
+		batch = make([]*Entry, 0, r.batchSize)
+	}
+
+	for {
+		select {
+		case entry, ok := <-r.entries:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= r.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package decisionlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	entries []*Entry
+	closed  bool
+}
+
+func (s *memorySink) Write(ctx context.Context, entries []*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestHashInput(t *testing.T) {
+	a := HashInput(map[string]string{"method": "GET", "path": "/api/v1/users"})
+	b := HashInput(map[string]string{"path": "/api/v1/users", "method": "GET"})
+	c := HashInput(map[string]string{"method": "POST", "path": "/api/v1/users"})
+
+	assert.Len(t, a, 64)
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestRecorder_FlushesOnClose(t *testing.T) {
+	sink := &memorySink{}
+	recorder := NewRecorder(sink, logger.New("error"), 10)
+
+	recorder.Record(&Entry{RequestID: "req-1", Allowed: true})
+	recorder.Record(&Entry{RequestID: "req-2", Allowed: false})
+	require.NoError(t, recorder.Close())
+
+	assert.True(t, sink.closed)
+	require.Len(t, sink.entries, 2)
+	assert.Equal(t, "req-1", sink.entries[0].RequestID)
+	assert.False(t, sink.entries[0].Timestamp.IsZero())
+}
+
+func TestRecorder_DropsAfterClose(t *testing.T) {
+	sink := &memorySink{}
+	recorder := NewRecorder(sink, logger.New("error"), 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				recorder.Record(&Entry{RequestID: "req"})
+			}
+		}()
+	}
+	require.NoError(t, recorder.Close())
+	wg.Wait()
+
+	assert.NotPanics(t, func() {
+		recorder.Record(&Entry{RequestID: "late"})
+	})
+	require.NoError(t, recorder.Close())
+	for _, entry := range sink.entries {
+		assert.NotEqual(t, "late", entry.RequestID)
+	}
+}
+
+func TestFileSink_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	sink, err := NewFileSink(path, 200, 2)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, sink.Write(context.Background(), []*Entry{{
+			Principal:    "1",
+			Path:         "/api/v1/users",
+			MatchedRules: []string{"admin_users"},
+		}}))
+	}
+	require.NoError(t, sink.Close())
+
+	assert.FileExists(t, path+".1")
+	assert.FileExists(t, path+".2")
+	assert.NoFileExists(t, path+".3")
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	require.True(t, scanner.Scan())
+	var entry Entry
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+	assert.Equal(t, []string{"admin_users"}, entry.MatchedRules)
+}
@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/bundle"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrNotLoaded       = errors.New("no policy bundle loaded")
+	ErrUndefinedResult = errors.New("policy returned an undefined result")
+)
+
+// Engine evaluates policies in-process. Loading a bundle swaps the compiled
+// policies atomically, so requests in flight finish on the old revision and
+// new ones see the new revision without a restart.
+type Engine struct {
+	mu     sync.RWMutex
+	state  *state
+	tracer trace.Tracer
+}
+
+type state struct {
+	revision string
+	compiler *ast.Compiler
+	store    storage.Store
+
+	mu       sync.Mutex
+	prepared map[string]rego.PreparedEvalQuery
+}
+
+func New() *Engine {
+	return &Engine{
+		tracer: otel.Tracer("opa-engine"),
+	}
+}
+
+// Load compiles bundle and makes it the active policy set.
+func (e *Engine) Load(ctx context.Context, b *models.PolicyBundle) error {
+	compiler, err := bundle.Compile(b)
+	if err != nil {
+		return fmt.Errorf("failed to compile bundle %s: %w", b.Revision, err)
+	}
+
+	data := b.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	next := &state{
+		revision: b.Revision,
+		compiler: compiler,
+		store:    inmem.NewFromObject(data),
+		prepared: map[string]rego.PreparedEvalQuery{},
+	}
+
+	e.mu.Lock()
+	e.state = next
+	e.mu.Unlock()
+	return nil
+}
+
+// Revision returns the revision of the loaded bundle.
+func (e *Engine) Revision() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.state == nil {
+		return ""
+	}
+	return e.state.revision
+}
+
+// Query evaluates the document at path (e.g. "authz/decision") with input
+// and decodes the result into out.
+func (e *Engine) Query(ctx context.Context, path string, input interface{}, out interface{}) error {
+	e.mu.RLock()
+	st := e.state
+	e.mu.RUnlock()
+	if st == nil {
+		return ErrNotLoaded
+	}
+
+	ctx, span := e.tracer.Start(ctx, "OPA.Eval", trace.WithAttributes(
+		attribute.String("opa.path", path),
+		attribute.String("opa.revision", st.revision),
+	))
+	defer span.End()
+
+	query, err := st.prepare(ctx, path)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	results, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to evaluate %s: %w", path, err)
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return ErrUndefinedResult
+	}
+
+	// Round-trip through JSON so callers decode into their own types exactly
+	// as they would from the OPA REST API.
+	raw, err := json.Marshal(results[0].Expressions[0].Value)
+	if err != nil {
+		return fmt.Errorf("failed to encode policy result: %w", err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode policy result: %w", err)
+	}
+	return nil
+}
+
+// Partial partially evaluates query with the given references left unknown
+// and returns the residual queries. Policies that need support rules cannot
+// be expressed as plain conditions and are rejected.
+func (e *Engine) Partial(ctx context.Context, query string, input interface{}, unknowns []string) ([]ast.Body, error) {
+	e.mu.RLock()
+	st := e.state
+	e.mu.RUnlock()
+	if st == nil {
+		return nil, ErrNotLoaded
+	}
+
+	ctx, span := e.tracer.Start(ctx, "OPA.Partial", trace.WithAttributes(
+		attribute.String("opa.query", query),
+		attribute.String("opa.revision", st.revision),
+	))
+	defer span.End()
+
+	partial, err := rego.New(
+		rego.Query(query),
+		rego.Compiler(st.compiler),
+		rego.Store(st.store),
+		rego.Input(input),
+		rego.Unknowns(unknowns),
+	).Partial(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to partially evaluate %s: %w", query, err)
+	}
+	if len(partial.Support) > 0 {
+		return nil, fmt.Errorf("partial evaluation of %s produced support rules", query)
+	}
+	return partial.Queries, nil
+}
+
+func (s *state) prepare(ctx context.Context, path string) (rego.PreparedEvalQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if query, ok := s.prepared[path]; ok {
+		return query, nil
+	}
+
+	query, err := rego.New(
+		rego.Query(PathToRef(path)),
+		rego.Compiler(s.compiler),
+		rego.Store(s.store),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("failed to prepare %s: %w", path, err)
+	}
+
+	s.prepared[path] = query
+	return query, nil
+}
+
+// PathToRef turns a REST-style document path ("authz/allow") into a Rego
+// reference ("data.authz.allow").
+func PathToRef(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "data"
+	}
+	return "data." + strings.ReplaceAll(path, "/", ".")
+}
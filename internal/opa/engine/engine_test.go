@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/bundle"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/policies"
+)
+
+type decision struct {
+	Allow        bool     `json:"allow"`
+	MatchedRules []string `json:"matched_rules"`
+}
+
+func TestEngine_Query(t *testing.T) {
+	builtin, err := bundle.LoadFS(policies.FS)
+	require.NoError(t, err)
+
+	e := New()
+	var out decision
+	assert.ErrorIs(t, e.Query(context.Background(), "authz/decision", nil, &out), ErrNotLoaded)
+
+	require.NoError(t, e.Load(context.Background(), builtin))
+	assert.Equal(t, builtin.Revision, e.Revision())
+
+	input := map[string]interface{}{
+		"action":   "users:list",
+		"resource": map[string]interface{}{"type": "user"},
+		"user":     map[string]interface{}{"id": "1", "roles": []string{"admin"}},
+	}
+	require.NoError(t, e.Query(context.Background(), "authz/decision", input, &out))
+	assert.True(t, out.Allow)
+	assert.Contains(t, out.MatchedRules, "admin_users")
+}
+
+func TestEngine_HotReload(t *testing.T) {
+	e := New()
+	denyAll := &models.PolicyBundle{
+		Revision: "deny-all",
+		Modules: map[string]string{
+			"authz.rego": "package authz\n\ndefault allow := false\n\ndecision := {\"allow\": allow, \"matched_rules\": []}\n",
+		},
+	}
+	allowAll := &models.PolicyBundle{
+		Revision: "allow-all",
+		Modules: map[string]string{
+			"authz.rego": "package authz\n\ndefault allow := true\n\ndecision := {\"allow\": allow, \"matched_rules\": [\"everything\"]}\n",
+		},
+	}
+
+	var allowed bool
+	require.NoError(t, e.Load(context.Background(), denyAll))
+	require.NoError(t, e.Query(context.Background(), "authz/allow", map[string]interface{}{}, &allowed))
+	assert.False(t, allowed)
+
+	require.NoError(t, e.Load(context.Background(), allowAll))
+	require.NoError(t, e.Query(context.Background(), "authz/allow", map[string]interface{}{}, &allowed))
+	assert.True(t, allowed)
+	assert.Equal(t, "allow-all", e.Revision())
+}
+
+func TestEngine_LoadRejectsInvalidBundle(t *testing.T) {
+	e := New()
+
+	err := e.Load(context.Background(), &models.PolicyBundle{
+		Modules: map[string]string{"authz.rego": "package authz\n\nallow if {"},
+	})
+	assert.Error(t, err)
+
+	err = e.Load(context.Background(), &models.PolicyBundle{
+		Modules: map[string]string{"authz.rego": "package authz\n\ndefault allow := false\n"},
+	})
+	assert.ErrorContains(t, err, "data.authz.decision")
+	assert.Equal(t, "", e.Revision())
+}
+
+func TestPathToRef(t *testing.T) {
+	assert.Equal(t, "data.authz.allow", PathToRef("authz/allow"))
+	assert.Equal(t, "data.authz.data.filtered_user_fields", PathToRef("/authz/data/filtered_user_fields"))
+	assert.Equal(t, "data", PathToRef(""))
+}
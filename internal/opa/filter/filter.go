@@ -0,0 +1,193 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+)
+
+// RowRef is the unknown that list filter rules use to refer to the row being
+// checked, e.g. input.row.id == to_number(input.user.id).
+const RowRef = "input.row"
+
+var (
+	ErrUnknownResource = errors.New("no row filter registered for resource")
+	ErrUnsupported     = errors.New("policy cannot be translated into a row filter")
+)
+
+// Partialer partially evaluates query with the given unknowns and returns the
+// residual queries. It is implemented by the embedded evaluator and by the
+// remote OPA client (through the Compile API).
+type Partialer interface {
+	Partial(ctx context.Context, query string, input interface{}, unknowns []string) ([]ast.Body, error)
+}
+
+// Resource describes how a list endpoint is filtered: the rule that decides
+// whether a row is visible and the row fields that may appear in filters,
+// mapped to their SQL columns.
+type Resource struct {
+	Rule    string
+	Columns map[string]string
+}
+
+// Builder turns row-level policy rules into RowFilters.
+type Builder struct {
+	partialer Partialer
+	resources map[string]Resource
+}
+
+func NewBuilder(partialer Partialer) *Builder {
+	return &Builder{
+		partialer: partialer,
+		resources: map[string]Resource{},
+	}
+}
+
+func (b *Builder) Register(name string, resource Resource) {
+	b.resources[name] = resource
+}
+
+// Build partially evaluates the resource's rule for input, leaving the row
+// unknown, and translates what remains into a RowFilter.
+func (b *Builder) Build(ctx context.Context, name string, input interface{}) (*models.RowFilter, error) {
+	resource, ok := b.resources[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownResource, name)
+	}
+
+	queries, err := b.partialer.Partial(ctx, resource.Rule+" == true", input, []string{RowRef})
+	if err != nil {
+		return nil, err
+	}
+	return Translate(queries, resource.Columns)
+}
+
+var operators = map[string]string{
+	ast.Equal.Name:         "=",
+	ast.Equality.Name:      "=",
+	ast.NotEqual.Name:      "<>",
+	ast.LessThan.Name:      "<",
+	ast.LessThanEq.Name:    "<=",
+	ast.GreaterThan.Name:   ">",
+	ast.GreaterThanEq.Name: ">=",
+}
+
+// flipped gives the operator to use when the row reference is on the right.
+var flipped = map[string]string{
+	"=":  "=",
+	"<>": "<>",
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+}
+
+// Translate converts residual queries into a RowFilter. Each query becomes a
+// clause; an empty query means the rule holds for every row. Only
+// comparisons between a whitelisted row field and a constant are supported.
+func Translate(queries []ast.Body, columns map[string]string) (*models.RowFilter, error) {
+	filter := &models.RowFilter{}
+
+	for _, query := range queries {
+		if len(query) == 0 {
+			return &models.RowFilter{Unrestricted: true}, nil
+		}
+
+		clause := make([]models.RowCondition, 0, len(query))
+		for _, expr := range query {
+			condition, err := translateExpr(expr, columns)
+			if err != nil {
+				return nil, err
+			}
+			clause = append(clause, condition)
+		}
+		filter.Clauses = append(filter.Clauses, clause)
+	}
+
+	return filter, nil
+}
+
+func translateExpr(expr *ast.Expr, columns map[string]string) (models.RowCondition, error) {
+	if expr.Negated || len(expr.With) > 0 {
+		return models.RowCondition{}, fmt.Errorf("%w: %v", ErrUnsupported, expr)
+	}
+
+	// A bare reference such as input.row.is_active means "is true".
+	if term, ok := expr.Terms.(*ast.Term); ok {
+		column, err := columnFor(term, columns)
+		if err != nil {
+			return models.RowCondition{}, err
+		}
+		return models.RowCondition{Column: column, Operator: "=", Value: true}, nil
+	}
+
+	if !expr.IsCall() || len(expr.Operands()) != 2 {
+		return models.RowCondition{}, fmt.Errorf("%w: %v", ErrUnsupported, expr)
+	}
+
+	operator, ok := operators[expr.Operator().String()]
+	if !ok {
+		return models.RowCondition{}, fmt.Errorf("%w: operator %v", ErrUnsupported, expr.Operator())
+	}
+
+	left, right := expr.Operand(0), expr.Operand(1)
+	if !isRowRef(left) {
+		left, right = right, left
+		operator = flipped[operator]
+	}
+
+	column, err := columnFor(left, columns)
+	if err != nil {
+		return models.RowCondition{}, err
+	}
+	value, err := constant(right)
+	if err != nil {
+		return models.RowCondition{}, err
+	}
+
+	return models.RowCondition{Column: column, Operator: operator, Value: value}, nil
+}
+
+func isRowRef(term *ast.Term) bool {
+	ref, ok := term.Value.(ast.Ref)
+	return ok && ref.HasPrefix(ast.MustParseRef(RowRef))
+}
+
+func columnFor(term *ast.Term, columns map[string]string) (string, error) {
+	if !isRowRef(term) {
+		return "", fmt.Errorf("%w: expected a reference to %s, got %v", ErrUnsupported, RowRef, term)
+	}
+
+	ref := term.Value.(ast.Ref)
+	field := strings.TrimPrefix(ref.String(), RowRef+".")
+	column, ok := columns[field]
+	if !ok {
+		return "", fmt.Errorf("%w: field %q is not filterable", ErrUnsupported, field)
+	}
+	return column, nil
+}
+
+func constant(term *ast.Term) (interface{}, error) {
+	switch value := term.Value.(type) {
+	case ast.String:
+		return string(value), nil
+	case ast.Boolean:
+		return bool(value), nil
+	case ast.Number:
+		if i, ok := value.Int64(); ok {
+			return i, nil
+		}
+		f, err := json.Number(value.String()).Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupported, value)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("%w: expected a constant, got %v", ErrUnsupported, term)
+	}
+}
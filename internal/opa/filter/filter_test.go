@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/bundle"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/engine"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/policies"
+)
+
+var userColumns = map[string]string{"id": "id", "is_active": "is_active"}
+
+func newBuilder(t *testing.T) *Builder {
+	b, err := bundle.LoadFS(policies.FS)
+	require.NoError(t, err)
+
+	e := engine.New()
+	require.NoError(t, e.Load(context.Background(), b))
+
+	builder := NewBuilder(e)
+	builder.Register("users", Resource{Rule: "data.authz.filters.users", Columns: userColumns})
+	return builder
+}
+
+func input(id string, roles ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"user": map[string]interface{}{"id": id, "roles": roles},
+	}
+}
+
+func TestBuild_UsersPolicy(t *testing.T) {
+	builder := newBuilder(t)
+	ctx := context.Background()
+
+	t.Run("AdminIsUnrestricted", func(t *testing.T) {
+		filter, err := builder.Build(ctx, "users", input("1", "admin"))
+		require.NoError(t, err)
+		assert.True(t, filter.Unrestricted)
+	})
+
+	t.Run("UserSeesOwnRow", func(t *testing.T) {
+		filter, err := builder.Build(ctx, "users", input("7", "user"))
+		require.NoError(t, err)
+		assert.False(t, filter.Unrestricted)
+		assert.Equal(t, [][]models.RowCondition{
+			{{Column: "id", Operator: "=", Value: int64(7)}},
+		}, filter.Clauses)
+	})
+
+	t.Run("AnonymousSeesNothing", func(t *testing.T) {
+		filter, err := builder.Build(ctx, "users", map[string]interface{}{})
+		require.NoError(t, err)
+		assert.False(t, filter.Unrestricted)
+		assert.Empty(t, filter.Clauses)
+	})
+
+	t.Run("UnknownResource", func(t *testing.T) {
+		_, err := builder.Build(ctx, "orders", input("1"))
+		assert.True(t, errors.Is(err, ErrUnknownResource))
+	})
+}
+
+func TestTranslate(t *testing.T) {
+	t.Run("FlipsReversedComparisons", func(t *testing.T) {
+		queries := []ast.Body{ast.MustParseBody(`10 > input.row.id; input.row.is_active`)}
+
+		filter, err := Translate(queries, userColumns)
+		require.NoError(t, err)
+		assert.Equal(t, [][]models.RowCondition{{
+			{Column: "id", Operator: "<", Value: int64(10)},
+			{Column: "is_active", Operator: "=", Value: true},
+		}}, filter.Clauses)
+	})
+
+	t.Run("RejectsUnlistedFields", func(t *testing.T) {
+		queries := []ast.Body{ast.MustParseBody(`input.row.password == "x"`)}
+
+		_, err := Translate(queries, userColumns)
+		assert.True(t, errors.Is(err, ErrUnsupported))
+	})
+
+	t.Run("RejectsNegation", func(t *testing.T) {
+		queries := []ast.Body{ast.MustParseBody(`not input.row.is_active`)}
+
+		_, err := Translate(queries, userColumns)
+		assert.True(t, errors.Is(err, ErrUnsupported))
+	})
+}
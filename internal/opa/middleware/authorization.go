@@ -1,47 +1,94 @@
 package middleware
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/client"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/decisionlog"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/filter"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
 )
 
+// FailMode decides what happens to a request when OPA cannot be reached.
+type FailMode string
+
+const (
+	// FailClosed rejects every request while OPA is unavailable.
+	FailClosed FailMode = "fail-closed"
+	// FailOpenForReads lets safe methods (GET, HEAD, OPTIONS) through while
+	// OPA is unavailable (see client.Unavailable) and rejects everything
+	// else, including reads OPA answered with an error.
+	FailOpenForReads FailMode = "fail-open-for-reads"
+)
+
+const decisionPath = "authz/decision"
+
+// Querier evaluates a policy document. It is implemented by the remote OPA
+// client and by the embedded evaluator.
+type Querier interface {
+	Query(ctx context.Context, path string, input interface{}, out interface{}) error
+}
+
 type OPAMiddleware struct {
-	opaURL    string
-	logger    logger.Logger
-	jwtSecret string
+	client       Querier
+	failMode     FailMode
+	logger       logger.Logger
+	jwtSecret    string
+	decisionLog  *decisionlog.Recorder
+	shadow       *Shadow
+	revision     func() string
+	rowFilters   *filter.Builder
+	fieldRules   map[string]string
+	accountCheck func(ctx context.Context, userID string) error
+	resolveUser  func(ctx context.Context, user *User) error
 }
 
+// User is the caller as policies see it in input.user. Roles include those
+// granted through Groups.
 type User struct {
-	ID    string   `json:"id"`
-	Email string   `json:"email"`
-	Roles []string `json:"roles"`
+	ID     string   `json:"id"`
+	Email  string   `json:"email"`
+	Roles  []string `json:"roles"`
+	Groups []string `json:"groups"`
 }
 
-type OPAInput struct {
-	Method string `json:"method"`
-	Path   string `json:"path"`
-	User   *User  `json:"user"`
+// Resource identifies what a request acts on. ID is empty for collections.
+type Resource struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
 }
 
-type OPARequest struct {
-	Input OPAInput `json:"input"`
+// OPAInput is the document policies are evaluated against. Policies decide on
+// Action and Resource, which routes declare through Permissions.Require;
+// Method and Path are included for logging only.
+type OPAInput struct {
+	Action   string    `json:"action"`
+	Resource *Resource `json:"resource,omitempty"`
+	Method   string    `json:"method"`
+	Path     string    `json:"path"`
+	User     *User     `json:"user"`
 }
 
-type OPAResponse struct {
-	Result bool `json:"result"`
+// Decision is the result of evaluating the authz policy for an input.
+type Decision struct {
+	Allow        bool     `json:"allow"`
+	MatchedRules []string `json:"matched_rules"`
 }
 
-func NewOPAMiddleware(opaURL string, logger logger.Logger) *OPAMiddleware {
+func NewOPAMiddleware(opaClient Querier, failMode FailMode, logger logger.Logger) *OPAMiddleware {
+	if failMode != FailOpenForReads {
+		failMode = FailClosed
+	}
 	return &OPAMiddleware{
-		opaURL: opaURL,
-		logger: logger,
+		client:   opaClient,
+		failMode: failMode,
+		logger:   logger,
 	}
 }
 
@@ -49,50 +96,221 @@ func (m *OPAMiddleware) SetJWTSecret(secret string) {
 	m.jwtSecret = secret
 }
 
+// SetAccountCheck makes Authorize reject tokens whose user check refuses,
+// e.g. because the account has been suspended since the token was issued.
+// The error check returns is passed on as the response.
+func (m *OPAMiddleware) SetAccountCheck(check func(ctx context.Context, userID string) error) {
+	m.accountCheck = check
+}
+
+// SetUserResolver makes Authorize refresh the caller's roles and groups
+// with resolve, so that changes to group membership apply before the token
+// is renewed. The error resolve returns is passed on as the response.
+func (m *OPAMiddleware) SetUserResolver(resolve func(ctx context.Context, user *User) error) {
+	m.resolveUser = resolve
+}
+
+// SetDecisionLog records every decision made by Authorize.
+func (m *OPAMiddleware) SetDecisionLog(recorder *decisionlog.Recorder) {
+	m.decisionLog = recorder
+}
+
+// SetShadow evaluates a candidate policy alongside every enforced decision.
+func (m *OPAMiddleware) SetShadow(shadow *Shadow) {
+	m.shadow = shadow
+}
+
+// SetRowFilters enables FilterRows for the resources registered on builder.
+func (m *OPAMiddleware) SetRowFilters(builder *filter.Builder) {
+	m.rowFilters = builder
+}
+
+// SetFieldFilter makes FilterFields evaluate the policy document at path
+// (e.g. "authz/data/filtered_user_fields") to decide which fields of
+// resource the caller may see. The document must be a set of field names.
+func (m *OPAMiddleware) SetFieldFilter(resource, path string) {
+	if m.fieldRules == nil {
+		m.fieldRules = make(map[string]string)
+	}
+	m.fieldRules[resource] = path
+}
+
+// SetRevisionFunc sets where the policy revision reported in decision logs
+// and explanations comes from.
+func (m *OPAMiddleware) SetRevisionFunc(revision func() string) {
+	m.revision = revision
+}
+
+// PolicyRevision returns the revision of the policy currently in force.
+func (m *OPAMiddleware) PolicyRevision() string {
+	if m.revision == nil {
+		return ""
+	}
+	return m.revision()
+}
+
+// Evaluate asks OPA for a decision on input without enforcing it.
+func (m *OPAMiddleware) Evaluate(ctx context.Context, input OPAInput) (*Decision, error) {
+	var decision Decision
+	if err := m.client.Query(ctx, decisionPath, input, &decision); err != nil {
+		return nil, err
+	}
+	if decision.MatchedRules == nil {
+		decision.MatchedRules = []string{}
+	}
+	return &decision, nil
+}
+
+// Authorize authenticates the bearer token and stores the caller in
+// Locals("user"). What the caller may do is decided per route by the handler
+// returned from Permissions.Require.
 func (m *OPAMiddleware) Authorize() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Extract token from Authorization header
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Missing authorization header",
-			})
+			return problem.New(problem.CodeUnauthorized, "Missing authorization header")
 		}
 
 		// Remove Bearer prefix
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid authorization header format",
-			})
+			return problem.New(problem.CodeUnauthorized, "Invalid authorization header format")
 		}
 
 		// Parse and validate token
 		user, err := m.parseToken(tokenString)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token",
-			})
+			return problem.Wrap(err, problem.CodeUnauthorized, "Invalid token")
+		}
+
+		if m.accountCheck != nil {
+			if err := m.accountCheck(requestContext(c), user.ID); err != nil {
+				return err
+			}
+		}
+		if m.resolveUser != nil {
+			if err := m.resolveUser(requestContext(c), user); err != nil {
+				return err
+			}
 		}
 
 		// Store user in context
 		c.Locals("user", user)
 
-		// Check authorization with OPA
-		allowed, err := m.checkAuthorization(c.Method(), c.Path(), user)
+		return c.Next()
+	}
+}
+
+// UserID returns the ID of the caller Authorize stored, or "" if there is
+// none.
+func UserID(c *fiber.Ctx) string {
+	if user, ok := c.Locals("user").(*User); ok && user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// check asks OPA whether the caller may perform permission on the current
+// request and either continues the chain or rejects the request.
+func (m *OPAMiddleware) check(c *fiber.Ctx, permission Permission) error {
+	user, _ := c.Locals("user").(*User)
+	input := OPAInput{
+		Action:   permission.Action,
+		Resource: &Resource{Type: permission.Resource},
+		Method:   c.Method(),
+		Path:     c.Path(),
+		User:     user,
+	}
+	if permission.IDParam != "" {
+		input.Resource.ID = c.Params(permission.IDParam)
+	}
+
+	start := time.Now()
+	decision, err := m.Evaluate(requestContext(c), input)
+	latency := time.Since(start)
+
+	if err != nil {
+		m.logger.Error("Failed to check authorization: ", err)
+		// Only an outage lets reads through; an error OPA answered with,
+		// such as a missing policy, denies like any other broken policy
+		allowed := m.failMode == FailOpenForReads && isReadMethod(c.Method()) && client.Unavailable(err)
+		m.record(c, input, &Decision{Allow: allowed}, latency, err)
+		if allowed {
+			m.logger.Warn("OPA unavailable, allowing read request: ", c.Method(), " ", c.Path())
+			return c.Next()
+		}
+		return problem.Wrap(err, problem.CodeServiceUnavailable, "Authorization service unavailable")
+	}
+
+	m.record(c, input, decision, latency, nil)
+	if m.shadow != nil {
+		requestID, _ := c.Locals("request_id").(string)
+		m.shadow.Compare(requestContext(c), requestID, input, decision)
+	}
+
+	if !decision.Allow {
+		return problem.New(problem.CodeForbidden, "Access denied")
+	}
+
+	return c.Next()
+}
+
+// FilterRows asks the policy which rows of resource the caller may see and
+// stores the answer in Locals("row_filter") for the handler to apply. It must
+// run after Authorize.
+func (m *OPAMiddleware) FilterRows(resource string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.rowFilters == nil {
+			return c.Next()
+		}
+
+		user, _ := c.Locals("user").(*User)
+		input := OPAInput{
+			Resource: &Resource{Type: resource},
+			Method:   c.Method(),
+			Path:     c.Path(),
+			User:     user,
+		}
+
+		rowFilter, err := m.rowFilters.Build(requestContext(c), resource, input)
 		if err != nil {
-			m.logger.Error("Failed to check authorization: ", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Authorization check failed",
-			})
+			m.logger.Error("Failed to build row filter: ", err)
+			return problem.Wrap(err, problem.CodeServiceUnavailable, "Authorization service unavailable")
+		}
+
+		c.Locals("row_filter", rowFilter)
+		return c.Next()
+	}
+}
+
+// FilterFields stores the fields of resource the caller may see in
+// Locals("field_filter") as a []string. Nothing is stored when no field
+// filter is registered for resource, meaning every field is visible. On
+// routes for a single resource, its :id is sent as input.resource.id, so
+// policies can let callers see more of their own record.
+func (m *OPAMiddleware) FilterFields(resource string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path, ok := m.fieldRules[resource]
+		if !ok {
+			return c.Next()
 		}
 
-		if !allowed {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Access denied",
-			})
+		user, _ := c.Locals("user").(*User)
+		input := OPAInput{
+			Resource: &Resource{Type: resource, ID: c.Params("id")},
+			Method:   c.Method(),
+			Path:     c.Path(),
+			User:     user,
 		}
 
+		fields := []string{}
+		if err := m.client.Query(requestContext(c), path, input, &fields); err != nil {
+			m.logger.Error("Failed to evaluate field filter: ", err)
+			return problem.Wrap(err, problem.CodeServiceUnavailable, "Authorization service unavailable")
+		}
+
+		c.Locals("field_filter", fields)
 		return c.Next()
 	}
 }
@@ -110,54 +328,75 @@ func (m *OPAMiddleware) parseToken(tokenString string) (*User, error) {
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		roles, _ := claims["roles"].([]interface{})
-		roleStrings := make([]string, len(roles))
-		for i, r := range roles {
-			roleStrings[i] = r.(string)
-		}
-
 		return &User{
-			ID:    claims["sub"].(string),
-			Email: claims["email"].(string),
-			Roles: roleStrings,
+			ID:     claims["sub"].(string),
+			Email:  claims["email"].(string),
+			Roles:  ClaimStrings(claims, "roles"),
+			Groups: ClaimStrings(claims, "groups"),
 		}, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
 
-func (m *OPAMiddleware) checkAuthorization(method, path string, user *User) (bool, error) {
-	// Create OPA request
-	opaReq := OPARequest{
-		Input: OPAInput{
-			Method: method,
-			Path:   path,
-			User:   user,
-		},
+// ClaimStrings returns the string list claim name, or an empty list if the
+// token has none.
+func ClaimStrings(claims jwt.MapClaims, name string) []string {
+	values, _ := claims[name].([]interface{})
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			strs = append(strs, str)
+		}
 	}
+	return strs
+}
 
-	// Marshal request
-	body, err := json.Marshal(opaReq)
-	if err != nil {
-		return false, err
+func (m *OPAMiddleware) record(c *fiber.Ctx, input OPAInput, decision *Decision, latency time.Duration, evalErr error) {
+	if m.decisionLog == nil {
+		return
 	}
 
-	// Send request to OPA
-	resp, err := http.Post(
-		fmt.Sprintf("%s/v1/data/authz/allow", m.opaURL),
-		"application/json",
-		bytes.NewBuffer(body),
-	)
-	if err != nil {
-		return false, err
+	requestID, _ := c.Locals("request_id").(string)
+	entry := &decisionlog.Entry{
+		RequestID:      requestID,
+		InputHash:      decisionlog.HashInput(input),
+		Method:         input.Method,
+		Path:           input.Path,
+		Action:         input.Action,
+		Allowed:        decision.Allow,
+		MatchedRules:   decision.MatchedRules,
+		PolicyRevision: m.PolicyRevision(),
+		LatencyMS:      float64(latency.Microseconds()) / 1000,
+	}
+	if input.User != nil {
+		entry.Principal = input.User.ID
+	}
+	if input.Resource != nil {
+		entry.Resource = input.Resource.Type
+		if input.Resource.ID != "" {
+			entry.Resource += ":" + input.Resource.ID
+		}
 	}
-	defer resp.Body.Close()
+	if evalErr != nil {
+		entry.Error = evalErr.Error()
+	}
+	m.decisionLog.Record(entry)
+}
 
-	// Parse response
-	var opaResp OPAResponse
-	if err := json.NewDecoder(resp.Body).Decode(&opaResp); err != nil {
-		return false, err
+// requestContext returns the tracing context stored by the Tracing middleware
+// so OPA calls join the request's trace, falling back to the user context.
+func requestContext(c *fiber.Ctx) context.Context {
+	if ctx, ok := c.Locals("otel-context").(context.Context); ok {
+		return ctx
 	}
+	return c.UserContext()
+}
 
-	return opaResp.Result, nil
-}
\ No newline at end of file
+func isReadMethod(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+	return false
+}
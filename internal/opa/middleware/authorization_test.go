@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/client"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+)
+
+// fieldsQuerier answers field filter queries with a fixed set of fields.
+type fieldsQuerier struct {
+	path   string
+	input  interface{}
+	fields []string
+	err    error
+}
+
+func (q *fieldsQuerier) Query(ctx context.Context, path string, input interface{}, out interface{}) error {
+	q.path, q.input = path, input
+	if q.err != nil {
+		return q.err
+	}
+	body, _ := json.Marshal(q.fields)
+	return json.Unmarshal(body, out)
+}
+
+func newFieldsApp(m *OPAMiddleware) *fiber.App {
+	app := newTestApp()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &User{ID: "42", Roles: []string{"user"}})
+		return c.Next()
+	})
+	fields := func(c *fiber.Ctx) error {
+		fields, ok := c.Locals("field_filter").([]string)
+		if !ok {
+			return c.SendString("all")
+		}
+		return c.JSON(fields)
+	}
+	app.Get("/users/export", m.FilterFields("users"), fields)
+	app.Get("/users/:id", m.FilterFields("users"), fields)
+	return app
+}
+
+func TestFilterFields(t *testing.T) {
+	t.Run("Stores The Visible Fields", func(t *testing.T) {
+		querier := &fieldsQuerier{fields: []string{"id", "username"}}
+		m := NewOPAMiddleware(querier, FailClosed, new(MockLogger))
+		m.SetFieldFilter("users", "authz/data/filtered_user_fields")
+
+		resp, err := newFieldsApp(m).Test(httptest.NewRequest("GET", "/users/export", nil))
+		require.NoError(t, err)
+
+		var fields []string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&fields))
+		assert.Equal(t, []string{"id", "username"}, fields)
+		assert.Equal(t, "authz/data/filtered_user_fields", querier.path)
+		assert.Equal(t, &Resource{Type: "users"}, querier.input.(OPAInput).Resource)
+	})
+
+	t.Run("Sends The Resource ID", func(t *testing.T) {
+		querier := &fieldsQuerier{fields: []string{"id", "email"}}
+		m := NewOPAMiddleware(querier, FailClosed, new(MockLogger))
+		m.SetFieldFilter("users", "authz/data/filtered_user_fields")
+
+		_, err := newFieldsApp(m).Test(httptest.NewRequest("GET", "/users/42", nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, &Resource{Type: "users", ID: "42"}, querier.input.(OPAInput).Resource)
+	})
+
+	t.Run("Passes Through Without A Filter", func(t *testing.T) {
+		m := NewOPAMiddleware(&fieldsQuerier{}, FailClosed, new(MockLogger))
+
+		resp, err := newFieldsApp(m).Test(httptest.NewRequest("GET", "/users/export", nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "all", string(body))
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		m := NewOPAMiddleware(&fieldsQuerier{err: errors.New("timeout")}, FailClosed, new(MockLogger))
+		m.SetFieldFilter("users", "authz/data/filtered_user_fields")
+
+		resp, err := newFieldsApp(m).Test(httptest.NewRequest("GET", "/users/export", nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	})
+}
+
+func TestAuthorizeAccountCheck(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "42", "email": "alice@example.com", "roles": []string{"user"},
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	authorize := func(check func(ctx context.Context, userID string) error) int {
+		m := NewOPAMiddleware(&fieldsQuerier{}, FailClosed, new(MockLogger))
+		m.SetJWTSecret("secret")
+		m.SetAccountCheck(check)
+		app := newTestApp()
+		app.Get("/me", m.Authorize(), func(c *fiber.Ctx) error { return c.SendString(UserID(c)) })
+
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	var checked string
+	assert.Equal(t, fiber.StatusOK, authorize(func(ctx context.Context, userID string) error {
+		checked = userID
+		return nil
+	}))
+	assert.Equal(t, "42", checked)
+
+	// Tokens stop working once the account is locked
+	assert.Equal(t, fiber.StatusLocked, authorize(func(ctx context.Context, userID string) error {
+		return service.ErrAccountLocked
+	}))
+}
+
+func TestAuthorizeUserResolver(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "42", "email": "alice@example.com", "roles": []string{"user"}, "groups": []string{"engineering"},
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	authorize := func(resolve func(ctx context.Context, user *User) error) (int, *User) {
+		m := NewOPAMiddleware(&fieldsQuerier{}, FailClosed, new(MockLogger))
+		m.SetJWTSecret("secret")
+		if resolve != nil {
+			m.SetUserResolver(resolve)
+		}
+		var seen *User
+		app := newTestApp()
+		app.Get("/me", m.Authorize(), func(c *fiber.Ctx) error {
+			seen, _ = c.Locals("user").(*User)
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode, seen
+	}
+
+	t.Run("Token Claims Without A Resolver", func(t *testing.T) {
+		status, user := authorize(nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, []string{"user"}, user.Roles)
+		assert.Equal(t, []string{"engineering"}, user.Groups)
+	})
+
+	t.Run("Resolved Roles And Groups Replace The Token's", func(t *testing.T) {
+		status, user := authorize(func(ctx context.Context, user *User) error {
+			user.Roles, user.Groups = []string{"admin", "user"}, []string{"engineering", "platform-admins"}
+			return nil
+		})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, []string{"admin", "user"}, user.Roles)
+		assert.Equal(t, []string{"engineering", "platform-admins"}, user.Groups)
+	})
+
+	t.Run("Resolver Errors Are Returned", func(t *testing.T) {
+		status, _ := authorize(func(ctx context.Context, user *User) error {
+			return service.ErrAccountDeactivated
+		})
+		assert.Equal(t, fiber.StatusForbidden, status)
+	})
+}
+
+// failingQuerier fails every query with err.
+type failingQuerier struct {
+	err error
+}
+
+func (q *failingQuerier) Query(ctx context.Context, path string, input interface{}, out interface{}) error {
+	return q.err
+}
+
+func TestFailOpenForReads(t *testing.T) {
+	cases := map[string]struct {
+		err    error
+		status int
+	}{
+		"circuit open":     {client.ErrCircuitOpen, fiber.StatusOK},
+		"timeout":          {context.DeadlineExceeded, fiber.StatusOK},
+		"server error":     {&client.StatusError{StatusCode: 502}, fiber.StatusOK},
+		"transport error":  {&net.OpError{Op: "dial", Err: errors.New("connection refused")}, fiber.StatusOK},
+		"missing policy":   {&client.StatusError{StatusCode: 404}, fiber.StatusServiceUnavailable},
+		"undefined result": {client.ErrUndefinedResult, fiber.StatusServiceUnavailable},
+		"bad response":     {errors.New("failed to decode opa response"), fiber.StatusServiceUnavailable},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			app := newTestApp()
+			authz := NewPermissions(app)
+			mockLogger := new(MockLogger)
+			mockLogger.On("Warn", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+			authz.SetEnforcer(NewOPAMiddleware(&failingQuerier{err: tc.err}, FailOpenForReads, mockLogger))
+			ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+			app.Get("/users", authz.Require("users:list", "user", ""), ok)
+			app.Post("/users", authz.Require("users:create", "user", ""), ok)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/users", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode)
+
+			// Writes are never let through
+			resp, err = app.Test(httptest.NewRequest("POST", "/users", nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Permission is what a route requires of the caller: an action such as
+// "users:read" on a resource type such as "user". IDParam names the route
+// parameter holding the resource ID, if any.
+type Permission struct {
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+	IDParam  string `json:"id_param,omitempty"`
+}
+
+// RoutePermission is one row of the route/permission matrix. Permission is
+// nil for routes that were registered without Require.
+type RoutePermission struct {
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Permission *Permission `json:"permission"`
+}
+
+// Permissions records the permission each route declares and enforces it
+// through the OPA middleware:
+//
+//	users.Get("/:id", authz.Require("users:read", "user", "id"), handler)
+//
+// Until an enforcer is set, Require only records the declaration, so routes
+// can be registered the same way whether or not OPA is enabled.
+type Permissions struct {
+	app *fiber.App
+
+	mu       sync.RWMutex
+	enforcer *OPAMiddleware
+	pending  *Permission
+	routes   map[string]*Permission
+}
+
+// NewPermissions hooks into route registration on app. It must be created
+// before the routes it annotates are registered.
+func NewPermissions(app *fiber.App) *Permissions {
+	p := &Permissions{
+		app:    app,
+		routes: map[string]*Permission{},
+	}
+	app.Hooks().OnRoute(p.onRoute)
+	return p
+}
+
+// SetEnforcer makes Require check permissions with m.
+func (p *Permissions) SetEnforcer(m *OPAMiddleware) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enforcer = m
+}
+
+// Require declares that the route it is registered on needs permission for
+// action on resource. It must be the first handler passed to the route and
+// run after Authorize.
+func (p *Permissions) Require(action, resource, idParam string) fiber.Handler {
+	permission := Permission{Action: action, Resource: resource, IDParam: idParam}
+
+	p.mu.Lock()
+	p.pending = &permission
+	p.mu.Unlock()
+
+	return func(c *fiber.Ctx) error {
+		p.mu.RLock()
+		enforcer := p.enforcer
+		p.mu.RUnlock()
+
+		if enforcer == nil {
+			return c.Next()
+		}
+		return enforcer.check(c, permission)
+	}
+}
+
+// When requires a further permission of the requests for which cond holds,
+// for query parameters that widen what a route does:
+//
+//	users.Delete("/:id", authz.Require("users:delete", "user", "id"),
+//		authz.When(purge, "users:purge", "user", "id"), handler)
+//
+// It must come after the route's Require. Conditional permissions are not
+// listed in the route matrix.
+func (p *Permissions) When(cond func(c *fiber.Ctx) bool, action, resource, idParam string) fiber.Handler {
+	permission := Permission{Action: action, Resource: resource, IDParam: idParam}
+
+	return func(c *fiber.Ctx) error {
+		p.mu.RLock()
+		enforcer := p.enforcer
+		p.mu.RUnlock()
+
+		if enforcer == nil || !cond(c) {
+			return c.Next()
+		}
+		return enforcer.check(c, permission)
+	}
+}
+
+// onRoute attaches the permission declared by the last Require call to the
+// route being registered. Get registers HEAD before GET, so the declaration
+// is kept until a route with any other method consumes it.
+func (p *Permissions) onRoute(route fiber.Route) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending == nil {
+		return nil
+	}
+	p.routes[routeKey(route.Method, route.Path)] = p.pending
+	if route.Method != fiber.MethodHead {
+		p.pending = nil
+	}
+	return nil
+}
+
+// Matrix lists every route registered on the app with the permission it
+// requires, sorted by path and method. HEAD routes mirror GET and are left out.
+func (p *Permissions) Matrix() []RoutePermission {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var matrix []RoutePermission
+	for _, route := range p.app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+		matrix = append(matrix, RoutePermission{
+			Method:     route.Method,
+			Path:       route.Path,
+			Permission: p.routes[routeKey(route.Method, route.Path)],
+		})
+	}
+
+	sort.Slice(matrix, func(i, j int) bool {
+		if matrix[i].Path != matrix[j].Path {
+			return matrix[i].Path < matrix[j].Path
+		}
+		return matrix[i].Method < matrix[j].Method
+	})
+	return matrix
+}
+
+// WriteMatrix writes the route/permission matrix as a Markdown table.
+func (p *Permissions) WriteMatrix(w io.Writer) {
+	fmt.Fprintln(w, "| Method | Path | Action | Resource | ID param |")
+	fmt.Fprintln(w, "|--------|------|--------|----------|----------|")
+	for _, route := range p.Matrix() {
+		action, resource, idParam := "-", "-", "-"
+		if route.Permission != nil {
+			action, resource = route.Permission.Action, route.Permission.Resource
+			if route.Permission.IDParam != "" {
+				idParam = route.Permission.IDParam
+			}
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", route.Method, route.Path, action, resource, idParam)
+	}
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimiddleware "github.com/witslab-sahil/fiber-boilerplate/internal/middleware"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+// newTestApp returns an app that renders errors the way the server does.
+func newTestApp() *fiber.App {
+	return fiber.New(fiber.Config{ErrorHandler: apimiddleware.ErrorHandler(logger.New("error"))})
+}
+
+// recordingQuerier allows admins and remembers the last input it was asked about.
+type recordingQuerier struct {
+	input OPAInput
+}
+
+func (q *recordingQuerier) Query(ctx context.Context, path string, input interface{}, out interface{}) error {
+	q.input = input.(OPAInput)
+	allow := q.input.User != nil && len(q.input.User.Roles) > 0 && q.input.User.Roles[0] == "admin"
+	body, _ := json.Marshal(Decision{Allow: allow})
+	return json.Unmarshal(body, out)
+}
+
+func newPermissionsApp(querier Querier, roles ...string) (*fiber.App, *Permissions) {
+	app := newTestApp()
+	authz := NewPermissions(app)
+	if querier != nil {
+		authz.SetEnforcer(NewOPAMiddleware(querier, FailClosed, new(MockLogger)))
+	}
+
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &User{ID: "1", Roles: roles})
+		return c.Next()
+	})
+
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	users := app.Group("/users")
+	users.Get("/", authz.Require("users:list", "user", ""), ok)
+	users.Get("/:id", authz.Require("users:read", "user", "id"), ok)
+	users.Post("/", ok)
+	return app, authz
+}
+
+func TestPermissions_Matrix(t *testing.T) {
+	_, authz := newPermissionsApp(nil)
+
+	matrix := authz.Matrix()
+	require.Len(t, matrix, 3)
+
+	byRoute := map[string]*Permission{}
+	for _, route := range matrix {
+		byRoute[route.Method+" "+route.Path] = route.Permission
+	}
+	assert.Equal(t, &Permission{Action: "users:list", Resource: "user"}, byRoute["GET /users/"])
+	assert.Equal(t, &Permission{Action: "users:read", Resource: "user", IDParam: "id"}, byRoute["GET /users/:id"])
+	assert.Contains(t, byRoute, "POST /users/")
+	assert.Nil(t, byRoute["POST /users/"])
+}
+
+func TestPermissions_Require(t *testing.T) {
+	t.Run("Sends Action And Resource", func(t *testing.T) {
+		querier := &recordingQuerier{}
+		app, _ := newPermissionsApp(querier, "admin")
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/users/42", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "users:read", querier.input.Action)
+		assert.Equal(t, &Resource{Type: "user", ID: "42"}, querier.input.Resource)
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		app, _ := newPermissionsApp(&recordingQuerier{}, "user")
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/users/42", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Not Enforced Without OPA", func(t *testing.T) {
+		app, _ := newPermissionsApp(nil, "user")
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/users/42", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
+
+// actionQuerier allows only the listed actions.
+type actionQuerier struct {
+	allowed map[string]bool
+}
+
+func (q *actionQuerier) Query(ctx context.Context, path string, input interface{}, out interface{}) error {
+	body, _ := json.Marshal(Decision{Allow: q.allowed[input.(OPAInput).Action]})
+	return json.Unmarshal(body, out)
+}
+
+func TestPermissions_When(t *testing.T) {
+	app := newTestApp()
+	authz := NewPermissions(app)
+	authz.SetEnforcer(NewOPAMiddleware(&actionQuerier{allowed: map[string]bool{"users:delete": true}}, FailClosed, new(MockLogger)))
+
+	purge := func(c *fiber.Ctx) bool { return c.QueryBool("purge") }
+	app.Delete("/users/:id", authz.Require("users:delete", "user", "id"), authz.When(purge, "users:purge", "user", "id"), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/users/42", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/users/42?purge=true", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+	// The condition does not replace the route's declared permission
+	matrix := authz.Matrix()
+	require.Len(t, matrix, 1)
+	assert.Equal(t, "users:delete", matrix[0].Permission.Action)
+}
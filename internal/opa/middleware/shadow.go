@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const shadowDecisionPath = "authz/shadow/decision"
+
+// Shadow evaluates the candidate policy in the authz.shadow package next to
+// the enforced one and reports where they disagree. It runs off the request
+// path and never changes the response.
+type Shadow struct {
+	querier       Querier
+	logger        logger.Logger
+	timeout       time.Duration
+	slots         chan struct{}
+	evaluations   metric.Int64Counter
+	disagreements metric.Int64Counter
+}
+
+// NewShadow creates a shadow evaluator running at most maxInFlight
+// evaluations at once; requests arriving while all slots are busy are skipped.
+func NewShadow(querier Querier, logger logger.Logger, timeout time.Duration, maxInFlight int) *Shadow {
+	if maxInFlight <= 0 {
+		maxInFlight = 64
+	}
+
+	meter := otel.Meter("opa-shadow")
+
+	evaluations, _ := meter.Int64Counter(
+		"authz_shadow_evaluations_total",
+		metric.WithDescription("Total number of shadow policy evaluations"),
+	)
+
+	disagreements, _ := meter.Int64Counter(
+		"authz_shadow_disagreements_total",
+		metric.WithDescription("Total number of requests where the shadow policy disagreed with the enforced policy"),
+	)
+
+	return &Shadow{
+		querier:       querier,
+		logger:        logger,
+		timeout:       timeout,
+		slots:         make(chan struct{}, maxInFlight),
+		evaluations:   evaluations,
+		disagreements: disagreements,
+	}
+}
+
+// Compare evaluates the shadow policy for input in the background.
+func (s *Shadow) Compare(ctx context.Context, requestID string, input OPAInput, enforced *Decision) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.logger.Debug("Shadow policy evaluation skipped, too many in flight")
+		return
+	}
+
+	// Detach from the request so the evaluation outlives it, but keep the
+	// trace so the shadow span shows up under the request.
+	shadowCtx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+
+	go func() {
+		defer func() { <-s.slots }()
+
+		evalCtx, cancel := context.WithTimeout(shadowCtx, s.timeout)
+		defer cancel()
+		s.compare(evalCtx, requestID, input, enforced)
+	}()
+}
+
+func (s *Shadow) compare(ctx context.Context, requestID string, input OPAInput, enforced *Decision) {
+	var shadow Decision
+	if err := s.querier.Query(ctx, shadowDecisionPath, input, &shadow); err != nil {
+		s.evaluations.Add(ctx, 1, metric.WithAttributes(attribute.String("status", "error")))
+		s.logger.Warn("Failed to evaluate shadow policy: ", err)
+		return
+	}
+	s.evaluations.Add(ctx, 1, metric.WithAttributes(attribute.String("status", "success")))
+
+	if shadow.Allow == enforced.Allow {
+		return
+	}
+
+	kind := "would_deny"
+	if shadow.Allow {
+		kind = "would_allow"
+	}
+
+	s.disagreements.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("kind", kind),
+		attribute.String("method", input.Method),
+	))
+
+	s.logger.WithFields(map[string]interface{}{
+		"request_id":             requestID,
+		"disagreement":           kind,
+		"input":                  input,
+		"enforced_allow":         enforced.Allow,
+		"enforced_matched_rules": enforced.MatchedRules,
+		"shadow_allow":           shadow.Allow,
+		"shadow_matched_rules":   shadow.MatchedRules,
+	}).Warn("Shadow policy disagrees with enforced policy")
+}
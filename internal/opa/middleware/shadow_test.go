@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+)
+
+type stubQuerier struct {
+	decision Decision
+}
+
+func (q *stubQuerier) Query(ctx context.Context, path string, input interface{}, out interface{}) error {
+	body, _ := json.Marshal(q.decision)
+	return json.Unmarshal(body, out)
+}
+
+type MockLogger struct {
+	mock.Mock
+}
+
+func (m *MockLogger) Debug(args ...interface{})                 {}
+func (m *MockLogger) Debugf(format string, args ...interface{}) {}
+func (m *MockLogger) Info(args ...interface{})                  {}
+func (m *MockLogger) Infof(format string, args ...interface{})  {}
+func (m *MockLogger) Warn(args ...interface{}) {
+	m.Called(args...)
+}
+func (m *MockLogger) Warnf(format string, args ...interface{})  {}
+func (m *MockLogger) Error(args ...interface{})                 {}
+func (m *MockLogger) Errorf(format string, args ...interface{}) {}
+func (m *MockLogger) Fatal(args ...interface{})                 {}
+func (m *MockLogger) Fatalf(format string, args ...interface{}) {}
+func (m *MockLogger) WithFields(fields map[string]interface{}) logger.Logger {
+	m.Called(fields)
+	return m
+}
+
+func TestShadow_Compare(t *testing.T) {
+	input := OPAInput{Action: "users:delete", Resource: &Resource{Type: "user", ID: "2"}, Method: "DELETE", Path: "/api/v1/users/2", User: &User{ID: "1", Roles: []string{"admin"}}}
+
+	t.Run("Agreement Is Not Logged", func(t *testing.T) {
+		mockLogger := new(MockLogger)
+		shadow := NewShadow(&stubQuerier{decision: Decision{Allow: true}}, mockLogger, time.Second, 1)
+
+		shadow.compare(context.Background(), "req-1", input, &Decision{Allow: true})
+		mockLogger.AssertNotCalled(t, "WithFields", mock.Anything)
+	})
+
+	t.Run("Would Deny", func(t *testing.T) {
+		mockLogger := new(MockLogger)
+		shadow := NewShadow(&stubQuerier{decision: Decision{Allow: false}}, mockLogger, time.Second, 1)
+
+		mockLogger.On("WithFields", mock.MatchedBy(func(fields map[string]interface{}) bool {
+			return fields["disagreement"] == "would_deny" &&
+				fields["request_id"] == "req-1" &&
+				fields["input"] == input
+		})).Once()
+		mockLogger.On("Warn", "Shadow policy disagrees with enforced policy").Once()
+
+		shadow.compare(context.Background(), "req-1", input, &Decision{Allow: true, MatchedRules: []string{"admin_users"}})
+		mockLogger.AssertExpectations(t)
+	})
+
+	t.Run("Skips When Busy", func(t *testing.T) {
+		mockLogger := new(MockLogger)
+		shadow := NewShadow(&stubQuerier{}, mockLogger, time.Second, 1)
+		shadow.slots <- struct{}{}
+
+		shadow.Compare(context.Background(), "req-1", input, &Decision{Allow: true})
+		assert.Len(t, shadow.slots, 1)
+		mockLogger.AssertNotCalled(t, "WithFields", mock.Anything)
+	})
+}
@@ -6,66 +6,134 @@ import future.keywords.in
 
 default allow := false
 
-# Allow health check endpoint for everyone
+# A request is allowed when at least one named rule below matches. Naming the
+# rules lets decision logs and the explain endpoint report why.
 allow if {
-    input.path == "/health"
-    input.method == "GET"
+    count(matched_rules) > 0
 }
 
-# Allow user registration without authentication
-allow if {
-    input.path == "/api/v1/auth/register"
-    input.method == "POST"
+decision := {
+    "allow": allow,
+    "matched_rules": matched_rules,
 }
 
-# Allow login without authentication
-allow if {
-    input.path == "/api/v1/auth/login"
-    input.method == "POST"
+# Routes declare the action and resource they need when they are registered
+# (see GET /api/v1/authz/routes for the full matrix), so rules match on
+# input.action and input.resource rather than on URL paths. Public routes such
+# as login and registration never reach the policy.
+
+# Authenticated users can read their own profile
+matched_rules contains "self_read_profile" if {
+    input.action == "users:read"
+    own_user
 }
 
-# Authenticated users can access their own profile
-allow if {
-    input.method == "GET"
-    input.path == sprintf("/api/v1/users/%s", [input.user.id])
+# Authenticated users can update their own profile
+matched_rules contains "self_update_profile" if {
+    input.action == "users:update"
+    own_user
+}
+
+# Users can see which groups they are in and the roles they hold
+matched_rules contains "self_read_access" if {
+    input.action == "users:read_access"
+    own_user
+}
+
+# Users can ask for their own data to be erased, check on the request and
+# cancel it during its grace period
+matched_rules contains "self_erasure" if {
+    input.action == "users:erase"
+    own_user
+}
+
+# Authenticated users can export their own data; the routes only ever act
+# on the caller
+matched_rules contains "self_data_export" if {
+    input.action == "users:data_export"
     input.user.id != ""
 }
 
-# Authenticated users can update their own profile
-allow if {
-    input.method == "PUT"
-    input.path == sprintf("/api/v1/users/%s", [input.user.id])
+# Authenticated users can list users; data.authz.filters.users decides
+# which rows they get back
+matched_rules contains "list_users" if {
+    input.action == "users:list"
     input.user.id != ""
 }
 
-# Admin users can access all user endpoints
-allow if {
-    input.path_prefix == "/api/v1/users"
+# Anyone signed in can read the attribute schema, to know which attributes
+# they may set on their own profile
+matched_rules contains "read_attribute_schema" if {
+    input.action == "users:read_attribute_schema"
+    input.user.id != ""
+}
+
+# Exports follow the same row and field filters as the listing, so anyone
+# who can list users can export them
+matched_rules contains "export_users" if {
+    input.action == "users:export"
+    input.user.id != ""
+}
+
+# Admin users can manage users
+matched_rules contains "admin_users" if {
+    action_in("users")
     "admin" in input.user.roles
 }
 
-# Admin users can trigger workflows
-allow if {
-    input.path_prefix == "/api/v1/workflows"
+# Admin users can trigger and inspect workflows
+matched_rules contains "admin_workflows" if {
+    action_in("workflows")
+    "admin" in input.user.roles
+}
+
+# Admin users can inspect authorization decisions and routes
+matched_rules contains "admin_authz" if {
+    action_in("authz")
+    "admin" in input.user.roles
+}
+
+# Admin users can manage groups. Roles granted through groups are already
+# in input.user.roles; input.user.groups names the groups themselves
+matched_rules contains "admin_groups" if {
+    action_in("groups")
+    "admin" in input.user.roles
+}
+
+# Admin users can manage policy bundles
+matched_rules contains "admin_policies" if {
+    action_in("policies")
     "admin" in input.user.roles
 }
 
 # Users with workflow_executor role can trigger specific workflows
-allow if {
-    input.path == "/api/v1/workflows/user-onboarding"
-    input.method == "POST"
+matched_rules contains "workflow_executor_onboarding" if {
+    input.action == "workflows:start"
+    input.resource.type == "user-onboarding"
     "workflow_executor" in input.user.roles
 }
 
+# The request acts on the caller's own user record
+own_user if {
+    input.resource.type == "user"
+    input.user.id != ""
+    input.resource.id == input.user.id
+}
+
+# The action belongs to namespace, e.g. "users:read" is in "users"
+action_in(namespace) if {
+    startswith(input.action, concat("", [namespace, ":"]))
+}
+
 # Rate limiting rules
 rate_limit := 100 if {
     "premium" in input.user.roles
 } else := 10
 
 # Resource access rules
-resource_access[resource] if {
+resource_access contains resource if {
     some role in input.user.roles
-    some resource in data.roles[role].resources
+    some resource in roles[role].resources
 }
 
 # Define role permissions
@@ -86,4 +154,4 @@ roles := {
         "resources": ["profile", "analytics"],
         "actions": ["read", "update"]
     }
-}
\ No newline at end of file
+}
@@ -5,49 +5,101 @@ import future.keywords.if
 import future.keywords.in
 
 # Filter user data based on roles
-filtered_user_fields[field] if {
+filtered_user_fields contains field if {
     field := "id"
 }
 
-filtered_user_fields[field] if {
+filtered_user_fields contains field if {
     field := "username"
 }
 
-filtered_user_fields[field] if {
+filtered_user_fields contains field if {
     field := "email"
     check_email_access
 }
 
-filtered_user_fields[field] if {
+filtered_user_fields contains field if {
     field := "first_name"
 }
 
-filtered_user_fields[field] if {
+filtered_user_fields contains field if {
     field := "last_name"
 }
 
-filtered_user_fields[field] if {
+filtered_user_fields contains field if {
     field := "created_at"
     "admin" in input.user.roles
 }
 
-filtered_user_fields[field] if {
+filtered_user_fields contains field if {
     field := "updated_at"
     "admin" in input.user.roles
 }
 
-filtered_user_fields[field] if {
+filtered_user_fields contains field if {
     field := "is_active"
     "admin" in input.user.roles
 }
 
+filtered_user_fields contains field if {
+    field := "status"
+    "admin" in input.user.roles
+}
+
+filtered_user_fields contains field if {
+    field := "status_reason"
+    "admin" in input.user.roles
+}
+
+filtered_user_fields contains field if {
+    field := "suspended_until"
+    "admin" in input.user.roles
+}
+
+filtered_user_fields contains field if {
+    field := "roles"
+    "admin" in input.user.roles
+}
+
+filtered_user_fields contains field if {
+    field := "version"
+}
+
+# Custom attributes are as private as email addresses
+filtered_user_fields contains field if {
+    field := "attributes"
+    check_email_access
+}
+
+filtered_user_fields contains field if {
+    field := "deleted_at"
+    "admin" in input.user.roles
+}
+
+# Relations embedded with ?include=
+filtered_user_fields contains field if {
+    field := "erasure"
+    "admin" in input.user.roles
+}
+
+filtered_user_fields contains field if {
+    field := "groups"
+    "admin" in input.user.roles
+}
+
+filtered_user_fields contains field if {
+    field := "effective_roles"
+    "admin" in input.user.roles
+}
+
 # Check if user can see email addresses
 check_email_access if {
     "admin" in input.user.roles
 }
 
+# FilterFields sends the user being read, if any, as the resource id
 check_email_access if {
-    input.user.id == input.resource.user_id
+    input.user.id == input.resource.id
 }
 
 # Data transformation rules
@@ -59,12 +111,12 @@ transform_user(user) := transformed if {
 }
 
 # Workflow visibility rules
-visible_workflows[workflow] if {
+visible_workflows contains workflow if {
     workflow := "user-onboarding"
     "admin" in input.user.roles
 }
 
-visible_workflows[workflow] if {
+visible_workflows contains workflow if {
     workflow := "user-onboarding"
     "workflow_executor" in input.user.roles
 }
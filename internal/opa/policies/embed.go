@@ -0,0 +1,9 @@
+package policies
+
+import "embed"
+
+// FS holds the policies shipped with the service. They are the default bundle
+// used until an administrator activates another one.
+//
+//go:embed *.rego
+var FS embed.FS
@@ -0,0 +1,19 @@
+package authz.filters
+
+import future.keywords.if
+import future.keywords.in
+
+# Row filters for list endpoints. Each rule is partially evaluated with
+# input.row unknown, and what remains is translated into a SQL WHERE clause,
+# so only comparisons between input.row fields and constants may depend on
+# the row.
+
+# Admins can list every user
+users if {
+    "admin" in input.user.roles
+}
+
+# Everyone else only sees their own account
+users if {
+    input.row.id == to_number(input.user.id)
+}
@@ -0,0 +1,46 @@
+package policies
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/bundle"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/policytest"
+)
+
+// TestPolicies runs the suites in testdata against the built-in policies and
+// requires every rule to be exercised by at least one case.
+func TestPolicies(t *testing.T) {
+	b, err := bundle.LoadFS(FS)
+	require.NoError(t, err)
+
+	runner, err := policytest.NewRunner(b)
+	require.NoError(t, err)
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	var suites []*policytest.Suite
+	for _, path := range paths {
+		suite, err := policytest.LoadSuite(path)
+		require.NoError(t, err)
+		suites = append(suites, suite)
+	}
+
+	report := runner.Run(context.Background(), suites...)
+	for _, result := range report.Results {
+		assert.True(t, result.Passed, "%s: %s: %s", result.Suite, result.Case, result.Failure)
+	}
+	for _, rule := range report.Uncovered() {
+		t.Errorf("rule not covered by any case: %s:%d %s", rule.File, rule.Line, rule.Rule)
+	}
+
+	if testing.Verbose() {
+		report.Write(os.Stdout, true)
+	}
+}
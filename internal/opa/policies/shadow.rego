@@ -0,0 +1,29 @@
+package authz.shadow
+
+import future.keywords.contains
+import future.keywords.if
+import future.keywords.in
+
+# Candidate policy evaluated alongside data.authz when OPA_SHADOW_ENABLED is
+# set. Its result never affects the response; every request where it would
+# decide differently from the enforced policy is logged with the full input
+# and counted in the authz_shadow_disagreements_total metric.
+#
+# To trial a change, edit the rules below and upload the bundle. Once the
+# disagreements are the ones you expect, move the rules into authz.rego.
+# Until then this package mirrors the enforced policy.
+
+default allow := false
+
+allow if {
+    count(matched_rules) > 0
+}
+
+decision := {
+    "allow": allow,
+    "matched_rules": matched_rules,
+}
+
+matched_rules contains rule if {
+    some rule in data.authz.matched_rules
+}
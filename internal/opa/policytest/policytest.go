@@ -0,0 +1,316 @@
+// Package policytest runs declarative test suites against a policy bundle and
+// reports which rules the suites exercised.
+package policytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/cover"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/bundle"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultQuery is evaluated for cases that do not name a query.
+const DefaultQuery = "data.authz.decision"
+
+// Suite is a list of cases, usually loaded from a YAML or JSON file.
+type Suite struct {
+	Name  string `json:"name"`
+	Cases []Case `json:"cases"`
+}
+
+// Case evaluates Query with Input and checks the result. Allow and
+// MatchedRules check the fields of a decision document; Expect compares the
+// whole result. Undefined expects the query to produce no result at all.
+type Case struct {
+	Name         string                 `json:"name"`
+	Query        string                 `json:"query,omitempty"`
+	Input        map[string]interface{} `json:"input"`
+	Allow        *bool                  `json:"allow,omitempty"`
+	MatchedRules []string               `json:"matched_rules,omitempty"`
+	Expect       interface{}            `json:"expect,omitempty"`
+	Undefined    bool                   `json:"undefined,omitempty"`
+}
+
+// Result is the outcome of a single case.
+type Result struct {
+	Suite   string `json:"suite"`
+	Case    string `json:"case"`
+	Passed  bool   `json:"passed"`
+	Failure string `json:"failure,omitempty"`
+}
+
+// RuleCoverage reports whether any case made a rule succeed.
+type RuleCoverage struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Covered bool   `json:"covered"`
+}
+
+// Report collects the results of one or more suites run against a bundle.
+type Report struct {
+	Results []Result       `json:"results"`
+	Rules   []RuleCoverage `json:"rules"`
+}
+
+// LoadSuite reads a suite from a .yaml, .yml or .json file.
+func LoadSuite(path string) (*Suite, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+
+	var suite Suite
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &suite)
+	case ".json":
+		err = json.Unmarshal(content, &suite)
+	default:
+		return nil, fmt.Errorf("suite %s must be a .yaml, .yml or .json file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+
+	if suite.Name == "" {
+		suite.Name = filepath.Base(path)
+	}
+	return &suite, nil
+}
+
+// Runner evaluates suites against a compiled bundle.
+type Runner struct {
+	compiler *ast.Compiler
+	store    storage.Store
+	cover    *cover.Cover
+}
+
+func NewRunner(b *models.PolicyBundle) (*Runner, error) {
+	compiler, err := bundle.Compile(b)
+	if err != nil {
+		return nil, err
+	}
+
+	data := b.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	return &Runner{
+		compiler: compiler,
+		store:    inmem.NewFromObject(data),
+		cover:    cover.New(),
+	}, nil
+}
+
+// Run evaluates every case in suites. Coverage accumulates across all the
+// suites run by the same Runner.
+func (r *Runner) Run(ctx context.Context, suites ...*Suite) *Report {
+	report := &Report{}
+	for _, suite := range suites {
+		for i, c := range suite.Cases {
+			name := c.Name
+			if name == "" {
+				name = fmt.Sprintf("case %d", i+1)
+			}
+			result := Result{Suite: suite.Name, Case: name, Passed: true}
+			if err := r.runCase(ctx, c); err != nil {
+				result.Passed = false
+				result.Failure = err.Error()
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+	report.Rules = r.coverage()
+	return report
+}
+
+func (r *Runner) runCase(ctx context.Context, c Case) error {
+	query := c.Query
+	if query == "" {
+		query = DefaultQuery
+	}
+
+	input := c.Input
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+
+	results, err := rego.New(
+		rego.Query(query),
+		rego.Compiler(r.compiler),
+		rego.Store(r.store),
+		rego.Input(input),
+		rego.QueryTracer(r.cover),
+	).Eval(ctx)
+	if err != nil {
+		return fmt.Errorf("evaluation failed: %w", err)
+	}
+
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		if c.Undefined {
+			return nil
+		}
+		return fmt.Errorf("%s is undefined", query)
+	}
+	if c.Undefined {
+		return fmt.Errorf("expected %s to be undefined", query)
+	}
+
+	got, err := normalize(results[0].Expressions[0].Value)
+	if err != nil {
+		return err
+	}
+
+	if c.Expect != nil {
+		want, err := normalize(c.Expect)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("expected %s, got %s", encode(want), encode(got))
+		}
+	}
+
+	if c.Allow != nil {
+		allow := got
+		if decision, ok := got.(map[string]interface{}); ok {
+			allow = decision["allow"]
+		}
+		if allow != *c.Allow {
+			return fmt.Errorf("expected allow %v, got %s", *c.Allow, encode(allow))
+		}
+	}
+
+	if c.MatchedRules != nil {
+		decision, _ := got.(map[string]interface{})
+		matched := []string{}
+		rules, _ := decision["matched_rules"].([]interface{})
+		for _, rule := range rules {
+			matched = append(matched, fmt.Sprint(rule))
+		}
+		want := append([]string{}, c.MatchedRules...)
+		sort.Strings(matched)
+		sort.Strings(want)
+		if !reflect.DeepEqual(matched, want) {
+			return fmt.Errorf("expected matched rules %v, got %v", want, matched)
+		}
+	}
+
+	return nil
+}
+
+// coverage lists every non-default rule in the bundle and whether any case
+// made it succeed.
+func (r *Runner) coverage() []RuleCoverage {
+	report := r.cover.Report(r.compiler.Modules)
+
+	var rules []RuleCoverage
+	for file, module := range r.compiler.Modules {
+		for _, rule := range module.Rules {
+			if rule.Default || rule.Head.Location == nil {
+				continue
+			}
+			rules = append(rules, RuleCoverage{
+				File:    file,
+				Line:    rule.Head.Location.Row,
+				Rule:    ruleName(module, rule),
+				Covered: report.IsCovered(rule.Location.File, rule.Head.Location.Row),
+			})
+		}
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].File != rules[j].File {
+			return rules[i].File < rules[j].File
+		}
+		return rules[i].Line < rules[j].Line
+	})
+	return rules
+}
+
+func ruleName(module *ast.Module, rule *ast.Rule) string {
+	name := strings.TrimPrefix(module.Package.Path.String(), "data.") + "." + rule.Head.Ref().String()
+	// Name contributions to partial sets by their constant key, so that each
+	// matched_rules entry is reported separately.
+	if rule.Head.Key != nil && rule.Head.Value == nil && rule.Head.Key.IsGround() {
+		name += "[" + rule.Head.Key.String() + "]"
+	}
+	return name
+}
+
+// Failed returns the number of failed cases.
+func (r *Report) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if !result.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Uncovered returns the rules no case made succeed.
+func (r *Report) Uncovered() []RuleCoverage {
+	var uncovered []RuleCoverage
+	for _, rule := range r.Rules {
+		if !rule.Covered {
+			uncovered = append(uncovered, rule)
+		}
+	}
+	return uncovered
+}
+
+// Write prints failures, a summary and, if coverage is set, the rules that
+// were not exercised.
+func (r *Report) Write(w io.Writer, coverage bool) {
+	for _, result := range r.Results {
+		if !result.Passed {
+			fmt.Fprintf(w, "FAIL %s: %s\n     %s\n", result.Suite, result.Case, result.Failure)
+		}
+	}
+
+	uncovered := r.Uncovered()
+	fmt.Fprintf(w, "%d passed, %d failed; %d/%d rules covered\n",
+		len(r.Results)-r.Failed(), r.Failed(), len(r.Rules)-len(uncovered), len(r.Rules))
+
+	if coverage {
+		for _, rule := range uncovered {
+			fmt.Fprintf(w, "  not covered: %s:%d %s\n", rule.File, rule.Line, rule.Rule)
+		}
+	}
+}
+
+// normalize round-trips v through JSON so that results and expectations
+// loaded from YAML compare with the same types.
+func normalize(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	var out interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return out, nil
+}
+
+func encode(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
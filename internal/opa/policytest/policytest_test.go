@@ -0,0 +1,96 @@
+package policytest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+)
+
+const testModule = `package authz
+
+import future.keywords.contains
+import future.keywords.if
+
+default allow := false
+
+allow if count(matched_rules) > 0
+
+decision := {"allow": allow, "matched_rules": matched_rules}
+
+matched_rules contains "health" if input.path == "/health"
+
+matched_rules contains "never" if input.path == "/nowhere"
+`
+
+func newTestRunner(t *testing.T) *Runner {
+	runner, err := NewRunner(&models.PolicyBundle{
+		Modules: map[string]string{"authz.rego": testModule},
+	})
+	require.NoError(t, err)
+	return runner
+}
+
+func TestRunner_Run(t *testing.T) {
+	allow, deny := true, false
+	suite := &Suite{Name: "unit", Cases: []Case{
+		{Name: "allowed", Input: map[string]interface{}{"path": "/health"}, Allow: &allow, MatchedRules: []string{"health"}},
+		{Name: "wrong expectation", Input: map[string]interface{}{"path": "/health"}, Allow: &deny},
+		{Name: "whole result", Query: "data.authz.allow", Input: map[string]interface{}{"path": "/other"}, Expect: false},
+		{Name: "undefined", Query: "data.authz.missing", Undefined: true},
+	}}
+
+	report := newTestRunner(t).Run(context.Background(), suite)
+
+	require.Len(t, report.Results, 4)
+	assert.True(t, report.Results[0].Passed)
+	assert.False(t, report.Results[1].Passed)
+	assert.Contains(t, report.Results[1].Failure, "expected allow false")
+	assert.True(t, report.Results[2].Passed)
+	assert.True(t, report.Results[3].Passed)
+	assert.Equal(t, 1, report.Failed())
+
+	uncovered := report.Uncovered()
+	require.Len(t, uncovered, 1)
+	assert.Equal(t, `authz.matched_rules["never"]`, uncovered[0].Rule)
+
+	var out bytes.Buffer
+	report.Write(&out, true)
+	assert.Contains(t, out.String(), "FAIL unit: wrong expectation")
+	assert.Contains(t, out.String(), "3 passed, 1 failed")
+	assert.Contains(t, out.String(), `not covered: authz.rego:14 authz.matched_rules["never"]`)
+}
+
+func TestLoadSuite(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "suite.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`
+cases:
+  - name: health
+    input: {method: GET, path: /health}
+    allow: true
+`), 0o644))
+
+	suite, err := LoadSuite(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, "suite.yaml", suite.Name)
+	require.Len(t, suite.Cases, 1)
+	assert.Equal(t, "/health", suite.Cases[0].Input["path"])
+	assert.True(t, *suite.Cases[0].Allow)
+
+	jsonPath := filepath.Join(dir, "suite.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"name": "json", "cases": [{"name": "health", "expect": 1}]}`), 0o644))
+
+	suite, err = LoadSuite(jsonPath)
+	require.NoError(t, err)
+	assert.Equal(t, "json", suite.Name)
+
+	_, err = LoadSuite(filepath.Join(dir, "suite.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,236 @@
+// Package problem defines the application's error type and renders it as an
+// RFC 7807 problem document. Every error code is listed in docs/errors.md.
+package problem
+
+import (
+	"net/http"
+
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
+)
+
+// ContentType is the media type problem documents are served with.
+const ContentType = "application/problem+json"
+
+// TypeBase prefixes an error code to form the problem's type URI, which
+// points at the code's entry in the error catalogue.
+const TypeBase = "https://github.com/witslab-sahil/fiber-boilerplate/blob/main/docs/errors.md#"
+
+// Code identifies a kind of error. Codes are part of the API: clients may
+// branch on them, so they are never renamed or reused.
+type Code string
+
+const (
+	// Generic errors
+	CodeInvalidRequest       Code = "invalid_request"
+	CodeValidationFailed     Code = "validation_failed"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeForbidden            Code = "forbidden"
+	CodeNotFound             Code = "not_found"
+	CodeMethodNotAllowed     Code = "method_not_allowed"
+	CodeUnsupportedMediaType Code = "unsupported_media_type"
+	CodePreconditionFailed   Code = "precondition_failed"
+	CodePreconditionRequired Code = "precondition_required"
+	CodeConflict             Code = "conflict"
+	CodeInternal             Code = "internal_error"
+	CodeServiceUnavailable   Code = "service_unavailable"
+
+	// Idempotency keys
+	CodeIdempotencyKeyReused     Code = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress Code = "idempotency_key_in_progress"
+
+	// Authentication
+	CodeInvalidCredentials         Code = "invalid_credentials"
+	CodeAccountPendingVerification Code = "account_pending_verification"
+	CodeAccountSuspended           Code = "account_suspended"
+	CodeAccountLocked              Code = "account_locked"
+	CodeAccountDeactivated         Code = "account_deactivated"
+
+	// Users
+	CodeUserNotFound            Code = "user_not_found"
+	CodeUserAlreadyExists       Code = "user_already_exists"
+	CodeUserNotDeleted          Code = "user_not_deleted"
+	CodeConcurrentModification  Code = "concurrent_modification"
+	CodeInvalidPatch            Code = "invalid_patch"
+	CodeUnprocessablePatch      Code = "unprocessable_patch"
+	CodePatchTestFailed         Code = "patch_test_failed"
+	CodeBatchUpdateFailed       Code = "batch_update_failed"
+	CodeInvalidStatusTransition Code = "invalid_status_transition"
+	CodeInvalidAttributeSchema  Code = "invalid_attribute_schema"
+
+	// Groups
+	CodeGroupNotFound       Code = "group_not_found"
+	CodeGroupAlreadyExists  Code = "group_already_exists"
+	CodeGroupMemberNotFound Code = "group_member_not_found"
+	CodeGroupCycle          Code = "group_cycle"
+
+	// Imports and exports
+	CodeInvalidImport        Code = "invalid_import"
+	CodeImportNotFound       Code = "import_not_found"
+	CodeExportNotFound       Code = "export_not_found"
+	CodeExportNotReady       Code = "export_not_ready"
+	CodeDataExportNotFound   Code = "data_export_not_found"
+	CodeDataExportInProgress Code = "data_export_in_progress"
+	CodeDataExportNotReady   Code = "data_export_not_ready"
+	CodeDataExportExpired    Code = "data_export_expired"
+	CodeInvalidDownloadLink  Code = "invalid_download_link"
+
+	// Erasure
+	CodeErasureNotFound       Code = "erasure_not_found"
+	CodeErasureInProgress     Code = "erasure_in_progress"
+	CodeErasureNotCancellable Code = "erasure_not_cancellable"
+
+	// Policies and workflows
+	CodePolicyBundleNotFound Code = "policy_bundle_not_found"
+	CodeInvalidPolicyBundle  Code = "invalid_policy_bundle"
+	CodeNoPreviousRevision   Code = "no_previous_revision"
+	CodeWorkflowNotFound     Code = "workflow_not_found"
+)
+
+type definition struct {
+	status int
+	title  string
+}
+
+var catalogue = map[Code]definition{
+	CodeInvalidRequest:       {http.StatusBadRequest, "Invalid request"},
+	CodeValidationFailed:     {http.StatusUnprocessableEntity, "Validation failed"},
+	CodeUnauthorized:         {http.StatusUnauthorized, "Unauthorized"},
+	CodeForbidden:            {http.StatusForbidden, "Forbidden"},
+	CodeNotFound:             {http.StatusNotFound, "Not found"},
+	CodeMethodNotAllowed:     {http.StatusMethodNotAllowed, "Method not allowed"},
+	CodeUnsupportedMediaType: {http.StatusUnsupportedMediaType, "Unsupported media type"},
+	CodePreconditionFailed:   {http.StatusPreconditionFailed, "Precondition failed"},
+	CodePreconditionRequired: {http.StatusPreconditionRequired, "Precondition required"},
+	CodeConflict:             {http.StatusConflict, "Conflict"},
+	CodeInternal:             {http.StatusInternalServerError, "Internal server error"},
+	CodeServiceUnavailable:   {http.StatusServiceUnavailable, "Service unavailable"},
+
+	CodeIdempotencyKeyReused:     {http.StatusUnprocessableEntity, "Idempotency key reused"},
+	CodeIdempotencyKeyInProgress: {http.StatusConflict, "Idempotency key in progress"},
+
+	CodeInvalidCredentials:         {http.StatusUnauthorized, "Invalid credentials"},
+	CodeAccountPendingVerification: {http.StatusForbidden, "Account pending verification"},
+	CodeAccountSuspended:           {http.StatusForbidden, "Account suspended"},
+	CodeAccountLocked:              {http.StatusLocked, "Account locked"},
+	CodeAccountDeactivated:         {http.StatusForbidden, "Account deactivated"},
+
+	CodeUserNotFound:            {http.StatusNotFound, "User not found"},
+	CodeUserAlreadyExists:       {http.StatusConflict, "User already exists"},
+	CodeUserNotDeleted:          {http.StatusConflict, "User is not deleted"},
+	CodeConcurrentModification:  {http.StatusConflict, "Concurrent modification"},
+	CodeInvalidPatch:            {http.StatusBadRequest, "Invalid patch"},
+	CodeUnprocessablePatch:      {http.StatusUnprocessableEntity, "Patch cannot be applied"},
+	CodePatchTestFailed:         {http.StatusConflict, "Patch test failed"},
+	CodeBatchUpdateFailed:       {http.StatusUnprocessableEntity, "Batch update failed"},
+	CodeInvalidStatusTransition: {http.StatusConflict, "Invalid status transition"},
+	CodeInvalidAttributeSchema:  {http.StatusUnprocessableEntity, "Invalid attribute schema"},
+
+	CodeGroupNotFound:       {http.StatusNotFound, "Group not found"},
+	CodeGroupAlreadyExists:  {http.StatusConflict, "Group already exists"},
+	CodeGroupMemberNotFound: {http.StatusNotFound, "Group member not found"},
+	CodeGroupCycle:          {http.StatusConflict, "Group cycle"},
+
+	CodeInvalidImport:        {http.StatusBadRequest, "Invalid import"},
+	CodeImportNotFound:       {http.StatusNotFound, "User import not found"},
+	CodeExportNotFound:       {http.StatusNotFound, "User export not found"},
+	CodeExportNotReady:       {http.StatusConflict, "User export not ready"},
+	CodeDataExportNotFound:   {http.StatusNotFound, "Data export not found"},
+	CodeDataExportInProgress: {http.StatusConflict, "Data export in progress"},
+	CodeDataExportNotReady:   {http.StatusConflict, "Data export not ready"},
+	CodeDataExportExpired:    {http.StatusGone, "Download link expired"},
+	CodeInvalidDownloadLink:  {http.StatusForbidden, "Invalid download link"},
+
+	CodeErasureNotFound:       {http.StatusNotFound, "Erasure request not found"},
+	CodeErasureInProgress:     {http.StatusConflict, "Erasure in progress"},
+	CodeErasureNotCancellable: {http.StatusConflict, "Erasure not cancellable"},
+
+	CodePolicyBundleNotFound: {http.StatusNotFound, "Policy bundle not found"},
+	CodeInvalidPolicyBundle:  {http.StatusUnprocessableEntity, "Invalid policy bundle"},
+	CodeNoPreviousRevision:   {http.StatusConflict, "No previous revision"},
+	CodeWorkflowNotFound:     {http.StatusNotFound, "Workflow not found"},
+}
+
+// Codes lists every error code in the catalogue.
+func Codes() []Code {
+	codes := make([]Code, 0, len(catalogue))
+	for code := range catalogue {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// Status returns the HTTP status a code is served with.
+func (c Code) Status() int {
+	if def, ok := catalogue[c]; ok {
+		return def.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Title returns the short, fixed summary of a code.
+func (c Code) Title() string {
+	if def, ok := catalogue[c]; ok {
+		return def.title
+	}
+	return http.StatusText(c.Status())
+}
+
+// Error is an application error. Detail is shown to the client; Err, the
+// underlying cause, is only logged.
+type Error struct {
+	Code   Code
+	Detail string
+	Fields []validation.FieldError
+	Err    error
+}
+
+// New returns an error with code and a detail for the client.
+func New(code Code, detail string) *Error {
+	return &Error{Code: code, Detail: detail}
+}
+
+// Wrap returns an error with code and detail caused by err.
+func Wrap(err error, code Code, detail string) *Error {
+	return &Error{Code: code, Detail: detail, Err: err}
+}
+
+func (e *Error) Error() string {
+	message := string(e.Code)
+	if e.Detail != "" {
+		message += ": " + e.Detail
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Document is the body of an application/problem+json response.
+type Document struct {
+	Type      string                  `json:"type"`
+	Title     string                  `json:"title"`
+	Status    int                     `json:"status"`
+	Detail    string                  `json:"detail,omitempty"`
+	Instance  string                  `json:"instance,omitempty"`
+	Code      Code                    `json:"code"`
+	RequestID string                  `json:"request_id,omitempty"`
+	Errors    []validation.FieldError `json:"errors,omitempty"`
+}
+
+// Document renders e for the request to instance.
+func (e *Error) Document(instance, requestID string) Document {
+	return Document{
+		Type:      TypeBase + string(e.Code),
+		Title:     e.Code.Title(),
+		Status:    e.Code.Status(),
+		Detail:    e.Detail,
+		Instance:  instance,
+		Code:      e.Code,
+		RequestID: requestID,
+		Errors:    e.Fields,
+	}
+}
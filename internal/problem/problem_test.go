@@ -0,0 +1,44 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogueIsDocumented(t *testing.T) {
+	doc, err := os.ReadFile("../../docs/errors.md")
+	require.NoError(t, err)
+
+	for _, code := range Codes() {
+		assert.Contains(t, string(doc), "\n### "+string(code)+"\n", "code %s is not documented", code)
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Wrap(cause, CodeServiceUnavailable, "Workflow service unavailable")
+
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "service_unavailable: Workflow service unavailable: connection refused", err.Error())
+
+	doc := err.Document("/api/v1/workflows", "req-1")
+	assert.Equal(t, TypeBase+"service_unavailable", doc.Type)
+	assert.Equal(t, "Service unavailable", doc.Title)
+	assert.Equal(t, http.StatusServiceUnavailable, doc.Status)
+	assert.Equal(t, "Workflow service unavailable", doc.Detail)
+	assert.Equal(t, "/api/v1/workflows", doc.Instance)
+	assert.Equal(t, "req-1", doc.RequestID)
+	assert.False(t, strings.Contains(doc.Detail, cause.Error()))
+}
+
+func TestUnknownCode(t *testing.T) {
+	code := Code("no_such_code")
+	assert.Equal(t, http.StatusInternalServerError, code.Status())
+	assert.Equal(t, "Internal Server Error", code.Title())
+}
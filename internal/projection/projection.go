@@ -0,0 +1,199 @@
+// Package projection trims responses to the fields a client selects with
+// ?fields= and embeds the related resources it asks for with ?include=. A
+// resource declares its fields and relations once, and every handler that
+// renders it accepts both parameters.
+package projection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+)
+
+// Includer loads a relation for each of items, the values being rendered,
+// and returns the related values in the same order. A nil value is rendered
+// as null.
+type Includer func(ctx context.Context, items []interface{}) ([]interface{}, error)
+
+// Resource declares the fields and relations the responses for one kind of
+// resource offer.
+type Resource struct {
+	fields   map[string]bool
+	required []string
+	includes map[string]Includer
+}
+
+// NewResource declares a resource whose JSON fields clients may select from
+// fields. The required fields, e.g. the ID, are returned whatever is
+// selected.
+func NewResource(fields []string, required ...string) *Resource {
+	r := &Resource{
+		fields:   make(map[string]bool, len(fields)),
+		required: required,
+		includes: map[string]Includer{},
+	}
+	for _, field := range fields {
+		r.fields[field] = true
+	}
+	return r
+}
+
+// Include offers a relation that clients embed with ?include=name. It
+// panics if name is one of the resource's fields, which the relation would
+// overwrite.
+func (r *Resource) Include(name string, includer Includer) {
+	if r.fields[name] {
+		panic(fmt.Sprintf("projection: include %q has the name of a field", name))
+	}
+	r.includes[name] = includer
+}
+
+// Projection is what one request selected.
+type Projection struct {
+	resource *Resource
+	// fields is nil when every field is selected
+	fields  map[string]bool
+	include []string
+}
+
+// Parse reads ?fields= and ?include= from the request. Names the resource
+// does not offer are rejected as invalid requests, and names missing from
+// the caller's field filter, Locals("field_filter"), as forbidden. It
+// returns nil, which renders values unchanged, when neither is given.
+func (r *Resource) Parse(c *fiber.Ctx) (*Projection, error) {
+	fields, include := splitList(c.Query("fields")), splitList(c.Query("include"))
+	if fields == nil && include == nil {
+		return nil, nil
+	}
+
+	// Set by the OPA field filter middleware; nil means every field is visible
+	filter, _ := c.Locals("field_filter").([]string)
+	visible := func(name string) bool {
+		if filter == nil {
+			return true
+		}
+		for _, allowed := range filter {
+			if allowed == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	p := &Projection{resource: r, include: include}
+	if fields != nil {
+		p.fields = make(map[string]bool, len(fields)+len(r.required))
+		for _, field := range fields {
+			if !r.fields[field] {
+				return nil, problem.New(problem.CodeInvalidRequest, fmt.Sprintf("Unknown field %q; fields are %s", field, strings.Join(sortedKeys(r.fields), ", ")))
+			}
+			if !visible(field) {
+				return nil, problem.New(problem.CodeForbidden, fmt.Sprintf("You may not see field %q", field))
+			}
+			p.fields[field] = true
+		}
+		for _, field := range r.required {
+			p.fields[field] = true
+		}
+	}
+	for _, name := range include {
+		if _, ok := r.includes[name]; !ok {
+			return nil, problem.New(problem.CodeInvalidRequest, fmt.Sprintf("Unknown include %q; includes are %s", name, strings.Join(sortedKeys(r.includes), ", ")))
+		}
+		if !visible(name) {
+			return nil, problem.New(problem.CodeForbidden, fmt.Sprintf("You may not include %q", name))
+		}
+	}
+	return p, nil
+}
+
+// Render projects item. A nil projection returns it unchanged.
+func (p *Projection) Render(ctx context.Context, item interface{}) (interface{}, error) {
+	if p == nil {
+		return item, nil
+	}
+	documents, err := p.render(ctx, []interface{}{item})
+	if err != nil {
+		return nil, err
+	}
+	return documents[0], nil
+}
+
+// RenderList projects every element of items, a slice, loading each
+// relation once for all of them. A nil projection returns items unchanged.
+func (p *Projection) RenderList(ctx context.Context, items interface{}) (interface{}, error) {
+	if p == nil {
+		return items, nil
+	}
+	value := reflect.ValueOf(items)
+	if value.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("projection: RenderList of %T", items)
+	}
+	list := make([]interface{}, value.Len())
+	for i := range list {
+		list[i] = value.Index(i).Interface()
+	}
+	return p.render(ctx, list)
+}
+
+func (p *Projection) render(ctx context.Context, items []interface{}) ([]map[string]interface{}, error) {
+	documents := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			return nil, fmt.Errorf("projection: %T is not rendered as an object", item)
+		}
+
+		documents[i] = make(map[string]interface{}, len(fields)+len(p.include))
+		for name, value := range fields {
+			if p.fields == nil || p.fields[name] {
+				documents[i][name] = value
+			}
+		}
+	}
+
+	for _, name := range p.include {
+		related, err := p.resource.includes[name](ctx, items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to include %s: %w", name, err)
+		}
+		for i := range documents {
+			documents[i][name] = related[i]
+		}
+	}
+	return documents, nil
+}
+
+// splitList splits a comma-separated parameter, dropping blanks and
+// repeats. It returns nil for an empty parameter.
+func splitList(value string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,141 @@
+package projection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/problem"
+)
+
+type item struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func newResource() *Resource {
+	r := NewResource([]string{"id", "name", "email"}, "id")
+	r.Include("owner", func(ctx context.Context, items []interface{}) ([]interface{}, error) {
+		related := make([]interface{}, len(items))
+		for i, it := range items {
+			related[i] = map[string]uint{"owner_of": it.(item).ID}
+		}
+		return related, nil
+	})
+	return r
+}
+
+// serve renders items through the resource's projection for target, with
+// fieldFilter stored as the caller's field filter if not nil.
+func serve(t *testing.T, r *Resource, target string, fieldFilter []string, items []item) (int, string) {
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		var appErr *problem.Error
+		if errors.As(err, &appErr) {
+			return c.Status(appErr.Code.Status()).SendString(string(appErr.Code))
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}})
+	app.Get("/items", func(c *fiber.Ctx) error {
+		if fieldFilter != nil {
+			c.Locals("field_filter", fieldFilter)
+		}
+		view, err := r.Parse(c)
+		if err != nil {
+			return err
+		}
+		rendered, err := view.RenderList(c.Context(), items)
+		if err != nil {
+			return err
+		}
+		return c.JSON(rendered)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestProjection(t *testing.T) {
+	items := []item{{ID: 1, Name: "alice", Email: "alice@example.com"}, {ID: 2, Name: "bob", Email: "bob@example.com"}}
+
+	t.Run("Unchanged Without Parameters", func(t *testing.T) {
+		status, body := serve(t, newResource(), "/items", nil, items)
+
+		assert.Equal(t, fiber.StatusOK, status)
+		expected, _ := json.Marshal(items)
+		assert.JSONEq(t, string(expected), body)
+	})
+
+	t.Run("Selects Fields And Keeps Required Ones", func(t *testing.T) {
+		status, body := serve(t, newResource(), "/items?fields=name,+name", nil, items)
+
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.JSONEq(t, `[{"id":1,"name":"alice"},{"id":2,"name":"bob"}]`, body)
+	})
+
+	t.Run("Embeds Includes", func(t *testing.T) {
+		status, body := serve(t, newResource(), "/items?fields=id&include=owner", nil, items)
+
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.JSONEq(t, `[{"id":1,"owner":{"owner_of":1}},{"id":2,"owner":{"owner_of":2}}]`, body)
+	})
+
+	t.Run("Rejects Unknown Names", func(t *testing.T) {
+		status, body := serve(t, newResource(), "/items?fields=password", nil, items)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, string(problem.CodeInvalidRequest), body)
+
+		status, _ = serve(t, newResource(), "/items?include=sessions", nil, items)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("Checks Field Filter", func(t *testing.T) {
+		filter := []string{"id", "name"}
+
+		status, _ := serve(t, newResource(), "/items?fields=name", filter, items)
+		assert.Equal(t, fiber.StatusOK, status)
+
+		status, body := serve(t, newResource(), "/items?fields=email", filter, items)
+		assert.Equal(t, fiber.StatusForbidden, status)
+		assert.Equal(t, string(problem.CodeForbidden), body)
+
+		status, _ = serve(t, newResource(), "/items?include=owner", filter, items)
+		assert.Equal(t, fiber.StatusForbidden, status)
+	})
+
+	t.Run("Include Errors", func(t *testing.T) {
+		r := newResource()
+		r.Include("owner", func(ctx context.Context, items []interface{}) ([]interface{}, error) {
+			return nil, errors.New("database error")
+		})
+
+		status, _ := serve(t, r, "/items?include=owner", nil, items)
+		assert.Equal(t, fiber.StatusInternalServerError, status)
+	})
+}
+
+func TestIncludeNamedLikeAFieldPanics(t *testing.T) {
+	r := newResource()
+
+	assert.PanicsWithValue(t, `projection: include "email" has the name of a field`, func() {
+		r.Include("email", func(ctx context.Context, items []interface{}) ([]interface{}, error) {
+			return make([]interface{}, len(items)), nil
+		})
+	})
+}
+
+func TestNilProjection(t *testing.T) {
+	var view *Projection
+	it := item{ID: 1}
+
+	rendered, err := view.Render(context.Background(), it)
+	assert.NoError(t, err)
+	assert.Equal(t, it, rendered)
+}
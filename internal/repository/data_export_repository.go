@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+)
+
+type DataExportRepository interface {
+	Create(export *models.DataExport) error
+	GetByID(id string) (*models.DataExport, error)
+	Update(id string, fields map[string]interface{}) error
+	ListForUser(userID uint) ([]*models.DataExport, error)
+	Delete(id string) error
+}
+
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+	return &dataExportRepository{
+		db: db,
+	}
+}
+
+func (r *dataExportRepository) Create(export *models.DataExport) error {
+	return r.db.Create(export).Error
+}
+
+func (r *dataExportRepository) GetByID(id string) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.Where("id = ?", id).First(&export).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *dataExportRepository) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.DataExport{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// ListForUser returns the user's data exports, newest first.
+func (r *dataExportRepository) ListForUser(userID uint) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&exports).Error
+	return exports, err
+}
+
+func (r *dataExportRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&models.DataExport{}).Error
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+)
+
+type ErasureRepository interface {
+	Create(request *models.ErasureRequest) error
+	GetByID(id string) (*models.ErasureRequest, error)
+	LatestForUser(userID uint) (*models.ErasureRequest, error)
+	LatestForUsers(userIDs []uint) ([]*models.ErasureRequest, error)
+	ListForUser(userID uint) ([]*models.ErasureRequest, error)
+	Update(id string, fields map[string]interface{}) error
+	Transition(id string, from []string, fields map[string]interface{}) (bool, error)
+	Complete(certificate *models.ErasureCertificate, fields map[string]interface{}) error
+}
+
+type erasureRepository struct {
+	db *gorm.DB
+}
+
+func NewErasureRepository(db *gorm.DB) ErasureRepository {
+	return &erasureRepository{
+		db: db,
+	}
+}
+
+func (r *erasureRepository) Create(request *models.ErasureRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *erasureRepository) GetByID(id string) (*models.ErasureRequest, error) {
+	var request models.ErasureRequest
+	err := r.db.Preload("Certificate").Where("id = ?", id).First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// LatestForUser returns the most recent erasure request for the user.
+func (r *erasureRepository) LatestForUser(userID uint) (*models.ErasureRequest, error) {
+	var request models.ErasureRequest
+	err := r.db.Preload("Certificate").Where("user_id = ?", userID).Order("created_at DESC").First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// LatestForUsers returns the most recent erasure request of each of the
+// users that has one, in no particular order. Users rarely have more than
+// one request, so every request is read and all but the latest dropped.
+func (r *erasureRepository) LatestForUsers(userIDs []uint) ([]*models.ErasureRequest, error) {
+	var requests []*models.ErasureRequest
+	if len(userIDs) == 0 {
+		return requests, nil
+	}
+	err := r.db.Preload("Certificate").Where("user_id IN ?", userIDs).Order("user_id, created_at DESC").Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+	latest := requests[:0]
+	for _, request := range requests {
+		if len(latest) == 0 || latest[len(latest)-1].UserID != request.UserID {
+			latest = append(latest, request)
+		}
+	}
+	return latest, nil
+}
+
+// ListForUser returns the user's erasure requests, newest first.
+func (r *erasureRepository) ListForUser(userID uint) ([]*models.ErasureRequest, error) {
+	var requests []*models.ErasureRequest
+	err := r.db.Preload("Certificate").Where("user_id = ?", userID).Order("created_at DESC").Find(&requests).Error
+	return requests, err
+}
+
+func (r *erasureRepository) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.ErasureRequest{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// Transition updates the request only if its status is one of from, and
+// reports whether it did. It settles races between cancellation and the
+// end of the grace period.
+func (r *erasureRepository) Transition(id string, from []string, fields map[string]interface{}) (bool, error) {
+	result := r.db.Model(&models.ErasureRequest{}).Where("id = ? AND status IN ?", id, from).Updates(fields)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Complete stores the certificate and updates the request in one
+// transaction.
+func (r *erasureRepository) Complete(certificate *models.ErasureCertificate, fields map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(certificate).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ErasureRequest{}).Where("id = ?", certificate.RequestID).Updates(fields).Error
+	})
+}
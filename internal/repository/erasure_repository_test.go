@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ErasureRepositoryTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	repo ErasureRepository
+}
+
+func (suite *ErasureRepositoryTestSuite) SetupSuite() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(suite.T(), err)
+
+	err = db.AutoMigrate(&models.ErasureRequest{}, &models.ErasureCertificate{})
+	assert.NoError(suite.T(), err)
+
+	suite.db = db
+	suite.repo = NewErasureRepository(db)
+}
+
+func (suite *ErasureRepositoryTestSuite) TearDownTest() {
+	suite.db.Exec("DELETE FROM erasure_requests")
+	suite.db.Exec("DELETE FROM erasure_certificates")
+}
+
+func (suite *ErasureRepositoryTestSuite) TestLatestForUsers() {
+	now := time.Now()
+	requests := []*models.ErasureRequest{
+		{ID: "a-old", UserID: 42, Status: models.ErasureStatusCancelled, CreatedAt: now.Add(-time.Hour)},
+		{ID: "a-new", UserID: 42, Status: models.ErasureStatusPending, CreatedAt: now},
+		{ID: "b", UserID: 43, Status: models.ErasureStatusPending, CreatedAt: now.Add(-time.Minute)},
+		{ID: "c", UserID: 44, Status: models.ErasureStatusPending, CreatedAt: now},
+	}
+	for _, request := range requests {
+		assert.NoError(suite.T(), suite.repo.Create(request))
+	}
+
+	latest, err := suite.repo.LatestForUsers([]uint{42, 43, 45})
+	assert.NoError(suite.T(), err)
+	ids := map[uint]string{}
+	for _, request := range latest {
+		ids[request.UserID] = request.ID
+	}
+	assert.Equal(suite.T(), map[uint]string{42: "a-new", 43: "b"}, ids)
+
+	latest, err = suite.repo.LatestForUsers(nil)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), latest)
+}
+
+func TestErasureRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(ErasureRepositoryTestSuite))
+}
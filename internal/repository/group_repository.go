@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GroupRepository interface {
+	Create(group *models.Group) error
+	GetByID(id uint) (*models.Group, error)
+	GetByName(name string) (*models.Group, error)
+	GetByIDs(ids []uint) ([]*models.Group, error)
+	List(page, pageSize int) ([]*models.Group, int64, error)
+	Update(group *models.Group) error
+	Delete(id uint) error
+	AddMember(member *models.GroupMember) (bool, error)
+	GetMember(groupID uint, memberType string, memberID uint) (*models.GroupMember, error)
+	RemoveMember(groupID uint, memberType string, memberID uint) (bool, error)
+	ListMembers(groupID uint) ([]*models.GroupMember, error)
+	ParentIDs(memberType string, memberIDs []uint) ([]uint, error)
+	Memberships(memberType string, memberIDs []uint) ([]*models.GroupMember, error)
+	RemoveUser(userID uint) (int64, error)
+	LockNesting() error
+	Transaction(fn func(repo GroupRepository) error) error
+}
+
+// groupNestingLock is the Postgres advisory lock key LockNesting takes.
+const groupNestingLock = 0x67726f7570 // "group"
+
+type groupRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupRepository(db *gorm.DB) GroupRepository {
+	return &groupRepository{
+		db: db,
+	}
+}
+
+func (r *groupRepository) Create(group *models.Group) error {
+	return r.db.Create(group).Error
+}
+
+func (r *groupRepository) GetByID(id uint) (*models.Group, error) {
+	var group models.Group
+	err := r.db.First(&group, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *groupRepository) GetByName(name string) (*models.Group, error) {
+	var group models.Group
+	err := r.db.Where("name = ?", name).First(&group).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetByIDs returns the groups with the given IDs, ordered by name. IDs with
+// no group are left out.
+func (r *groupRepository) GetByIDs(ids []uint) ([]*models.Group, error) {
+	var groups []*models.Group
+	if len(ids) == 0 {
+		return groups, nil
+	}
+	err := r.db.Where("id IN ?", ids).Order("name").Find(&groups).Error
+	return groups, err
+}
+
+func (r *groupRepository) List(page, pageSize int) ([]*models.Group, int64, error) {
+	var groups []*models.Group
+	var total int64
+
+	if err := r.db.Model(&models.Group{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := r.db.Order("name").Offset(offset).Limit(pageSize).Find(&groups).Error
+	return groups, total, err
+}
+
+func (r *groupRepository) Update(group *models.Group) error {
+	return r.db.Save(group).Error
+}
+
+// Delete removes the group, its members and its own memberships of other
+// groups, so that nothing is granted through it any more.
+func (r *groupRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("group_id = ? OR (member_type = ? AND member_id = ?)", id, models.GroupMemberGroup, id).
+			Delete(&models.GroupMember{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&models.Group{}, id).Error
+	})
+}
+
+// AddMember stores member and reports whether it did; it does not if the
+// member is already in the group.
+func (r *groupRepository) AddMember(member *models.GroupMember) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(member)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetMember returns the membership, or nil if the user or group is not a
+// direct member of the group.
+func (r *groupRepository) GetMember(groupID uint, memberType string, memberID uint) (*models.GroupMember, error) {
+	var member models.GroupMember
+	err := r.db.Where("group_id = ? AND member_type = ? AND member_id = ?", groupID, memberType, memberID).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// RemoveMember reports whether the member was in the group.
+func (r *groupRepository) RemoveMember(groupID uint, memberType string, memberID uint) (bool, error) {
+	result := r.db.Where("group_id = ? AND member_type = ? AND member_id = ?", groupID, memberType, memberID).
+		Delete(&models.GroupMember{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListMembers returns the direct members of the group, groups first.
+func (r *groupRepository) ListMembers(groupID uint) ([]*models.GroupMember, error) {
+	var members []*models.GroupMember
+	err := r.db.Where("group_id = ?", groupID).Order("member_type, member_id").Find(&members).Error
+	return members, err
+}
+
+// ParentIDs returns the IDs of the groups the given users or groups are
+// direct members of.
+func (r *groupRepository) ParentIDs(memberType string, memberIDs []uint) ([]uint, error) {
+	var ids []uint
+	if len(memberIDs) == 0 {
+		return ids, nil
+	}
+	err := r.db.Model(&models.GroupMember{}).
+		Where("member_type = ? AND member_id IN ?", memberType, memberIDs).
+		Distinct().Pluck("group_id", &ids).Error
+	return ids, err
+}
+
+// Memberships returns the direct memberships of the given users or groups.
+func (r *groupRepository) Memberships(memberType string, memberIDs []uint) ([]*models.GroupMember, error) {
+	var members []*models.GroupMember
+	if len(memberIDs) == 0 {
+		return members, nil
+	}
+	err := r.db.Where("member_type = ? AND member_id IN ?", memberType, memberIDs).Find(&members).Error
+	return members, err
+}
+
+// RemoveUser takes the user out of every group.
+func (r *groupRepository) RemoveUser(userID uint) (int64, error) {
+	result := r.db.Where("member_type = ? AND member_id = ?", models.GroupMemberUser, userID).
+		Delete(&models.GroupMember{})
+	return result.RowsAffected, result.Error
+}
+
+// LockNesting makes other transactions that change group nesting wait until
+// the current one ends, so that a nesting check and the insert it allows
+// happen as one. It must be called within Transaction. It only locks on
+// Postgres; the SQLite databases of the tests are not shared.
+func (r *groupRepository) LockNesting() error {
+	if r.db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return r.db.Exec("SELECT pg_advisory_xact_lock(?)", groupNestingLock).Error
+}
+
+// Transaction calls fn with a repository whose changes are committed if fn
+// returns nil and rolled back otherwise.
+func (r *groupRepository) Transaction(fn func(repo GroupRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&groupRepository{db: tx})
+	})
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GroupRepositoryTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	repo GroupRepository
+}
+
+func (suite *GroupRepositoryTestSuite) SetupSuite() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(suite.T(), err)
+
+	err = db.AutoMigrate(&models.Group{}, &models.GroupMember{})
+	assert.NoError(suite.T(), err)
+
+	suite.db = db
+	suite.repo = NewGroupRepository(db)
+}
+
+func (suite *GroupRepositoryTestSuite) TearDownTest() {
+	suite.db.Exec("DELETE FROM groups")
+	suite.db.Exec("DELETE FROM group_members")
+}
+
+func (suite *GroupRepositoryTestSuite) createGroup(name string, roles ...string) *models.Group {
+	group := &models.Group{Name: name, Roles: roles}
+	assert.NoError(suite.T(), suite.repo.Create(group))
+	return group
+}
+
+func (suite *GroupRepositoryTestSuite) TestCreateAndGet() {
+	group := suite.createGroup("engineering", "deployer")
+
+	found, err := suite.repo.GetByName("engineering")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), group.ID, found.ID)
+	assert.Equal(suite.T(), []string{"deployer"}, found.Roles)
+
+	// Names are unique
+	assert.Error(suite.T(), suite.repo.Create(&models.Group{Name: "engineering"}))
+
+	missing, err := suite.repo.GetByID(99999)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), missing)
+}
+
+func (suite *GroupRepositoryTestSuite) TestMembers() {
+	engineering := suite.createGroup("engineering")
+	platform := suite.createGroup("platform")
+
+	added, err := suite.repo.AddMember(&models.GroupMember{GroupID: engineering.ID, MemberType: models.GroupMemberGroup, MemberID: platform.ID})
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), added)
+	added, err = suite.repo.AddMember(&models.GroupMember{GroupID: platform.ID, MemberType: models.GroupMemberUser, MemberID: 42})
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), added)
+
+	// Adding a member twice changes nothing
+	added, err = suite.repo.AddMember(&models.GroupMember{GroupID: platform.ID, MemberType: models.GroupMemberUser, MemberID: 42, AddedBy: "7"})
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), added)
+	member, err := suite.repo.GetMember(platform.ID, models.GroupMemberUser, 42)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), member.AddedBy)
+	member, err = suite.repo.GetMember(platform.ID, models.GroupMemberGroup, 42)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), member)
+
+	parents, err := suite.repo.ParentIDs(models.GroupMemberUser, []uint{42})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []uint{platform.ID}, parents)
+	parents, err = suite.repo.ParentIDs(models.GroupMemberGroup, []uint{platform.ID})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []uint{engineering.ID}, parents)
+
+	// A user and a group with the same ID are different members
+	parents, err = suite.repo.ParentIDs(models.GroupMemberGroup, []uint{42})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), parents)
+
+	memberships, err := suite.repo.Memberships(models.GroupMemberUser, []uint{42, 7})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), memberships, 1)
+	assert.Equal(suite.T(), platform.ID, memberships[0].GroupID)
+	memberships, err = suite.repo.Memberships(models.GroupMemberGroup, []uint{42})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), memberships)
+
+	removed, err := suite.repo.RemoveMember(platform.ID, models.GroupMemberUser, 42)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), removed)
+	removed, err = suite.repo.RemoveMember(platform.ID, models.GroupMemberUser, 42)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), removed)
+}
+
+func (suite *GroupRepositoryTestSuite) TestDeleteRemovesMemberships() {
+	engineering := suite.createGroup("engineering")
+	platform := suite.createGroup("platform")
+	suite.repo.AddMember(&models.GroupMember{GroupID: engineering.ID, MemberType: models.GroupMemberGroup, MemberID: platform.ID})
+	suite.repo.AddMember(&models.GroupMember{GroupID: platform.ID, MemberType: models.GroupMemberUser, MemberID: 42})
+	suite.repo.AddMember(&models.GroupMember{GroupID: engineering.ID, MemberType: models.GroupMemberUser, MemberID: 7})
+
+	assert.NoError(suite.T(), suite.repo.Delete(platform.ID))
+
+	var memberships []*models.GroupMember
+	suite.db.Find(&memberships)
+	assert.Len(suite.T(), memberships, 1)
+	assert.Equal(suite.T(), uint(7), memberships[0].MemberID)
+
+	erased, err := suite.repo.RemoveUser(7)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), erased)
+}
+
+func (suite *GroupRepositoryTestSuite) TestTransaction() {
+	engineering := suite.createGroup("engineering")
+
+	err := suite.repo.Transaction(func(repo GroupRepository) error {
+		assert.NoError(suite.T(), repo.LockNesting())
+		_, err := repo.AddMember(&models.GroupMember{GroupID: engineering.ID, MemberType: models.GroupMemberUser, MemberID: 42})
+		assert.NoError(suite.T(), err)
+		return errors.New("rolled back")
+	})
+	assert.Error(suite.T(), err)
+
+	member, err := suite.repo.GetMember(engineering.ID, models.GroupMemberUser, 42)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), member)
+}
+
+func TestGroupRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GroupRepositoryTestSuite))
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	Reserve(record *models.IdempotencyKey) (*models.IdempotencyKey, error)
+	Complete(record *models.IdempotencyKey) error
+	Release(record *models.IdempotencyKey) error
+	DeleteExpired(now time.Time) (int64, error)
+	DeleteForPrincipal(principal string) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+// Reserve stores record unless its key is already held for the same
+// principal and route, in which case it returns the record holding it. An
+// expired record no longer holds its key and is replaced.
+func (r *idempotencyRepository) Reserve(record *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	var existing *models.IdempotencyKey
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		scope := tx.Where("key = ? AND principal = ? AND route = ?", record.Key, record.Principal, record.Route)
+		if err := scope.Session(&gorm.Session{}).Where("expires_at <= ?", record.CreatedAt).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil || result.RowsAffected == 1 {
+			return result.Error
+		}
+
+		var found models.IdempotencyKey
+		if err := scope.Session(&gorm.Session{}).First(&found).Error; err != nil {
+			return err
+		}
+		existing = &found
+		return nil
+	})
+	return existing, err
+}
+
+// Complete stores the response to a reserved record.
+func (r *idempotencyRepository) Complete(record *models.IdempotencyKey) error {
+	record.Status = models.IdempotencyStatusCompleted
+	return r.db.Model(&models.IdempotencyKey{}).
+		Where("key = ? AND principal = ? AND route = ?", record.Key, record.Principal, record.Route).
+		Select("status", "response_status", "response_headers", "response_body", "expires_at").
+		Updates(record).Error
+}
+
+// Release frees a reserved key whose request was not answered, so that it
+// can be used again.
+func (r *idempotencyRepository) Release(record *models.IdempotencyKey) error {
+	return r.db.
+		Where("key = ? AND principal = ? AND route = ? AND status = ?", record.Key, record.Principal, record.Route, models.IdempotencyStatusInProgress).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired removes every record that expired before now.
+func (r *idempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteForPrincipal removes every record made by principal, for erasure
+// requests.
+func (r *idempotencyRepository) DeleteForPrincipal(principal string) (int64, error) {
+	result := r.db.Where("principal = ?", principal).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
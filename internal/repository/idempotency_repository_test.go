@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newIdempotencyRepository(t *testing.T) IdempotencyRepository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.IdempotencyKey{}))
+	return NewIdempotencyRepository(db)
+}
+
+func idempotencyKey(key string, now time.Time) *models.IdempotencyKey {
+	return &models.IdempotencyKey{
+		Key:         key,
+		Principal:   "42",
+		Route:       "/api/v1/users",
+		RequestHash: "hash",
+		Status:      models.IdempotencyStatusInProgress,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Minute),
+	}
+}
+
+func TestIdempotencyRepository(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Reserves A Key Once", func(t *testing.T) {
+		repo := newIdempotencyRepository(t)
+
+		existing, err := repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+
+		existing, err = repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Equal(t, models.IdempotencyStatusInProgress, existing.Status)
+
+		// Another caller's key is separate
+		other := idempotencyKey("a", now)
+		other.Principal = "7"
+		existing, err = repo.Reserve(other)
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	t.Run("Stores The Response", func(t *testing.T) {
+		repo := newIdempotencyRepository(t)
+		record := idempotencyKey("a", now)
+		_, err := repo.Reserve(record)
+		require.NoError(t, err)
+
+		record.ResponseStatus = 201
+		record.ResponseHeaders = map[string]string{"Location": "/api/v1/users/1"}
+		record.ResponseBody = []byte(`{"id":1}`)
+		record.ExpiresAt = now.Add(time.Hour)
+		require.NoError(t, repo.Complete(record))
+
+		existing, err := repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Equal(t, models.IdempotencyStatusCompleted, existing.Status)
+		assert.Equal(t, 201, existing.ResponseStatus)
+		assert.Equal(t, "/api/v1/users/1", existing.ResponseHeaders["Location"])
+		assert.Equal(t, `{"id":1}`, string(existing.ResponseBody))
+
+		// Completed keys are not released
+		require.NoError(t, repo.Release(record))
+		existing, err = repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		assert.NotNil(t, existing)
+	})
+
+	t.Run("Completes Only Its Own Key", func(t *testing.T) {
+		repo := newIdempotencyRepository(t)
+		anonymous := idempotencyKey("a", now)
+		anonymous.Principal = ""
+		_, err := repo.Reserve(anonymous)
+		require.NoError(t, err)
+		_, err = repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+
+		anonymous.ResponseStatus = 201
+		require.NoError(t, repo.Complete(anonymous))
+
+		existing, err := repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Equal(t, models.IdempotencyStatusInProgress, existing.Status)
+		assert.Zero(t, existing.ResponseStatus)
+	})
+
+	t.Run("Releases A Key", func(t *testing.T) {
+		repo := newIdempotencyRepository(t)
+		record := idempotencyKey("a", now)
+		_, err := repo.Reserve(record)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Release(record))
+		existing, err := repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	t.Run("Replaces Expired Keys", func(t *testing.T) {
+		repo := newIdempotencyRepository(t)
+		_, err := repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		_, err = repo.Reserve(idempotencyKey("b", now.Add(-2*time.Minute)))
+		require.NoError(t, err)
+
+		existing, err := repo.Reserve(idempotencyKey("a", now.Add(2*time.Minute)))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+
+		deleted, err := repo.DeleteExpired(now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+
+	t.Run("Deletes A Principal's Keys", func(t *testing.T) {
+		repo := newIdempotencyRepository(t)
+		_, err := repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		_, err = repo.Reserve(idempotencyKey("b", now))
+		require.NoError(t, err)
+		other := idempotencyKey("a", now)
+		other.Principal = "7"
+		_, err = repo.Reserve(other)
+		require.NoError(t, err)
+
+		deleted, err := repo.DeleteForPrincipal("42")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+
+		existing, err := repo.Reserve(idempotencyKey("a", now))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+)
+
+type PolicyBundleRepository interface {
+	Create(bundle *models.PolicyBundle) error
+	GetByRevision(revision string) (*models.PolicyBundle, error)
+	GetActive() (*models.PolicyBundle, error)
+	List() ([]*models.PolicyBundle, error)
+	Activate(revision, activatedBy string) error
+	ListActivations(limit int) ([]*models.PolicyActivation, error)
+}
+
+type policyBundleRepository struct {
+	db *gorm.DB
+}
+
+func NewPolicyBundleRepository(db *gorm.DB) PolicyBundleRepository {
+	return &policyBundleRepository{
+		db: db,
+	}
+}
+
+func (r *policyBundleRepository) Create(bundle *models.PolicyBundle) error {
+	return r.db.Create(bundle).Error
+}
+
+func (r *policyBundleRepository) GetByRevision(revision string) (*models.PolicyBundle, error) {
+	var bundle models.PolicyBundle
+	err := r.db.Where("revision = ?", revision).First(&bundle).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+func (r *policyBundleRepository) GetActive() (*models.PolicyBundle, error) {
+	var bundle models.PolicyBundle
+	err := r.db.Where("active = ?", true).First(&bundle).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+func (r *policyBundleRepository) List() ([]*models.PolicyBundle, error) {
+	var bundles []*models.PolicyBundle
+	err := r.db.Order("created_at DESC").Find(&bundles).Error
+	return bundles, err
+}
+
+// Activate marks revision as the only active bundle and appends to the
+// activation history in a single transaction.
+func (r *policyBundleRepository) Activate(revision, activatedBy string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Model(&models.PolicyBundle{}).
+			Where("active = ? AND revision <> ?", true, revision).
+			Update("active", false).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.PolicyBundle{}).
+			Where("revision = ?", revision).
+			Updates(map[string]interface{}{"active": true, "activated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Create(&models.PolicyActivation{
+			Revision:    revision,
+			ActivatedBy: activatedBy,
+			CreatedAt:   now,
+		}).Error
+	})
+}
+
+func (r *policyBundleRepository) ListActivations(limit int) ([]*models.PolicyActivation, error) {
+	var activations []*models.PolicyActivation
+	err := r.db.Order("id DESC").Limit(limit).Find(&activations).Error
+	return activations, err
+}
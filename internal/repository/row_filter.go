@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+)
+
+var rowFilterOperators = map[string]bool{
+	"=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+// applyRowFilter restricts db to the rows allowed by filter. A nil filter
+// leaves the query unrestricted. Column names come from the filter
+// translator's whitelist, never from user input.
+func applyRowFilter(db *gorm.DB, filter *models.RowFilter) (*gorm.DB, error) {
+	if filter == nil || filter.Unrestricted {
+		return db, nil
+	}
+	if len(filter.Clauses) == 0 {
+		return db.Where("1 = 0"), nil
+	}
+
+	clauses := make([]string, 0, len(filter.Clauses))
+	var args []interface{}
+	for _, clause := range filter.Clauses {
+		conditions := make([]string, 0, len(clause))
+		for _, condition := range clause {
+			if !rowFilterOperators[condition.Operator] {
+				return nil, fmt.Errorf("unsupported row filter operator %q", condition.Operator)
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", condition.Column, condition.Operator))
+			args = append(args, condition.Value)
+		}
+		clauses = append(clauses, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return db.Where(strings.Join(clauses, " OR "), args...), nil
+}
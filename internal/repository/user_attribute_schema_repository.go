@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserAttributeSchemaRepository interface {
+	Create(schema *models.UserAttributeSchema) (bool, error)
+	Latest() (*models.UserAttributeSchema, error)
+}
+
+type userAttributeSchemaRepository struct {
+	db *gorm.DB
+}
+
+func NewUserAttributeSchemaRepository(db *gorm.DB) UserAttributeSchemaRepository {
+	return &userAttributeSchemaRepository{
+		db: db,
+	}
+}
+
+// Create stores schema under its version and reports whether it did; it
+// does not if another schema already has that version.
+func (r *userAttributeSchemaRepository) Create(schema *models.UserAttributeSchema) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "version"}}, DoNothing: true}).Create(schema)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Latest returns the schema with the highest version, or nil if none has
+// been published.
+func (r *userAttributeSchemaRepository) Latest() (*models.UserAttributeSchema, error) {
+	var schema models.UserAttributeSchema
+	err := r.db.Order("version DESC").First(&schema).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &schema, nil
+}
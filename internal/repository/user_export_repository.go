@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+)
+
+type UserExportRepository interface {
+	Create(export *models.UserExport) error
+	GetByID(id string) (*models.UserExport, error)
+	Update(id string, fields map[string]interface{}) error
+	ListByCreator(createdBy string) ([]*models.UserExport, error)
+	Delete(id string) error
+}
+
+type userExportRepository struct {
+	db *gorm.DB
+}
+
+func NewUserExportRepository(db *gorm.DB) UserExportRepository {
+	return &userExportRepository{
+		db: db,
+	}
+}
+
+func (r *userExportRepository) Create(export *models.UserExport) error {
+	return r.db.Create(export).Error
+}
+
+func (r *userExportRepository) GetByID(id string) (*models.UserExport, error) {
+	var export models.UserExport
+	err := r.db.Where("id = ?", id).First(&export).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *userExportRepository) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.UserExport{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *userExportRepository) ListByCreator(createdBy string) ([]*models.UserExport, error) {
+	var exports []*models.UserExport
+	err := r.db.Where("created_by = ?", createdBy).Order("created_at").Find(&exports).Error
+	return exports, err
+}
+
+func (r *userExportRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&models.UserExport{}).Error
+}
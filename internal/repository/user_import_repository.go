@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/gorm"
+)
+
+type UserImportRepository interface {
+	Create(job *models.UserImport, rows []*models.UserImportRow) error
+	GetByID(id string) (*models.UserImport, error)
+	Update(id string, fields map[string]interface{}) error
+	PendingRows(id string, limit int) ([]*models.UserImportRow, error)
+	FailedRows(id string) ([]*models.UserImportRow, error)
+	ApplyRow(row *models.UserImportRow, user *models.User) (bool, error)
+	EraseUser(userID uint, email string) (int64, error)
+	WithContext(ctx context.Context) UserImportRepository
+}
+
+type userImportRepository struct {
+	db *gorm.DB
+}
+
+func NewUserImportRepository(db *gorm.DB) UserImportRepository {
+	return &userImportRepository{
+		db: db,
+	}
+}
+
+func (r *userImportRepository) Create(job *models.UserImport, rows []*models.UserImportRow) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+}
+
+func (r *userImportRepository) GetByID(id string) (*models.UserImport, error) {
+	var job models.UserImport
+	err := r.db.Where("id = ?", id).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *userImportRepository) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.UserImport{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// PendingRows returns the first limit rows not processed yet, in file order.
+func (r *userImportRepository) PendingRows(id string, limit int) ([]*models.UserImportRow, error) {
+	var rows []*models.UserImportRow
+	err := r.db.
+		Where("import_id = ? AND status = ?", id, models.ImportRowPending).
+		Order("line").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+func (r *userImportRepository) FailedRows(id string) ([]*models.UserImportRow, error) {
+	var rows []*models.UserImportRow
+	err := r.db.
+		Where("import_id = ? AND status = ?", id, models.ImportRowFailed).
+		Order("line").
+		Find(&rows).Error
+	return rows, err
+}
+
+// WithContext returns a repository whose queries run with ctx and whose user
+// revisions are recorded against the audit.Actor it carries.
+func (r *userImportRepository) WithContext(ctx context.Context) UserImportRepository {
+	return &userImportRepository{db: r.db.WithContext(ctx)}
+}
+
+// ApplyRow saves user, creating it if it has no ID, and records its revision,
+// records the outcome on row and counts it on the import, all in one
+// transaction so that a retried batch never applies or counts a row twice.
+// user is nil when nothing is to be written, i.e. for failed rows and dry
+// runs. An existing user is only updated if the stored row still has the
+// version user was read at; if it does not, nothing is written and ApplyRow
+// reports false.
+func (r *userImportRepository) ApplyRow(row *models.UserImportRow, user *models.User) (bool, error) {
+	var version uint
+	if user != nil {
+		version = user.Version
+	}
+	applied := true
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if user != nil {
+			operation := models.RevisionCreate
+			if user.ID == 0 {
+				if err := tx.Create(user).Error; err != nil {
+					return err
+				}
+			} else {
+				operation = models.RevisionUpdate
+				user.Version++
+				result := tx.Model(user).Where("id = ? AND version = ?", user.ID, version).Select("*").Omit("created_at").Updates(user)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					applied = false
+					return nil
+				}
+			}
+			if err := createUserRevision(tx, user, operation); err != nil {
+				return err
+			}
+			row.UserID = &user.ID
+		}
+
+		if err := tx.Model(row).Updates(map[string]interface{}{
+			"status":  row.Status,
+			"error":   row.Error,
+			"user_id": row.UserID,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Each final row status names the import counter it adds to
+		return tx.Model(&models.UserImport{}).Where("id = ?", row.ImportID).Updates(map[string]interface{}{
+			"processed": gorm.Expr("processed + 1"),
+			row.Status:  gorm.Expr(row.Status + " + 1"),
+		}).Error
+	})
+	if err != nil || !applied {
+		if user != nil {
+			user.Version = version
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// EraseUser clears the uploaded record of every import row for the user:
+// rows that created or updated it, and rows that carried its email but
+// failed before reaching it.
+func (r *userImportRepository) EraseUser(userID uint, email string) (int64, error) {
+	pattern := `%"email":"` + escapeLike(email) + `"%`
+	result := r.db.Model(&models.UserImportRow{}).
+		Where(`user_id = ? OR record LIKE ? ESCAPE '\'`, userID, pattern).
+		Updates(map[string]interface{}{"record": "{}", "error": ""})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/audit"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type UserImportRepositoryTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	repo UserImportRepository
+}
+
+func (suite *UserImportRepositoryTestSuite) SetupSuite() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(suite.T(), err)
+
+	err = db.AutoMigrate(&models.User{}, &models.UserImport{}, &models.UserImportRow{}, &models.UserRevision{})
+	assert.NoError(suite.T(), err)
+
+	suite.db = db
+	suite.repo = NewUserImportRepository(db)
+}
+
+func (suite *UserImportRepositoryTestSuite) TearDownTest() {
+	suite.db.Exec("DELETE FROM user_import_rows")
+	suite.db.Exec("DELETE FROM user_imports")
+	suite.db.Exec("DELETE FROM users")
+	suite.db.Exec("DELETE FROM user_revisions")
+}
+
+func (suite *UserImportRepositoryTestSuite) TestApplyRows() {
+	job := &models.UserImport{ID: "job", Format: models.ImportFormatCSV, Mode: models.ImportModeUpsert, Status: models.ImportStatusRunning, TotalRows: 4, Processed: 1, Failed: 1}
+	rows := []*models.UserImportRow{
+		{ImportID: "job", Line: 4, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "carol@example.com"}},
+		{ImportID: "job", Line: 2, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "alice@example.com"}},
+		{ImportID: "job", Line: 3, Status: models.ImportRowFailed, Error: "email is not a valid address"},
+		{ImportID: "job", Line: 5, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "dave@example.com"}},
+	}
+	assert.NoError(suite.T(), suite.repo.Create(job, rows))
+	repo := suite.repo.WithContext(audit.WithActor(context.Background(), audit.Actor{UserID: "9"}))
+
+	existing := &models.User{Email: "carol@example.com", Username: "carol", Password: "hashedpassword"}
+	suite.db.Create(existing)
+
+	pending, err := suite.repo.PendingRows("job", 2)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), pending, 2)
+	assert.Equal(suite.T(), 2, pending[0].Line)
+	assert.Equal(suite.T(), "alice@example.com", pending[0].Record.Email)
+	assert.Equal(suite.T(), 4, pending[1].Line)
+
+	// A new, inactive user
+	pending[0].Status = models.ImportRowCreated
+	created := &models.User{Email: "alice@example.com", Username: "alice", Password: "!", Status: models.UserStatusDeactivated}
+	applied, err := repo.ApplyRow(pending[0], created)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), applied)
+	assert.NotZero(suite.T(), created.ID)
+
+	// An update to an existing one
+	pending[1].Status = models.ImportRowUpdated
+	existing.LastName = "King"
+	applied, err = repo.ApplyRow(pending[1], existing)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), applied)
+
+	var stored, updated models.User
+	suite.db.First(&stored, created.ID)
+	assert.Equal(suite.T(), models.UserStatusDeactivated, stored.Status)
+	suite.db.First(&updated, existing.ID)
+	assert.Equal(suite.T(), "King", updated.LastName)
+
+	// Both changes are in the users' history
+	var revisions []models.UserRevision
+	suite.db.Order("id").Find(&revisions)
+	assert.Len(suite.T(), revisions, 2)
+	assert.Equal(suite.T(), models.RevisionCreate, revisions[0].Operation)
+	assert.Equal(suite.T(), models.RevisionUpdate, revisions[1].Operation)
+	assert.Equal(suite.T(), "King", revisions[1].User.LastName)
+	assert.Equal(suite.T(), "9", revisions[1].ChangedBy)
+
+	pending, err = suite.repo.PendingRows("job", 2)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), pending, 1)
+	pending[0].Status, pending[0].Error = models.ImportRowFailed, "username is required to create a user"
+	applied, err = suite.repo.ApplyRow(pending[0], nil)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), applied)
+
+	got, err := suite.repo.GetByID("job")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, got.Processed)
+	assert.Equal(suite.T(), 1, got.Created)
+	assert.Equal(suite.T(), 1, got.Updated)
+	assert.Equal(suite.T(), 2, got.Failed)
+
+	failed, err := suite.repo.FailedRows("job")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), failed, 2)
+	assert.Equal(suite.T(), 3, failed[0].Line)
+	assert.Equal(suite.T(), "username is required to create a user", failed[1].Error)
+
+	var row models.UserImportRow
+	suite.db.Where("import_id = ? AND line = ?", "job", 2).First(&row)
+	assert.Equal(suite.T(), created.ID, *row.UserID)
+}
+
+func (suite *UserImportRepositoryTestSuite) TestApplyRowVersionConflict() {
+	job := &models.UserImport{ID: "job", Format: models.ImportFormatCSV, Mode: models.ImportModeUpsert, Status: models.ImportStatusRunning, TotalRows: 1}
+	rows := []*models.UserImportRow{
+		{ImportID: "job", Line: 2, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "carol@example.com"}},
+	}
+	assert.NoError(suite.T(), suite.repo.Create(job, rows))
+	existing := &models.User{Email: "carol@example.com", Username: "carol", Password: "hashedpassword", LastName: "Queen"}
+	suite.db.Create(existing)
+
+	// Someone else edits the user after the import read it
+	suite.db.Model(&models.User{}).Where("id = ?", existing.ID).Updates(map[string]interface{}{"last_name": "Edited", "version": existing.Version + 1})
+
+	pending, err := suite.repo.PendingRows("job", 1)
+	assert.NoError(suite.T(), err)
+	pending[0].Status = models.ImportRowUpdated
+	existing.LastName = "King"
+	applied, err := suite.repo.ApplyRow(pending[0], existing)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), applied)
+	assert.Equal(suite.T(), uint(1), existing.Version)
+
+	var stored models.User
+	suite.db.First(&stored, existing.ID)
+	assert.Equal(suite.T(), "Edited", stored.LastName)
+
+	// Nothing about the row is recorded
+	got, err := suite.repo.GetByID("job")
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), got.Processed)
+	var revisions int64
+	suite.db.Model(&models.UserRevision{}).Count(&revisions)
+	assert.Zero(suite.T(), revisions)
+}
+
+func (suite *UserImportRepositoryTestSuite) TestEraseUser() {
+	userID := uint(7)
+	job := &models.UserImport{ID: "job", Format: models.ImportFormatCSV, Mode: models.ImportModeCreate, Status: models.ImportStatusCompleted}
+	rows := []*models.UserImportRow{
+		{ImportID: "job", Line: 2, Status: models.ImportRowCreated, UserID: &userID, Record: models.ImportRecord{Email: "alice@example.com", Username: "alice"}},
+		{ImportID: "job", Line: 3, Status: models.ImportRowFailed, Error: "username is taken", Record: models.ImportRecord{Email: "alice@example.com", Username: "alice2"}},
+		{ImportID: "job", Line: 4, Status: models.ImportRowCreated, Record: models.ImportRecord{Email: "alice_x@example.com", Username: "other"}},
+	}
+	assert.NoError(suite.T(), suite.repo.Create(job, rows))
+
+	erased, err := suite.repo.EraseUser(userID, "alice@example.com")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), erased)
+
+	var stored []models.UserImportRow
+	suite.db.Where("import_id = ?", "job").Order("line").Find(&stored)
+	assert.Empty(suite.T(), stored[0].Record.Email)
+	assert.Empty(suite.T(), stored[1].Record.Username)
+	assert.Empty(suite.T(), stored[1].Error)
+	// The underscore is not a wildcard
+	assert.Equal(suite.T(), "other", stored[2].Record.Username)
+}
+
+func (suite *UserImportRepositoryTestSuite) TestGetByIDNotFound() {
+	job, err := suite.repo.GetByID("missing")
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), job)
+}
+
+func TestUserImportRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(UserImportRepositoryTestSuite))
+}
@@ -1,20 +1,45 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/witslab-sahil/fiber-boilerplate/internal/audit"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
 	Create(user *models.User) error
-	GetAll(page, pageSize int) ([]*models.User, int64, error)
+	GetAll(query *models.UserListQuery) ([]*models.User, int64, error)
+	GetPage(query *models.UserListQuery) ([]*models.User, error)
+	Count(query *models.UserListQuery) (int64, error)
+	Stream(query *models.UserListQuery, fn func(*models.User) error) error
 	GetByID(id uint) (*models.User, error)
+	GetByIDs(ids []uint) ([]*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	GetByUsername(username string) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
+	GetByIDUnscoped(id uint) (*models.User, error)
+	Update(user *models.User) (bool, error)
+	Delete(user *models.User) (bool, error)
+	Restore(id uint) error
+	Purge(id uint) error
+	Anonymize(id uint) (int64, error)
+	Transaction(fn func(repo UserRepository) error) error
+	CreateStatusTransition(transition *models.UserStatusTransition) error
+	ListStatusTransitions(userID uint) ([]*models.UserStatusTransition, error)
+	EraseStatusTransitions(userID uint) (int64, error)
+	ListRevisions(userID uint) ([]*models.UserRevision, error)
+	GetRevisionAt(userID uint, at time.Time) (*models.UserRevision, error)
+	EraseRevisions(userID uint) (int64, error)
+	WithContext(ctx context.Context) UserRepository
 }
 
 type userRepository struct {
@@ -27,25 +52,189 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	}
 }
 
+// Create inserts user and records its first revision.
 func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return createUserRevision(tx, user, models.RevisionCreate)
+	})
 }
 
-func (r *userRepository) GetAll(page, pageSize int) ([]*models.User, int64, error) {
+func (r *userRepository) GetAll(query *models.UserListQuery) ([]*models.User, int64, error) {
 	var users []*models.User
 	var total int64
 
-	offset := (page - 1) * pageSize
+	offset := (query.Page - 1) * query.PageSize
 
-	err := r.db.Model(&models.User{}).Count(&total).Error
+	db, err := applyRowFilter(r.db.Model(&models.User{}), query.RowFilter)
 	if err != nil {
 		return nil, 0, err
 	}
+	db = applyUserFilters(db, query)
 
-	err = r.db.Offset(offset).Limit(pageSize).Find(&users).Error
+	err = db.Session(&gorm.Session{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = applyUserOrder(db, query).Offset(offset).Limit(query.PageSize).Find(&users).Error
 	return users, total, err
 }
 
+// GetPage returns up to query.Limit+1 users past query.Cursor in
+// (created_at, id) order, so the caller can tell whether another page
+// follows. Backward cursors return the rows before the cursor, nearest first.
+func (r *userRepository) GetPage(query *models.UserListQuery) ([]*models.User, error) {
+	var users []*models.User
+
+	db, err := applyRowFilter(r.db.Model(&models.User{}), query.RowFilter)
+	if err != nil {
+		return nil, err
+	}
+	db = applyUserFilters(db, query)
+
+	// Walking backward through an ascending listing is a descending scan,
+	// and vice versa
+	desc := query.Desc
+	if query.Cursor != nil && query.Cursor.Backward {
+		desc = !desc
+	}
+
+	if query.Cursor != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		db = db.Where(
+			fmt.Sprintf("(created_at %s @t OR (created_at = @t AND id %s @id))", op, op),
+			sql.Named("t", query.Cursor.CreatedAt),
+			sql.Named("id", query.Cursor.ID),
+		)
+	}
+
+	err = db.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}, Desc: desc}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc}).
+		Limit(query.Limit + 1).
+		Find(&users).Error
+	return users, err
+}
+
+// Count returns the number of users matching query's filters.
+func (r *userRepository) Count(query *models.UserListQuery) (int64, error) {
+	var total int64
+
+	db, err := applyRowFilter(r.db.Model(&models.User{}), query.RowFilter)
+	if err != nil {
+		return 0, err
+	}
+	err = applyUserFilters(db, query).Count(&total).Error
+	return total, err
+}
+
+// Stream calls fn for every user matching query's filters, in listing order,
+// reading them through a database cursor rather than loading them all.
+// Pagination is ignored. It stops at the first error fn returns.
+func (r *userRepository) Stream(query *models.UserListQuery, fn func(*models.User) error) error {
+	db, err := applyRowFilter(r.db.Model(&models.User{}), query.RowFilter)
+	if err != nil {
+		return err
+	}
+
+	rows, err := applyUserOrder(applyUserFilters(db, query), query).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := r.db.ScanRows(rows, &user); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func applyUserFilters(db *gorm.DB, query *models.UserListQuery) *gorm.DB {
+	switch query.Deleted {
+	case models.DeletedInclude:
+		db = db.Unscoped()
+	case models.DeletedOnly:
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if len(query.IDs) > 0 {
+		db = db.Where("id IN ?", query.IDs)
+	}
+	if query.IsActive != nil {
+		if *query.IsActive {
+			db = db.Where("status = ?", models.UserStatusActive)
+		} else {
+			db = db.Where("status <> ?", models.UserStatusActive)
+		}
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Role != "" {
+		db = db.Where("? = ANY(roles)", query.Role)
+	}
+	if query.CreatedAfter != nil {
+		db = db.Where("created_at >= ?", *query.CreatedAfter)
+	}
+	if query.CreatedBefore != nil {
+		db = db.Where("created_at < ?", *query.CreatedBefore)
+	}
+	if query.EmailDomain != "" {
+		db = db.Where(`LOWER(email) LIKE ? ESCAPE '\'`, "%@"+escapeLike(strings.ToLower(query.EmailDomain)))
+	}
+	if len(query.Attributes) > 0 {
+		// Containment, so the GIN index on attributes is used
+		filter, _ := json.Marshal(query.Attributes)
+		db = db.Where("attributes @> ?::jsonb", string(filter))
+	}
+	if query.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(query.Search)) + "%"
+		db = db.Where(
+			`LOWER(username) LIKE @q ESCAPE '\' OR LOWER(email) LIKE @q ESCAPE '\' OR LOWER(first_name) LIKE @q ESCAPE '\' OR LOWER(last_name) LIKE @q ESCAPE '\'`,
+			sql.Named("q", pattern),
+		)
+	}
+	return db
+}
+
+// applyUserOrder sorts by the requested fields, or by search relevance when
+// searching, and always ends with id so that pages are stable.
+func applyUserOrder(db *gorm.DB, query *models.UserListQuery) *gorm.DB {
+	if len(query.Sort) == 0 && query.Search != "" {
+		prefix := escapeLike(strings.ToLower(query.Search)) + "%"
+		return db.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:  `CASE WHEN LOWER(username) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\' OR LOWER(first_name) LIKE ? ESCAPE '\' OR LOWER(last_name) LIKE ? ESCAPE '\' THEN 0 ELSE 1 END, id`,
+			Vars: []interface{}{prefix, prefix, prefix, prefix},
+		}})
+	}
+
+	for _, field := range query.Sort {
+		// Columns are checked against models.UserSortColumns by the caller;
+		// re-check here so an unchecked value can never reach the SQL.
+		if !models.UserSortColumns[field.Column] {
+			continue
+		}
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: field.Column}, Desc: field.Desc})
+	}
+	return db.Order("id")
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func (r *userRepository) GetByID(id uint) (*models.User, error) {
 	var user models.User
 	err := r.db.First(&user, id).Error
@@ -58,6 +247,17 @@ func (r *userRepository) GetByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// GetByIDs returns the users with the given IDs, in no particular order.
+// IDs with no user, or a soft-deleted one, are left out.
+func (r *userRepository) GetByIDs(ids []uint) ([]*models.User, error) {
+	var users []*models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("email = ?", email).First(&user).Error
@@ -82,10 +282,185 @@ func (r *userRepository) GetByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+// GetByIDUnscoped returns the user with id whether or not it is
+// soft-deleted.
+func (r *userRepository) GetByIDUnscoped(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.Unscoped().First(&user, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Update saves user and bumps its version, but only if the stored row still
+// has the version user was read at, and reports whether it did. It stops
+// concurrent writers from silently overwriting each other.
+func (r *userRepository) Update(user *models.User) (bool, error) {
+	version := user.Version
+	user.Version++
+	saved := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(user).Where("version = ?", version).Select("*").Omit("created_at").Updates(user)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		saved = true
+		return createUserRevision(tx, user, models.RevisionUpdate)
+	})
+	if err != nil || !saved {
+		user.Version = version
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete soft-deletes user if the stored row still has the version user was
+// read at, and reports whether it did.
+func (r *userRepository) Delete(user *models.User) (bool, error) {
+	deleted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("version = ?", user.Version).Delete(&models.User{}, user.ID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+		return createStoredUserRevision(tx, user.ID, models.RevisionDelete)
+	})
+	return deleted && err == nil, err
+}
+
+// Restore undoes a soft delete.
+func (r *userRepository) Restore(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return createStoredUserRevision(tx, id, models.RevisionRestore)
+	})
+}
+
+// Purge removes the user's row, history, status changes and group
+// memberships for good, whether or not it is soft-deleted.
+func (r *userRepository) Purge(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserStatusTransition{}).Error; err != nil {
+			return err
+		}
+		err := tx.Where("member_type = ? AND member_id = ?", models.GroupMemberUser, id).Delete(&models.GroupMember{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.User{}, id).Error
+	})
+}
+
+// Anonymize replaces the user's personal data with placeholders and
+// soft-deletes the row. The row itself is kept so that references to the
+// user ID stay valid.
+func (r *userRepository) Anonymize(id uint) (int64, error) {
+	placeholder := fmt.Sprintf("erased-%d", id)
+	result := r.db.Unscoped().Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":           placeholder + "@erased.invalid",
+		"username":        placeholder,
+		"password":        utils.UnusablePassword,
+		"first_name":      "",
+		"last_name":       "",
+		"roles":           nil,
+		"status":          models.UserStatusDeactivated,
+		"status_reason":   "",
+		"suspended_until": nil,
+		"attributes":      nil,
+		"version":         gorm.Expr("version + 1"),
+		"deleted_at":      gorm.Expr("COALESCE(deleted_at, ?)", time.Now()),
+	})
+	return result.RowsAffected, result.Error
+}
+
+// WithContext returns a repository whose queries run with ctx and whose
+// revisions are recorded against the audit.Actor it carries.
+func (r *userRepository) WithContext(ctx context.Context) UserRepository {
+	return &userRepository{db: r.db.WithContext(ctx)}
+}
+
+// Transaction calls fn with a repository whose changes are committed if fn
+// returns nil and rolled back otherwise. Transactions started within fn are
+// nested as savepoints.
+func (r *userRepository) Transaction(fn func(repo UserRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&userRepository{db: tx})
+	})
+}
+
+// CreateStatusTransition records a change of a user's status.
+func (r *userRepository) CreateStatusTransition(transition *models.UserStatusTransition) error {
+	return r.db.Create(transition).Error
+}
+
+// ListStatusTransitions returns the user's status changes, oldest first.
+func (r *userRepository) ListStatusTransitions(userID uint) ([]*models.UserStatusTransition, error) {
+	var transitions []*models.UserStatusTransition
+	err := r.db.Where("user_id = ?", userID).Order("created_at, id").Find(&transitions).Error
+	return transitions, err
+}
+
+// EraseStatusTransitions removes the user's status changes, reasons
+// included, for erasure requests.
+func (r *userRepository) EraseStatusTransitions(userID uint) (int64, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.UserStatusTransition{})
+	return result.RowsAffected, result.Error
+}
+
+// ListRevisions returns the user's revisions, oldest first.
+func (r *userRepository) ListRevisions(userID uint) ([]*models.UserRevision, error) {
+	var revisions []*models.UserRevision
+	err := r.db.Where("user_id = ?", userID).Order("created_at, id").Find(&revisions).Error
+	return revisions, err
+}
+
+// GetRevisionAt returns the user's latest revision made at or before at, or
+// nil if there is none.
+func (r *userRepository) GetRevisionAt(userID uint, at time.Time) (*models.UserRevision, error) {
+	var revision models.UserRevision
+	err := r.db.Where("user_id = ? AND created_at <= ?", userID, at).Order("created_at DESC, id DESC").First(&revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// EraseRevisions removes the user's history, for erasure requests.
+func (r *userRepository) EraseRevisions(userID uint) (int64, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.UserRevision{})
+	return result.RowsAffected, result.Error
+}
+
+// createUserRevision records user as it stands after operation, made by the
+// audit.Actor carried by tx's context.
+func createUserRevision(tx *gorm.DB, user *models.User, operation string) error {
+	actor := audit.ActorFrom(tx.Statement.Context)
+	return tx.Create(models.NewUserRevision(user, operation, actor.UserID, actor.RequestID)).Error
 }
 
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
-}
\ No newline at end of file
+// createStoredUserRevision records the stored row of user id after
+// operation, for changes made without loading the user.
+func createStoredUserRevision(tx *gorm.DB, id uint, operation string) error {
+	var user models.User
+	if err := tx.Unscoped().First(&user, id).Error; err != nil {
+		return err
+	}
+	return createUserRevision(tx, &user, operation)
+}
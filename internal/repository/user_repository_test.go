@@ -1,10 +1,15 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/audit"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -20,7 +25,7 @@ func (suite *UserRepositoryTestSuite) SetupSuite() {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(suite.T(), err)
 
-	err = db.AutoMigrate(&models.User{})
+	err = db.AutoMigrate(&models.User{}, &models.UserStatusTransition{}, &models.UserRevision{}, &models.GroupMember{})
 	assert.NoError(suite.T(), err)
 
 	suite.db = db
@@ -29,6 +34,8 @@ func (suite *UserRepositoryTestSuite) SetupSuite() {
 
 func (suite *UserRepositoryTestSuite) TearDownTest() {
 	suite.db.Exec("DELETE FROM users")
+	suite.db.Exec("DELETE FROM user_revisions")
+	suite.db.Exec("DELETE FROM group_members")
 }
 
 func (suite *UserRepositoryTestSuite) TestCreate() {
@@ -38,7 +45,7 @@ func (suite *UserRepositoryTestSuite) TestCreate() {
 		Password:  "hashedpassword",
 		FirstName: "Test",
 		LastName:  "User",
-		IsActive:  true,
+		Status:    models.UserStatusActive,
 	}
 
 	err := suite.repo.Create(user)
@@ -64,6 +71,23 @@ func (suite *UserRepositoryTestSuite) TestGetByID() {
 	assert.Nil(suite.T(), notFound)
 }
 
+func (suite *UserRepositoryTestSuite) TestGetByIDs() {
+	alice := &models.User{Email: "alice@example.com", Username: "alice", Password: "hashedpassword"}
+	bob := &models.User{Email: "bob@example.com", Username: "bob", Password: "hashedpassword"}
+	suite.db.Create(alice)
+	suite.db.Create(bob)
+	suite.db.Delete(bob)
+
+	found, err := suite.repo.GetByIDs([]uint{alice.ID, bob.ID, 99999})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), found, 1)
+	assert.Equal(suite.T(), alice.ID, found[0].ID)
+
+	found, err = suite.repo.GetByIDs(nil)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), found)
+}
+
 func (suite *UserRepositoryTestSuite) TestGetByEmail() {
 	user := &models.User{
 		Email:    "test@example.com",
@@ -110,17 +134,224 @@ func (suite *UserRepositoryTestSuite) TestGetAll() {
 		suite.db.Create(user)
 	}
 
-	users, total, err := suite.repo.GetAll(1, 3)
+	users, total, err := suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 3})
 	assert.NoError(suite.T(), err)
 	assert.Len(suite.T(), users, 3)
 	assert.Equal(suite.T(), int64(5), total)
 
-	users, total, err = suite.repo.GetAll(2, 3)
+	users, total, err = suite.repo.GetAll(&models.UserListQuery{Page: 2, PageSize: 3})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), users, 2)
+	assert.Equal(suite.T(), int64(5), total)
+}
+
+func (suite *UserRepositoryTestSuite) TestGetAllWithRowFilter() {
+	var ids []uint
+	for i := 0; i < 5; i++ {
+		user := &models.User{
+			Email:    "test" + string(rune('a'+i)) + "@example.com",
+			Username: "testuser" + string(rune('a'+i)),
+			Password: "hashedpassword",
+		}
+		suite.db.Create(user)
+		ids = append(ids, user.ID)
+	}
+
+	own := &models.RowFilter{Clauses: [][]models.RowCondition{
+		{{Column: "id", Operator: "=", Value: ids[1]}},
+	}}
+	users, total, err := suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 3, RowFilter: own})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), users, 1)
+	assert.Equal(suite.T(), ids[1], users[0].ID)
+	assert.Equal(suite.T(), int64(1), total)
+
+	either := &models.RowFilter{Clauses: [][]models.RowCondition{
+		{{Column: "id", Operator: "=", Value: ids[0]}},
+		{{Column: "id", Operator: ">=", Value: ids[3]}},
+	}}
+	users, total, err = suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 2, RowFilter: either})
 	assert.NoError(suite.T(), err)
 	assert.Len(suite.T(), users, 2)
+	assert.Equal(suite.T(), int64(3), total)
+
+	users, total, err = suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, RowFilter: &models.RowFilter{}})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), users)
+	assert.Equal(suite.T(), int64(0), total)
+
+	users, total, err = suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, RowFilter: &models.RowFilter{Unrestricted: true}})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), users, 5)
 	assert.Equal(suite.T(), int64(5), total)
 }
 
+func (suite *UserRepositoryTestSuite) TestGetAllFiltersSortAndSearch() {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fixtures := []*models.User{
+		{Email: "alice@example.com", Username: "alice", FirstName: "Alice", LastName: "Majors", Status: models.UserStatusActive, CreatedAt: base},
+		{Email: "bob@corp.io", Username: "bob", FirstName: "Bob", LastName: "Jones", Status: models.UserStatusActive, CreatedAt: base.AddDate(0, 1, 0)},
+		{Email: "john@example.com", Username: "jdoe", FirstName: "John", LastName: "Doe", Status: models.UserStatusActive, CreatedAt: base.AddDate(0, 2, 0)},
+		{Email: "carol@example.com", Username: "carol_1", FirstName: "Carol", LastName: "King", Status: models.UserStatusActive, CreatedAt: base.AddDate(0, 3, 0)},
+	}
+	for _, user := range fixtures {
+		user.Password = "hashedpassword"
+		suite.db.Create(user)
+	}
+	suite.db.Model(fixtures[1]).Update("status", models.UserStatusDeactivated)
+
+	usernames := func(users []*models.User) []string {
+		names := make([]string, len(users))
+		for i, user := range users {
+			names[i] = user.Username
+		}
+		return names
+	}
+
+	inactive := false
+	users, total, err := suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, IsActive: &inactive})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), total)
+	assert.Equal(suite.T(), []string{"bob"}, usernames(users))
+
+	users, _, err = suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, Status: models.UserStatusDeactivated})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"bob"}, usernames(users))
+
+	after, before := base.AddDate(0, 1, 0), base.AddDate(0, 3, 0)
+	users, _, err = suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, CreatedAfter: &after, CreatedBefore: &before})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"bob", "jdoe"}, usernames(users))
+
+	users, _, err = suite.repo.GetAll(&models.UserListQuery{
+		Page: 1, PageSize: 10, EmailDomain: "EXAMPLE.com",
+		Sort: []models.SortField{{Column: "created_at", Desc: true}},
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"carol_1", "jdoe", "alice"}, usernames(users))
+
+	// Prefix matches ("Jones", "john@") are listed before the substring
+	// match in "Majors"
+	users, total, err = suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, Search: "JO"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), total)
+	assert.Equal(suite.T(), []string{"bob", "jdoe", "alice"}, usernames(users))
+
+	users, _, err = suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, Search: "ajo"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"alice"}, usernames(users))
+
+	// Wildcards in the search term are matched literally
+	users, _, err = suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, Search: "_"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"carol_1"}, usernames(users))
+}
+
+func (suite *UserRepositoryTestSuite) TestStream() {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"alice", "bob", "carol", "dave"} {
+		suite.db.Create(&models.User{
+			Email:     name + "@example.com",
+			Username:  name,
+			Password:  "hashedpassword",
+			Status:    models.UserStatusActive,
+			CreatedAt: base.AddDate(0, i, 0),
+		})
+	}
+
+	var streamed []string
+	after := base.AddDate(0, 1, 0)
+	err := suite.repo.Stream(&models.UserListQuery{
+		Page: 1, PageSize: 1, CreatedAfter: &after,
+		Sort: []models.SortField{{Column: "created_at", Desc: true}},
+	}, func(user *models.User) error {
+		streamed = append(streamed, user.Username)
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"dave", "carol", "bob"}, streamed)
+
+	// An error from fn stops the stream
+	stop := fmt.Errorf("stop")
+	count := 0
+	err = suite.repo.Stream(&models.UserListQuery{}, func(user *models.User) error {
+		count++
+		return stop
+	})
+	assert.ErrorIs(suite.T(), err, stop)
+	assert.Equal(suite.T(), 1, count)
+}
+
+func (suite *UserRepositoryTestSuite) TestGetPageKeyset() {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Pairs of users share a timestamp so that pages must break ties on id
+	created := make([]*models.User, 7)
+	for i := range created {
+		created[i] = &models.User{
+			Email:     fmt.Sprintf("user%d@example.com", i),
+			Username:  fmt.Sprintf("user%d", i),
+			Password:  "hashedpassword",
+			CreatedAt: base.Add(time.Duration(i/2) * time.Hour),
+		}
+		suite.db.Create(created[i])
+	}
+	// id returns the ID of the nth fixture, counting from 1
+	id := func(n int) uint { return created[n-1].ID }
+	expect := func(ns ...int) []uint {
+		out := make([]uint, len(ns))
+		for i, n := range ns {
+			out[i] = id(n)
+		}
+		return out
+	}
+
+	ids := func(users []*models.User) []uint {
+		out := make([]uint, len(users))
+		for i, user := range users {
+			out[i] = user.ID
+		}
+		return out
+	}
+	after := func(user *models.User, desc, backward bool) *models.Cursor {
+		return &models.Cursor{CreatedAt: user.CreatedAt, ID: user.ID, Desc: desc, Backward: backward}
+	}
+
+	// Walk forward three at a time; each page fetches one extra row
+	var seen []uint
+	var cursor *models.Cursor
+	for {
+		users, err := suite.repo.GetPage(&models.UserListQuery{Limit: 3, Cursor: cursor})
+		assert.NoError(suite.T(), err)
+		more := len(users) > 3
+		if more {
+			users = users[:3]
+		}
+		seen = append(seen, ids(users)...)
+		if !more {
+			break
+		}
+		cursor = after(users[len(users)-1], false, false)
+	}
+	assert.Equal(suite.T(), expect(1, 2, 3, 4, 5, 6, 7), seen)
+
+	// Backward from the sixth user scans descending from the cursor
+	users, err := suite.repo.GetPage(&models.UserListQuery{Limit: 3, Cursor: after(created[5], false, true)})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), expect(5, 4, 3, 2), ids(users))
+
+	users, err = suite.repo.GetPage(&models.UserListQuery{Limit: 2, Desc: true})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), expect(7, 6, 5), ids(users))
+
+	users, err = suite.repo.GetPage(&models.UserListQuery{Limit: 2, Desc: true, Cursor: after(users[1], true, false)})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), expect(5, 4, 3), ids(users))
+
+	total, err := suite.repo.Count(&models.UserListQuery{Limit: 2})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(7), total)
+}
+
 func (suite *UserRepositoryTestSuite) TestUpdate() {
 	user := &models.User{
 		Email:    "test@example.com",
@@ -131,13 +362,41 @@ func (suite *UserRepositoryTestSuite) TestUpdate() {
 
 	user.FirstName = "Updated"
 	user.LastName = "Name"
-	err := suite.repo.Update(user)
+	saved, err := suite.repo.Update(user)
 	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), saved)
 
 	updated, err := suite.repo.GetByID(user.ID)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), "Updated", updated.FirstName)
 	assert.Equal(suite.T(), "Name", updated.LastName)
+	assert.Equal(suite.T(), uint(2), updated.Version)
+}
+
+func (suite *UserRepositoryTestSuite) TestUpdateStaleVersion() {
+	user := &models.User{Email: "test@example.com", Username: "testuser", Password: "hashedpassword"}
+	suite.db.Create(user)
+
+	first, _ := suite.repo.GetByID(user.ID)
+	second, _ := suite.repo.GetByID(user.ID)
+
+	first.FirstName = "First"
+	saved, err := suite.repo.Update(first)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), saved)
+
+	second.FirstName = "Second"
+	saved, err = suite.repo.Update(second)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), saved)
+	assert.Equal(suite.T(), uint(1), second.Version)
+
+	deleted, err := suite.repo.Delete(second)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), deleted)
+
+	stored, _ := suite.repo.GetByID(user.ID)
+	assert.Equal(suite.T(), "First", stored.FirstName)
 }
 
 func (suite *UserRepositoryTestSuite) TestDelete() {
@@ -148,14 +407,235 @@ func (suite *UserRepositoryTestSuite) TestDelete() {
 	}
 	suite.db.Create(user)
 
-	err := suite.repo.Delete(user.ID)
+	deleted, err := suite.repo.Delete(user)
 	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), deleted)
 
 	var count int64
 	suite.db.Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
 	assert.Equal(suite.T(), int64(0), count)
 }
 
+func (suite *UserRepositoryTestSuite) TestSoftDeleteLifecycle() {
+	live := &models.User{Email: "live@example.com", Username: "live", Password: "hashedpassword"}
+	gone := &models.User{Email: "gone@example.com", Username: "gone", Password: "hashedpassword"}
+	suite.db.Create(live)
+	suite.db.Create(gone)
+	_, err := suite.repo.Delete(gone)
+	assert.NoError(suite.T(), err)
+
+	usernames := func(deleted string) []string {
+		users, _, err := suite.repo.GetAll(&models.UserListQuery{Page: 1, PageSize: 10, Deleted: deleted, Sort: []models.SortField{{Column: "username"}}})
+		assert.NoError(suite.T(), err)
+		var names []string
+		for _, user := range users {
+			names = append(names, user.Username)
+		}
+		return names
+	}
+	assert.Equal(suite.T(), []string{"live"}, usernames(""))
+	assert.Equal(suite.T(), []string{"gone"}, usernames(models.DeletedOnly))
+	assert.Equal(suite.T(), []string{"gone", "live"}, usernames(models.DeletedInclude))
+
+	found, err := suite.repo.GetByIDUnscoped(gone.ID)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), found.DeletedAt.Valid)
+
+	// Uniqueness only applies among live users
+	again := &models.User{Email: "gone@example.com", Username: "gone", Password: "hashedpassword"}
+	assert.NoError(suite.T(), suite.repo.Create(again))
+	assert.Error(suite.T(), suite.repo.Create(&models.User{Email: "live@example.com", Username: "other", Password: "hashedpassword"}))
+
+	assert.NoError(suite.T(), suite.repo.Purge(again.ID))
+	assert.NoError(suite.T(), suite.repo.Restore(gone.ID))
+	restored, err := suite.repo.GetByID(gone.ID)
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), restored)
+
+	suite.db.Create(&models.GroupMember{GroupID: 1, MemberType: models.GroupMemberUser, MemberID: gone.ID})
+	suite.db.Create(&models.UserStatusTransition{UserID: gone.ID, From: models.UserStatusActive, To: models.UserStatusLocked, Reason: "spam", ChangedBy: "1"})
+	assert.NoError(suite.T(), suite.repo.Purge(gone.ID))
+	found, err = suite.repo.GetByIDUnscoped(gone.ID)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), found)
+	var memberships int64
+	suite.db.Model(&models.GroupMember{}).Where("member_id = ?", gone.ID).Count(&memberships)
+	assert.Zero(suite.T(), memberships)
+	transitions, err := suite.repo.ListStatusTransitions(gone.ID)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), transitions)
+}
+
+func (suite *UserRepositoryTestSuite) TestAnonymize() {
+	user := &models.User{Email: "alice@example.com", Username: "alice", Password: "hashedpassword", FirstName: "Alice", Status: models.UserStatusActive}
+	suite.db.Create(user)
+
+	changed, err := suite.repo.Anonymize(user.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), changed)
+
+	anonymized, err := suite.repo.GetByIDUnscoped(user.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), fmt.Sprintf("erased-%d@erased.invalid", user.ID), anonymized.Email)
+	assert.Equal(suite.T(), fmt.Sprintf("erased-%d", user.ID), anonymized.Username)
+	assert.Empty(suite.T(), anonymized.FirstName)
+	assert.Equal(suite.T(), models.UserStatusDeactivated, anonymized.Status)
+	assert.True(suite.T(), anonymized.DeletedAt.Valid)
+
+	// The original email can be registered again
+	assert.NoError(suite.T(), suite.repo.Create(&models.User{Email: "alice@example.com", Username: "alice", Password: "hashedpassword"}))
+}
+
+func (suite *UserRepositoryTestSuite) TestGetAllByIDs() {
+	for _, name := range []string{"alice", "bob", "carol"} {
+		suite.db.Create(&models.User{Email: name + "@example.com", Username: name, Password: "hashedpassword"})
+	}
+	alice, _ := suite.repo.GetByUsername("alice")
+	carol, _ := suite.repo.GetByUsername("carol")
+
+	var names []string
+	err := suite.repo.Stream(&models.UserListQuery{IDs: []uint{carol.ID, alice.ID, 99999}}, func(user *models.User) error {
+		names = append(names, user.Username)
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"alice", "carol"}, names)
+}
+
+func (suite *UserRepositoryTestSuite) TestTransaction() {
+	user := &models.User{Email: "alice@example.com", Username: "alice", Password: "hashedpassword", Status: models.UserStatusActive}
+	suite.db.Create(user)
+
+	// A failed nested transaction only undoes its own changes
+	err := suite.repo.Transaction(func(repo UserRepository) error {
+		user.FirstName = "Alice"
+		if _, err := repo.Update(user); err != nil {
+			return err
+		}
+		nestedErr := repo.Transaction(func(repo UserRepository) error {
+			user.Status = models.UserStatusDeactivated
+			if _, err := repo.Update(user); err != nil {
+				return err
+			}
+			return errors.New("rolled back")
+		})
+		assert.Error(suite.T(), nestedErr)
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+
+	found, _ := suite.repo.GetByID(user.ID)
+	assert.Equal(suite.T(), "Alice", found.FirstName)
+	assert.Equal(suite.T(), models.UserStatusActive, found.Status)
+
+	err = suite.repo.Transaction(func(repo UserRepository) error {
+		found.LastName = "Smith"
+		if _, err := repo.Update(found); err != nil {
+			return err
+		}
+		return errors.New("rolled back")
+	})
+	assert.Error(suite.T(), err)
+
+	found, _ = suite.repo.GetByID(user.ID)
+	assert.Empty(suite.T(), found.LastName)
+}
+
 func TestUserRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(UserRepositoryTestSuite))
-}
\ No newline at end of file
+}
+
+func (suite *UserRepositoryTestSuite) TestStatusTransitions() {
+	user := &models.User{Email: "alice@example.com", Username: "alice", Password: "hashedpassword"}
+	suite.db.Create(user)
+
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(suite.T(), suite.repo.CreateStatusTransition(&models.UserStatusTransition{
+		UserID: user.ID, From: models.UserStatusActive, To: models.UserStatusSuspended, Reason: "spam", Until: &until, ChangedBy: "1",
+	}))
+	assert.NoError(suite.T(), suite.repo.CreateStatusTransition(&models.UserStatusTransition{
+		UserID: user.ID, From: models.UserStatusSuspended, To: models.UserStatusActive, ChangedBy: models.StatusChangedBySystem,
+	}))
+	assert.NoError(suite.T(), suite.repo.CreateStatusTransition(&models.UserStatusTransition{
+		UserID: user.ID + 1, From: models.UserStatusActive, To: models.UserStatusLocked, ChangedBy: "1",
+	}))
+
+	transitions, err := suite.repo.ListStatusTransitions(user.ID)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), transitions, 2)
+	assert.Equal(suite.T(), models.UserStatusSuspended, transitions[0].To)
+	assert.Equal(suite.T(), "spam", transitions[0].Reason)
+	assert.True(suite.T(), until.Equal(*transitions[0].Until))
+	assert.Equal(suite.T(), models.StatusChangedBySystem, transitions[1].ChangedBy)
+
+	erased, err := suite.repo.EraseStatusTransitions(user.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), erased)
+	transitions, err = suite.repo.ListStatusTransitions(user.ID)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), transitions)
+	transitions, err = suite.repo.ListStatusTransitions(user.ID + 1)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), transitions, 1)
+}
+
+func (suite *UserRepositoryTestSuite) TestRevisions() {
+	ctx := audit.WithActor(context.Background(), audit.Actor{UserID: "9", RequestID: "req-1"})
+	repo := suite.repo.WithContext(ctx)
+
+	user := &models.User{Email: "alice@example.com", Username: "alice", Password: "hashedpassword", Status: models.UserStatusActive}
+	assert.NoError(suite.T(), repo.Create(user))
+	user.FirstName = "Alice"
+	saved, err := repo.Update(user)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), saved)
+
+	// Stale updates record nothing
+	stale := *user
+	stale.Version = 1
+	saved, err = repo.Update(&stale)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), saved)
+
+	deleted, err := repo.Delete(user)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), deleted)
+	// Changes made without an actor are recorded anonymously
+	assert.NoError(suite.T(), suite.repo.Restore(user.ID))
+
+	revisions, err := suite.repo.ListRevisions(user.ID)
+	assert.NoError(suite.T(), err)
+	var operations []string
+	for _, revision := range revisions {
+		operations = append(operations, revision.Operation)
+	}
+	assert.Equal(suite.T(), []string{models.RevisionCreate, models.RevisionUpdate, models.RevisionDelete, models.RevisionRestore}, operations)
+	assert.Empty(suite.T(), revisions[0].User.FirstName)
+	assert.Equal(suite.T(), "Alice", revisions[1].User.FirstName)
+	assert.Equal(suite.T(), uint(2), revisions[1].Version)
+	assert.Equal(suite.T(), "9", revisions[1].ChangedBy)
+	assert.Equal(suite.T(), "req-1", revisions[1].RequestID)
+	assert.NotNil(suite.T(), revisions[2].User.DeletedAt)
+	assert.Nil(suite.T(), revisions[3].User.DeletedAt)
+	assert.Equal(suite.T(), uint(3), revisions[3].Version)
+	assert.Empty(suite.T(), revisions[3].ChangedBy)
+
+	// The password hash is never part of a snapshot
+	var snapshot string
+	suite.db.Raw("SELECT snapshot FROM user_revisions WHERE id = ?", revisions[0].ID).Scan(&snapshot)
+	assert.NotContains(suite.T(), snapshot, "hashedpassword")
+
+	// Point-in-time reads find the latest revision at or before the time
+	first := revisions[0].CreatedAt
+	suite.db.Model(&models.UserRevision{}).Where("user_id = ? AND id > ?", user.ID, revisions[0].ID).Update("created_at", first.Add(time.Hour))
+	revision, err := suite.repo.GetRevisionAt(user.ID, first.Add(30*time.Minute))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.RevisionCreate, revision.Operation)
+	revision, err = suite.repo.GetRevisionAt(user.ID, first.Add(-time.Second))
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), revision)
+
+	erased, err := suite.repo.EraseRevisions(user.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(4), erased)
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrDataExportNotFound    = errors.New("data export not found")
+	ErrDataExportInProgress  = errors.New("a data export is already in progress")
+	ErrDataExportNotReady    = errors.New("data export is not ready")
+	ErrDataExportExpired     = errors.New("data export link has expired")
+	ErrInvalidDownloadLink   = errors.New("invalid download link")
+	ErrDataExportUnavailable = errors.New("data exports are unavailable")
+)
+
+// DataExportSection contributes one subsystem's data about a user to a data
+// export. Collect returns a value that is written to the archive as
+// <name>.json; a nil value leaves the section out.
+type DataExportSection interface {
+	Name() string
+	Collect(ctx context.Context, user *models.User) (interface{}, error)
+}
+
+type dataExportSection struct {
+	name    string
+	collect func(ctx context.Context, user *models.User) (interface{}, error)
+}
+
+// NewDataExportSection makes a DataExportSection from a function.
+func NewDataExportSection(name string, collect func(ctx context.Context, user *models.User) (interface{}, error)) DataExportSection {
+	return &dataExportSection{name: name, collect: collect}
+}
+
+func (s *dataExportSection) Name() string { return s.name }
+
+func (s *dataExportSection) Collect(ctx context.Context, user *models.User) (interface{}, error) {
+	return s.collect(ctx, user)
+}
+
+// DataExportWorkflows assembles data exports in the background and emails
+// the user when they are ready.
+type DataExportWorkflows interface {
+	StartDataExport(ctx context.Context, export *models.DataExport, user *models.User) (string, error)
+}
+
+type DataExportService interface {
+	Request(ctx context.Context, userID uint) (*models.DataExport, error)
+	Get(ctx context.Context, id string, userID uint) (*models.DataExport, error)
+	Build(ctx context.Context, id string) (*models.DataExport, error)
+	Fail(ctx context.Context, id, reason string) error
+	DownloadURL(export *models.DataExport) string
+	Open(ctx context.Context, id string, expires int64, signature string) (*models.DataExport, io.ReadCloser, error)
+	DeleteForUser(ctx context.Context, userID uint) (int64, error)
+	RegisterSection(section DataExportSection)
+}
+
+type dataExportService struct {
+	exports    repository.DataExportRepository
+	users      repository.UserRepository
+	files      FileStore
+	workflows  DataExportWorkflows
+	baseURL    string
+	linkTTL    time.Duration
+	signingKey []byte
+	logger     logger.Logger
+	tracer     trace.Tracer
+
+	mu       sync.RWMutex
+	sections []DataExportSection
+}
+
+// NewDataExportService creates the data export service. Download links
+// point at baseURL, stay valid for linkTTL after the archive is built and
+// are signed with signingKey. files or workflows may be nil, in which case
+// exports cannot be built or started.
+func NewDataExportService(exports repository.DataExportRepository, users repository.UserRepository, files FileStore, workflows DataExportWorkflows, baseURL string, linkTTL time.Duration, signingKey string, logger logger.Logger) DataExportService {
+	return &dataExportService{
+		exports:    exports,
+		users:      users,
+		files:      files,
+		workflows:  workflows,
+		baseURL:    baseURL,
+		linkTTL:    linkTTL,
+		signingKey: []byte(signingKey),
+		logger:     logger,
+		tracer:     otel.Tracer("data-export-service"),
+	}
+}
+
+// RegisterSection adds a subsystem's section to every data export.
+func (s *dataExportService) RegisterSection(section DataExportSection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sections = append(s.sections, section)
+}
+
+// Request starts assembling a data export for the user.
+func (s *dataExportService) Request(ctx context.Context, userID uint) (*models.DataExport, error) {
+	ctx, span := s.tracer.Start(ctx, "DataExportService.Request")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(userID)))
+	if s.workflows == nil {
+		return nil, ErrDataExportUnavailable
+	}
+
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	previous, err := s.exports.ListForUser(userID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to list data exports: %w", err)
+	}
+	for _, export := range previous {
+		if export.Status == models.ExportStatusRunning {
+			return nil, ErrDataExportInProgress
+		}
+	}
+
+	export := &models.DataExport{
+		ID:     uuid.New().String(),
+		UserID: userID,
+		Status: models.ExportStatusRunning,
+	}
+	if err := s.exports.Create(export); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to store data export: %w", err)
+	}
+
+	workflowID, err := s.workflows.StartDataExport(ctx, export, user)
+	if err != nil {
+		span.RecordError(err)
+		s.Fail(ctx, export.ID, "failed to start data export workflow")
+		return nil, fmt.Errorf("failed to start data export workflow: %w", err)
+	}
+
+	export.WorkflowID = workflowID
+	if err := s.exports.Update(export.ID, map[string]interface{}{"workflow_id": workflowID}); err != nil {
+		s.logger.Errorf("Failed to record workflow of data export %s: %v", export.ID, err)
+	}
+
+	s.logger.Infof("Data export %s requested by user %d", export.ID, userID)
+	return export, nil
+}
+
+// Get returns one of the user's data exports. Exports of other users are
+// reported as not found.
+func (s *dataExportService) Get(ctx context.Context, id string, userID uint) (*models.DataExport, error) {
+	export, err := s.exports.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if export == nil || export.UserID != userID {
+		return nil, ErrDataExportNotFound
+	}
+	return export, nil
+}
+
+// Build collects every registered section into the export's archive and
+// starts the download link's lifetime. A completed export is returned as
+// is, so a retried build does nothing.
+func (s *dataExportService) Build(ctx context.Context, id string) (*models.DataExport, error) {
+	ctx, span := s.tracer.Start(ctx, "DataExportService.Build")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("data_export.id", id))
+
+	export, err := s.exports.GetByID(id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if export == nil {
+		return nil, ErrDataExportNotFound
+	}
+	if export.Status != models.ExportStatusRunning {
+		return export, nil
+	}
+	if s.files == nil {
+		return nil, ErrDataExportUnavailable
+	}
+
+	user, err := s.users.GetByID(export.UserID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	sections, err := s.writeArchive(ctx, export, user)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expires := now.Add(s.linkTTL)
+	if err := s.exports.Update(export.ID, map[string]interface{}{
+		"status":       models.ExportStatusCompleted,
+		"sections":     sections,
+		"completed_at": &now,
+		"expires_at":   &expires,
+	}); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to record data export: %w", err)
+	}
+
+	export.Status = models.ExportStatusCompleted
+	export.Sections = sections
+	export.CompletedAt = &now
+	export.ExpiresAt = &expires
+	s.logger.Infof("Data export %s built with %d sections", export.ID, len(sections))
+	return export, nil
+}
+
+func (s *dataExportService) writeArchive(ctx context.Context, export *models.DataExport, user *models.User) ([]string, error) {
+	s.mu.RLock()
+	sections := append([]DataExportSection(nil), s.sections...)
+	s.mu.RUnlock()
+
+	file, err := s.files.Create(export.FileName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data export file: %w", err)
+	}
+
+	archive := zip.NewWriter(file)
+	names := make([]string, 0, len(sections))
+	fail := func(err error) ([]string, error) {
+		archive.Close()
+		file.Close()
+		s.files.Remove(export.FileName())
+		return nil, err
+	}
+
+	for _, section := range sections {
+		data, err := section.Collect(ctx, user)
+		if err != nil {
+			return fail(fmt.Errorf("data export section %s failed: %w", section.Name(), err))
+		}
+		if data == nil {
+			continue
+		}
+		if err := writeJSONEntry(archive, section.Name()+".json", data); err != nil {
+			return fail(err)
+		}
+		names = append(names, section.Name())
+	}
+
+	manifest := models.DataExportManifest{
+		ExportID:    export.ID,
+		UserID:      user.ID,
+		GeneratedAt: time.Now().UTC(),
+		Sections:    names,
+	}
+	if err := writeJSONEntry(archive, "manifest.json", manifest); err != nil {
+		return fail(err)
+	}
+
+	if err := archive.Close(); err != nil {
+		file.Close()
+		s.files.Remove(export.FileName())
+		return nil, fmt.Errorf("failed to write data export: %w", err)
+	}
+	return names, file.Close()
+}
+
+func writeJSONEntry(archive *zip.Writer, name string, value interface{}) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// Fail records that the export could not be built.
+func (s *dataExportService) Fail(ctx context.Context, id, reason string) error {
+	now := time.Now()
+	return s.exports.Update(id, map[string]interface{}{
+		"status":       models.ExportStatusFailed,
+		"error":        reason,
+		"completed_at": &now,
+	})
+}
+
+// DownloadURL returns the signed link a completed export can be downloaded
+// from without logging in, until it expires.
+func (s *dataExportService) DownloadURL(export *models.DataExport) string {
+	if export.Status != models.ExportStatusCompleted || export.ExpiresAt == nil {
+		return ""
+	}
+	expires := export.ExpiresAt.Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(export.ID, expires))
+	return s.baseURL + "/api/v1/data-exports/" + export.ID + "/download?" + query.Encode()
+}
+
+// Open returns the archive a signed download link points at.
+func (s *dataExportService) Open(ctx context.Context, id string, expires int64, signature string) (*models.DataExport, io.ReadCloser, error) {
+	if !hmac.Equal([]byte(signature), []byte(s.sign(id, expires))) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+	if time.Now().Unix() > expires {
+		return nil, nil, ErrDataExportExpired
+	}
+
+	export, err := s.exports.GetByID(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if export == nil {
+		return nil, nil, ErrDataExportNotFound
+	}
+	if export.Status != models.ExportStatusCompleted {
+		return nil, nil, ErrDataExportNotReady
+	}
+	if s.files == nil {
+		return nil, nil, ErrDataExportUnavailable
+	}
+
+	file, err := s.files.Open(export.FileName())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open data export: %w", err)
+	}
+	return export, file, nil
+}
+
+// DeleteForUser removes every data export of the user along with its
+// archive, and returns how many there were.
+func (s *dataExportService) DeleteForUser(ctx context.Context, userID uint) (int64, error) {
+	exports, err := s.exports.ListForUser(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list data exports: %w", err)
+	}
+
+	for _, export := range exports {
+		if s.files != nil {
+			if err := s.files.Remove(export.FileName()); err != nil {
+				return 0, fmt.Errorf("failed to remove data export file: %w", err)
+			}
+		}
+		if err := s.exports.Delete(export.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete data export: %w", err)
+		}
+	}
+	return int64(len(exports)), nil
+}
+
+func (s *dataExportService) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(id + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/storage"
+)
+
+type MockDataExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockDataExportRepository) Create(export *models.DataExport) error {
+	args := m.Called(export)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) GetByID(id string) (*models.DataExport, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) Update(id string, fields map[string]interface{}) error {
+	args := m.Called(id, fields)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) ListForUser(userID uint) ([]*models.DataExport, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*models.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) Delete(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockDataExportWorkflows struct {
+	mock.Mock
+}
+
+func (m *MockDataExportWorkflows) StartDataExport(ctx context.Context, export *models.DataExport, user *models.User) (string, error) {
+	args := m.Called(ctx, export, user)
+	return args.String(0), args.Error(1)
+}
+
+func TestDataExportService_Request(t *testing.T) {
+	t.Run("Starts The Workflow", func(t *testing.T) {
+		mockExports := new(MockDataExportRepository)
+		mockUsers := new(MockUserRepository)
+		mockWorkflows := new(MockDataExportWorkflows)
+		service := NewDataExportService(mockExports, mockUsers, nil, mockWorkflows, "http://api.example.com", time.Hour, "key", new(MockLogger))
+
+		user := &models.User{ID: 1, Email: "alice@example.com"}
+		mockUsers.On("GetByID", uint(1)).Return(user, nil).Once()
+		mockExports.On("ListForUser", uint(1)).Return([]*models.DataExport{{Status: models.ExportStatusCompleted}}, nil).Once()
+		mockExports.On("Create", mock.MatchedBy(func(e *models.DataExport) bool {
+			return e.UserID == 1 && e.Status == models.ExportStatusRunning
+		})).Return(nil).Once()
+		mockWorkflows.On("StartDataExport", mock.Anything, mock.Anything, user).Return("data-export-x", nil).Once()
+		mockExports.On("Update", mock.Anything, map[string]interface{}{"workflow_id": "data-export-x"}).Return(nil).Once()
+
+		export, err := service.Request(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "data-export-x", export.WorkflowID)
+		mockExports.AssertExpectations(t)
+	})
+
+	t.Run("Already In Progress", func(t *testing.T) {
+		mockExports := new(MockDataExportRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewDataExportService(mockExports, mockUsers, nil, new(MockDataExportWorkflows), "", time.Hour, "key", new(MockLogger))
+
+		mockUsers.On("GetByID", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+		mockExports.On("ListForUser", uint(1)).Return([]*models.DataExport{{Status: models.ExportStatusRunning}}, nil).Once()
+
+		_, err := service.Request(context.Background(), 1)
+
+		assert.ErrorIs(t, err, ErrDataExportInProgress)
+	})
+
+	t.Run("Without Workflows", func(t *testing.T) {
+		service := NewDataExportService(new(MockDataExportRepository), new(MockUserRepository), nil, nil, "", time.Hour, "key", new(MockLogger))
+
+		_, err := service.Request(context.Background(), 1)
+
+		assert.ErrorIs(t, err, ErrDataExportUnavailable)
+	})
+}
+
+func TestDataExportService_Build(t *testing.T) {
+	t.Run("Archives Every Section", func(t *testing.T) {
+		files, err := storage.NewLocal(t.TempDir())
+		assert.NoError(t, err)
+		mockExports := new(MockDataExportRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewDataExportService(mockExports, mockUsers, files, nil, "http://api.example.com", time.Hour, "key", new(MockLogger))
+
+		service.RegisterSection(NewDataExportSection("profile", func(ctx context.Context, user *models.User) (interface{}, error) {
+			return user.ToResponse(), nil
+		}))
+		service.RegisterSection(NewDataExportSection("preferences", func(ctx context.Context, user *models.User) (interface{}, error) {
+			return nil, nil
+		}))
+
+		mockExports.On("GetByID", "exp").Return(&models.DataExport{ID: "exp", UserID: 1, Status: models.ExportStatusRunning}, nil).Once()
+		mockUsers.On("GetByID", uint(1)).Return(&models.User{ID: 1, Email: "alice@example.com", Username: "alice"}, nil).Once()
+		mockExports.On("Update", "exp", mock.MatchedBy(func(fields map[string]interface{}) bool {
+			return fields["status"] == models.ExportStatusCompleted
+		})).Return(nil).Once()
+
+		export, err := service.Build(context.Background(), "exp")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"profile"}, export.Sections)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *export.ExpiresAt, 2*time.Second)
+
+		file, err := files.Open(export.FileName())
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		file.Close()
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		assert.NoError(t, err)
+
+		contents := map[string][]byte{}
+		for _, entry := range archive.File {
+			r, _ := entry.Open()
+			contents[entry.Name], _ = io.ReadAll(r)
+			r.Close()
+		}
+		assert.Len(t, contents, 2)
+
+		var profile models.UserResponse
+		assert.NoError(t, json.Unmarshal(contents["profile.json"], &profile))
+		assert.Equal(t, "alice@example.com", profile.Email)
+
+		var manifest models.DataExportManifest
+		assert.NoError(t, json.Unmarshal(contents["manifest.json"], &manifest))
+		assert.Equal(t, []string{"profile"}, manifest.Sections)
+	})
+
+	t.Run("Leaves No File When A Section Fails", func(t *testing.T) {
+		dir := t.TempDir()
+		files, _ := storage.NewLocal(dir)
+		mockExports := new(MockDataExportRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewDataExportService(mockExports, mockUsers, files, nil, "", time.Hour, "key", new(MockLogger))
+		service.RegisterSection(NewDataExportSection("audit_log", func(ctx context.Context, user *models.User) (interface{}, error) {
+			return nil, errors.New("database unavailable")
+		}))
+
+		mockExports.On("GetByID", "exp").Return(&models.DataExport{ID: "exp", UserID: 1, Status: models.ExportStatusRunning}, nil).Once()
+		mockUsers.On("GetByID", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+
+		_, err := service.Build(context.Background(), "exp")
+
+		assert.ErrorContains(t, err, "audit_log")
+		_, err = files.Open("data-export-exp.zip")
+		assert.Error(t, err)
+		mockExports.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDataExportService_DownloadURL(t *testing.T) {
+	mockExports := new(MockDataExportRepository)
+	files, _ := storage.NewLocal(t.TempDir())
+	service := NewDataExportService(mockExports, new(MockUserRepository), files, nil, "http://api.example.com", time.Hour, "key", new(MockLogger))
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	export := &models.DataExport{ID: "exp", Status: models.ExportStatusCompleted, ExpiresAt: &expires}
+	writer, _ := files.Create(export.FileName())
+	writer.Close()
+	mockExports.On("GetByID", "exp").Return(export, nil)
+
+	link, err := url.Parse(service.DownloadURL(export))
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v1/data-exports/exp/download", link.Path)
+	signature := link.Query().Get("signature")
+	assert.Equal(t, strconv.FormatInt(expires.Unix(), 10), link.Query().Get("expires"))
+
+	t.Run("Valid Link", func(t *testing.T) {
+		_, file, err := service.Open(context.Background(), "exp", expires.Unix(), signature)
+		assert.NoError(t, err)
+		file.Close()
+	})
+
+	t.Run("Extended Expiry", func(t *testing.T) {
+		_, _, err := service.Open(context.Background(), "exp", expires.Add(time.Hour).Unix(), signature)
+		assert.ErrorIs(t, err, ErrInvalidDownloadLink)
+	})
+
+	t.Run("Expired Link", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		expired := service.DownloadURL(&models.DataExport{ID: "exp", Status: models.ExportStatusCompleted, ExpiresAt: &past})
+		link, _ := url.Parse(expired)
+
+		_, _, err := service.Open(context.Background(), "exp", past.Unix(), link.Query().Get("signature"))
+		assert.ErrorIs(t, err, ErrDataExportExpired)
+	})
+
+	t.Run("No Link Before Completion", func(t *testing.T) {
+		assert.Empty(t, service.DownloadURL(&models.DataExport{ID: "exp", Status: models.ExportStatusRunning}))
+	})
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrErasureNotFound       = errors.New("erasure request not found")
+	ErrErasureInProgress     = errors.New("an erasure request is already in progress")
+	ErrErasureNotCancellable = errors.New("erasure request can no longer be cancelled")
+	ErrErasureUnavailable    = errors.New("data erasure is unavailable")
+)
+
+// ErasureHook erases or anonymizes one subsystem's data about a user. Hooks
+// must be idempotent, since an erasure is retried until every hook succeeds,
+// and return the number of records they changed.
+type ErasureHook interface {
+	Name() string
+	Erase(ctx context.Context, user *models.User) (int64, error)
+}
+
+type erasureHook struct {
+	name  string
+	erase func(ctx context.Context, user *models.User) (int64, error)
+}
+
+// NewErasureHook makes an ErasureHook from a function.
+func NewErasureHook(name string, erase func(ctx context.Context, user *models.User) (int64, error)) ErasureHook {
+	return &erasureHook{name: name, erase: erase}
+}
+
+func (h *erasureHook) Name() string { return h.name }
+
+func (h *erasureHook) Erase(ctx context.Context, user *models.User) (int64, error) {
+	return h.erase(ctx, user)
+}
+
+// ErasureWorkflows runs erasure requests through their grace period.
+type ErasureWorkflows interface {
+	StartErasure(ctx context.Context, requestID string, grace time.Duration) (string, error)
+	CancelErasure(ctx context.Context, workflowID string) error
+}
+
+type ErasureService interface {
+	Request(ctx context.Context, userID uint, reason, requestedBy string) (*models.ErasureRequest, error)
+	Get(ctx context.Context, userID uint) (*models.ErasureRequest, error)
+	GetForUsers(ctx context.Context, userIDs []uint) (map[uint]*models.ErasureRequest, error)
+	Cancel(ctx context.Context, userID uint) (*models.ErasureRequest, error)
+	Execute(ctx context.Context, requestID string) (*models.ErasureRequest, error)
+	VerifyCertificate(certificate *models.ErasureCertificate) bool
+	RegisterHook(hook ErasureHook)
+}
+
+type erasureService struct {
+	requests   repository.ErasureRepository
+	users      repository.UserRepository
+	workflows  ErasureWorkflows
+	grace      time.Duration
+	signingKey []byte
+	logger     logger.Logger
+	tracer     trace.Tracer
+
+	mu    sync.RWMutex
+	hooks []ErasureHook
+}
+
+// NewErasureService creates the erasure service. Requests wait for grace
+// before anything is erased, and certificates are signed with signingKey.
+// workflows may be nil where requests are only executed, as in the worker.
+func NewErasureService(requests repository.ErasureRepository, users repository.UserRepository, workflows ErasureWorkflows, grace time.Duration, signingKey string, logger logger.Logger) ErasureService {
+	return &erasureService{
+		requests:   requests,
+		users:      users,
+		workflows:  workflows,
+		grace:      grace,
+		signingKey: []byte(signingKey),
+		logger:     logger,
+		tracer:     otel.Tracer("erasure-service"),
+	}
+}
+
+// RegisterHook adds a subsystem to every erasure. Hooks run in registration
+// order, so the hook that anonymizes the user row itself should come last.
+func (s *erasureService) RegisterHook(hook ErasureHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Request records an erasure request for the user and starts its grace
+// period.
+func (s *erasureService) Request(ctx context.Context, userID uint, reason, requestedBy string) (*models.ErasureRequest, error) {
+	ctx, span := s.tracer.Start(ctx, "ErasureService.Request")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(userID)))
+	if s.workflows == nil {
+		return nil, ErrErasureUnavailable
+	}
+
+	user, err := s.users.GetByIDUnscoped(userID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	latest, err := s.requests.LatestForUser(userID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get erasure request: %w", err)
+	}
+	if latest != nil && (latest.Status == models.ErasureStatusPending || latest.Status == models.ErasureStatusRunning) {
+		return nil, ErrErasureInProgress
+	}
+
+	request := &models.ErasureRequest{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Status:      models.ErasureStatusPending,
+		Reason:      reason,
+		RequestedBy: requestedBy,
+		GraceUntil:  time.Now().Add(s.grace),
+	}
+	if err := s.requests.Create(request); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to store erasure request: %w", err)
+	}
+
+	workflowID, err := s.workflows.StartErasure(ctx, request.ID, s.grace)
+	if err != nil {
+		span.RecordError(err)
+		s.requests.Update(request.ID, map[string]interface{}{
+			"status": models.ErasureStatusFailed,
+			"error":  "failed to start erasure workflow",
+		})
+		return nil, fmt.Errorf("failed to start erasure workflow: %w", err)
+	}
+
+	request.WorkflowID = workflowID
+	if err := s.requests.Update(request.ID, map[string]interface{}{"workflow_id": workflowID}); err != nil {
+		s.logger.Errorf("Failed to record workflow of erasure request %s: %v", request.ID, err)
+	}
+
+	s.logger.Infof("Erasure of user %d requested, due %s", userID, request.GraceUntil.Format(time.RFC3339))
+	return request, nil
+}
+
+// Get returns the user's latest erasure request, with its certificate once
+// completed.
+func (s *erasureService) Get(ctx context.Context, userID uint) (*models.ErasureRequest, error) {
+	request, err := s.requests.LatestForUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get erasure request: %w", err)
+	}
+	if request == nil {
+		return nil, ErrErasureNotFound
+	}
+	return request, nil
+}
+
+// GetForUsers returns the latest erasure request of each of the users that
+// has one, by user ID.
+func (s *erasureService) GetForUsers(ctx context.Context, userIDs []uint) (map[uint]*models.ErasureRequest, error) {
+	requests, err := s.requests.LatestForUsers(userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get erasure requests: %w", err)
+	}
+	latest := make(map[uint]*models.ErasureRequest, len(requests))
+	for _, request := range requests {
+		latest[request.UserID] = request
+	}
+	return latest, nil
+}
+
+// Cancel withdraws the user's pending erasure request. Once the grace
+// period is over the request can no longer be cancelled.
+func (s *erasureService) Cancel(ctx context.Context, userID uint) (*models.ErasureRequest, error) {
+	ctx, span := s.tracer.Start(ctx, "ErasureService.Cancel")
+	defer span.End()
+
+	request, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.requests.Transition(request.ID, []string{models.ErasureStatusPending}, map[string]interface{}{
+		"status": models.ErasureStatusCancelled,
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to cancel erasure request: %w", err)
+	}
+	if !cancelled {
+		return nil, ErrErasureNotCancellable
+	}
+	request.Status = models.ErasureStatusCancelled
+
+	// The workflow checks the status before erasing anything, so a failed
+	// signal only keeps it waiting until the grace period ends
+	if s.workflows != nil && request.WorkflowID != "" {
+		if err := s.workflows.CancelErasure(ctx, request.WorkflowID); err != nil {
+			s.logger.Errorf("Failed to signal cancellation of erasure request %s: %v", request.ID, err)
+		}
+	}
+
+	s.logger.Infof("Erasure of user %d cancelled", userID)
+	return request, nil
+}
+
+// Execute runs every erasure hook for the request and issues its
+// certificate. A cancelled or already completed request is returned as is.
+func (s *erasureService) Execute(ctx context.Context, requestID string) (*models.ErasureRequest, error) {
+	ctx, span := s.tracer.Start(ctx, "ErasureService.Execute")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("erasure.id", requestID))
+
+	// A retried execution finds the request already running
+	claimed, err := s.requests.Transition(requestID, []string{models.ErasureStatusPending, models.ErasureStatusRunning}, map[string]interface{}{
+		"status": models.ErasureStatusRunning,
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to start erasure: %w", err)
+	}
+
+	request, err := s.requests.GetByID(requestID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get erasure request: %w", err)
+	}
+	if request == nil {
+		return nil, ErrErasureNotFound
+	}
+	if !claimed {
+		return request, nil
+	}
+
+	user, err := s.users.GetByIDUnscoped(request.UserID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		// Purged since the request was made; the hooks still clear what
+		// other subsystems hold about the ID
+		user = &models.User{ID: request.UserID}
+	}
+
+	s.mu.RLock()
+	hooks := append([]ErasureHook(nil), s.hooks...)
+	s.mu.RUnlock()
+
+	steps := make([]models.ErasureStep, 0, len(hooks))
+	for _, hook := range hooks {
+		records, err := hook.Erase(ctx, user)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("erasure hook %s failed: %w", hook.Name(), err)
+		}
+		steps = append(steps, models.ErasureStep{Hook: hook.Name(), Records: records})
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	certificate := &models.ErasureCertificate{
+		ID:          uuid.New().String(),
+		RequestID:   request.ID,
+		UserID:      request.UserID,
+		Steps:       steps,
+		RequestedAt: request.CreatedAt.UTC().Truncate(time.Second),
+		ErasedAt:    now,
+	}
+	certificate.Signature = s.sign(certificate)
+
+	if err := s.requests.Complete(certificate, map[string]interface{}{
+		"status":       models.ErasureStatusCompleted,
+		"completed_at": &now,
+	}); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to store erasure certificate: %w", err)
+	}
+
+	request.Status = models.ErasureStatusCompleted
+	request.CompletedAt = &now
+	request.Certificate = certificate
+	s.logger.Infof("Erased user %d in %d steps", request.UserID, len(steps))
+	return request, nil
+}
+
+// VerifyCertificate reports whether the certificate's signature matches its
+// contents.
+func (s *erasureService) VerifyCertificate(certificate *models.ErasureCertificate) bool {
+	return hmac.Equal([]byte(certificate.Signature), []byte(s.sign(certificate)))
+}
+
+func (s *erasureService) sign(certificate *models.ErasureCertificate) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write(certificate.SigningPayload())
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+)
+
+type MockErasureRepository struct {
+	mock.Mock
+}
+
+func (m *MockErasureRepository) Create(request *models.ErasureRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *MockErasureRepository) GetByID(id string) (*models.ErasureRequest, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureRepository) LatestForUser(userID uint) (*models.ErasureRequest, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureRepository) LatestForUsers(userIDs []uint) ([]*models.ErasureRequest, error) {
+	args := m.Called(userIDs)
+	return args.Get(0).([]*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureRepository) ListForUser(userID uint) ([]*models.ErasureRequest, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*models.ErasureRequest), args.Error(1)
+}
+
+func (m *MockErasureRepository) Update(id string, fields map[string]interface{}) error {
+	args := m.Called(id, fields)
+	return args.Error(0)
+}
+
+func (m *MockErasureRepository) Transition(id string, from []string, fields map[string]interface{}) (bool, error) {
+	args := m.Called(id, from, fields)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockErasureRepository) Complete(certificate *models.ErasureCertificate, fields map[string]interface{}) error {
+	args := m.Called(certificate, fields)
+	return args.Error(0)
+}
+
+type MockErasureWorkflows struct {
+	mock.Mock
+}
+
+func (m *MockErasureWorkflows) StartErasure(ctx context.Context, requestID string, grace time.Duration) (string, error) {
+	args := m.Called(ctx, requestID, grace)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockErasureWorkflows) CancelErasure(ctx context.Context, workflowID string) error {
+	args := m.Called(ctx, workflowID)
+	return args.Error(0)
+}
+
+func TestErasureService_Request(t *testing.T) {
+	grace := 72 * time.Hour
+
+	t.Run("Starts The Grace Period", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		mockUsers := new(MockUserRepository)
+		mockWorkflows := new(MockErasureWorkflows)
+		service := NewErasureService(mockRequests, mockUsers, mockWorkflows, grace, "key", new(MockLogger))
+
+		mockUsers.On("GetByIDUnscoped", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+		mockRequests.On("LatestForUser", uint(1)).Return(&models.ErasureRequest{Status: models.ErasureStatusCancelled}, nil).Once()
+		mockRequests.On("Create", mock.MatchedBy(func(r *models.ErasureRequest) bool {
+			return r.UserID == 1 && r.Status == models.ErasureStatusPending && r.RequestedBy == "1" && r.GraceUntil.After(time.Now().Add(71*time.Hour))
+		})).Return(nil).Once()
+		mockWorkflows.On("StartErasure", mock.Anything, mock.Anything, grace).Return("user-erasure-x", nil).Once()
+		mockRequests.On("Update", mock.Anything, map[string]interface{}{"workflow_id": "user-erasure-x"}).Return(nil).Once()
+
+		request, err := service.Request(context.Background(), 1, "leaving", "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "user-erasure-x", request.WorkflowID)
+		mockRequests.AssertExpectations(t)
+		mockWorkflows.AssertExpectations(t)
+	})
+
+	t.Run("Already In Progress", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewErasureService(mockRequests, mockUsers, new(MockErasureWorkflows), grace, "key", new(MockLogger))
+
+		mockUsers.On("GetByIDUnscoped", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+		mockRequests.On("LatestForUser", uint(1)).Return(&models.ErasureRequest{Status: models.ErasureStatusPending}, nil).Once()
+
+		_, err := service.Request(context.Background(), 1, "", "1")
+
+		assert.ErrorIs(t, err, ErrErasureInProgress)
+		mockRequests.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("User Not Found", func(t *testing.T) {
+		mockUsers := new(MockUserRepository)
+		service := NewErasureService(new(MockErasureRepository), mockUsers, new(MockErasureWorkflows), grace, "key", new(MockLogger))
+
+		mockUsers.On("GetByIDUnscoped", uint(1)).Return(nil, nil).Once()
+
+		_, err := service.Request(context.Background(), 1, "", "1")
+
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("Without Workflows", func(t *testing.T) {
+		service := NewErasureService(new(MockErasureRepository), new(MockUserRepository), nil, grace, "key", new(MockLogger))
+
+		_, err := service.Request(context.Background(), 1, "", "1")
+
+		assert.ErrorIs(t, err, ErrErasureUnavailable)
+	})
+
+	t.Run("Marks The Request Failed When The Workflow Does Not Start", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		mockUsers := new(MockUserRepository)
+		mockWorkflows := new(MockErasureWorkflows)
+		service := NewErasureService(mockRequests, mockUsers, mockWorkflows, grace, "key", new(MockLogger))
+
+		mockUsers.On("GetByIDUnscoped", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+		mockRequests.On("LatestForUser", uint(1)).Return(nil, nil).Once()
+		mockRequests.On("Create", mock.Anything).Return(nil).Once()
+		mockWorkflows.On("StartErasure", mock.Anything, mock.Anything, grace).Return("", errors.New("unavailable")).Once()
+		mockRequests.On("Update", mock.Anything, mock.MatchedBy(func(fields map[string]interface{}) bool {
+			return fields["status"] == models.ErasureStatusFailed
+		})).Return(nil).Once()
+
+		_, err := service.Request(context.Background(), 1, "", "1")
+
+		assert.Error(t, err)
+		mockRequests.AssertExpectations(t)
+	})
+}
+
+func TestErasureService_Cancel(t *testing.T) {
+	t.Run("Cancels A Pending Request", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		mockWorkflows := new(MockErasureWorkflows)
+		service := NewErasureService(mockRequests, new(MockUserRepository), mockWorkflows, time.Hour, "key", new(MockLogger))
+
+		mockRequests.On("LatestForUser", uint(1)).Return(&models.ErasureRequest{ID: "req", Status: models.ErasureStatusPending, WorkflowID: "wf"}, nil).Once()
+		mockRequests.On("Transition", "req", []string{models.ErasureStatusPending}, mock.Anything).Return(true, nil).Once()
+		mockWorkflows.On("CancelErasure", mock.Anything, "wf").Return(nil).Once()
+
+		request, err := service.Cancel(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.ErasureStatusCancelled, request.Status)
+		mockWorkflows.AssertExpectations(t)
+	})
+
+	t.Run("Too Late", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		mockWorkflows := new(MockErasureWorkflows)
+		service := NewErasureService(mockRequests, new(MockUserRepository), mockWorkflows, time.Hour, "key", new(MockLogger))
+
+		mockRequests.On("LatestForUser", uint(1)).Return(&models.ErasureRequest{ID: "req", Status: models.ErasureStatusRunning, WorkflowID: "wf"}, nil).Once()
+		mockRequests.On("Transition", "req", []string{models.ErasureStatusPending}, mock.Anything).Return(false, nil).Once()
+
+		_, err := service.Cancel(context.Background(), 1)
+
+		assert.ErrorIs(t, err, ErrErasureNotCancellable)
+		mockWorkflows.AssertNotCalled(t, "CancelErasure", mock.Anything, mock.Anything)
+	})
+
+	t.Run("No Request", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		service := NewErasureService(mockRequests, new(MockUserRepository), nil, time.Hour, "key", new(MockLogger))
+
+		mockRequests.On("LatestForUser", uint(1)).Return(nil, nil).Once()
+
+		_, err := service.Cancel(context.Background(), 1)
+
+		assert.ErrorIs(t, err, ErrErasureNotFound)
+	})
+}
+
+func TestErasureService_Execute(t *testing.T) {
+	claim := []string{models.ErasureStatusPending, models.ErasureStatusRunning}
+	pending := func() *models.ErasureRequest {
+		return &models.ErasureRequest{ID: "req", UserID: 1, Status: models.ErasureStatusRunning, CreatedAt: time.Now()}
+	}
+
+	t.Run("Runs The Hooks In Order And Signs A Certificate", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewErasureService(mockRequests, mockUsers, nil, time.Hour, "key", new(MockLogger))
+
+		var order []string
+		for _, name := range []string{"exports", "users"} {
+			name := name
+			service.RegisterHook(NewErasureHook(name, func(ctx context.Context, user *models.User) (int64, error) {
+				assert.Equal(t, "alice@example.com", user.Email)
+				order = append(order, name)
+				return 2, nil
+			}))
+		}
+
+		mockRequests.On("Transition", "req", claim, mock.Anything).Return(true, nil).Once()
+		mockRequests.On("GetByID", "req").Return(pending(), nil).Once()
+		mockUsers.On("GetByIDUnscoped", uint(1)).Return(&models.User{ID: 1, Email: "alice@example.com"}, nil).Once()
+		mockRequests.On("Complete", mock.Anything, mock.MatchedBy(func(fields map[string]interface{}) bool {
+			return fields["status"] == models.ErasureStatusCompleted
+		})).Return(nil).Once()
+
+		request, err := service.Execute(context.Background(), "req")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"exports", "users"}, order)
+		assert.Equal(t, models.ErasureStatusCompleted, request.Status)
+		assert.Equal(t, []models.ErasureStep{{Hook: "exports", Records: 2}, {Hook: "users", Records: 2}}, request.Certificate.Steps)
+		assert.True(t, service.VerifyCertificate(request.Certificate))
+
+		// Any change to the certificate breaks its signature
+		request.Certificate.Steps[0].Records = 0
+		assert.False(t, service.VerifyCertificate(request.Certificate))
+		mockRequests.AssertExpectations(t)
+	})
+
+	t.Run("Leaves A Cancelled Request Alone", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		service := NewErasureService(mockRequests, new(MockUserRepository), nil, time.Hour, "key", new(MockLogger))
+		service.RegisterHook(NewErasureHook("users", func(ctx context.Context, user *models.User) (int64, error) {
+			t.Fatal("hook ran for a cancelled request")
+			return 0, nil
+		}))
+
+		mockRequests.On("Transition", "req", claim, mock.Anything).Return(false, nil).Once()
+		mockRequests.On("GetByID", "req").Return(&models.ErasureRequest{ID: "req", Status: models.ErasureStatusCancelled}, nil).Once()
+
+		request, err := service.Execute(context.Background(), "req")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.ErasureStatusCancelled, request.Status)
+	})
+
+	t.Run("Fails Without A Certificate When A Hook Fails", func(t *testing.T) {
+		mockRequests := new(MockErasureRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewErasureService(mockRequests, mockUsers, nil, time.Hour, "key", new(MockLogger))
+		service.RegisterHook(NewErasureHook("exports", func(ctx context.Context, user *models.User) (int64, error) {
+			return 0, errors.New("disk full")
+		}))
+
+		mockRequests.On("Transition", "req", claim, mock.Anything).Return(true, nil).Once()
+		mockRequests.On("GetByID", "req").Return(pending(), nil).Once()
+		mockUsers.On("GetByIDUnscoped", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+
+		_, err := service.Execute(context.Background(), "req")
+
+		assert.ErrorContains(t, err, "exports")
+		mockRequests.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything)
+	})
+}
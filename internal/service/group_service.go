@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrGroupNotFound      = errors.New("group not found")
+	ErrGroupAlreadyExists = errors.New("group already exists")
+	// ErrGroupCycle means adding a group as a member would nest a group
+	// within itself.
+	ErrGroupCycle = errors.New("group cannot be nested within itself")
+	// ErrInvalidGroupMember means a member request names neither or both of
+	// a user and a group.
+	ErrInvalidGroupMember = errors.New("invalid group member")
+	// ErrGroupMemberNotFound means the user or group is not a direct member
+	// of the group.
+	ErrGroupMemberNotFound = errors.New("group member not found")
+)
+
+type GroupService interface {
+	Create(ctx context.Context, req *models.CreateGroupRequest, by string) (*models.Group, error)
+	GetByID(ctx context.Context, id uint) (*models.Group, error)
+	GetAll(ctx context.Context, page, pageSize int) ([]*models.Group, int64, error)
+	Update(ctx context.Context, id uint, req *models.UpdateGroupRequest) (*models.Group, error)
+	Delete(ctx context.Context, id uint) error
+	AddMember(ctx context.Context, groupID uint, req *models.AddGroupMemberRequest, by string) (*models.GroupMember, bool, error)
+	RemoveMember(ctx context.Context, groupID uint, memberType string, memberID uint) error
+	ListMembers(ctx context.Context, groupID uint) ([]*models.GroupMember, error)
+	UserAccess(ctx context.Context, userID uint) (*models.UserAccess, error)
+	UsersAccess(ctx context.Context, userIDs []uint) (map[uint]*models.UserAccess, error)
+}
+
+type groupService struct {
+	groups repository.GroupRepository
+	users  repository.UserRepository
+	logger logger.Logger
+	tracer trace.Tracer
+}
+
+func NewGroupService(groups repository.GroupRepository, users repository.UserRepository, logger logger.Logger) GroupService {
+	return &groupService{
+		groups: groups,
+		users:  users,
+		logger: logger,
+		tracer: otel.Tracer("group-service"),
+	}
+}
+
+func (s *groupService) Create(ctx context.Context, req *models.CreateGroupRequest, by string) (*models.Group, error) {
+	_, span := s.tracer.Start(ctx, "GroupService.Create")
+	defer span.End()
+
+	if err := checkRoles(req.Roles); err != nil {
+		return nil, err
+	}
+	if err := s.checkNameAvailable(0, req.Name); err != nil {
+		return nil, err
+	}
+
+	group := &models.Group{
+		Name:        req.Name,
+		Description: req.Description,
+		Roles:       req.Roles,
+		CreatedBy:   by,
+	}
+	if err := s.groups.Create(group); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int64("group.id", int64(group.ID)))
+	s.logger.Infof("Group %s created by %s", group.Name, by)
+	return group, nil
+}
+
+func (s *groupService) GetByID(ctx context.Context, id uint) (*models.Group, error) {
+	_, span := s.tracer.Start(ctx, "GroupService.GetByID")
+	defer span.End()
+
+	return s.get(id)
+}
+
+func (s *groupService) GetAll(ctx context.Context, page, pageSize int) ([]*models.Group, int64, error) {
+	_, span := s.tracer.Start(ctx, "GroupService.GetAll")
+	defer span.End()
+
+	groups, total, err := s.groups.List(page, pageSize)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, fmt.Errorf("failed to get groups: %w", err)
+	}
+	return groups, total, nil
+}
+
+func (s *groupService) Update(ctx context.Context, id uint, req *models.UpdateGroupRequest) (*models.Group, error) {
+	_, span := s.tracer.Start(ctx, "GroupService.Update")
+	defer span.End()
+
+	group, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRoles(req.Roles); err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		if err := s.checkNameAvailable(id, req.Name); err != nil {
+			return nil, err
+		}
+		group.Name = req.Name
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+	if req.Roles != nil {
+		group.Roles = req.Roles
+	}
+
+	if err := s.groups.Update(group); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to update group: %w", err)
+	}
+	return group, nil
+}
+
+// Delete removes the group along with its memberships, so its members lose
+// the roles it granted.
+func (s *groupService) Delete(ctx context.Context, id uint) error {
+	_, span := s.tracer.Start(ctx, "GroupService.Delete")
+	defer span.End()
+
+	if _, err := s.get(id); err != nil {
+		return err
+	}
+	if err := s.groups.Delete(id); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+
+	s.logger.Infof("Group %d deleted", id)
+	return nil
+}
+
+// AddMember adds a user or group to the group and reports whether it did. If
+// it was already a member, its existing membership is returned.
+func (s *groupService) AddMember(ctx context.Context, groupID uint, req *models.AddGroupMemberRequest, by string) (*models.GroupMember, bool, error) {
+	_, span := s.tracer.Start(ctx, "GroupService.AddMember")
+	defer span.End()
+
+	if (req.UserID == 0) == (req.GroupID == 0) {
+		return nil, false, fmt.Errorf("%w: set exactly one of user_id and group_id", ErrInvalidGroupMember)
+	}
+	if _, err := s.get(groupID); err != nil {
+		return nil, false, err
+	}
+
+	member := &models.GroupMember{GroupID: groupID, AddedBy: by}
+	if req.UserID != 0 {
+		user, err := s.users.GetByID(req.UserID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil {
+			return nil, false, ErrUserNotFound
+		}
+		member.MemberType, member.MemberID = models.GroupMemberUser, req.UserID
+	} else {
+		if req.GroupID == groupID {
+			return nil, false, ErrGroupCycle
+		}
+		if _, err := s.get(req.GroupID); err != nil {
+			return nil, false, err
+		}
+		member.MemberType, member.MemberID = models.GroupMemberGroup, req.GroupID
+	}
+
+	var added bool
+	err := s.groups.Transaction(func(repo repository.GroupRepository) error {
+		if member.MemberType == models.GroupMemberGroup {
+			// Concurrent additions could each pass the check and together
+			// close a cycle, so they take turns
+			if err := repo.LockNesting(); err != nil {
+				return fmt.Errorf("failed to lock group nesting: %w", err)
+			}
+			// The new member must not already contain this group
+			ancestors, err := s.ancestors(repo, models.GroupMemberGroup, []uint{groupID})
+			if err != nil {
+				return err
+			}
+			if ancestors[member.MemberID] {
+				return ErrGroupCycle
+			}
+		}
+
+		var err error
+		if added, err = repo.AddMember(member); err != nil {
+			return fmt.Errorf("failed to add group member: %w", err)
+		}
+		if !added {
+			existing, err := repo.GetMember(groupID, member.MemberType, member.MemberID)
+			if err != nil {
+				return fmt.Errorf("failed to get group member: %w", err)
+			}
+			if existing != nil {
+				member = existing
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, false, err
+	}
+
+	span.SetAttributes(attribute.Bool("member.added", added))
+	if added {
+		s.logger.Infof("%s %d added to group %d by %s", member.MemberType, member.MemberID, groupID, by)
+	}
+	return member, added, nil
+}
+
+func (s *groupService) RemoveMember(ctx context.Context, groupID uint, memberType string, memberID uint) error {
+	_, span := s.tracer.Start(ctx, "GroupService.RemoveMember")
+	defer span.End()
+
+	if _, err := s.get(groupID); err != nil {
+		return err
+	}
+	removed, err := s.groups.RemoveMember(groupID, memberType, memberID)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to remove group member: %w", err)
+	}
+	if !removed {
+		return ErrGroupMemberNotFound
+	}
+	return nil
+}
+
+func (s *groupService) ListMembers(ctx context.Context, groupID uint) ([]*models.GroupMember, error) {
+	_, span := s.tracer.Start(ctx, "GroupService.ListMembers")
+	defer span.End()
+
+	if _, err := s.get(groupID); err != nil {
+		return nil, err
+	}
+	members, err := s.groups.ListMembers(groupID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	return members, nil
+}
+
+// UserAccess resolves the groups the user is in, directly or through nested
+// groups, and unions the roles those groups grant with the user's own.
+func (s *groupService) UserAccess(ctx context.Context, userID uint) (*models.UserAccess, error) {
+	_, span := s.tracer.Start(ctx, "GroupService.UserAccess")
+	defer span.End()
+
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	ancestors, err := s.ancestors(s.groups, models.GroupMemberUser, []uint{userID})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	ids := make([]uint, 0, len(ancestors))
+	for id := range ancestors {
+		ids = append(ids, id)
+	}
+	groups, err := s.groups.GetByIDs(ids)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+
+	access := userAccess(user, groups)
+	span.SetAttributes(attribute.Int("user.groups", len(access.Groups)))
+	return access, nil
+}
+
+// UsersAccess resolves the access of several users at once, reading each
+// level of group nesting once for all of them. Users that do not exist, or
+// are soft-deleted, are left out of the result.
+func (s *groupService) UsersAccess(ctx context.Context, userIDs []uint) (map[uint]*models.UserAccess, error) {
+	_, span := s.tracer.Start(ctx, "GroupService.UsersAccess")
+	defer span.End()
+
+	users, err := s.users.GetByIDs(userIDs)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	// The groups each user and each group reached is a direct member of
+	parents := map[string]map[uint][]uint{models.GroupMemberUser: {}, models.GroupMemberGroup: {}}
+	memberType, memberIDs := models.GroupMemberUser, make([]uint, 0, len(users))
+	for _, user := range users {
+		memberIDs = append(memberIDs, user.ID)
+	}
+	reached := map[uint]bool{}
+	for len(memberIDs) > 0 {
+		members, err := s.groups.Memberships(memberType, memberIDs)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to get group memberships: %w", err)
+		}
+		next := []uint{}
+		for _, member := range members {
+			parents[memberType][member.MemberID] = append(parents[memberType][member.MemberID], member.GroupID)
+			if !reached[member.GroupID] {
+				reached[member.GroupID] = true
+				next = append(next, member.GroupID)
+			}
+		}
+		memberType, memberIDs = models.GroupMemberGroup, next
+	}
+
+	ids := make([]uint, 0, len(reached))
+	for id := range reached {
+		ids = append(ids, id)
+	}
+	groups, err := s.groups.GetByIDs(ids)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+
+	access := make(map[uint]*models.UserAccess, len(users))
+	for _, user := range users {
+		// Every group above the user, walked through the nesting read above
+		in := map[uint]bool{}
+		pending := parents[models.GroupMemberUser][user.ID]
+		for len(pending) > 0 {
+			id := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			if !in[id] {
+				in[id] = true
+				pending = append(pending, parents[models.GroupMemberGroup][id]...)
+			}
+		}
+		var userGroups []*models.Group
+		for _, group := range groups {
+			if in[group.ID] {
+				userGroups = append(userGroups, group)
+			}
+		}
+		access[user.ID] = userAccess(user, userGroups)
+	}
+
+	span.SetAttributes(attribute.Int("users.count", len(access)))
+	return access, nil
+}
+
+// userAccess combines the user's own roles with those of groups, the groups
+// they are in, ordered by name.
+func userAccess(user *models.User, groups []*models.Group) *models.UserAccess {
+	access := &models.UserAccess{UserID: user.ID, Groups: []string{}, Roles: []string{}}
+	seen := map[string]bool{}
+	addRoles := func(roles []string) {
+		for _, role := range roles {
+			if !seen[role] {
+				seen[role] = true
+				access.Roles = append(access.Roles, role)
+			}
+		}
+	}
+	addRoles(user.Roles)
+	for _, group := range groups {
+		access.Groups = append(access.Groups, group.Name)
+		addRoles(group.Roles)
+	}
+	sort.Strings(access.Roles)
+	return access
+}
+
+// ancestors returns the IDs of every group the given members are in,
+// directly or through nested groups. Nesting is kept acyclic by AddMember,
+// but each group is visited once regardless.
+func (s *groupService) ancestors(groups repository.GroupRepository, memberType string, memberIDs []uint) (map[uint]bool, error) {
+	found := map[uint]bool{}
+	for len(memberIDs) > 0 {
+		parents, err := groups.ParentIDs(memberType, memberIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent groups: %w", err)
+		}
+		memberType, memberIDs = models.GroupMemberGroup, nil
+		for _, id := range parents {
+			if !found[id] {
+				found[id] = true
+				memberIDs = append(memberIDs, id)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (s *groupService) get(id uint) (*models.Group, error) {
+	group, err := s.groups.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+// checkNameAvailable makes sure no group other than id is called name.
+func (s *groupService) checkNameAvailable(id uint, name string) error {
+	existing, err := s.groups.GetByName(name)
+	if err != nil {
+		return fmt.Errorf("failed to check existing group: %w", err)
+	}
+	if existing != nil && existing.ID != id {
+		return ErrGroupAlreadyExists
+	}
+	return nil
+}
+
+// checkRoles rejects empty role names.
+func checkRoles(roles []string) error {
+	for _, role := range roles {
+		if strings.TrimSpace(role) == "" {
+			return fmt.Errorf("%w: role names cannot be empty", ErrInvalidRole)
+		}
+	}
+	return nil
+}
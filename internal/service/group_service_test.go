@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+)
+
+type MockGroupRepository struct {
+	mock.Mock
+}
+
+func (m *MockGroupRepository) Create(group *models.Group) error {
+	args := m.Called(group)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) GetByID(id uint) (*models.Group, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) GetByName(name string) (*models.Group, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) GetByIDs(ids []uint) ([]*models.Group, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*models.Group), args.Error(1)
+}
+
+func (m *MockGroupRepository) List(page, pageSize int) ([]*models.Group, int64, error) {
+	args := m.Called(page, pageSize)
+	return args.Get(0).([]*models.Group), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockGroupRepository) Update(group *models.Group) error {
+	args := m.Called(group)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) AddMember(member *models.GroupMember) (bool, error) {
+	args := m.Called(member)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockGroupRepository) GetMember(groupID uint, memberType string, memberID uint) (*models.GroupMember, error) {
+	args := m.Called(groupID, memberType, memberID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroupMember), args.Error(1)
+}
+
+func (m *MockGroupRepository) RemoveMember(groupID uint, memberType string, memberID uint) (bool, error) {
+	args := m.Called(groupID, memberType, memberID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockGroupRepository) ListMembers(groupID uint) ([]*models.GroupMember, error) {
+	args := m.Called(groupID)
+	return args.Get(0).([]*models.GroupMember), args.Error(1)
+}
+
+func (m *MockGroupRepository) ParentIDs(memberType string, memberIDs []uint) ([]uint, error) {
+	args := m.Called(memberType, memberIDs)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockGroupRepository) Memberships(memberType string, memberIDs []uint) ([]*models.GroupMember, error) {
+	args := m.Called(memberType, memberIDs)
+	return args.Get(0).([]*models.GroupMember), args.Error(1)
+}
+
+func (m *MockGroupRepository) RemoveUser(userID uint) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockGroupRepository) LockNesting() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockGroupRepository) Transaction(fn func(repo repository.GroupRepository) error) error {
+	m.Called()
+	return fn(m)
+}
+
+func TestGroupService_Create(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		groups := new(MockGroupRepository)
+		service := NewGroupService(groups, new(MockUserRepository), new(MockLogger))
+
+		groups.On("GetByName", "engineering").Return(nil, nil).Once()
+		groups.On("Create", mock.MatchedBy(func(g *models.Group) bool {
+			return g.Name == "engineering" && g.CreatedBy == "1" && len(g.Roles) == 1
+		})).Return(nil).Once()
+
+		group, err := service.Create(context.Background(), &models.CreateGroupRequest{Name: "engineering", Roles: []string{"deployer"}}, "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "engineering", group.Name)
+		groups.AssertExpectations(t)
+	})
+
+	t.Run("Name Taken", func(t *testing.T) {
+		groups := new(MockGroupRepository)
+		service := NewGroupService(groups, new(MockUserRepository), new(MockLogger))
+		groups.On("GetByName", "engineering").Return(&models.Group{ID: 3, Name: "engineering"}, nil).Once()
+
+		_, err := service.Create(context.Background(), &models.CreateGroupRequest{Name: "engineering"}, "1")
+
+		assert.ErrorIs(t, err, ErrGroupAlreadyExists)
+		groups.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Empty Role", func(t *testing.T) {
+		groups := new(MockGroupRepository)
+		service := NewGroupService(groups, new(MockUserRepository), new(MockLogger))
+
+		_, err := service.Create(context.Background(), &models.CreateGroupRequest{Name: "engineering", Roles: []string{" "}}, "1")
+
+		assert.ErrorIs(t, err, ErrInvalidRole)
+	})
+}
+
+func TestGroupService_AddMember(t *testing.T) {
+	t.Run("Adds User", func(t *testing.T) {
+		groups := new(MockGroupRepository)
+		users := new(MockUserRepository)
+		service := NewGroupService(groups, users, new(MockLogger))
+
+		groups.On("GetByID", uint(1)).Return(&models.Group{ID: 1}, nil).Once()
+		users.On("GetByID", uint(42)).Return(&models.User{ID: 42}, nil).Once()
+		groups.On("Transaction").Return().Once()
+		groups.On("AddMember", &models.GroupMember{GroupID: 1, MemberType: models.GroupMemberUser, MemberID: 42, AddedBy: "1"}).Return(true, nil).Once()
+
+		member, added, err := service.AddMember(context.Background(), 1, &models.AddGroupMemberRequest{UserID: 42}, "1")
+
+		assert.NoError(t, err)
+		assert.True(t, added)
+		assert.Equal(t, models.GroupMemberUser, member.MemberType)
+		groups.AssertExpectations(t)
+		groups.AssertNotCalled(t, "LockNesting")
+	})
+
+	t.Run("Already A Member", func(t *testing.T) {
+		groups := new(MockGroupRepository)
+		users := new(MockUserRepository)
+		service := NewGroupService(groups, users, new(MockLogger))
+		existing := &models.GroupMember{GroupID: 1, MemberType: models.GroupMemberUser, MemberID: 42, AddedBy: "7"}
+
+		groups.On("GetByID", uint(1)).Return(&models.Group{ID: 1}, nil).Once()
+		users.On("GetByID", uint(42)).Return(&models.User{ID: 42}, nil).Once()
+		groups.On("Transaction").Return().Once()
+		groups.On("AddMember", mock.Anything).Return(false, nil).Once()
+		groups.On("GetMember", uint(1), models.GroupMemberUser, uint(42)).Return(existing, nil).Once()
+
+		member, added, err := service.AddMember(context.Background(), 1, &models.AddGroupMemberRequest{UserID: 42}, "1")
+
+		assert.NoError(t, err)
+		assert.False(t, added)
+		assert.Equal(t, existing, member)
+	})
+
+	t.Run("Checks Nesting Under Lock", func(t *testing.T) {
+		groups := new(MockGroupRepository)
+		service := NewGroupService(groups, new(MockUserRepository), new(MockLogger))
+
+		groups.On("GetByID", uint(1)).Return(&models.Group{ID: 1}, nil)
+		groups.On("GetByID", uint(2)).Return(&models.Group{ID: 2}, nil)
+		var locked bool
+		groups.On("Transaction").Return().Once()
+		groups.On("LockNesting").Run(func(mock.Arguments) { locked = true }).Return(nil).Once()
+		groups.On("ParentIDs", models.GroupMemberGroup, []uint{1}).Run(func(mock.Arguments) {
+			assert.True(t, locked, "nesting is checked after taking the lock")
+		}).Return([]uint{}, nil).Once()
+		groups.On("AddMember", &models.GroupMember{GroupID: 1, MemberType: models.GroupMemberGroup, MemberID: 2, AddedBy: "1"}).Return(true, nil).Once()
+
+		_, added, err := service.AddMember(context.Background(), 1, &models.AddGroupMemberRequest{GroupID: 2}, "1")
+
+		assert.NoError(t, err)
+		assert.True(t, added)
+		groups.AssertExpectations(t)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		groups := new(MockGroupRepository)
+		users := new(MockUserRepository)
+		service := NewGroupService(groups, users, new(MockLogger))
+
+		groups.On("GetByID", uint(1)).Return(&models.Group{ID: 1}, nil).Once()
+		users.On("GetByID", uint(42)).Return(nil, nil).Once()
+
+		_, _, err := service.AddMember(context.Background(), 1, &models.AddGroupMemberRequest{UserID: 42}, "1")
+
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("Needs Exactly One Member", func(t *testing.T) {
+		service := NewGroupService(new(MockGroupRepository), new(MockUserRepository), new(MockLogger))
+
+		_, _, err := service.AddMember(context.Background(), 1, &models.AddGroupMemberRequest{}, "1")
+		assert.ErrorIs(t, err, ErrInvalidGroupMember)
+
+		_, _, err = service.AddMember(context.Background(), 1, &models.AddGroupMemberRequest{UserID: 42, GroupID: 2}, "1")
+		assert.ErrorIs(t, err, ErrInvalidGroupMember)
+	})
+
+	t.Run("Rejects Cycles", func(t *testing.T) {
+		// engineering (1) contains platform (2), which contains sre (3)
+		groups := new(MockGroupRepository)
+		service := NewGroupService(groups, new(MockUserRepository), new(MockLogger))
+
+		groups.On("GetByID", uint(3)).Return(&models.Group{ID: 3}, nil)
+		groups.On("GetByID", uint(1)).Return(&models.Group{ID: 1}, nil)
+		groups.On("ParentIDs", models.GroupMemberGroup, []uint{3}).Return([]uint{2}, nil)
+		groups.On("ParentIDs", models.GroupMemberGroup, []uint{2}).Return([]uint{1}, nil)
+		groups.On("ParentIDs", models.GroupMemberGroup, []uint{1}).Return([]uint{}, nil)
+		groups.On("Transaction").Return()
+		groups.On("LockNesting").Return(nil)
+
+		_, _, err := service.AddMember(context.Background(), 3, &models.AddGroupMemberRequest{GroupID: 1}, "1")
+		assert.ErrorIs(t, err, ErrGroupCycle)
+
+		_, _, err = service.AddMember(context.Background(), 3, &models.AddGroupMemberRequest{GroupID: 3}, "1")
+		assert.ErrorIs(t, err, ErrGroupCycle)
+
+		groups.AssertNotCalled(t, "AddMember", mock.Anything)
+	})
+}
+
+func TestGroupService_RemoveMember(t *testing.T) {
+	groups := new(MockGroupRepository)
+	service := NewGroupService(groups, new(MockUserRepository), new(MockLogger))
+
+	groups.On("GetByID", uint(1)).Return(&models.Group{ID: 1}, nil)
+	groups.On("RemoveMember", uint(1), models.GroupMemberUser, uint(42)).Return(false, nil).Once()
+
+	err := service.RemoveMember(context.Background(), 1, models.GroupMemberUser, 42)
+	assert.ErrorIs(t, err, ErrGroupMemberNotFound)
+}
+
+func TestGroupService_UserAccess(t *testing.T) {
+	t.Run("Unions Roles Of Nested Groups", func(t *testing.T) {
+		// User 42 is in sre and platform; sre is in platform, which is in
+		// engineering
+		groups := new(MockGroupRepository)
+		users := new(MockUserRepository)
+		service := NewGroupService(groups, users, new(MockLogger))
+
+		users.On("GetByID", uint(42)).Return(&models.User{ID: 42, Roles: []string{"user"}}, nil).Once()
+		groups.On("ParentIDs", models.GroupMemberUser, []uint{42}).Return([]uint{3, 2}, nil).Once()
+		groups.On("ParentIDs", models.GroupMemberGroup, []uint{3, 2}).Return([]uint{2, 1}, nil).Once()
+		groups.On("ParentIDs", models.GroupMemberGroup, []uint{1}).Return([]uint{}, nil).Once()
+		groups.On("GetByIDs", mock.MatchedBy(func(ids []uint) bool { return len(ids) == 3 })).Return([]*models.Group{
+			{ID: 1, Name: "engineering", Roles: []string{"viewer"}},
+			{ID: 2, Name: "platform", Roles: []string{"deployer", "user"}},
+			{ID: 3, Name: "sre", Roles: []string{"admin"}},
+		}, nil).Once()
+
+		access, err := service.UserAccess(context.Background(), 42)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"engineering", "platform", "sre"}, access.Groups)
+		assert.Equal(t, []string{"admin", "deployer", "user", "viewer"}, access.Roles)
+		groups.AssertExpectations(t)
+	})
+
+	t.Run("No Groups", func(t *testing.T) {
+		groups := new(MockGroupRepository)
+		users := new(MockUserRepository)
+		service := NewGroupService(groups, users, new(MockLogger))
+
+		users.On("GetByID", uint(42)).Return(&models.User{ID: 42}, nil).Once()
+		groups.On("ParentIDs", models.GroupMemberUser, []uint{42}).Return([]uint{}, nil).Once()
+		groups.On("GetByIDs", []uint{}).Return([]*models.Group{}, nil).Once()
+
+		access, err := service.UserAccess(context.Background(), 42)
+
+		assert.NoError(t, err)
+		assert.Empty(t, access.Groups)
+		assert.Empty(t, access.Roles)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		users := new(MockUserRepository)
+		service := NewGroupService(new(MockGroupRepository), users, new(MockLogger))
+		users.On("GetByID", uint(42)).Return(nil, nil).Once()
+
+		_, err := service.UserAccess(context.Background(), 42)
+
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestGroupService_UsersAccess(t *testing.T) {
+	// User 42 is in sre, which is in platform; user 43 is in platform too;
+	// user 44 is in no group, and user 45 does not exist
+	groups := new(MockGroupRepository)
+	users := new(MockUserRepository)
+	service := NewGroupService(groups, users, new(MockLogger))
+
+	users.On("GetByIDs", []uint{42, 43, 44, 45}).Return([]*models.User{
+		{ID: 42, Roles: []string{"user"}},
+		{ID: 43, Roles: []string{"user"}},
+		{ID: 44, Roles: []string{"viewer"}},
+	}, nil).Once()
+	groups.On("Memberships", models.GroupMemberUser, []uint{42, 43, 44}).Return([]*models.GroupMember{
+		{GroupID: 3, MemberType: models.GroupMemberUser, MemberID: 42},
+		{GroupID: 2, MemberType: models.GroupMemberUser, MemberID: 43},
+	}, nil).Once()
+	groups.On("Memberships", models.GroupMemberGroup, []uint{3, 2}).Return([]*models.GroupMember{
+		{GroupID: 2, MemberType: models.GroupMemberGroup, MemberID: 3},
+	}, nil).Once()
+	groups.On("GetByIDs", mock.MatchedBy(func(ids []uint) bool { return len(ids) == 2 })).Return([]*models.Group{
+		{ID: 2, Name: "platform", Roles: []string{"deployer"}},
+		{ID: 3, Name: "sre", Roles: []string{"admin"}},
+	}, nil).Once()
+
+	access, err := service.UsersAccess(context.Background(), []uint{42, 43, 44, 45})
+
+	assert.NoError(t, err)
+	assert.Len(t, access, 3)
+	assert.Equal(t, []string{"platform", "sre"}, access[42].Groups)
+	assert.Equal(t, []string{"admin", "deployer", "user"}, access[42].Roles)
+	assert.Equal(t, []string{"platform"}, access[43].Groups)
+	assert.Equal(t, []string{"deployer", "user"}, access[43].Roles)
+	assert.Empty(t, access[44].Groups)
+	assert.Equal(t, []string{"viewer"}, access[44].Roles)
+	assert.Nil(t, access[45])
+	groups.AssertExpectations(t)
+}
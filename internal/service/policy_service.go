@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/opa/bundle"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrBundleNotFound     = errors.New("policy bundle not found")
+	ErrInvalidBundle      = errors.New("invalid policy bundle")
+	ErrNoPreviousRevision = errors.New("no previous policy revision to roll back to")
+)
+
+// PolicyLoader makes a bundle the policy in force, either by hot-reloading the
+// embedded evaluator or by pushing it to a remote OPA.
+type PolicyLoader interface {
+	Load(ctx context.Context, bundle *models.PolicyBundle) error
+}
+
+type PolicyService interface {
+	Validate(ctx context.Context, req *models.UploadPolicyBundleRequest) (string, error)
+	Upload(ctx context.Context, req *models.UploadPolicyBundleRequest, createdBy string) (*models.PolicyBundle, error)
+	List(ctx context.Context) ([]*models.PolicyBundleSummary, error)
+	Get(ctx context.Context, revision string) (*models.PolicyBundle, error)
+	Activate(ctx context.Context, revision, activatedBy string) (*models.PolicyBundleSummary, error)
+	Rollback(ctx context.Context, activatedBy string) (*models.PolicyBundleSummary, error)
+	Bootstrap(ctx context.Context, defaults *models.PolicyBundle) error
+	Sync(ctx context.Context) error
+	Watch(ctx context.Context, interval time.Duration)
+	ActiveRevision() string
+}
+
+type policyService struct {
+	repo   repository.PolicyBundleRepository
+	loader PolicyLoader
+	logger logger.Logger
+	tracer trace.Tracer
+
+	// mu serialises loads so that a manual activation and a background sync
+	// cannot interleave.
+	mu       sync.Mutex
+	revision string
+}
+
+// NewPolicyService creates the bundle manager. loader may be nil, in which
+// case activations are only recorded and running API instances pick them up
+// through Sync.
+func NewPolicyService(repo repository.PolicyBundleRepository, loader PolicyLoader, logger logger.Logger) PolicyService {
+	return &policyService{
+		repo:   repo,
+		loader: loader,
+		logger: logger,
+		tracer: otel.Tracer("policy-service"),
+	}
+}
+
+func (s *policyService) Validate(ctx context.Context, req *models.UploadPolicyBundleRequest) (string, error) {
+	_, span := s.tracer.Start(ctx, "PolicyService.Validate")
+	defer span.End()
+
+	b := newBundle(req)
+	if _, err := bundle.Compile(b); err != nil {
+		span.RecordError(err)
+		return "", fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	return b.Revision, nil
+}
+
+func (s *policyService) Upload(ctx context.Context, req *models.UploadPolicyBundleRequest, createdBy string) (*models.PolicyBundle, error) {
+	ctx, span := s.tracer.Start(ctx, "PolicyService.Upload")
+	defer span.End()
+
+	revision, err := s.Validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("policy.revision", revision))
+
+	existing, err := s.repo.GetByRevision(revision)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to check existing bundle: %w", err)
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	b := newBundle(req)
+	b.CreatedBy = createdBy
+	if err := s.repo.Create(b); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to store bundle: %w", err)
+	}
+
+	s.logger.Infof("Policy bundle uploaded: %s", b.Revision)
+	return b, nil
+}
+
+func (s *policyService) List(ctx context.Context) ([]*models.PolicyBundleSummary, error) {
+	bundles, err := s.repo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bundles: %w", err)
+	}
+
+	summaries := make([]*models.PolicyBundleSummary, len(bundles))
+	for i, b := range bundles {
+		summaries[i] = b.ToSummary()
+	}
+	return summaries, nil
+}
+
+func (s *policyService) Get(ctx context.Context, revision string) (*models.PolicyBundle, error) {
+	b, err := s.repo.GetByRevision(revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle: %w", err)
+	}
+	if b == nil {
+		return nil, ErrBundleNotFound
+	}
+	return b, nil
+}
+
+func (s *policyService) Activate(ctx context.Context, revision, activatedBy string) (*models.PolicyBundleSummary, error) {
+	ctx, span := s.tracer.Start(ctx, "PolicyService.Activate")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("policy.revision", revision))
+
+	b, err := s.Get(ctx, revision)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx, b); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if err := s.repo.Activate(revision, activatedBy); err != nil {
+		span.RecordError(err)
+		s.logger.Errorf("Failed to record activation of %s, restoring previous policy: %v", revision, err)
+		s.restore(ctx)
+		return nil, fmt.Errorf("failed to activate bundle: %w", err)
+	}
+
+	s.logger.Infof("Policy bundle activated: %s by %s", revision, activatedBy)
+
+	activated, err := s.repo.GetByRevision(revision)
+	if err != nil || activated == nil {
+		return b.ToSummary(), nil
+	}
+	return activated.ToSummary(), nil
+}
+
+func (s *policyService) Rollback(ctx context.Context, activatedBy string) (*models.PolicyBundleSummary, error) {
+	activations, err := s.repo.ListActivations(100)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activations: %w", err)
+	}
+	if len(activations) == 0 {
+		return nil, ErrNoPreviousRevision
+	}
+
+	current := activations[0].Revision
+	for _, activation := range activations[1:] {
+		if activation.Revision != current {
+			return s.Activate(ctx, activation.Revision, activatedBy)
+		}
+	}
+	return nil, ErrNoPreviousRevision
+}
+
+// Bootstrap seeds the store with the built-in policies the first time the
+// service starts, then loads whatever bundle is active.
+func (s *policyService) Bootstrap(ctx context.Context, defaults *models.PolicyBundle) error {
+	active, err := s.repo.GetActive()
+	if err != nil {
+		return fmt.Errorf("failed to get active bundle: %w", err)
+	}
+
+	if active == nil {
+		b, err := s.Upload(ctx, &models.UploadPolicyBundleRequest{
+			Modules:     defaults.Modules,
+			Data:        defaults.Data,
+			Description: "Built-in policies",
+		}, "system")
+		if err != nil {
+			return err
+		}
+		if _, err := s.Activate(ctx, b.Revision, "system"); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	return s.Sync(ctx)
+}
+
+// Sync loads the active bundle if it differs from the one in force, which is
+// how activations made elsewhere (another replica, the CLI) take effect.
+func (s *policyService) Sync(ctx context.Context) error {
+	active, err := s.repo.GetActive()
+	if err != nil {
+		return fmt.Errorf("failed to get active bundle: %w", err)
+	}
+	if active == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if active.Revision == s.revision {
+		return nil
+	}
+	if err := s.load(ctx, active); err != nil {
+		return err
+	}
+	s.logger.Infof("Policy bundle reloaded: %s", active.Revision)
+	return nil
+}
+
+// Watch calls Sync every interval until ctx is cancelled.
+func (s *policyService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sync(ctx); err != nil {
+				s.logger.Error("Failed to sync policy bundle: ", err)
+			}
+		}
+	}
+}
+
+func (s *policyService) ActiveRevision() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revision
+}
+
+// load must be called with s.mu held.
+func (s *policyService) load(ctx context.Context, b *models.PolicyBundle) error {
+	if s.loader != nil {
+		if err := s.loader.Load(ctx, b); err != nil {
+			return fmt.Errorf("failed to load bundle %s: %w", b.Revision, err)
+		}
+	}
+	s.revision = b.Revision
+	return nil
+}
+
+// restore reloads the bundle recorded as active after a failed activation.
+// It must be called with s.mu held.
+func (s *policyService) restore(ctx context.Context) {
+	active, err := s.repo.GetActive()
+	if err != nil || active == nil {
+		return
+	}
+	if err := s.load(ctx, active); err != nil {
+		s.logger.Errorf("Failed to restore policy bundle %s: %v", active.Revision, err)
+	}
+}
+
+func newBundle(req *models.UploadPolicyBundleRequest) *models.PolicyBundle {
+	data := req.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	b := &models.PolicyBundle{
+		Modules:     req.Modules,
+		Data:        data,
+		Description: req.Description,
+	}
+	b.Revision = bundle.Revision(b)
+	return b
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+)
+
+type MockPolicyBundleRepository struct {
+	mock.Mock
+}
+
+func (m *MockPolicyBundleRepository) Create(bundle *models.PolicyBundle) error {
+	args := m.Called(bundle)
+	return args.Error(0)
+}
+
+func (m *MockPolicyBundleRepository) GetByRevision(revision string) (*models.PolicyBundle, error) {
+	args := m.Called(revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PolicyBundle), args.Error(1)
+}
+
+func (m *MockPolicyBundleRepository) GetActive() (*models.PolicyBundle, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PolicyBundle), args.Error(1)
+}
+
+func (m *MockPolicyBundleRepository) List() ([]*models.PolicyBundle, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.PolicyBundle), args.Error(1)
+}
+
+func (m *MockPolicyBundleRepository) Activate(revision, activatedBy string) error {
+	args := m.Called(revision, activatedBy)
+	return args.Error(0)
+}
+
+func (m *MockPolicyBundleRepository) ListActivations(limit int) ([]*models.PolicyActivation, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]*models.PolicyActivation), args.Error(1)
+}
+
+type MockPolicyLoader struct {
+	mock.Mock
+}
+
+func (m *MockPolicyLoader) Load(ctx context.Context, bundle *models.PolicyBundle) error {
+	args := m.Called(ctx, bundle)
+	return args.Error(0)
+}
+
+const validModule = "package authz\n\ndefault allow := false\n\ndecision := {\"allow\": allow, \"matched_rules\": []}\n"
+
+func TestPolicyService_Upload(t *testing.T) {
+	t.Run("Invalid Bundle", func(t *testing.T) {
+		mockRepo := new(MockPolicyBundleRepository)
+		service := NewPolicyService(mockRepo, nil, new(MockLogger))
+
+		_, err := service.Upload(context.Background(), &models.UploadPolicyBundleRequest{
+			Modules: map[string]string{"authz.rego": "package authz\n\nallow {"},
+		}, "1")
+		assert.ErrorIs(t, err, ErrInvalidBundle)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockPolicyBundleRepository)
+		service := NewPolicyService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByRevision", mock.AnythingOfType("string")).Return(nil, nil).Once()
+		mockRepo.On("Create", mock.AnythingOfType("*models.PolicyBundle")).Return(nil).Once()
+
+		bundle, err := service.Upload(context.Background(), &models.UploadPolicyBundleRequest{
+			Modules: map[string]string{"authz.rego": validModule},
+		}, "1")
+		assert.NoError(t, err)
+		assert.Len(t, bundle.Revision, 12)
+		assert.Equal(t, "1", bundle.CreatedBy)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestPolicyService_Activate(t *testing.T) {
+	mockRepo := new(MockPolicyBundleRepository)
+	mockLoader := new(MockPolicyLoader)
+	service := NewPolicyService(mockRepo, mockLoader, new(MockLogger))
+
+	bundle := &models.PolicyBundle{Revision: "abc123", Modules: map[string]string{"authz.rego": validModule}}
+	mockRepo.On("GetByRevision", "abc123").Return(bundle, nil)
+	mockLoader.On("Load", mock.Anything, bundle).Return(nil).Once()
+	mockRepo.On("Activate", "abc123", "1").Return(nil).Once()
+
+	summary, err := service.Activate(context.Background(), "abc123", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", summary.Revision)
+	assert.Equal(t, "abc123", service.ActiveRevision())
+	mockRepo.AssertExpectations(t)
+	mockLoader.AssertExpectations(t)
+}
+
+func TestPolicyService_Rollback(t *testing.T) {
+	t.Run("Activates Previous Revision", func(t *testing.T) {
+		mockRepo := new(MockPolicyBundleRepository)
+		service := NewPolicyService(mockRepo, nil, new(MockLogger))
+
+		previous := &models.PolicyBundle{Revision: "old"}
+		mockRepo.On("ListActivations", 100).Return([]*models.PolicyActivation{
+			{Revision: "new"}, {Revision: "new"}, {Revision: "old"},
+		}, nil)
+		mockRepo.On("GetByRevision", "old").Return(previous, nil)
+		mockRepo.On("Activate", "old", "1").Return(nil).Once()
+
+		summary, err := service.Rollback(context.Background(), "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "old", summary.Revision)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Nothing To Roll Back To", func(t *testing.T) {
+		mockRepo := new(MockPolicyBundleRepository)
+		service := NewPolicyService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("ListActivations", 100).Return([]*models.PolicyActivation{{Revision: "only"}}, nil)
+
+		_, err := service.Rollback(context.Background(), "1")
+		assert.ErrorIs(t, err, ErrNoPreviousRevision)
+	})
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
+	"github.com/xeipuuv/gojsonschema"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrInvalidAttributes means a user's attributes do not match the
+	// attribute schema. It wraps validation.Errors naming each attribute.
+	ErrInvalidAttributes = errors.New("invalid attributes")
+	// ErrInvalidAttributeSchema means a published schema is not a usable JSON
+	// Schema for attributes.
+	ErrInvalidAttributeSchema = errors.New("invalid attribute schema")
+	// ErrAttributeSchemaChanged means another schema was published since the
+	// version the caller read.
+	ErrAttributeSchemaChanged = errors.New("attribute schema has changed")
+)
+
+type UserAttributeService interface {
+	GetSchema(ctx context.Context) (*models.UserAttributeSchema, error)
+	PutSchema(ctx context.Context, version *uint, schema map[string]interface{}, by string) (*models.UserAttributeSchema, error)
+	Validate(ctx context.Context, attributes map[string]interface{}) error
+}
+
+type userAttributeService struct {
+	schemas repository.UserAttributeSchemaRepository
+	logger  logger.Logger
+	tracer  trace.Tracer
+
+	// The latest schema, compiled, so writes only compile it once
+	mu              sync.Mutex
+	compiledVersion uint
+	compiled        *gojsonschema.Schema
+}
+
+func NewUserAttributeService(schemas repository.UserAttributeSchemaRepository, logger logger.Logger) UserAttributeService {
+	return &userAttributeService{
+		schemas: schemas,
+		logger:  logger,
+		tracer:  otel.Tracer("user-attribute-service"),
+	}
+}
+
+// GetSchema returns the latest schema, or the default one if none has been
+// published.
+func (s *userAttributeService) GetSchema(ctx context.Context) (*models.UserAttributeSchema, error) {
+	_, span := s.tracer.Start(ctx, "UserAttributeService.GetSchema")
+	defer span.End()
+
+	schema, err := s.schemas.Latest()
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get attribute schema: %w", err)
+	}
+	if schema == nil {
+		return models.DefaultUserAttributeSchema(), nil
+	}
+	return schema, nil
+}
+
+// PutSchema publishes schema as the next version. A non-nil version is the
+// version the caller last read, 0 if no schema had been published;
+// publishing fails with ErrAttributeSchemaChanged if another schema was
+// published since. Existing attributes are not checked against the new
+// schema, only later writes.
+func (s *userAttributeService) PutSchema(ctx context.Context, version *uint, schema map[string]interface{}, by string) (*models.UserAttributeSchema, error) {
+	_, span := s.tracer.Start(ctx, "UserAttributeService.PutSchema")
+	defer span.End()
+
+	if _, err := compileAttributeSchema(schema); err != nil {
+		return nil, err
+	}
+
+	latest, err := s.schemas.Latest()
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get attribute schema: %w", err)
+	}
+	current := uint(0)
+	if latest != nil {
+		current = latest.Version
+	}
+	if version != nil && *version != current {
+		return nil, ErrAttributeSchemaChanged
+	}
+
+	published := &models.UserAttributeSchema{Version: current + 1, Schema: schema, CreatedBy: by}
+	created, err := s.schemas.Create(published)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to store attribute schema: %w", err)
+	}
+	if !created {
+		// Published concurrently
+		return nil, ErrAttributeSchemaChanged
+	}
+
+	span.SetAttributes(attribute.Int64("schema.version", int64(published.Version)))
+	s.logger.Infof("User attribute schema %d published by %s", published.Version, by)
+	return published, nil
+}
+
+// Validate checks attributes, nil meaning none, against the latest schema.
+func (s *userAttributeService) Validate(ctx context.Context, attributes map[string]interface{}) error {
+	_, span := s.tracer.Start(ctx, "UserAttributeService.Validate")
+	defer span.End()
+
+	compiled, err := s.latestCompiled()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(attributes))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttributes, err)
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	var errs validation.Errors
+	for _, resultErr := range result.Errors() {
+		field := "attributes"
+		if resultErr.Field() != gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			field += "." + resultErr.Field()
+		}
+		if property, ok := resultErr.Details()["property"].(string); ok && resultErr.Type() == "required" {
+			field += "." + property
+		}
+		errs = append(errs, validation.FieldError{
+			Field:   field,
+			Rule:    resultErr.Type(),
+			Message: field + ": " + resultErr.Description(),
+		})
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidAttributes, errs)
+}
+
+// latestCompiled returns the latest schema, compiling it if it has changed
+// since the last call.
+func (s *userAttributeService) latestCompiled() (*gojsonschema.Schema, error) {
+	latest, err := s.schemas.Latest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attribute schema: %w", err)
+	}
+	if latest == nil {
+		latest = models.DefaultUserAttributeSchema()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.compiled != nil && s.compiledVersion == latest.Version {
+		return s.compiled, nil
+	}
+	compiled, err := compileAttributeSchema(latest.Schema)
+	if err != nil {
+		return nil, err
+	}
+	s.compiled, s.compiledVersion = compiled, latest.Version
+	return compiled, nil
+}
+
+// compileAttributeSchema compiles schema, which must describe an object and
+// may only refer to its own definitions, so that compiling it never fetches
+// anything.
+func compileAttributeSchema(schema map[string]interface{}) (*gojsonschema.Schema, error) {
+	if schema["type"] != "object" {
+		return nil, fmt.Errorf(`%w: the schema must have "type": "object"`, ErrInvalidAttributeSchema)
+	}
+	if ref, ok := externalRef(schema); ok {
+		return nil, fmt.Errorf("%w: $ref %q must refer to a definition within the schema", ErrInvalidAttributeSchema, ref)
+	}
+
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAttributeSchema, err)
+	}
+	return compiled, nil
+}
+
+// externalRef returns the first $ref in value that does not start with "#".
+func externalRef(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if ref, ok := child.(string); ok && key == "$ref" && !strings.HasPrefix(ref, "#") {
+				return ref, true
+			}
+			if ref, ok := externalRef(child); ok {
+				return ref, true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if ref, ok := externalRef(child); ok {
+				return ref, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
+)
+
+type MockUserAttributeSchemaRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserAttributeSchemaRepository) Create(schema *models.UserAttributeSchema) (bool, error) {
+	args := m.Called(schema)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserAttributeSchemaRepository) Latest() (*models.UserAttributeSchema, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserAttributeSchema), args.Error(1)
+}
+
+// planSchema requires a plan of free or pro and allows an optional seat count.
+func planSchema() *models.UserAttributeSchema {
+	return &models.UserAttributeSchema{Version: 3, Schema: map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"plan"},
+		"properties": map[string]interface{}{
+			"plan":  map[string]interface{}{"enum": []interface{}{"free", "pro"}},
+			"seats": map[string]interface{}{"type": "integer", "minimum": 1},
+		},
+		"additionalProperties": false,
+	}}
+}
+
+func TestUserAttributeService_GetSchema(t *testing.T) {
+	t.Run("Default Until Published", func(t *testing.T) {
+		schemas := new(MockUserAttributeSchemaRepository)
+		service := NewUserAttributeService(schemas, new(MockLogger))
+		schemas.On("Latest").Return(nil, nil).Once()
+
+		schema, err := service.GetSchema(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(0), schema.Version)
+		assert.Equal(t, "object", schema.Schema["type"])
+	})
+}
+
+func TestUserAttributeService_PutSchema(t *testing.T) {
+	t.Run("Publishes Next Version", func(t *testing.T) {
+		schemas := new(MockUserAttributeSchemaRepository)
+		service := NewUserAttributeService(schemas, new(MockLogger))
+		schemas.On("Latest").Return(planSchema(), nil).Once()
+		schemas.On("Create", mock.MatchedBy(func(s *models.UserAttributeSchema) bool {
+			return s.Version == 4 && s.CreatedBy == "1"
+		})).Return(true, nil).Once()
+
+		version := uint(3)
+		schema, err := service.PutSchema(context.Background(), &version, planSchema().Schema, "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4), schema.Version)
+		schemas.AssertExpectations(t)
+	})
+
+	t.Run("Stale Version", func(t *testing.T) {
+		schemas := new(MockUserAttributeSchemaRepository)
+		service := NewUserAttributeService(schemas, new(MockLogger))
+		schemas.On("Latest").Return(planSchema(), nil).Once()
+
+		version := uint(2)
+		_, err := service.PutSchema(context.Background(), &version, planSchema().Schema, "1")
+
+		assert.ErrorIs(t, err, ErrAttributeSchemaChanged)
+		schemas.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Version 0 Requires None Published", func(t *testing.T) {
+		schemas := new(MockUserAttributeSchemaRepository)
+		service := NewUserAttributeService(schemas, new(MockLogger))
+		version := uint(0)
+
+		schemas.On("Latest").Return(nil, nil).Once()
+		schemas.On("Create", mock.MatchedBy(func(s *models.UserAttributeSchema) bool {
+			return s.Version == 1
+		})).Return(true, nil).Once()
+		schema, err := service.PutSchema(context.Background(), &version, planSchema().Schema, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), schema.Version)
+
+		schemas.On("Latest").Return(planSchema(), nil).Once()
+		_, err = service.PutSchema(context.Background(), &version, planSchema().Schema, "1")
+		assert.ErrorIs(t, err, ErrAttributeSchemaChanged)
+		schemas.AssertExpectations(t)
+	})
+
+	t.Run("Published Concurrently", func(t *testing.T) {
+		schemas := new(MockUserAttributeSchemaRepository)
+		service := NewUserAttributeService(schemas, new(MockLogger))
+		schemas.On("Latest").Return(nil, nil).Once()
+		schemas.On("Create", mock.Anything).Return(false, nil).Once()
+
+		_, err := service.PutSchema(context.Background(), nil, planSchema().Schema, "1")
+
+		assert.ErrorIs(t, err, ErrAttributeSchemaChanged)
+	})
+
+	t.Run("Rejects Unusable Schemas", func(t *testing.T) {
+		invalid := map[string]map[string]interface{}{
+			"not an object": {"type": "array"},
+			"remote ref":    {"type": "object", "properties": map[string]interface{}{"plan": map[string]interface{}{"$ref": "https://example.com/plan.json"}}},
+			"bad keyword":   {"type": "object", "minProperties": "two"},
+		}
+		for name, schema := range invalid {
+			t.Run(name, func(t *testing.T) {
+				schemas := new(MockUserAttributeSchemaRepository)
+				service := NewUserAttributeService(schemas, new(MockLogger))
+
+				_, err := service.PutSchema(context.Background(), nil, schema, "1")
+
+				assert.ErrorIs(t, err, ErrInvalidAttributeSchema)
+				schemas.AssertNotCalled(t, "Create", mock.Anything)
+			})
+		}
+	})
+}
+
+func TestUserAttributeService_Validate(t *testing.T) {
+	schemas := new(MockUserAttributeSchemaRepository)
+	service := NewUserAttributeService(schemas, new(MockLogger))
+	schemas.On("Latest").Return(planSchema(), nil)
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, service.Validate(context.Background(), map[string]interface{}{"plan": "pro", "seats": 5}))
+	})
+
+	t.Run("Names Each Invalid Attribute", func(t *testing.T) {
+		err := service.Validate(context.Background(), map[string]interface{}{"seats": 0, "team": "a"})
+
+		assert.ErrorIs(t, err, ErrInvalidAttributes)
+		var errs validation.Errors
+		assert.True(t, errors.As(err, &errs))
+		fields := map[string]string{}
+		for _, e := range errs {
+			fields[e.Field] = e.Rule
+		}
+		assert.Equal(t, "required", fields["attributes.plan"])
+		assert.Equal(t, "number_gte", fields["attributes.seats"])
+		assert.Equal(t, "additional_property_not_allowed", fields["attributes"])
+	})
+
+	t.Run("No Attributes", func(t *testing.T) {
+		assert.ErrorIs(t, service.Validate(context.Background(), nil), ErrInvalidAttributes)
+	})
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrExportNotFound     = errors.New("user export not found")
+	ErrExportNotReady     = errors.New("user export is not ready")
+	ErrInvalidExport      = errors.New("invalid user export")
+	ErrExportUnavailable  = errors.New("user exports are unavailable")
+	ErrNoExportableFields = errors.New("no user fields may be exported")
+)
+
+// FileStore keeps export files until they are downloaded.
+type FileStore interface {
+	Create(name string) (io.WriteCloser, error)
+	Open(name string) (io.ReadCloser, error)
+	Remove(name string) error
+}
+
+type UserExportService interface {
+	Write(ctx context.Context, query *models.UserListQuery, format string, fields []string, w io.Writer) (int, error)
+	Start(ctx context.Context, query *models.UserListQuery, format string, fields []string, createdBy string) (*models.UserExport, error)
+	Get(ctx context.Context, id, requestedBy string) (*models.UserExport, error)
+	Open(ctx context.Context, id, requestedBy string) (*models.UserExport, io.ReadCloser, error)
+	DeleteByCreator(ctx context.Context, createdBy string) (int64, error)
+	Wait()
+}
+
+type userExportService struct {
+	users   repository.UserRepository
+	exports repository.UserExportRepository
+	files   FileStore
+	logger  logger.Logger
+	tracer  trace.Tracer
+
+	// running tracks background exports so that shutdown can wait for them.
+	running sync.WaitGroup
+}
+
+// NewUserExportService creates the export service. files may be nil, in
+// which case only streaming exports are available.
+func NewUserExportService(users repository.UserRepository, exports repository.UserExportRepository, files FileStore, logger logger.Logger) UserExportService {
+	return &userExportService{
+		users:   users,
+		exports: exports,
+		files:   files,
+		logger:  logger,
+		tracer:  otel.Tracer("user-export-service"),
+	}
+}
+
+// Write streams the users matching query to w in format, keeping only the
+// export columns listed in fields (all of them if fields is nil), and returns
+// the number of users written.
+func (s *userExportService) Write(ctx context.Context, query *models.UserListQuery, format string, fields []string, w io.Writer) (int, error) {
+	ctx, span := s.tracer.Start(ctx, "UserExportService.Write")
+	defer span.End()
+
+	encoder, err := newUserEncoder(format, fields, w)
+	if err != nil {
+		return 0, err
+	}
+
+	span.SetAttributes(
+		attribute.String("export.format", format),
+		attribute.Bool("authz.row_filter", query.RowFilter != nil && !query.RowFilter.Unrestricted),
+	)
+
+	if err := encoder.Begin(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = s.users.Stream(query, func(user *models.User) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		count++
+		return encoder.Encode(user)
+	})
+	if err != nil {
+		span.RecordError(err)
+		return count, fmt.Errorf("failed to export users: %w", err)
+	}
+
+	if err := encoder.End(); err != nil {
+		return count, err
+	}
+
+	span.SetAttributes(attribute.Int("users.count", count))
+	return count, nil
+}
+
+// Start records an export job and writes it to file storage in the
+// background. The rows and fields are fixed by the caller's permissions at
+// the time of the request.
+func (s *userExportService) Start(ctx context.Context, query *models.UserListQuery, format string, fields []string, createdBy string) (*models.UserExport, error) {
+	if s.files == nil {
+		return nil, ErrExportUnavailable
+	}
+	// Fail bad formats and field sets now rather than in the background
+	if _, err := newUserEncoder(format, fields, io.Discard); err != nil {
+		return nil, err
+	}
+
+	export := &models.UserExport{
+		ID:        uuid.New().String(),
+		Format:    format,
+		Status:    models.ExportStatusRunning,
+		Query:     *query,
+		Fields:    fields,
+		CreatedBy: createdBy,
+	}
+	if err := s.exports.Create(export); err != nil {
+		return nil, fmt.Errorf("failed to store export: %w", err)
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.run(export)
+	}()
+
+	return export, nil
+}
+
+func (s *userExportService) run(export *models.UserExport) {
+	ctx, span := s.tracer.Start(context.Background(), "UserExportService.Run")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("export.id", export.ID))
+
+	rows, err := s.writeFile(ctx, export)
+	now := time.Now()
+	fields := map[string]interface{}{
+		"status":       models.ExportStatusCompleted,
+		"row_count":    rows,
+		"completed_at": &now,
+	}
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Errorf("User export %s failed: %v", export.ID, err)
+		fields["status"] = models.ExportStatusFailed
+		fields["error"] = "export failed"
+	}
+
+	if err := s.exports.Update(export.ID, fields); err != nil {
+		s.logger.Errorf("Failed to record result of user export %s: %v", export.ID, err)
+		return
+	}
+	s.logger.Infof("User export %s %s with %d rows", export.ID, fields["status"], rows)
+}
+
+func (s *userExportService) writeFile(ctx context.Context, export *models.UserExport) (int, error) {
+	file, err := s.files.Create(export.FileName())
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := s.Write(ctx, &export.Query, export.Format, export.Fields, file)
+	if err != nil {
+		file.Close()
+		s.files.Remove(export.FileName())
+		return rows, err
+	}
+	return rows, file.Close()
+}
+
+// Get returns an export started by requestedBy. Exports are private to the
+// caller who started them, since their contents follow that caller's
+// permissions.
+func (s *userExportService) Get(ctx context.Context, id, requestedBy string) (*models.UserExport, error) {
+	export, err := s.exports.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	if export == nil || export.CreatedBy != requestedBy {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+// Open returns a completed export's file for download.
+func (s *userExportService) Open(ctx context.Context, id, requestedBy string) (*models.UserExport, io.ReadCloser, error) {
+	export, err := s.Get(ctx, id, requestedBy)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != models.ExportStatusCompleted {
+		return nil, nil, ErrExportNotReady
+	}
+	if s.files == nil {
+		return nil, nil, ErrExportUnavailable
+	}
+
+	file, err := s.files.Open(export.FileName())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	return export, file, nil
+}
+
+// DeleteByCreator removes every export started by createdBy along with its
+// file, and returns how many there were.
+func (s *userExportService) DeleteByCreator(ctx context.Context, createdBy string) (int64, error) {
+	exports, err := s.exports.ListByCreator(createdBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list exports: %w", err)
+	}
+
+	for _, export := range exports {
+		if s.files != nil {
+			if err := s.files.Remove(export.FileName()); err != nil {
+				return 0, fmt.Errorf("failed to remove export file: %w", err)
+			}
+		}
+		if err := s.exports.Delete(export.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete export: %w", err)
+		}
+	}
+	return int64(len(exports)), nil
+}
+
+// Wait blocks until all background exports have finished.
+func (s *userExportService) Wait() {
+	s.running.Wait()
+}
+
+// userEncoder writes users in an export format.
+type userEncoder interface {
+	Begin() error
+	Encode(user *models.User) error
+	End() error
+}
+
+func newUserEncoder(format string, fields []string, w io.Writer) (userEncoder, error) {
+	columns := exportColumns(fields)
+	if len(columns) == 0 {
+		return nil, ErrNoExportableFields
+	}
+
+	switch format {
+	case models.ExportFormatCSV:
+		return &csvUserEncoder{w: csv.NewWriter(w), columns: columns}, nil
+	case models.ExportFormatNDJSON:
+		return &ndjsonUserEncoder{encoder: json.NewEncoder(w), columns: columns}, nil
+	case models.ExportFormatJSON:
+		return &jsonUserEncoder{w: w, columns: columns}, nil
+	}
+	return nil, fmt.Errorf("%w: format must be %q, %q or %q", ErrInvalidExport, models.ExportFormatCSV, models.ExportFormatNDJSON, models.ExportFormatJSON)
+}
+
+// CanExport reports whether fields allows at least one export column.
+func CanExport(fields []string) bool {
+	return len(exportColumns(fields)) > 0
+}
+
+// exportColumns returns the export columns allowed by fields, in export
+// order. A nil fields allows every column.
+func exportColumns(fields []string) []string {
+	if fields == nil {
+		return models.UserExportColumns
+	}
+
+	allowed := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		allowed[field] = true
+	}
+	var columns []string
+	for _, column := range models.UserExportColumns {
+		if allowed[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func userField(user *models.User, column string) interface{} {
+	switch column {
+	case "id":
+		return user.ID
+	case "email":
+		return user.Email
+	case "username":
+		return user.Username
+	case "first_name":
+		return user.FirstName
+	case "last_name":
+		return user.LastName
+	case "roles":
+		if user.Roles == nil {
+			return []string{}
+		}
+		return user.Roles
+	case "status":
+		return user.Status
+	case "is_active":
+		return user.Status == models.UserStatusActive
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
+	}
+	return nil
+}
+
+// csvUserEncoder writes a header row, then one row per user. Roles are
+// separated by semicolons, as in import files. Cells a spreadsheet would
+// take for a formula are escaped.
+type csvUserEncoder struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func (e *csvUserEncoder) Begin() error {
+	return e.w.Write(e.columns)
+}
+
+func (e *csvUserEncoder) Encode(user *models.User) error {
+	record := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		switch value := userField(user, column).(type) {
+		case uint:
+			record[i] = strconv.FormatUint(uint64(value), 10)
+		case bool:
+			record[i] = strconv.FormatBool(value)
+		case time.Time:
+			record[i] = value.UTC().Format(time.RFC3339)
+		case []string:
+			record[i] = strings.Join(value, ";")
+		case string:
+			record[i] = value
+		}
+		record[i] = csvCell(record[i])
+	}
+	return e.w.Write(record)
+}
+
+// csvCell prefixes a cell starting with a formula character with a quote,
+// so spreadsheets show it as text instead of evaluating it.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (e *csvUserEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonUserEncoder writes one JSON object per line.
+type ndjsonUserEncoder struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func (e *ndjsonUserEncoder) Begin() error { return nil }
+
+func (e *ndjsonUserEncoder) Encode(user *models.User) error {
+	return e.encoder.Encode(userObject(user, e.columns))
+}
+
+func (e *ndjsonUserEncoder) End() error { return nil }
+
+// jsonUserEncoder writes a JSON array with one object per line.
+type jsonUserEncoder struct {
+	w       io.Writer
+	columns []string
+	count   int
+}
+
+func (e *jsonUserEncoder) Begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonUserEncoder) Encode(user *models.User) error {
+	object, err := json.Marshal(userObject(user, e.columns))
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(object)
+	return err
+}
+
+func (e *jsonUserEncoder) End() error {
+	closing := "\n]\n"
+	if e.count == 0 {
+		closing = "]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+func userObject(user *models.User, columns []string) map[string]interface{} {
+	object := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		object[column] = userField(user, column)
+	}
+	return object
+}
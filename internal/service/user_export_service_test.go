@@ -0,0 +1,228 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/storage"
+)
+
+type MockUserExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserExportRepository) Create(export *models.UserExport) error {
+	args := m.Called(export)
+	return args.Error(0)
+}
+
+func (m *MockUserExportRepository) GetByID(id string) (*models.UserExport, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserExport), args.Error(1)
+}
+
+func (m *MockUserExportRepository) Update(id string, fields map[string]interface{}) error {
+	args := m.Called(id, fields)
+	return args.Error(0)
+}
+
+func (m *MockUserExportRepository) ListByCreator(createdBy string) ([]*models.UserExport, error) {
+	args := m.Called(createdBy)
+	return args.Get(0).([]*models.UserExport), args.Error(1)
+}
+
+func (m *MockUserExportRepository) Delete(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func exportFixtures() []*models.User {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []*models.User{
+		{ID: 1, Email: "alice@example.com", Username: "alice", FirstName: "Alice", Roles: []string{"admin", "user"}, Status: models.UserStatusActive, CreatedAt: created, UpdatedAt: created},
+		{ID: 2, Email: "bob@example.com", Username: "bob", LastName: "Builder, Jr.", Status: models.UserStatusSuspended, CreatedAt: created, UpdatedAt: created},
+	}
+}
+
+func TestUserExportService_Write(t *testing.T) {
+	query := &models.UserListQuery{Role: "user"}
+
+	t.Run("CSV", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserExportService(mockRepo, new(MockUserExportRepository), nil, new(MockLogger))
+		mockRepo.On("Stream", query, mock.Anything).Return(exportFixtures(), nil).Once()
+
+		var out bytes.Buffer
+		count, err := service.Write(context.Background(), query, models.ExportFormatCSV, nil, &out)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+		assert.Equal(t, "id,email,username,first_name,last_name,roles,status,is_active,created_at,updated_at\n"+
+			"1,alice@example.com,alice,Alice,,admin;user,active,true,2024-03-01T12:00:00Z,2024-03-01T12:00:00Z\n"+
+			"2,bob@example.com,bob,,\"Builder, Jr.\",,suspended,false,2024-03-01T12:00:00Z,2024-03-01T12:00:00Z\n", out.String())
+	})
+
+	t.Run("CSV Escapes Formulas", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserExportService(mockRepo, new(MockUserExportRepository), nil, new(MockLogger))
+		users := []*models.User{
+			{ID: 1, FirstName: "=HYPERLINK(\"http://evil\")", LastName: "+1", Roles: []string{"@admin"}},
+			{ID: 2, FirstName: "-2", LastName: "a=b"},
+		}
+		mockRepo.On("Stream", query, mock.Anything).Return(users, nil).Once()
+
+		var out bytes.Buffer
+		_, err := service.Write(context.Background(), query, models.ExportFormatCSV, []string{"id", "first_name", "last_name", "roles"}, &out)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "id,first_name,last_name,roles\n"+
+			"1,\"'=HYPERLINK(\"\"http://evil\"\")\",'+1,'@admin\n"+
+			"2,'-2,a=b,\n", out.String())
+	})
+
+	t.Run("NDJSON Keeps Only Allowed Fields", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserExportService(mockRepo, new(MockUserExportRepository), nil, new(MockLogger))
+		mockRepo.On("Stream", query, mock.Anything).Return(exportFixtures(), nil).Once()
+
+		var out bytes.Buffer
+		_, err := service.Write(context.Background(), query, models.ExportFormatNDJSON, []string{"username", "id", "password"}, &out)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"id\":1,\"username\":\"alice\"}\n{\"id\":2,\"username\":\"bob\"}\n", out.String())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserExportService(mockRepo, new(MockUserExportRepository), nil, new(MockLogger))
+		mockRepo.On("Stream", query, mock.Anything).Return(exportFixtures(), nil).Once()
+
+		var out bytes.Buffer
+		_, err := service.Write(context.Background(), query, models.ExportFormatJSON, []string{"id", "roles"}, &out)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "[\n{\"id\":1,\"roles\":[\"admin\",\"user\"]},\n{\"id\":2,\"roles\":[]}\n]\n", out.String())
+	})
+
+	t.Run("Empty JSON", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserExportService(mockRepo, new(MockUserExportRepository), nil, new(MockLogger))
+		mockRepo.On("Stream", query, mock.Anything).Return(nil, nil).Once()
+
+		var out bytes.Buffer
+		count, err := service.Write(context.Background(), query, models.ExportFormatJSON, nil, &out)
+
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+		assert.Equal(t, "[]\n", out.String())
+	})
+
+	t.Run("Rejects Unknown Format", func(t *testing.T) {
+		service := NewUserExportService(new(MockUserRepository), new(MockUserExportRepository), nil, new(MockLogger))
+
+		_, err := service.Write(context.Background(), query, "xml", nil, io.Discard)
+
+		assert.ErrorIs(t, err, ErrInvalidExport)
+	})
+
+	t.Run("Rejects Fields With No Columns", func(t *testing.T) {
+		service := NewUserExportService(new(MockUserRepository), new(MockUserExportRepository), nil, new(MockLogger))
+
+		_, err := service.Write(context.Background(), query, models.ExportFormatCSV, []string{}, io.Discard)
+
+		assert.ErrorIs(t, err, ErrNoExportableFields)
+	})
+}
+
+func TestUserExportService_Start(t *testing.T) {
+	query := &models.UserListQuery{}
+
+	t.Run("Writes The File In The Background", func(t *testing.T) {
+		files, err := storage.NewLocal(t.TempDir())
+		assert.NoError(t, err)
+
+		mockRepo := new(MockUserRepository)
+		mockExports := new(MockUserExportRepository)
+		service := NewUserExportService(mockRepo, mockExports, files, new(MockLogger))
+
+		mockExports.On("Create", mock.AnythingOfType("*models.UserExport")).Return(nil).Once()
+		mockRepo.On("Stream", mock.Anything, mock.Anything).Return(exportFixtures(), nil).Once()
+		mockExports.On("Update", mock.Anything, mock.MatchedBy(func(fields map[string]interface{}) bool {
+			return fields["status"] == models.ExportStatusCompleted && fields["row_count"] == 2
+		})).Return(nil).Once()
+
+		export, err := service.Start(context.Background(), query, models.ExportFormatNDJSON, []string{"id"}, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, models.ExportStatusRunning, export.Status)
+		service.Wait()
+
+		export.Status = models.ExportStatusCompleted
+		mockExports.On("GetByID", export.ID).Return(export, nil)
+
+		_, file, err := service.Open(context.Background(), export.ID, "1")
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(file)
+		file.Close()
+		assert.Equal(t, "{\"id\":1}\n{\"id\":2}\n", string(content))
+
+		// Exports are private to whoever started them
+		_, _, err = service.Open(context.Background(), export.ID, "2")
+		assert.ErrorIs(t, err, ErrExportNotFound)
+		mockExports.AssertExpectations(t)
+	})
+
+	t.Run("Marks A Failed Export", func(t *testing.T) {
+		dir := t.TempDir()
+		files, err := storage.NewLocal(dir)
+		assert.NoError(t, err)
+
+		mockRepo := new(MockUserRepository)
+		mockExports := new(MockUserExportRepository)
+		service := NewUserExportService(mockRepo, mockExports, files, new(MockLogger))
+
+		mockExports.On("Create", mock.Anything).Return(nil).Once()
+		mockRepo.On("Stream", mock.Anything, mock.Anything).Return(nil, errors.New("connection reset")).Once()
+		mockExports.On("Update", mock.Anything, mock.MatchedBy(func(fields map[string]interface{}) bool {
+			return fields["status"] == models.ExportStatusFailed
+		})).Return(nil).Once()
+
+		export, err := service.Start(context.Background(), query, models.ExportFormatCSV, nil, "1")
+		assert.NoError(t, err)
+		service.Wait()
+
+		_, err = files.Open(export.FileName())
+		assert.Error(t, err)
+		mockExports.AssertExpectations(t)
+	})
+
+	t.Run("Not Ready", func(t *testing.T) {
+		files, err := storage.NewLocal(t.TempDir())
+		assert.NoError(t, err)
+
+		mockExports := new(MockUserExportRepository)
+		service := NewUserExportService(new(MockUserRepository), mockExports, files, new(MockLogger))
+		mockExports.On("GetByID", "job").Return(&models.UserExport{ID: "job", Status: models.ExportStatusRunning, CreatedBy: "1"}, nil)
+
+		_, _, err = service.Open(context.Background(), "job", "1")
+
+		assert.ErrorIs(t, err, ErrExportNotReady)
+	})
+
+	t.Run("Unavailable Without Storage", func(t *testing.T) {
+		service := NewUserExportService(new(MockUserRepository), new(MockUserExportRepository), nil, new(MockLogger))
+
+		_, err := service.Start(context.Background(), query, models.ExportFormatCSV, nil, "1")
+
+		assert.ErrorIs(t, err, ErrExportUnavailable)
+	})
+}
@@ -0,0 +1,507 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/audit"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/utils"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxImportRows caps the number of records in one import file.
+const MaxImportRows = 50000
+
+var (
+	ErrImportNotFound      = errors.New("user import not found")
+	ErrInvalidImport       = errors.New("invalid user import")
+	ErrImportUnavailable   = errors.New("user imports are unavailable")
+	errImportEmailTaken    = errors.New("a user with this email already exists")
+	errImportUsernameTaken = errors.New("username is already taken")
+	errImportStatusChange  = errors.New("is_active cannot change the status of an existing user")
+	errImportUserChanged   = errors.New("the user was changed while the import ran")
+)
+
+// importColumns are the CSV header names an import file may use.
+var importColumns = map[string]bool{
+	"email":      true,
+	"username":   true,
+	"first_name": true,
+	"last_name":  true,
+	"roles":      true,
+	"is_active":  true,
+}
+
+// ImportStarter starts the background workflow that processes an import and
+// returns its workflow ID.
+type ImportStarter interface {
+	StartImport(ctx context.Context, importID string) (string, error)
+}
+
+type UserImportService interface {
+	Start(ctx context.Context, req *models.StartUserImportRequest, file io.Reader, createdBy string) (*models.UserImport, error)
+	Get(ctx context.Context, id string) (*models.UserImportReport, error)
+	ProcessBatch(ctx context.Context, id string, size int, progress func(partial *models.ImportBatchResult)) (*models.ImportBatchResult, error)
+	Finish(ctx context.Context, id string, failure string) (*models.UserImport, error)
+}
+
+type userImportService struct {
+	imports repository.UserImportRepository
+	users   repository.UserRepository
+	starter ImportStarter
+	logger  logger.Logger
+	tracer  trace.Tracer
+}
+
+// NewUserImportService creates the import service. starter may be nil in
+// processes that only run batches, such as the worker; Start then fails with
+// ErrImportUnavailable.
+func NewUserImportService(imports repository.UserImportRepository, users repository.UserRepository, starter ImportStarter, logger logger.Logger) UserImportService {
+	return &userImportService{
+		imports: imports,
+		users:   users,
+		starter: starter,
+		logger:  logger,
+		tracer:  otel.Tracer("user-import-service"),
+	}
+}
+
+// Start parses and validates every record of file, stores them as a new
+// import job and hands the job to the background workflow. Records that fail
+// validation are reported on the job rather than failing the whole upload;
+// only a file that cannot be read at all is rejected with ErrInvalidImport.
+func (s *userImportService) Start(ctx context.Context, req *models.StartUserImportRequest, file io.Reader, createdBy string) (*models.UserImport, error) {
+	ctx, span := s.tracer.Start(ctx, "UserImportService.Start")
+	defer span.End()
+
+	if s.starter == nil {
+		return nil, ErrImportUnavailable
+	}
+
+	switch req.Mode {
+	case models.ImportModeCreate, models.ImportModeUpsert:
+	default:
+		return nil, fmt.Errorf("%w: mode must be %q or %q", ErrInvalidImport, models.ImportModeCreate, models.ImportModeUpsert)
+	}
+
+	var rows []*models.UserImportRow
+	var err error
+	switch req.Format {
+	case models.ImportFormatCSV:
+		rows, err = parseImportCSV(file)
+	case models.ImportFormatNDJSON:
+		rows, err = parseImportNDJSON(file)
+	default:
+		err = fmt.Errorf("format must be %q or %q", models.ImportFormatCSV, models.ImportFormatNDJSON)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the file has no records", ErrInvalidImport)
+	}
+
+	job := &models.UserImport{
+		ID:        uuid.New().String(),
+		Format:    req.Format,
+		Mode:      req.Mode,
+		DryRun:    req.DryRun,
+		Onboard:   req.Onboard && !req.DryRun,
+		Status:    models.ImportStatusPending,
+		TotalRows: len(rows),
+		CreatedBy: createdBy,
+	}
+
+	validateImportRows(rows, req.Mode)
+	for _, row := range rows {
+		row.ImportID = job.ID
+		if row.Status == models.ImportRowFailed {
+			job.Processed++
+			job.Failed++
+		}
+	}
+
+	span.SetAttributes(
+		attribute.String("import.id", job.ID),
+		attribute.Int("import.rows", job.TotalRows),
+		attribute.Int("import.invalid_rows", job.Failed),
+	)
+
+	if err := s.imports.Create(job, rows); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to store import: %w", err)
+	}
+
+	workflowID, err := s.starter.StartImport(ctx, job.ID)
+	if err != nil {
+		span.RecordError(err)
+		s.imports.Update(job.ID, map[string]interface{}{
+			"status": models.ImportStatusFailed,
+			"error":  "failed to start import workflow",
+		})
+		return nil, fmt.Errorf("failed to start import workflow: %w", err)
+	}
+
+	job.Status = models.ImportStatusRunning
+	job.WorkflowID = workflowID
+	if err := s.imports.Update(job.ID, map[string]interface{}{
+		"status":      job.Status,
+		"workflow_id": job.WorkflowID,
+	}); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to update import: %w", err)
+	}
+
+	s.logger.Infof("User import %s started with %d rows (%d invalid)", job.ID, job.TotalRows, job.Failed)
+	return job, nil
+}
+
+func (s *userImportService) Get(ctx context.Context, id string) (*models.UserImportReport, error) {
+	job, err := s.imports.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+	if job == nil {
+		return nil, ErrImportNotFound
+	}
+
+	failed, err := s.imports.FailedRows(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import errors: %w", err)
+	}
+	return job.ToReport(failed), nil
+}
+
+// ProcessBatch applies up to size pending rows of an import, calling progress
+// with the partial result after each row. Rows are marked as they are
+// applied, so a batch that is retried after a crash picks up where it left off.
+func (s *userImportService) ProcessBatch(ctx context.Context, id string, size int, progress func(partial *models.ImportBatchResult)) (*models.ImportBatchResult, error) {
+	ctx, span := s.tracer.Start(ctx, "UserImportService.ProcessBatch")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("import.id", id))
+
+	job, err := s.imports.GetByID(id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+	if job == nil {
+		return nil, ErrImportNotFound
+	}
+
+	rows, err := s.imports.PendingRows(id, size)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get import rows: %w", err)
+	}
+
+	// Imported changes are recorded as made by whoever started the import
+	imports := s.imports.WithContext(audit.WithActor(ctx, audit.Actor{UserID: job.CreatedBy}))
+	result := &models.ImportBatchResult{Done: len(rows) < size}
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		user, err := s.applyRow(job, row)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to import line %d: %w", row.Line, err)
+		}
+		applied, err := imports.ApplyRow(row, user)
+		if err == nil && !applied {
+			// The user was edited after applyRow read it; the row fails
+			// rather than overwriting the edit
+			row.Status, row.Error = models.ImportRowFailed, errImportUserChanged.Error()
+			_, err = imports.ApplyRow(row, nil)
+		}
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to import line %d: %w", row.Line, err)
+		}
+
+		result.Processed++
+		result.LastLine = row.Line
+		if job.Onboard && row.Status == models.ImportRowCreated {
+			result.Created = append(result.Created, models.ImportedUser{
+				ID:       user.ID,
+				Email:    user.Email,
+				Username: user.Username,
+			})
+		}
+		if progress != nil {
+			progress(result)
+		}
+	}
+
+	span.SetAttributes(attribute.Int("import.batch_rows", result.Processed))
+	return result, nil
+}
+
+// applyRow decides what happens to row, setting its status and error, and
+// returns the user to save, or nil if nothing is to be written.
+func (s *userImportService) applyRow(job *models.UserImport, row *models.UserImportRow) (*models.User, error) {
+	record := row.Record
+
+	existing, err := s.users.GetByEmail(record.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	if existing != nil {
+		if job.Mode != models.ImportModeUpsert {
+			row.Status, row.Error = models.ImportRowFailed, errImportEmailTaken.Error()
+			return nil, nil
+		}
+		// Status changes are audited, so they go through the status
+		// endpoints rather than imports
+		if record.IsActive != nil && *record.IsActive != (existing.Status == models.UserStatusActive) {
+			row.Status, row.Error = models.ImportRowFailed, errImportStatusChange.Error()
+			return nil, nil
+		}
+		user = existing
+		row.Status = models.ImportRowUpdated
+	} else {
+		if record.Username == "" {
+			row.Status, row.Error = models.ImportRowFailed, "username is required to create a user"
+			return nil, nil
+		}
+		user = &models.User{
+			Email:    record.Email,
+			Password: utils.UnusablePassword,
+			Status:   models.UserStatusActive,
+		}
+		if record.IsActive != nil && !*record.IsActive {
+			user.Status = models.UserStatusDeactivated
+		}
+		row.Status = models.ImportRowCreated
+	}
+
+	if record.Username != "" && record.Username != user.Username {
+		taken, err := s.users.GetByUsername(record.Username)
+		if err != nil {
+			return nil, err
+		}
+		if taken != nil {
+			row.Status, row.Error = models.ImportRowFailed, errImportUsernameTaken.Error()
+			return nil, nil
+		}
+		user.Username = record.Username
+	}
+	if record.FirstName != "" {
+		user.FirstName = record.FirstName
+	}
+	if record.LastName != "" {
+		user.LastName = record.LastName
+	}
+	if record.Roles != nil {
+		user.Roles = record.Roles
+	}
+
+	if job.DryRun {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// Finish marks an import as completed, or as failed with the reason given
+// in failure.
+func (s *userImportService) Finish(ctx context.Context, id string, failure string) (*models.UserImport, error) {
+	now := time.Now()
+	fields := map[string]interface{}{
+		"status":       models.ImportStatusCompleted,
+		"completed_at": &now,
+	}
+	if failure != "" {
+		fields["status"] = models.ImportStatusFailed
+		fields["error"] = failure
+	}
+
+	if err := s.imports.Update(id, fields); err != nil {
+		return nil, fmt.Errorf("failed to update import: %w", err)
+	}
+
+	job, err := s.imports.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+	if job == nil {
+		return nil, ErrImportNotFound
+	}
+
+	s.logger.Infof("User import %s %s: %d created, %d updated, %d failed", id, job.Status, job.Created, job.Updated, job.Failed)
+	return job, nil
+}
+
+// parseImportCSV reads a CSV file with a header row. Roles are separated by
+// semicolons within their cell.
+func parseImportCSV(file io.Reader) ([]*models.UserImportRow, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "password" {
+			return nil, errors.New("passwords cannot be imported; imported users set theirs during onboarding")
+		}
+		if !importColumns[name] {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("missing email column")
+	}
+
+	var rows []*models.UserImportRow
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("the file has more than %d records", MaxImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := &models.UserImportRow{Line: line, Status: models.ImportRowPending}
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		row.Record = models.ImportRecord{
+			Email:     cell("email"),
+			Username:  cell("username"),
+			FirstName: cell("first_name"),
+			LastName:  cell("last_name"),
+		}
+		if value := cell("roles"); value != "" {
+			for _, role := range strings.Split(value, ";") {
+				row.Record.Roles = append(row.Record.Roles, strings.TrimSpace(role))
+			}
+		}
+		if value := cell("is_active"); value != "" {
+			active, err := strconv.ParseBool(value)
+			if err != nil {
+				row.Status, row.Error = models.ImportRowFailed, "is_active must be true or false"
+			}
+			row.Record.IsActive = &active
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseImportNDJSON reads one JSON object per line. Blank lines are skipped;
+// a line that is not a valid record fails only that row.
+func parseImportNDJSON(file io.Reader) ([]*models.UserImportRow, error) {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []*models.UserImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("the file has more than %d records", MaxImportRows)
+		}
+
+		row := &models.UserImportRow{Line: line, Status: models.ImportRowPending}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.Record); err != nil {
+			row.Status, row.Error = models.ImportRowFailed, "invalid JSON record: "+err.Error()
+		}
+		row.Record.Email = strings.TrimSpace(row.Record.Email)
+		row.Record.Username = strings.TrimSpace(row.Record.Username)
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+// validateImportRows fails rows with invalid fields, and rows repeating an
+// email or username seen earlier in the file.
+func validateImportRows(rows []*models.UserImportRow, mode string) {
+	emails := make(map[string]int)
+	usernames := make(map[string]int)
+
+	for _, row := range rows {
+		if row.Status == models.ImportRowFailed {
+			continue
+		}
+		if err := validateImportRecord(&row.Record, mode); err != nil {
+			row.Status, row.Error = models.ImportRowFailed, err.Error()
+			continue
+		}
+
+		email := strings.ToLower(row.Record.Email)
+		if first, ok := emails[email]; ok {
+			row.Status, row.Error = models.ImportRowFailed, fmt.Sprintf("duplicate email, first seen on line %d", first)
+			continue
+		}
+		emails[email] = row.Line
+
+		if row.Record.Username != "" {
+			if first, ok := usernames[row.Record.Username]; ok {
+				row.Status, row.Error = models.ImportRowFailed, fmt.Sprintf("duplicate username, first seen on line %d", first)
+				continue
+			}
+			usernames[row.Record.Username] = row.Line
+		}
+	}
+}
+
+func validateImportRecord(record *models.ImportRecord, mode string) error {
+	if err := validation.Validate(record); err != nil {
+		return err
+	}
+	// Upserts may leave the username out for existing users; whether a row
+	// creates a user is only known when it is applied
+	if record.Username == "" && mode == models.ImportModeCreate {
+		return errors.New("username is required")
+	}
+	for _, role := range record.Roles {
+		if role == "" {
+			return errors.New("roles must not be empty")
+		}
+	}
+	return nil
+}
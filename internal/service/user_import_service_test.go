@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/utils"
+)
+
+type MockUserImportRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserImportRepository) Create(job *models.UserImport, rows []*models.UserImportRow) error {
+	args := m.Called(job, rows)
+	return args.Error(0)
+}
+
+func (m *MockUserImportRepository) GetByID(id string) (*models.UserImport, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserImport), args.Error(1)
+}
+
+func (m *MockUserImportRepository) Update(id string, fields map[string]interface{}) error {
+	args := m.Called(id, fields)
+	return args.Error(0)
+}
+
+func (m *MockUserImportRepository) PendingRows(id string, limit int) ([]*models.UserImportRow, error) {
+	args := m.Called(id, limit)
+	return args.Get(0).([]*models.UserImportRow), args.Error(1)
+}
+
+func (m *MockUserImportRepository) FailedRows(id string) ([]*models.UserImportRow, error) {
+	args := m.Called(id)
+	return args.Get(0).([]*models.UserImportRow), args.Error(1)
+}
+
+func (m *MockUserImportRepository) ApplyRow(row *models.UserImportRow, user *models.User) (bool, error) {
+	args := m.Called(row, user)
+	if user != nil && user.ID == 0 {
+		user.ID = 100
+	}
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserImportRepository) EraseUser(userID uint, email string) (int64, error) {
+	args := m.Called(userID, email)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserImportRepository) WithContext(ctx context.Context) repository.UserImportRepository {
+	return m
+}
+
+type MockImportStarter struct {
+	mock.Mock
+}
+
+func (m *MockImportStarter) StartImport(ctx context.Context, importID string) (string, error) {
+	args := m.Called(ctx, importID)
+	return args.String(0), args.Error(1)
+}
+
+func TestUserImportService_Start(t *testing.T) {
+	t.Run("Validates Every Row", func(t *testing.T) {
+		mockImports := new(MockUserImportRepository)
+		mockStarter := new(MockImportStarter)
+		service := NewUserImportService(mockImports, new(MockUserRepository), mockStarter, new(MockLogger))
+
+		file := strings.Join([]string{
+			"email,username,first_name,roles,is_active",
+			"alice@example.com,alice,Alice,admin;user,true",
+			"not-an-email,bob,Bob,,",
+			"carol@example.com,ca,Carol,,",
+			"ALICE@example.com,alice2,Alice,,",
+			"dave@example.com,dave,Dave,,maybe",
+			"erin@example.com,admin,Erin,,",
+			"frank@example.com,frank smith,Frank,,",
+		}, "\n")
+
+		var stored []*models.UserImportRow
+		mockImports.On("Create", mock.AnythingOfType("*models.UserImport"), mock.Anything).
+			Run(func(args mock.Arguments) { stored = args.Get(1).([]*models.UserImportRow) }).
+			Return(nil).Once()
+		mockStarter.On("StartImport", mock.Anything, mock.AnythingOfType("string")).Return("user-import-1", nil).Once()
+		mockImports.On("Update", mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+
+		job, err := service.Start(context.Background(), &models.StartUserImportRequest{
+			Format: models.ImportFormatCSV,
+			Mode:   models.ImportModeCreate,
+		}, strings.NewReader(file), "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.ImportStatusRunning, job.Status)
+		assert.Equal(t, "user-import-1", job.WorkflowID)
+		assert.Equal(t, 7, job.TotalRows)
+		assert.Equal(t, 6, job.Failed)
+		assert.Equal(t, 6, job.Processed)
+
+		assert.Len(t, stored, 7)
+		assert.Equal(t, models.ImportRowPending, stored[0].Status)
+		assert.Equal(t, []string{"admin", "user"}, stored[0].Record.Roles)
+		assert.Equal(t, 2, stored[0].Line)
+		assert.Equal(t, "email must be a valid email address", stored[1].Error)
+		assert.Equal(t, "username must be at least 3 characters", stored[2].Error)
+		assert.Equal(t, "duplicate email, first seen on line 2", stored[3].Error)
+		assert.Equal(t, "is_active must be true or false", stored[4].Error)
+		// Usernames follow the same rules as in the API
+		assert.Equal(t, "username is reserved", stored[5].Error)
+		assert.Equal(t, "username may only contain letters, digits, dots, dashes and underscores, and must start with a letter or digit", stored[6].Error)
+		mockStarter.AssertExpectations(t)
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		mockImports := new(MockUserImportRepository)
+		mockStarter := new(MockImportStarter)
+		service := NewUserImportService(mockImports, new(MockUserRepository), mockStarter, new(MockLogger))
+
+		file := "{\"email\":\"alice@example.com\"}\n\n{\"email\":\"bob@example.com\",\"password\":\"x\"}\n{oops\n"
+
+		var stored []*models.UserImportRow
+		mockImports.On("Create", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { stored = args.Get(1).([]*models.UserImportRow) }).
+			Return(nil).Once()
+		mockStarter.On("StartImport", mock.Anything, mock.Anything).Return("user-import-1", nil).Once()
+		mockImports.On("Update", mock.Anything, mock.Anything).Return(nil).Once()
+
+		_, err := service.Start(context.Background(), &models.StartUserImportRequest{
+			Format: models.ImportFormatNDJSON,
+			Mode:   models.ImportModeUpsert,
+		}, strings.NewReader(file), "1")
+
+		assert.NoError(t, err)
+		assert.Len(t, stored, 3)
+		// Usernames are optional when upserting
+		assert.Equal(t, models.ImportRowPending, stored[0].Status)
+		assert.Equal(t, 3, stored[1].Line)
+		assert.Contains(t, stored[1].Error, "unknown field \"password\"")
+		assert.Equal(t, 4, stored[2].Line)
+		assert.Equal(t, models.ImportRowFailed, stored[2].Status)
+	})
+
+	t.Run("Rejects Unreadable Files", func(t *testing.T) {
+		service := NewUserImportService(new(MockUserImportRepository), new(MockUserRepository), new(MockImportStarter), new(MockLogger))
+
+		for name, file := range map[string]string{
+			"password column": "email,username,password\na@example.com,alice,secret\n",
+			"unknown column":  "email,nickname\na@example.com,al\n",
+			"missing email":   "username\nalice\n",
+			"no records":      "email,username\n",
+			"ragged rows":     "email,username\na@example.com\n",
+		} {
+			_, err := service.Start(context.Background(), &models.StartUserImportRequest{
+				Format: models.ImportFormatCSV,
+				Mode:   models.ImportModeCreate,
+			}, strings.NewReader(file), "1")
+			assert.ErrorIs(t, err, ErrInvalidImport, name)
+		}
+
+		_, err := service.Start(context.Background(), &models.StartUserImportRequest{
+			Format: "xlsx",
+			Mode:   models.ImportModeCreate,
+		}, strings.NewReader(""), "1")
+		assert.ErrorIs(t, err, ErrInvalidImport)
+	})
+
+	t.Run("Without Workflows", func(t *testing.T) {
+		mockImports := new(MockUserImportRepository)
+		service := NewUserImportService(mockImports, new(MockUserRepository), nil, new(MockLogger))
+
+		_, err := service.Start(context.Background(), &models.StartUserImportRequest{
+			Format: models.ImportFormatCSV,
+			Mode:   models.ImportModeCreate,
+		}, strings.NewReader("email,username\na@example.com,alice\n"), "1")
+		assert.ErrorIs(t, err, ErrImportUnavailable)
+		mockImports.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Workflow Start Failure", func(t *testing.T) {
+		mockImports := new(MockUserImportRepository)
+		mockStarter := new(MockImportStarter)
+		service := NewUserImportService(mockImports, new(MockUserRepository), mockStarter, new(MockLogger))
+
+		mockImports.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+		mockStarter.On("StartImport", mock.Anything, mock.Anything).Return("", errors.New("unavailable")).Once()
+		mockImports.On("Update", mock.Anything, mock.MatchedBy(func(fields map[string]interface{}) bool {
+			return fields["status"] == models.ImportStatusFailed
+		})).Return(nil).Once()
+
+		_, err := service.Start(context.Background(), &models.StartUserImportRequest{
+			Format: models.ImportFormatCSV,
+			Mode:   models.ImportModeCreate,
+		}, strings.NewReader("email,username\na@example.com,alice\n"), "1")
+		assert.Error(t, err)
+		mockImports.AssertExpectations(t)
+	})
+}
+
+func TestUserImportService_ProcessBatch(t *testing.T) {
+	inactive := false
+	pending := func() []*models.UserImportRow {
+		return []*models.UserImportRow{
+			{ImportID: "job", Line: 2, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "new@example.com", Username: "newbie", IsActive: &inactive}},
+			{ImportID: "job", Line: 3, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "old@example.com", Username: "oldie", LastName: "Renamed"}},
+			{ImportID: "job", Line: 4, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "taken@example.com", Username: "alice"}},
+		}
+	}
+	setup := func(job *models.UserImport) (*MockUserImportRepository, UserImportService) {
+		existing := &models.User{ID: 7, Email: "old@example.com", Username: "old", FirstName: "Old", LastName: "Name", Status: models.UserStatusActive}
+		mockImports := new(MockUserImportRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewUserImportService(mockImports, mockUsers, nil, new(MockLogger))
+
+		mockImports.On("GetByID", "job").Return(job, nil)
+		mockImports.On("PendingRows", "job", 10).Return(pending(), nil).Once()
+		mockUsers.On("GetByEmail", "new@example.com").Return(nil, nil)
+		mockUsers.On("GetByEmail", "old@example.com").Return(existing, nil)
+		mockUsers.On("GetByEmail", "taken@example.com").Return(nil, nil)
+		mockUsers.On("GetByUsername", "newbie").Return(nil, nil)
+		mockUsers.On("GetByUsername", "oldie").Return(nil, nil)
+		mockUsers.On("GetByUsername", "alice").Return(&models.User{ID: 1}, nil)
+		return mockImports, service
+	}
+
+	t.Run("Create Mode", func(t *testing.T) {
+		mockImports, service := setup(&models.UserImport{ID: "job", Mode: models.ImportModeCreate, Onboard: true})
+
+		mockImports.On("ApplyRow", mock.MatchedBy(func(row *models.UserImportRow) bool { return row.Line == 2 }), mock.MatchedBy(func(user *models.User) bool {
+			return user.Email == "new@example.com" && user.Status == models.UserStatusDeactivated && user.Password == utils.UnusablePassword
+		})).Return(true, nil).Once()
+		mockImports.On("ApplyRow", mock.Anything, (*models.User)(nil)).Return(true, nil).Twice()
+
+		var heartbeats []int
+		result, err := service.ProcessBatch(context.Background(), "job", 10, func(partial *models.ImportBatchResult) {
+			heartbeats = append(heartbeats, partial.LastLine)
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, result.Done)
+		assert.Equal(t, 3, result.Processed)
+		assert.Equal(t, []int{2, 3, 4}, heartbeats)
+		assert.Equal(t, []models.ImportedUser{{ID: 100, Email: "new@example.com", Username: "newbie"}}, result.Created)
+		mockImports.AssertExpectations(t)
+
+		rows := mockImports.Calls
+		assert.Equal(t, models.ImportRowFailed, rows[len(rows)-2].Arguments.Get(0).(*models.UserImportRow).Status)
+		assert.Equal(t, "a user with this email already exists", rows[len(rows)-2].Arguments.Get(0).(*models.UserImportRow).Error)
+		assert.Equal(t, "username is already taken", rows[len(rows)-1].Arguments.Get(0).(*models.UserImportRow).Error)
+	})
+
+	t.Run("Upsert Mode", func(t *testing.T) {
+		mockImports, service := setup(&models.UserImport{ID: "job", Mode: models.ImportModeUpsert})
+
+		mockImports.On("ApplyRow", mock.Anything, mock.MatchedBy(func(user *models.User) bool { return user.ID == 0 })).Return(true, nil).Once()
+		mockImports.On("ApplyRow", mock.MatchedBy(func(row *models.UserImportRow) bool {
+			return row.Status == models.ImportRowUpdated
+		}), mock.MatchedBy(func(user *models.User) bool {
+			return user.ID == 7 && user.Username == "oldie" && user.FirstName == "Old" && user.LastName == "Renamed"
+		})).Return(true, nil).Once()
+		mockImports.On("ApplyRow", mock.Anything, (*models.User)(nil)).Return(true, nil).Once()
+
+		result, err := service.ProcessBatch(context.Background(), "job", 10, nil)
+
+		assert.NoError(t, err)
+		// Onboarding was not requested
+		assert.Empty(t, result.Created)
+		mockImports.AssertExpectations(t)
+	})
+
+	t.Run("Upsert Cannot Change Status", func(t *testing.T) {
+		mockImports := new(MockUserImportRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewUserImportService(mockImports, mockUsers, nil, new(MockLogger))
+
+		mockImports.On("GetByID", "job").Return(&models.UserImport{ID: "job", Mode: models.ImportModeUpsert}, nil)
+		mockImports.On("PendingRows", "job", 10).Return([]*models.UserImportRow{
+			{ImportID: "job", Line: 2, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "old@example.com", IsActive: &inactive}},
+		}, nil).Once()
+		mockUsers.On("GetByEmail", "old@example.com").Return(&models.User{ID: 7, Email: "old@example.com", Status: models.UserStatusActive}, nil)
+		mockImports.On("ApplyRow", mock.MatchedBy(func(row *models.UserImportRow) bool {
+			return row.Status == models.ImportRowFailed && row.Error == "is_active cannot change the status of an existing user"
+		}), (*models.User)(nil)).Return(true, nil).Once()
+
+		_, err := service.ProcessBatch(context.Background(), "job", 10, nil)
+
+		assert.NoError(t, err)
+		mockImports.AssertExpectations(t)
+	})
+
+	t.Run("Upsert Fails When The User Changed", func(t *testing.T) {
+		mockImports := new(MockUserImportRepository)
+		mockUsers := new(MockUserRepository)
+		service := NewUserImportService(mockImports, mockUsers, nil, new(MockLogger))
+
+		mockImports.On("GetByID", "job").Return(&models.UserImport{ID: "job", Mode: models.ImportModeUpsert}, nil)
+		mockImports.On("PendingRows", "job", 10).Return([]*models.UserImportRow{
+			{ImportID: "job", Line: 2, Status: models.ImportRowPending, Record: models.ImportRecord{Email: "old@example.com", LastName: "Renamed"}},
+		}, nil).Once()
+		mockUsers.On("GetByEmail", "old@example.com").Return(&models.User{ID: 7, Email: "old@example.com", Status: models.UserStatusActive, Version: 3}, nil)
+		mockImports.On("ApplyRow", mock.Anything, mock.MatchedBy(func(user *models.User) bool { return user.ID == 7 })).Return(false, nil).Once()
+		mockImports.On("ApplyRow", mock.MatchedBy(func(row *models.UserImportRow) bool {
+			return row.Status == models.ImportRowFailed && row.Error == "the user was changed while the import ran"
+		}), (*models.User)(nil)).Return(true, nil).Once()
+
+		result, err := service.ProcessBatch(context.Background(), "job", 10, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Processed)
+		mockImports.AssertExpectations(t)
+	})
+
+	t.Run("Dry Run", func(t *testing.T) {
+		mockImports, service := setup(&models.UserImport{ID: "job", Mode: models.ImportModeUpsert, DryRun: true})
+
+		mockImports.On("ApplyRow", mock.Anything, (*models.User)(nil)).Return(true, nil).Times(3)
+
+		result, err := service.ProcessBatch(context.Background(), "job", 10, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result.Processed)
+		mockImports.AssertExpectations(t)
+		assert.Equal(t, models.ImportRowCreated, mockImports.Calls[2].Arguments.Get(0).(*models.UserImportRow).Status)
+		assert.Equal(t, models.ImportRowUpdated, mockImports.Calls[3].Arguments.Get(0).(*models.UserImportRow).Status)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockImports := new(MockUserImportRepository)
+		service := NewUserImportService(mockImports, new(MockUserRepository), nil, new(MockLogger))
+
+		mockImports.On("GetByID", "missing").Return(nil, nil).Once()
+
+		_, err := service.ProcessBatch(context.Background(), "missing", 10, nil)
+		assert.ErrorIs(t, err, ErrImportNotFound)
+	})
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/jsonpatch"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/utils"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/validation"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
@@ -20,28 +23,55 @@ var (
 	ErrUserNotFound     = errors.New("user not found")
 	ErrUserAlreadyExists = errors.New("user already exists")
 	ErrInvalidPassword  = errors.New("invalid password")
+	ErrUserNotDeleted   = errors.New("user is not deleted")
+	// ErrVersionMismatch means the user has been modified since the version
+	// the caller read.
+	ErrVersionMismatch = errors.New("user version does not match")
+	// ErrInvalidPatch means a patch document is malformed.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrUnprocessablePatch means a patch does not fit the user or would
+	// leave it invalid.
+	ErrUnprocessablePatch = errors.New("patch cannot be applied")
+	// ErrPatchTestFailed means a JSON Patch test operation did not match.
+	ErrPatchTestFailed = errors.New("patch test failed")
+	// ErrBatchFailed means an atomic batch update was rolled back because
+	// some of its updates failed.
+	ErrBatchFailed = errors.New("batch update failed")
+	// ErrInvalidRole means a role name is empty.
+	ErrInvalidRole = errors.New("invalid role")
 )
 
 type UserService interface {
 	Create(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error)
-	GetAll(ctx context.Context, page, pageSize int) ([]*models.UserResponse, int64, error)
+	GetAll(ctx context.Context, query *models.UserListQuery) ([]*models.UserResponse, int64, error)
+	GetPage(ctx context.Context, query *models.UserListQuery) (*models.UserPage, error)
+	GetBatch(ctx context.Context, query *models.UserListQuery) (*models.UserBatch, error)
 	GetByID(ctx context.Context, id uint) (*models.UserResponse, error)
+	GetAsOf(ctx context.Context, id uint, at time.Time) (*models.UserResponse, error)
+	History(ctx context.Context, id uint) ([]*models.UserRevision, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
-	Update(ctx context.Context, id uint, req *models.UpdateUserRequest) (*models.UserResponse, error)
-	Delete(ctx context.Context, id uint) error
+	Update(ctx context.Context, id uint, version uint, req *models.UpdateUserRequest) (*models.UserResponse, error)
+	Patch(ctx context.Context, id uint, version uint, format string, patch []byte) (*models.UserResponse, error)
+	Delete(ctx context.Context, id uint, version uint) error
+	Restore(ctx context.Context, id uint) (*models.UserResponse, error)
+	Purge(ctx context.Context, id uint) error
+	BatchUpdate(ctx context.Context, req *models.BatchUpdateUsersRequest, by string) (*models.BatchUpdateResult, error)
 	CreateUser(user *models.User) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 }
 
 type userService struct {
 	repo            repository.UserRepository
+	attributes      UserAttributeService
 	logger          logger.Logger
 	tracer          trace.Tracer
 	userCounter     metric.Int64Counter
 	requestDuration metric.Float64Histogram
 }
 
-func NewUserService(repo repository.UserRepository, logger logger.Logger) UserService {
+// NewUserService returns a UserService. Attributes are written unchecked if
+// attributes is nil.
+func NewUserService(repo repository.UserRepository, attributes UserAttributeService, logger logger.Logger) UserService {
 	meter := otel.Meter("user-service")
 	
 	userCounter, _ := meter.Int64Counter(
@@ -56,6 +86,7 @@ func NewUserService(repo repository.UserRepository, logger logger.Logger) UserSe
 	
 	return &userService{
 		repo:            repo,
+		attributes:      attributes,
 		logger:          logger,
 		tracer:          otel.Tracer("user-service"),
 		userCounter:     userCounter,
@@ -99,6 +130,10 @@ func (s *userService) Create(ctx context.Context, req *models.CreateUserRequest)
 		return nil, ErrUserAlreadyExists
 	}
 
+	if err := s.validateAttributes(ctx, req.Attributes); err != nil {
+		return nil, err
+	}
+
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		span.RecordError(err)
@@ -106,16 +141,17 @@ func (s *userService) Create(ctx context.Context, req *models.CreateUserRequest)
 	}
 
 	user := &models.User{
-		Email:     req.Email,
-		Username:  req.Username,
-		Password:  hashedPassword,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Roles:     req.Roles,
-		IsActive:  true,
+		Email:      req.Email,
+		Username:   req.Username,
+		Password:   hashedPassword,
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		Roles:      req.Roles,
+		Status:     models.UserStatusActive,
+		Attributes: req.Attributes,
 	}
 
-	if err := s.repo.Create(user); err != nil {
+	if err := s.repo.WithContext(ctx).Create(user); err != nil {
 		span.RecordError(err)
 		s.logger.Errorf("Failed to create user: %v", err)
 		return nil, fmt.Errorf("failed to create user: %w", err)
@@ -130,16 +166,18 @@ func (s *userService) Create(ctx context.Context, req *models.CreateUserRequest)
 	return user.ToResponse(), nil
 }
 
-func (s *userService) GetAll(ctx context.Context, page, pageSize int) ([]*models.UserResponse, int64, error) {
+func (s *userService) GetAll(ctx context.Context, query *models.UserListQuery) ([]*models.UserResponse, int64, error) {
 	ctx, span := s.tracer.Start(ctx, "UserService.GetAll")
 	defer span.End()
 
 	span.SetAttributes(
-		attribute.Int("pagination.page", page),
-		attribute.Int("pagination.page_size", pageSize),
+		attribute.Int("pagination.page", query.Page),
+		attribute.Int("pagination.page_size", query.PageSize),
+		attribute.Bool("authz.row_filter", query.RowFilter != nil && !query.RowFilter.Unrestricted),
+		attribute.Bool("users.search", query.Search != ""),
 	)
 
-	users, total, err := s.repo.GetAll(page, pageSize)
+	users, total, err := s.repo.GetAll(query)
 	if err != nil {
 		span.RecordError(err)
 		return nil, 0, fmt.Errorf("failed to get users: %w", err)
@@ -158,6 +196,102 @@ func (s *userService) GetAll(ctx context.Context, page, pageSize int) ([]*models
 	return responses, total, nil
 }
 
+// GetPage returns the page of users at query.Cursor (the first page if nil)
+// with cursors for the pages on either side. It never counts rows unless
+// query.IncludeTotal is set.
+func (s *userService) GetPage(ctx context.Context, query *models.UserListQuery) (*models.UserPage, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetPage")
+	defer span.End()
+
+	backward := query.Cursor != nil && query.Cursor.Backward
+	span.SetAttributes(
+		attribute.Int("pagination.limit", query.Limit),
+		attribute.Bool("pagination.cursor", query.Cursor != nil),
+		attribute.Bool("pagination.backward", backward),
+		attribute.Bool("authz.row_filter", query.RowFilter != nil && !query.RowFilter.Unrestricted),
+	)
+
+	users, err := s.repo.GetPage(query)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	more := len(users) > query.Limit
+	if more {
+		users = users[:query.Limit]
+	}
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	page := &models.UserPage{Users: make([]*models.UserResponse, len(users))}
+	for i, user := range users {
+		page.Users[i] = user.ToResponse()
+	}
+
+	if len(users) > 0 {
+		first, last := users[0], users[len(users)-1]
+		// Going forward, there is a previous page whenever we started from a
+		// cursor; going backward, there is always a next page.
+		if more || backward {
+			page.NextCursor = models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID, Desc: query.Desc}.Encode()
+		}
+		if (backward && more) || (!backward && query.Cursor != nil) {
+			page.PrevCursor = models.Cursor{CreatedAt: first.CreatedAt, ID: first.ID, Desc: query.Desc, Backward: true}.Encode()
+		}
+	}
+
+	if query.IncludeTotal {
+		total, err := s.repo.Count(query)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to count users: %w", err)
+		}
+		page.Total = &total
+	}
+
+	span.SetAttributes(attribute.Int("users.count", len(users)))
+	return page, nil
+}
+
+// GetBatch returns the users with query.IDs that pass query's filters, in
+// the order the IDs were given, and lists the IDs it did not find. Users the
+// row filter hides are reported as missing, like deleted ones.
+func (s *userService) GetBatch(ctx context.Context, query *models.UserListQuery) (*models.UserBatch, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetBatch")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("batch.size", len(query.IDs)),
+		attribute.Bool("authz.row_filter", query.RowFilter != nil && !query.RowFilter.Unrestricted),
+	)
+
+	found := make(map[uint]*models.UserResponse, len(query.IDs))
+	err := s.repo.Stream(query, func(user *models.User) error {
+		found[user.ID] = user.ToResponse()
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	batch := &models.UserBatch{Users: []*models.UserResponse{}, Missing: []uint{}}
+	for _, id := range query.IDs {
+		if user, ok := found[id]; ok {
+			batch.Users = append(batch.Users, user)
+		} else {
+			batch.Missing = append(batch.Missing, id)
+		}
+	}
+
+	span.SetAttributes(attribute.Int("batch.missing", len(batch.Missing)))
+	return batch, nil
+}
+
 func (s *userService) GetByID(ctx context.Context, id uint) (*models.UserResponse, error) {
 	ctx, span := s.tracer.Start(ctx, "UserService.GetByID")
 	defer span.End()
@@ -177,6 +311,54 @@ func (s *userService) GetByID(ctx context.Context, id uint) (*models.UserRespons
 	return user.ToResponse(), nil
 }
 
+// GetAsOf returns the user as it was at at, from its revisions. Users that
+// did not exist yet, or were deleted, at at are not found, and so are users
+// whose state at at predates their history.
+func (s *userService) GetAsOf(ctx context.Context, id uint, at time.Time) (*models.UserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetAsOf")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)), attribute.String("user.as_of", at.Format(time.RFC3339)))
+
+	revision, err := s.repo.GetRevisionAt(id, at)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user revision: %w", err)
+	}
+	if revision == nil || revision.Operation == models.RevisionDelete {
+		span.SetAttributes(attribute.Bool("user.not_found", true))
+		return nil, ErrUserNotFound
+	}
+
+	return revision.User, nil
+}
+
+// History returns the user's revisions, oldest first. Deleted users keep
+// their history until they are purged or erased.
+func (s *userService) History(ctx context.Context, id uint) ([]*models.UserRevision, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.History")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)))
+
+	user, err := s.repo.GetByIDUnscoped(id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		span.SetAttributes(attribute.Bool("user.not_found", true))
+		return nil, ErrUserNotFound
+	}
+
+	revisions, err := s.repo.ListRevisions(id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to list user revisions: %w", err)
+	}
+	return revisions, nil
+}
+
 func (s *userService) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	ctx, span := s.tracer.Start(ctx, "UserService.GetByEmail")
 	defer span.End()
@@ -196,7 +378,10 @@ func (s *userService) GetByEmail(ctx context.Context, email string) (*models.Use
 	return user, nil
 }
 
-func (s *userService) Update(ctx context.Context, id uint, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+// Update changes the non-empty fields of req. A non-zero version is the
+// version the caller last read; the update fails with ErrVersionMismatch if
+// the user has changed since.
+func (s *userService) Update(ctx context.Context, id uint, version uint, req *models.UpdateUserRequest) (*models.UserResponse, error) {
 	ctx, span := s.tracer.Start(ctx, "UserService.Update")
 	defer span.End()
 
@@ -210,30 +395,22 @@ func (s *userService) Update(ctx context.Context, id uint, req *models.UpdateUse
 		span.SetAttributes(attribute.Bool("user.not_found", true))
 		return nil, ErrUserNotFound
 	}
-
-	if req.Email != "" && req.Email != user.Email {
-		existingUser, err := s.repo.GetByEmail(req.Email)
-		if err != nil {
-			span.RecordError(err)
-			return nil, fmt.Errorf("failed to check existing email: %w", err)
-		}
-		if existingUser != nil && existingUser.ID != id {
-			return nil, ErrUserAlreadyExists
-		}
-		user.Email = req.Email
+	if version != 0 && version != user.Version {
+		return nil, ErrVersionMismatch
 	}
 
-	if req.Username != "" && req.Username != user.Username {
-		existingUser, err := s.repo.GetByUsername(req.Username)
-		if err != nil {
-			span.RecordError(err)
-			return nil, fmt.Errorf("failed to check existing username: %w", err)
-		}
-		if existingUser != nil && existingUser.ID != id {
-			return nil, ErrUserAlreadyExists
-		}
-		user.Username = req.Username
+	email, username := user.Email, user.Username
+	if req.Email != "" {
+		email = req.Email
 	}
+	if req.Username != "" {
+		username = req.Username
+	}
+	if err := s.checkAvailable(user, email, username); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	user.Email, user.Username = email, username
 
 	if req.FirstName != "" {
 		user.FirstName = req.FirstName
@@ -241,21 +418,142 @@ func (s *userService) Update(ctx context.Context, id uint, req *models.UpdateUse
 	if req.LastName != "" {
 		user.LastName = req.LastName
 	}
-	if req.IsActive != nil {
-		user.IsActive = *req.IsActive
+	if req.Attributes != nil {
+		if err := s.validateAttributes(ctx, req.Attributes); err != nil {
+			return nil, err
+		}
+		user.Attributes = req.Attributes
 	}
 
-	if err := s.repo.Update(user); err != nil {
+	saved, err := s.repo.WithContext(ctx).Update(user)
+	if err != nil {
 		span.RecordError(err)
 		s.logger.Errorf("Failed to update user: %v", err)
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
+	if !saved {
+		return nil, ErrVersionMismatch
+	}
 
 	s.logger.Infof("User updated successfully: %s", user.Email)
 	return user.ToResponse(), nil
 }
 
-func (s *userService) Delete(ctx context.Context, id uint) error {
+// Patch applies a JSON Merge Patch or JSON Patch to the patchable fields of
+// a user. Unlike Update, a patch can clear a field, so the patched user is
+// validated as a whole before it is saved.
+func (s *userService) Patch(ctx context.Context, id uint, version uint, format string, patch []byte) (*models.UserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.Patch")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)), attribute.String("patch.format", format))
+	user, err := s.repo.GetByID(id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		span.SetAttributes(attribute.Bool("user.not_found", true))
+		return nil, ErrUserNotFound
+	}
+	if version != 0 && version != user.Version {
+		return nil, ErrVersionMismatch
+	}
+
+	var patched map[string]interface{}
+	switch format {
+	case models.PatchFormatMerge:
+		patched, err = jsonpatch.MergePatch(models.NewPatchableUser(user).Document(), patch)
+	case models.PatchFormatJSON:
+		patched, err = jsonpatch.Apply(models.NewPatchableUser(user).Document(), patch)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidPatch, format)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, jsonpatch.ErrInvalidPatch):
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			return nil, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnprocessablePatch, err)
+	}
+
+	fields, err := models.DecodePatchableUser(patched)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnprocessablePatch, err)
+	}
+	// Email and username are required, so they cannot be cleared
+	if err := validation.Validate(fields); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnprocessablePatch, err)
+	}
+
+	// Attributes are only checked if the patch changed them, so a schema
+	// published since they were written doesn't block unrelated patches
+	if !reflect.DeepEqual(fields.Attributes, user.Attributes) {
+		if err := s.validateAttributes(ctx, fields.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.checkAvailable(user, *fields.Email, *fields.Username); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	fields.ApplyTo(user)
+
+	saved, err := s.repo.WithContext(ctx).Update(user)
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Errorf("Failed to patch user: %v", err)
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if !saved {
+		return nil, ErrVersionMismatch
+	}
+
+	s.logger.Infof("User patched successfully: %s", user.Email)
+	return user.ToResponse(), nil
+}
+
+// validateAttributes checks attributes against the attribute schema, if
+// there is an attribute service to check them with.
+func (s *userService) validateAttributes(ctx context.Context, attributes map[string]interface{}) error {
+	if s.attributes == nil {
+		return nil
+	}
+	return s.attributes.Validate(ctx, attributes)
+}
+
+// checkAvailable makes sure no other user already has the email or username
+// that user is about to take.
+func (s *userService) checkAvailable(user *models.User, email, username string) error {
+	if email != user.Email {
+		existingUser, err := s.repo.GetByEmail(email)
+		if err != nil {
+			return fmt.Errorf("failed to check existing email: %w", err)
+		}
+		if existingUser != nil && existingUser.ID != user.ID {
+			return ErrUserAlreadyExists
+		}
+	}
+
+	if username != user.Username {
+		existingUser, err := s.repo.GetByUsername(username)
+		if err != nil {
+			return fmt.Errorf("failed to check existing username: %w", err)
+		}
+		if existingUser != nil && existingUser.ID != user.ID {
+			return ErrUserAlreadyExists
+		}
+	}
+
+	return nil
+}
+
+// Delete soft-deletes a user. A non-zero version must match the user's
+// current version, as for Update.
+func (s *userService) Delete(ctx context.Context, id uint, version uint) error {
 	ctx, span := s.tracer.Start(ctx, "UserService.Delete")
 	defer span.End()
 
@@ -269,17 +567,238 @@ func (s *userService) Delete(ctx context.Context, id uint) error {
 		span.SetAttributes(attribute.Bool("user.not_found", true))
 		return ErrUserNotFound
 	}
+	if version != 0 && version != user.Version {
+		return ErrVersionMismatch
+	}
 
-	if err := s.repo.Delete(id); err != nil {
+	deleted, err := s.repo.WithContext(ctx).Delete(user)
+	if err != nil {
 		span.RecordError(err)
 		s.logger.Errorf("Failed to delete user: %v", err)
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	if !deleted {
+		return ErrVersionMismatch
+	}
 
 	s.logger.Infof("User deleted successfully: %s", user.Email)
 	return nil
 }
 
+// Restore brings back a soft-deleted user. It fails with
+// ErrUserAlreadyExists if the email or username has since been taken.
+func (s *userService) Restore(ctx context.Context, id uint) (*models.UserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.Restore")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)))
+	user, err := s.repo.GetByIDUnscoped(id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		span.SetAttributes(attribute.Bool("user.not_found", true))
+		return nil, ErrUserNotFound
+	}
+	if !user.DeletedAt.Valid {
+		return nil, ErrUserNotDeleted
+	}
+
+	existingUser, err := s.repo.GetByEmail(user.Email)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to check existing email: %w", err)
+	}
+	if existingUser == nil {
+		existingUser, err = s.repo.GetByUsername(user.Username)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to check existing username: %w", err)
+		}
+	}
+	if existingUser != nil {
+		span.SetAttributes(attribute.Bool("user.already_exists", true))
+		return nil, ErrUserAlreadyExists
+	}
+
+	if err := s.repo.WithContext(ctx).Restore(id); err != nil {
+		span.RecordError(err)
+		s.logger.Errorf("Failed to restore user: %v", err)
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	user.DeletedAt.Valid = false
+	s.logger.Infof("User restored successfully: %s", user.Email)
+	return user.ToResponse(), nil
+}
+
+// Purge permanently removes a user, deleted or not.
+func (s *userService) Purge(ctx context.Context, id uint) error {
+	ctx, span := s.tracer.Start(ctx, "UserService.Purge")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)))
+	user, err := s.repo.GetByIDUnscoped(id)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		span.SetAttributes(attribute.Bool("user.not_found", true))
+		return ErrUserNotFound
+	}
+
+	if err := s.repo.WithContext(ctx).Purge(id); err != nil {
+		span.RecordError(err)
+		s.logger.Errorf("Failed to purge user: %v", err)
+		return fmt.Errorf("failed to purge user: %w", err)
+	}
+
+	s.logger.Infof("User purged: %d", id)
+	return nil
+}
+
+// BatchUpdate applies req's updates in one transaction. In atomic mode, the
+// default, any failed update rolls the whole batch back and BatchUpdate
+// returns ErrBatchFailed along with the result; in partial mode each update
+// is applied on its own and the failures are only reported in the result.
+// Errors other than those of single updates abort the batch in either mode.
+// Status changes are recorded as made by by.
+func (s *userService) BatchUpdate(ctx context.Context, req *models.BatchUpdateUsersRequest, by string) (*models.BatchUpdateResult, error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.BatchUpdate")
+	defer span.End()
+
+	mode := req.Mode
+	if mode == "" {
+		mode = models.BatchModeAtomic
+	}
+	span.SetAttributes(attribute.String("batch.mode", mode), attribute.Int("batch.size", len(req.Updates)))
+
+	result := &models.BatchUpdateResult{Mode: mode, Items: make([]*models.BatchUpdateItem, 0, len(req.Updates))}
+	err := s.repo.WithContext(ctx).Transaction(func(repo repository.UserRepository) error {
+		for i := range req.Updates {
+			update := &req.Updates[i]
+			item := &models.BatchUpdateItem{ID: update.ID}
+			result.Items = append(result.Items, item)
+
+			if mode == models.BatchModeAtomic {
+				item.User, item.Error = s.applyBatchUpdate(repo, update, by)
+			} else {
+				// A savepoint per update, so a failed one leaves the rest
+				err := repo.Transaction(func(repo repository.UserRepository) error {
+					item.User, item.Error = s.applyBatchUpdate(repo, update, by)
+					return item.Error
+				})
+				if err != nil && item.Error == nil {
+					return err
+				}
+			}
+			if item.Error != nil && !isBatchItemError(item.Error) {
+				return item.Error
+			}
+		}
+		if mode == models.BatchModeAtomic && result.Failed() > 0 {
+			return ErrBatchFailed
+		}
+		return nil
+	})
+
+	failed := result.Failed()
+	span.SetAttributes(attribute.Int("batch.failed", failed))
+	switch {
+	case errors.Is(err, ErrBatchFailed):
+		// Updates that succeeded were rolled back with the rest
+		for _, item := range result.Items {
+			item.User = nil
+		}
+		return result, ErrBatchFailed
+	case err != nil:
+		span.RecordError(err)
+		s.logger.Errorf("Failed to update users: %v", err)
+		return nil, fmt.Errorf("failed to update users: %w", err)
+	}
+
+	result.Applied = true
+	s.logger.Infof("Batch update applied: %d updates, %d failed", len(result.Items), failed)
+	return result, nil
+}
+
+// applyBatchUpdate applies one update of a batch through repo.
+func (s *userService) applyBatchUpdate(repo repository.UserRepository, update *models.BatchUserUpdate, by string) (*models.UserResponse, error) {
+	user, err := repo.GetByID(update.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if update.Version != 0 && update.Version != user.Version {
+		return nil, ErrVersionMismatch
+	}
+
+	roles, err := batchRoles(user.Roles, update)
+	if err != nil {
+		return nil, err
+	}
+	user.Roles = roles
+
+	if update.Status != "" && update.Status != user.Status {
+		// Saves the roles along with the status
+		if err := changeStatus(repo, user, update.Status, update.Reason, nil, by); err != nil {
+			return nil, err
+		}
+		return user.ToResponse(), nil
+	}
+
+	saved, err := repo.Update(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if !saved {
+		return nil, ErrVersionMismatch
+	}
+	return user.ToResponse(), nil
+}
+
+// batchRoles returns the roles a user ends up with after update: its Roles,
+// if set, in place of current, plus AddRoles, minus RemoveRoles.
+func batchRoles(current []string, update *models.BatchUserUpdate) ([]string, error) {
+	for _, list := range [][]string{update.Roles, update.AddRoles, update.RemoveRoles} {
+		if err := checkRoles(list); err != nil {
+			return nil, err
+		}
+	}
+
+	base := current
+	if update.Roles != nil {
+		base = update.Roles
+	}
+	removed := make(map[string]bool, len(update.RemoveRoles))
+	for _, role := range update.RemoveRoles {
+		removed[role] = true
+	}
+
+	roles := []string{}
+	seen := map[string]bool{}
+	for _, list := range [][]string{base, update.AddRoles} {
+		for _, role := range list {
+			if !removed[role] && !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles, nil
+}
+
+// isBatchItemError reports whether err is a failure of a single update,
+// reported in the batch result, rather than one that aborts the batch.
+func isBatchItemError(err error) bool {
+	return errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrVersionMismatch) || errors.Is(err, ErrInvalidRole) ||
+		errors.Is(err, ErrInvalidStatusTransition)
+}
+
 func (s *userService) CreateUser(user *models.User) (*models.User, error) {
 	if err := s.repo.Create(user); err != nil {
 		s.logger.Errorf("Failed to create user: %v", err)
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
 	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"gorm.io/gorm"
 )
 
 type MockUserRepository struct {
@@ -20,11 +23,33 @@ func (m *MockUserRepository) Create(user *models.User) error {
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) GetAll(page, pageSize int) ([]*models.User, int64, error) {
-	args := m.Called(page, pageSize)
+func (m *MockUserRepository) GetAll(query *models.UserListQuery) ([]*models.User, int64, error) {
+	args := m.Called(query)
 	return args.Get(0).([]*models.User), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockUserRepository) GetPage(query *models.UserListQuery) ([]*models.User, error) {
+	args := m.Called(query)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Count(query *models.UserListQuery) (int64, error) {
+	args := m.Called(query)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) Stream(query *models.UserListQuery, fn func(*models.User) error) error {
+	args := m.Called(query, fn)
+	if users, ok := args.Get(0).([]*models.User); ok {
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
 func (m *MockUserRepository) GetByID(id uint) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -33,6 +58,11 @@ func (m *MockUserRepository) GetByID(id uint) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetByIDs(ids []uint) ([]*models.User, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
 	args := m.Called(email)
 	if args.Get(0) == nil {
@@ -49,16 +79,89 @@ func (m *MockUserRepository) GetByUsername(username string) (*models.User, error
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Update(user *models.User) error {
+func (m *MockUserRepository) GetByIDUnscoped(id uint) (*models.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Update(user *models.User) (bool, error) {
+	args := m.Called(user)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) Delete(user *models.User) (bool, error) {
 	args := m.Called(user)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) Restore(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Purge(id uint) error {
+	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id uint) error {
+func (m *MockUserRepository) Anonymize(id uint) (int64, error) {
 	args := m.Called(id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// Transaction runs fn against the mock itself; rollbacks are not simulated.
+func (m *MockUserRepository) Transaction(fn func(repo repository.UserRepository) error) error {
+	m.Called()
+	return fn(m)
+}
+
+func (m *MockUserRepository) CreateStatusTransition(transition *models.UserStatusTransition) error {
+	args := m.Called(transition)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) ListStatusTransitions(userID uint) ([]*models.UserStatusTransition, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.UserStatusTransition), args.Error(1)
+}
+
+func (m *MockUserRepository) ListRevisions(userID uint) ([]*models.UserRevision, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.UserRevision), args.Error(1)
+}
+
+func (m *MockUserRepository) GetRevisionAt(userID uint, at time.Time) (*models.UserRevision, error) {
+	args := m.Called(userID, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserRevision), args.Error(1)
+}
+
+func (m *MockUserRepository) EraseStatusTransitions(userID uint) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) EraseRevisions(userID uint) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// WithContext returns the mock itself; the context is not recorded.
+func (m *MockUserRepository) WithContext(ctx context.Context) repository.UserRepository {
+	return m
+}
+
 type MockLogger struct {
 	mock.Mock
 }
@@ -80,7 +183,7 @@ func (m *MockLogger) WithFields(fields map[string]interface{}) logger.Logger {
 func TestUserService_Create(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogger := new(MockLogger)
-	service := NewUserService(mockRepo, mockLogger)
+	service := NewUserService(mockRepo, nil, mockLogger)
 
 	t.Run("Success", func(t *testing.T) {
 		req := &models.CreateUserRequest{
@@ -102,6 +205,26 @@ func TestUserService_Create(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("Invalid Attributes", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		schemas := new(MockUserAttributeSchemaRepository)
+		schemas.On("Latest").Return(planSchema(), nil)
+		service := NewUserService(mockRepo, NewUserAttributeService(schemas, new(MockLogger)), mockLogger)
+		req := &models.CreateUserRequest{
+			Email:      "pro@example.com",
+			Username:   "prouser",
+			Password:   "password123",
+			Attributes: map[string]interface{}{"plan": "enterprise"},
+		}
+
+		mockRepo.On("GetByEmail", req.Email).Return(nil, nil).Once()
+		mockRepo.On("GetByUsername", req.Username).Return(nil, nil).Once()
+
+		_, err := service.Create(context.Background(), req)
+		assert.ErrorIs(t, err, ErrInvalidAttributes)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
 	t.Run("Email Already Exists", func(t *testing.T) {
 		req := &models.CreateUserRequest{
 			Email:    "existing@example.com",
@@ -141,7 +264,7 @@ func TestUserService_Create(t *testing.T) {
 func TestUserService_GetByID(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogger := new(MockLogger)
-	service := NewUserService(mockRepo, mockLogger)
+	service := NewUserService(mockRepo, nil, mockLogger)
 
 	t.Run("Success", func(t *testing.T) {
 		user := &models.User{
@@ -170,10 +293,54 @@ func TestUserService_GetByID(t *testing.T) {
 	})
 }
 
+func TestUserService_GetAsOf(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(mockRepo, nil, new(MockLogger))
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("GetRevisionAt", uint(1), at).Return(&models.UserRevision{
+			UserID: 1, Version: 2, Operation: models.RevisionUpdate, User: &models.UserResponse{ID: 1, Username: "before", Version: 2},
+		}, nil).Once()
+
+		result, err := service.GetAsOf(context.Background(), 1, at)
+		assert.NoError(t, err)
+		assert.Equal(t, "before", result.Username)
+	})
+
+	t.Run("Deleted Or Not Yet Created", func(t *testing.T) {
+		mockRepo.On("GetRevisionAt", uint(2), at).Return(&models.UserRevision{UserID: 2, Operation: models.RevisionDelete, User: &models.UserResponse{ID: 2}}, nil).Once()
+		mockRepo.On("GetRevisionAt", uint(3), at).Return(nil, nil).Once()
+
+		_, err := service.GetAsOf(context.Background(), 2, at)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		_, err = service.GetAsOf(context.Background(), 3, at)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestUserService_History(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(mockRepo, nil, new(MockLogger))
+
+	// Deleted users keep their history
+	mockRepo.On("GetByIDUnscoped", uint(1)).Return(&models.User{ID: 1, DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}, nil).Once()
+	mockRepo.On("ListRevisions", uint(1)).Return([]*models.UserRevision{{ID: 1, UserID: 1, Operation: models.RevisionCreate}}, nil).Once()
+	mockRepo.On("GetByIDUnscoped", uint(2)).Return(nil, nil).Once()
+
+	revisions, err := service.History(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+
+	_, err = service.History(context.Background(), 2)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestUserService_Update(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogger := new(MockLogger)
-	service := NewUserService(mockRepo, mockLogger)
+	service := NewUserService(mockRepo, nil, mockLogger)
 
 	t.Run("Success", func(t *testing.T) {
 		user := &models.User{
@@ -188,9 +355,9 @@ func TestUserService_Update(t *testing.T) {
 		}
 
 		mockRepo.On("GetByID", uint(1)).Return(user, nil).Once()
-		mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil).Once()
+		mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(true, nil).Once()
 
-		result, err := service.Update(context.Background(), 1, req)
+		result, err := service.Update(context.Background(), 1, 0, req)
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
 		assert.Equal(t, req.FirstName, result.FirstName)
@@ -204,7 +371,7 @@ func TestUserService_Update(t *testing.T) {
 
 		mockRepo.On("GetByID", uint(999)).Return(nil, nil).Once()
 
-		result, err := service.Update(context.Background(), 999, req)
+		result, err := service.Update(context.Background(), 999, 0, req)
 		assert.Error(t, err)
 		assert.Equal(t, ErrUserNotFound, err)
 		assert.Nil(t, result)
@@ -229,18 +396,183 @@ func TestUserService_Update(t *testing.T) {
 		mockRepo.On("GetByID", uint(1)).Return(user, nil).Once()
 		mockRepo.On("GetByEmail", req.Email).Return(existingUser, nil).Once()
 
-		result, err := service.Update(context.Background(), 1, req)
+		result, err := service.Update(context.Background(), 1, 0, req)
 		assert.Error(t, err)
 		assert.Equal(t, ErrUserAlreadyExists, err)
 		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Stale If-Match Version", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Version: 3}, nil).Once()
+
+		_, err := service.Update(context.Background(), 1, 2, &models.UpdateUserRequest{FirstName: "Updated"})
+
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("Modified Concurrently", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Version: 3}, nil).Once()
+		mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(false, nil).Once()
+
+		_, err := service.Update(context.Background(), 1, 3, &models.UpdateUserRequest{FirstName: "Updated"})
+
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserService_Patch(t *testing.T) {
+	newUser := func() *models.User {
+		return &models.User{ID: 1, Email: "alice@example.com", Username: "alice", FirstName: "Alice", LastName: "Smith", Status: models.UserStatusActive}
+	}
+
+	t.Run("Merge Patch Clears With Null", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *models.User) bool {
+			return u.FirstName == "Al" && u.LastName == "" && u.Email == "alice@example.com"
+		})).Return(true, nil).Once()
+
+		result, err := service.Patch(context.Background(), 1, 0, models.PatchFormatMerge, []byte(`{"first_name":"Al","last_name":null}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", result.LastName)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("JSON Patch", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+		mockRepo.On("GetByUsername", "asmith").Return(nil, nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *models.User) bool {
+			return u.Username == "asmith" && u.FirstName == ""
+		})).Return(true, nil).Once()
+
+		patch := `[
+			{"op":"test","path":"/username","value":"alice"},
+			{"op":"replace","path":"/username","value":"asmith"},
+			{"op":"remove","path":"/first_name"}
+		]`
+		_, err := service.Patch(context.Background(), 1, 0, models.PatchFormatJSON, []byte(patch))
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Test Operation Fails", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+
+		_, err := service.Patch(context.Background(), 1, 0, models.PatchFormatJSON, []byte(`[{"op":"test","path":"/email","value":"bob@example.com"}]`))
+
+		assert.ErrorIs(t, err, ErrPatchTestFailed)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("Rejects Invalid Results", func(t *testing.T) {
+		patches := map[string]struct {
+			format string
+			patch  string
+		}{
+			"clears email":         {models.PatchFormatMerge, `{"email":null}`},
+			"invalid email":        {models.PatchFormatMerge, `{"email":"not-an-email"}`},
+			"short username":       {models.PatchFormatMerge, `{"username":"al"}`},
+			"field not patchable":  {models.PatchFormatMerge, `{"roles":["admin"]}`},
+			"wrong type":           {models.PatchFormatMerge, `{"first_name":true}`},
+			"missing path":         {models.PatchFormatJSON, `[{"op":"replace","path":"/nickname","value":"al"}]`},
+			"status not patchable": {models.PatchFormatMerge, `{"status":"active"}`},
+			"reserved username":    {models.PatchFormatMerge, `{"username":"root"}`},
+		}
+		for name, tc := range patches {
+			t.Run(name, func(t *testing.T) {
+				mockRepo := new(MockUserRepository)
+				service := NewUserService(mockRepo, nil, new(MockLogger))
+				mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+
+				_, err := service.Patch(context.Background(), 1, 0, tc.format, []byte(tc.patch))
+
+				assert.ErrorIs(t, err, ErrUnprocessablePatch)
+				mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+		}
+	})
+
+	t.Run("Malformed Patch", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+
+		_, err := service.Patch(context.Background(), 1, 0, models.PatchFormatJSON, []byte(`{"op":"add"}`))
+
+		assert.ErrorIs(t, err, ErrInvalidPatch)
+	})
+
+	t.Run("Validates Changed Attributes", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		schemas := new(MockUserAttributeSchemaRepository)
+		schemas.On("Latest").Return(planSchema(), nil)
+		service := NewUserService(mockRepo, NewUserAttributeService(schemas, new(MockLogger)), new(MockLogger))
+
+		user := newUser()
+		user.Attributes = map[string]interface{}{"plan": "free"}
+		mockRepo.On("GetByID", uint(1)).Return(user, nil).Once()
+
+		_, err := service.Patch(context.Background(), 1, 0, models.PatchFormatMerge, []byte(`{"attributes":{"seats":0}}`))
+
+		assert.ErrorIs(t, err, ErrInvalidAttributes)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("Keeps Unchanged Attributes Unchecked", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		schemas := new(MockUserAttributeSchemaRepository)
+		service := NewUserService(mockRepo, NewUserAttributeService(schemas, new(MockLogger)), new(MockLogger))
+
+		// Written before plan was required
+		user := newUser()
+		user.Attributes = map[string]interface{}{"seats": float64(2)}
+		mockRepo.On("GetByID", uint(1)).Return(user, nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *models.User) bool {
+			return u.FirstName == "Al" && u.Attributes["seats"] == float64(2)
+		})).Return(true, nil).Once()
+
+		_, err := service.Patch(context.Background(), 1, 0, models.PatchFormatMerge, []byte(`{"first_name":"Al"}`))
+
+		assert.NoError(t, err)
+		schemas.AssertNotCalled(t, "Latest")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Email Already Exists", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+		mockRepo.On("GetByEmail", "bob@example.com").Return(&models.User{ID: 2}, nil).Once()
+
+		_, err := service.Patch(context.Background(), 1, 0, models.PatchFormatMerge, []byte(`{"email":"bob@example.com"}`))
+
+		assert.ErrorIs(t, err, ErrUserAlreadyExists)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
 }
 
 func TestUserService_Delete(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogger := new(MockLogger)
-	service := NewUserService(mockRepo, mockLogger)
+	service := NewUserService(mockRepo, nil, mockLogger)
 
 	t.Run("Success", func(t *testing.T) {
 		user := &models.User{
@@ -249,9 +581,9 @@ func TestUserService_Delete(t *testing.T) {
 		}
 
 		mockRepo.On("GetByID", uint(1)).Return(user, nil).Once()
-		mockRepo.On("Delete", uint(1)).Return(nil).Once()
+		mockRepo.On("Delete", user).Return(true, nil).Once()
 
-		err := service.Delete(context.Background(), 1)
+		err := service.Delete(context.Background(), 1, 0)
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
@@ -259,17 +591,98 @@ func TestUserService_Delete(t *testing.T) {
 	t.Run("User Not Found", func(t *testing.T) {
 		mockRepo.On("GetByID", uint(999)).Return(nil, nil).Once()
 
-		err := service.Delete(context.Background(), 999)
+		err := service.Delete(context.Background(), 999, 0)
 		assert.Error(t, err)
 		assert.Equal(t, ErrUserNotFound, err)
 		mockRepo.AssertExpectations(t)
 	})
 }
 
+func TestUserService_Restore(t *testing.T) {
+	deleted := func() *models.User {
+		return &models.User{
+			ID:        1,
+			Email:     "test@example.com",
+			Username:  "testuser",
+			DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true},
+		}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByIDUnscoped", uint(1)).Return(deleted(), nil).Once()
+		mockRepo.On("GetByEmail", "test@example.com").Return(nil, nil).Once()
+		mockRepo.On("GetByUsername", "testuser").Return(nil, nil).Once()
+		mockRepo.On("Restore", uint(1)).Return(nil).Once()
+
+		user, err := service.Restore(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Nil(t, user.DeletedAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Email Taken Since", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByIDUnscoped", uint(1)).Return(deleted(), nil).Once()
+		mockRepo.On("GetByEmail", "test@example.com").Return(&models.User{ID: 2}, nil).Once()
+
+		_, err := service.Restore(context.Background(), 1)
+		assert.ErrorIs(t, err, ErrUserAlreadyExists)
+		mockRepo.AssertNotCalled(t, "Restore", mock.Anything)
+	})
+
+	t.Run("Not Deleted", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByIDUnscoped", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+
+		_, err := service.Restore(context.Background(), 1)
+		assert.ErrorIs(t, err, ErrUserNotDeleted)
+	})
+
+	t.Run("User Not Found", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByIDUnscoped", uint(999)).Return(nil, nil).Once()
+
+		_, err := service.Restore(context.Background(), 999)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestUserService_Purge(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByIDUnscoped", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+		mockRepo.On("Purge", uint(1)).Return(nil).Once()
+
+		assert.NoError(t, service.Purge(context.Background(), 1))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("User Not Found", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		mockRepo.On("GetByIDUnscoped", uint(999)).Return(nil, nil).Once()
+
+		assert.ErrorIs(t, service.Purge(context.Background(), 999), ErrUserNotFound)
+		mockRepo.AssertNotCalled(t, "Purge", mock.Anything)
+	})
+}
+
 func TestUserService_GetAll(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogger := new(MockLogger)
-	service := NewUserService(mockRepo, mockLogger)
+	service := NewUserService(mockRepo, nil, mockLogger)
 
 	t.Run("Success", func(t *testing.T) {
 		users := []*models.User{
@@ -277,22 +690,260 @@ func TestUserService_GetAll(t *testing.T) {
 			{ID: 2, Email: "test2@example.com"},
 		}
 
-		mockRepo.On("GetAll", 1, 10).Return(users, int64(2), nil).Once()
+		query := &models.UserListQuery{Page: 1, PageSize: 10}
+		mockRepo.On("GetAll", query).Return(users, int64(2), nil).Once()
 
-		results, total, err := service.GetAll(context.Background(), 1, 10)
+		results, total, err := service.GetAll(context.Background(), query)
 		assert.NoError(t, err)
 		assert.Len(t, results, 2)
 		assert.Equal(t, int64(2), total)
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("PassesRowFilter", func(t *testing.T) {
+		query := &models.UserListQuery{Page: 1, PageSize: 10, RowFilter: &models.RowFilter{Clauses: [][]models.RowCondition{
+			{{Column: "id", Operator: "=", Value: int64(1)}},
+		}}}
+		users := []*models.User{{ID: 1, Email: "test1@example.com"}}
+
+		mockRepo.On("GetAll", query).Return(users, int64(1), nil).Once()
+
+		results, total, err := service.GetAll(context.Background(), query)
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, int64(1), total)
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("Error", func(t *testing.T) {
-		mockRepo.On("GetAll", 1, 10).Return([]*models.User{}, int64(0), errors.New("database error")).Once()
+		query := &models.UserListQuery{Page: 1, PageSize: 10}
+		mockRepo.On("GetAll", query).Return([]*models.User{}, int64(0), errors.New("database error")).Once()
 
-		results, total, err := service.GetAll(context.Background(), 1, 10)
+		results, total, err := service.GetAll(context.Background(), query)
 		assert.Error(t, err)
 		assert.Nil(t, results)
 		assert.Equal(t, int64(0), total)
 		mockRepo.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+
+func TestUserService_GetPage(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := func(ids ...uint) []*models.User {
+		users := make([]*models.User, len(ids))
+		for i, id := range ids {
+			users[i] = &models.User{ID: id, CreatedAt: base.Add(time.Duration(id) * time.Hour)}
+		}
+		return users
+	}
+	decode := func(t *testing.T, encoded string) *models.Cursor {
+		cursor, err := models.DecodeCursor(encoded)
+		assert.NoError(t, err)
+		return cursor
+	}
+
+	t.Run("FirstPage", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		query := &models.UserListQuery{Limit: 2}
+		mockRepo.On("GetPage", query).Return(rows(1, 2, 3), nil).Once()
+
+		page, err := service.GetPage(context.Background(), query)
+		assert.NoError(t, err)
+		assert.Len(t, page.Users, 2)
+		assert.Empty(t, page.PrevCursor)
+		assert.Equal(t, uint(2), decode(t, page.NextCursor).ID)
+		assert.Nil(t, page.Total)
+		mockRepo.AssertNotCalled(t, "Count", mock.Anything)
+	})
+
+	t.Run("LastPage", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		query := &models.UserListQuery{Limit: 2, Cursor: &models.Cursor{ID: 2, CreatedAt: base.Add(2 * time.Hour)}}
+		mockRepo.On("GetPage", query).Return(rows(3), nil).Once()
+
+		page, err := service.GetPage(context.Background(), query)
+		assert.NoError(t, err)
+		assert.Len(t, page.Users, 1)
+		assert.Empty(t, page.NextCursor)
+		prev := decode(t, page.PrevCursor)
+		assert.Equal(t, uint(3), prev.ID)
+		assert.True(t, prev.Backward)
+	})
+
+	t.Run("BackwardPageIsReversed", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, new(MockLogger))
+
+		query := &models.UserListQuery{Limit: 2, IncludeTotal: true, Cursor: &models.Cursor{ID: 4, CreatedAt: base.Add(4 * time.Hour), Backward: true}}
+		mockRepo.On("GetPage", query).Return(rows(3, 2, 1), nil).Once()
+		mockRepo.On("Count", query).Return(int64(5), nil).Once()
+
+		page, err := service.GetPage(context.Background(), query)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), page.Users[0].ID)
+		assert.Equal(t, uint(3), page.Users[1].ID)
+		assert.Equal(t, uint(2), decode(t, page.PrevCursor).ID)
+		assert.Equal(t, uint(3), decode(t, page.NextCursor).ID)
+		assert.Equal(t, int64(5), *page.Total)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserService_GetBatch(t *testing.T) {
+	mockLogger := new(MockLogger)
+
+	t.Run("Keeps Request Order And Reports Missing", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		query := &models.UserListQuery{IDs: []uint{3, 1, 2}}
+		mockRepo.On("Stream", query, mock.Anything).Return([]*models.User{{ID: 1}, {ID: 3}}, nil).Once()
+
+		batch, err := service.GetBatch(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Len(t, batch.Users, 2)
+		assert.Equal(t, uint(3), batch.Users[0].ID)
+		assert.Equal(t, uint(1), batch.Users[1].ID)
+		assert.Equal(t, []uint{2}, batch.Missing)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		query := &models.UserListQuery{IDs: []uint{1}}
+		mockRepo.On("Stream", query, mock.Anything).Return(nil, errors.New("database error")).Once()
+
+		batch, err := service.GetBatch(context.Background(), query)
+
+		assert.Error(t, err)
+		assert.Nil(t, batch)
+	})
+}
+
+func TestUserService_BatchUpdate(t *testing.T) {
+	mockLogger := new(MockLogger)
+
+	t.Run("Atomic Success", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Status: models.UserStatusActive, Roles: []string{"user"}}, nil).Once()
+		mockRepo.On("GetByID", uint(2)).Return(&models.User{ID: 2, Status: models.UserStatusActive, Roles: []string{"user", "editor"}}, nil).Once()
+		mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(true, nil).Twice()
+		mockRepo.On("CreateStatusTransition", mock.MatchedBy(func(transition *models.UserStatusTransition) bool {
+			return transition.UserID == 1 && transition.From == models.UserStatusActive && transition.To == models.UserStatusDeactivated &&
+				transition.Reason == "left" && transition.ChangedBy == "9"
+		})).Return(nil).Once()
+
+		result, err := service.BatchUpdate(context.Background(), &models.BatchUpdateUsersRequest{
+			Updates: []models.BatchUserUpdate{
+				{ID: 1, Status: models.UserStatusDeactivated, Reason: "left"},
+				{ID: 2, AddRoles: []string{"admin", "user"}, RemoveRoles: []string{"editor"}},
+			},
+		}, "9")
+
+		assert.NoError(t, err)
+		assert.True(t, result.Applied)
+		assert.Equal(t, models.BatchModeAtomic, result.Mode)
+		assert.Equal(t, models.UserStatusDeactivated, result.Items[0].User.Status)
+		assert.Equal(t, []string{"user", "admin"}, result.Items[1].User.Roles)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Atomic Failure Rolls Back", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Version: 2}, nil).Once()
+		mockRepo.On("GetByID", uint(2)).Return(nil, nil).Once()
+		mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(true, nil).Once()
+
+		result, err := service.BatchUpdate(context.Background(), &models.BatchUpdateUsersRequest{
+			Updates: []models.BatchUserUpdate{
+				{ID: 1, Version: 2, Roles: []string{"user"}},
+				{ID: 2, Status: models.UserStatusDeactivated},
+			},
+		}, "9")
+
+		assert.ErrorIs(t, err, ErrBatchFailed)
+		assert.False(t, result.Applied)
+		assert.Equal(t, 1, result.Failed())
+		assert.Nil(t, result.Items[0].User)
+		assert.ErrorIs(t, result.Items[1].Error, ErrUserNotFound)
+	})
+
+	t.Run("Partial Reports Each Update", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		mockRepo.On("Transaction").Return().Times(3)
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Version: 3}, nil).Once()
+		mockRepo.On("GetByID", uint(2)).Return(&models.User{ID: 2}, nil).Once()
+		mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(true, nil).Once()
+
+		result, err := service.BatchUpdate(context.Background(), &models.BatchUpdateUsersRequest{
+			Mode: models.BatchModePartial,
+			Updates: []models.BatchUserUpdate{
+				{ID: 1, Version: 2, Status: models.UserStatusDeactivated},
+				{ID: 2, Roles: []string{}},
+			},
+		}, "9")
+
+		assert.NoError(t, err)
+		assert.True(t, result.Applied)
+		assert.ErrorIs(t, result.Items[0].Error, ErrVersionMismatch)
+		assert.Equal(t, []string{}, result.Items[1].User.Roles)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty Role", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1}, nil).Once()
+
+		result, err := service.BatchUpdate(context.Background(), &models.BatchUpdateUsersRequest{
+			Updates: []models.BatchUserUpdate{{ID: 1, AddRoles: []string{" "}}},
+		}, "9")
+
+		assert.ErrorIs(t, err, ErrBatchFailed)
+		assert.ErrorIs(t, result.Items[0].Error, ErrInvalidRole)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("Invalid Status Transition", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Status: models.UserStatusDeactivated}, nil).Once()
+
+		result, err := service.BatchUpdate(context.Background(), &models.BatchUpdateUsersRequest{
+			Updates: []models.BatchUserUpdate{{ID: 1, Status: models.UserStatusLocked}},
+		}, "9")
+
+		assert.ErrorIs(t, err, ErrBatchFailed)
+		assert.ErrorIs(t, result.Items[0].Error, ErrInvalidStatusTransition)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("Database Error Aborts", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, nil, mockLogger)
+		mockRepo.On("Transaction").Return().Times(2)
+		mockRepo.On("GetByID", uint(1)).Return(nil, errors.New("database error")).Once()
+
+		result, err := service.BatchUpdate(context.Background(), &models.BatchUpdateUsersRequest{
+			Mode:    models.BatchModePartial,
+			Updates: []models.BatchUserUpdate{{ID: 1, Status: models.UserStatusDeactivated}, {ID: 2, Status: models.UserStatusDeactivated}},
+		}, "9")
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrBatchFailed)
+		assert.Nil(t, result)
+		mockRepo.AssertNotCalled(t, "GetByID", uint(2))
+	})
+}
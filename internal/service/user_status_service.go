@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/audit"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/repository"
+	"github.com/witslab-sahil/fiber-boilerplate/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrInvalidStatusTransition means the user's status cannot change to the
+	// one asked for, e.g. reactivating an active user.
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	// ErrInvalidSuspension means a suspension would end in the past.
+	ErrInvalidSuspension = errors.New("invalid suspension")
+
+	// The account errors say why a user may not sign in or use a token.
+	ErrAccountPendingVerification = errors.New("account is pending verification")
+	ErrAccountSuspended           = errors.New("account is suspended")
+	ErrAccountLocked              = errors.New("account is locked")
+	ErrAccountDeactivated         = errors.New("account is deactivated")
+)
+
+// SuspensionWorkflows ends suspensions when they run out.
+type SuspensionWorkflows interface {
+	ScheduleUnsuspend(ctx context.Context, userID uint, until time.Time) (string, error)
+}
+
+type UserStatusService interface {
+	Suspend(ctx context.Context, id uint, version uint, req *models.SuspendUserRequest, by string) (*models.UserResponse, error)
+	Reactivate(ctx context.Context, id uint, version uint, req *models.ReactivateUserRequest, by string) (*models.UserResponse, error)
+	EndSuspension(ctx context.Context, id uint, until time.Time) error
+	History(ctx context.Context, id uint) ([]*models.UserStatusTransition, error)
+	CheckAccount(ctx context.Context, id uint) error
+}
+
+type userStatusService struct {
+	users     repository.UserRepository
+	workflows SuspensionWorkflows
+	logger    logger.Logger
+	tracer    trace.Tracer
+	now       func() time.Time
+}
+
+// NewUserStatusService creates the user status service. workflows may be nil
+// where suspensions are only ended, as in the worker; suspensions with an end
+// then have to be ended by reactivating the user.
+func NewUserStatusService(users repository.UserRepository, workflows SuspensionWorkflows, logger logger.Logger) UserStatusService {
+	return &userStatusService{
+		users:     users,
+		workflows: workflows,
+		logger:    logger,
+		tracer:    otel.Tracer("user-status-service"),
+		now:       time.Now,
+	}
+}
+
+// Suspend suspends the user, until req.Until if set, and schedules the end
+// of the suspension. Suspending a suspended user replaces its reason and end.
+func (s *userStatusService) Suspend(ctx context.Context, id uint, version uint, req *models.SuspendUserRequest, by string) (*models.UserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "UserStatusService.Suspend")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)))
+	var until *time.Time
+	if req.Until != nil {
+		// Whole seconds, so the end read back from the database matches the
+		// one the timer carries
+		t := req.Until.UTC().Truncate(time.Second)
+		if !t.After(s.now()) {
+			return nil, fmt.Errorf("%w: until must be in the future", ErrInvalidSuspension)
+		}
+		until = &t
+	}
+
+	user, err := s.transition(ctx, id, version, models.UserStatusSuspended, req.Reason, until, by)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if until != nil && s.workflows != nil {
+		workflowID, err := s.workflows.ScheduleUnsuspend(ctx, id, *until)
+		if err != nil {
+			// The user stays suspended until reactivated by hand; logins
+			// already treat the suspension as over once until has passed
+			span.RecordError(err)
+			s.logger.Errorf("Failed to schedule end of suspension for user %d: %v", id, err)
+		} else {
+			span.SetAttributes(attribute.String("workflow.id", workflowID))
+		}
+	}
+
+	s.logger.Infof("User suspended: %d", id)
+	return user.ToResponse(), nil
+}
+
+// Reactivate makes the user active again, whether it is pending
+// verification, suspended, locked or deactivated.
+func (s *userStatusService) Reactivate(ctx context.Context, id uint, version uint, req *models.ReactivateUserRequest, by string) (*models.UserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "UserStatusService.Reactivate")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)))
+	user, err := s.transition(ctx, id, version, models.UserStatusActive, req.Reason, nil, by)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	s.logger.Infof("User reactivated: %d", id)
+	return user.ToResponse(), nil
+}
+
+// EndSuspension reactivates the user if it is still suspended until until.
+// Suspensions that were lifted, replaced or extended since the timer was
+// scheduled are left alone, so stale timers are harmless.
+func (s *userStatusService) EndSuspension(ctx context.Context, id uint, until time.Time) error {
+	ctx, span := s.tracer.Start(ctx, "UserStatusService.EndSuspension")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)))
+	ctx = audit.WithActor(ctx, audit.Actor{UserID: models.StatusChangedBySystem})
+	err := s.users.WithContext(ctx).Transaction(func(repo repository.UserRepository) error {
+		user, err := repo.GetByID(id)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil || user.Status != models.UserStatusSuspended || user.SuspendedUntil == nil || !user.SuspendedUntil.Equal(until) {
+			span.SetAttributes(attribute.Bool("suspension.stale", true))
+			return nil
+		}
+		return changeStatus(repo, user, models.UserStatusActive, "suspension ended", nil, models.StatusChangedBySystem)
+	})
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Errorf("Failed to end suspension of user %d: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// History returns the user's status changes, oldest first.
+func (s *userStatusService) History(ctx context.Context, id uint) ([]*models.UserStatusTransition, error) {
+	_, span := s.tracer.Start(ctx, "UserStatusService.History")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("user.id", int64(id)))
+	user, err := s.users.GetByID(id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	transitions, err := s.users.ListStatusTransitions(id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to list status transitions: %w", err)
+	}
+	return transitions, nil
+}
+
+// CheckAccount returns the account error that stops the user from using the
+// API, if any. Deleted users count as deactivated.
+func (s *userStatusService) CheckAccount(ctx context.Context, id uint) error {
+	user, err := s.users.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrAccountDeactivated
+	}
+	return AccountError(user, s.now())
+}
+
+// transition changes the status of user id, at version if not zero, and
+// records the change, in one transaction.
+func (s *userStatusService) transition(ctx context.Context, id uint, version uint, to, reason string, until *time.Time, by string) (*models.User, error) {
+	var user *models.User
+	err := s.users.WithContext(ctx).Transaction(func(repo repository.UserRepository) error {
+		var err error
+		user, err = repo.GetByID(id)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil {
+			return ErrUserNotFound
+		}
+		if version != 0 && version != user.Version {
+			return ErrVersionMismatch
+		}
+		return changeStatus(repo, user, to, reason, until, by)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// changeStatus moves user to status to, if allowed, saves it through repo
+// and records the transition.
+func changeStatus(repo repository.UserRepository, user *models.User, to, reason string, until *time.Time, by string) error {
+	from := user.Status
+	if !models.CanTransition(from, to) {
+		return fmt.Errorf("%w: a %s user cannot become %s", ErrInvalidStatusTransition, from, to)
+	}
+
+	user.Status, user.StatusReason, user.SuspendedUntil = to, reason, until
+	saved, err := repo.Update(user)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if !saved {
+		return ErrVersionMismatch
+	}
+
+	if err := repo.CreateStatusTransition(&models.UserStatusTransition{
+		UserID:    user.ID,
+		From:      from,
+		To:        to,
+		Reason:    reason,
+		Until:     until,
+		ChangedBy: by,
+	}); err != nil {
+		return fmt.Errorf("failed to record status transition: %w", err)
+	}
+	return nil
+}
+
+// AccountError returns the error that stops user from signing in or using
+// the API at now, or nil if it may.
+func AccountError(user *models.User, now time.Time) error {
+	switch user.EffectiveStatus(now) {
+	case models.UserStatusActive:
+		return nil
+	case models.UserStatusPendingVerification:
+		return ErrAccountPendingVerification
+	case models.UserStatusSuspended:
+		if user.SuspendedUntil != nil {
+			return fmt.Errorf("%w until %s", ErrAccountSuspended, user.SuspendedUntil.UTC().Format(time.RFC3339))
+		}
+		return ErrAccountSuspended
+	case models.UserStatusLocked:
+		return ErrAccountLocked
+	default:
+		return ErrAccountDeactivated
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+)
+
+type MockSuspensionWorkflows struct {
+	mock.Mock
+}
+
+func (m *MockSuspensionWorkflows) ScheduleUnsuspend(ctx context.Context, userID uint, until time.Time) (string, error) {
+	args := m.Called(ctx, userID, until)
+	return args.String(0), args.Error(1)
+}
+
+func TestCanTransition(t *testing.T) {
+	assert.True(t, models.CanTransition(models.UserStatusPendingVerification, models.UserStatusActive))
+	assert.True(t, models.CanTransition(models.UserStatusActive, models.UserStatusSuspended))
+	assert.True(t, models.CanTransition(models.UserStatusSuspended, models.UserStatusSuspended))
+	assert.True(t, models.CanTransition(models.UserStatusLocked, models.UserStatusActive))
+	assert.False(t, models.CanTransition(models.UserStatusActive, models.UserStatusActive))
+	assert.False(t, models.CanTransition(models.UserStatusDeactivated, models.UserStatusSuspended))
+	assert.False(t, models.CanTransition(models.UserStatusActive, models.UserStatusPendingVerification))
+	assert.False(t, models.CanTransition("unknown", models.UserStatusActive))
+}
+
+func TestUserStatusService_Suspend(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	newService := func(repo *MockUserRepository, workflows SuspensionWorkflows) *userStatusService {
+		s := NewUserStatusService(repo, workflows, new(MockLogger)).(*userStatusService)
+		s.now = func() time.Time { return now }
+		return s
+	}
+
+	t.Run("Suspends And Schedules The End", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockWorkflows := new(MockSuspensionWorkflows)
+		service := newService(mockRepo, mockWorkflows)
+		until := now.Add(48*time.Hour + 500*time.Millisecond)
+		truncated := now.Add(48 * time.Hour)
+
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Status: models.UserStatusActive, Version: 2}, nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *models.User) bool {
+			return u.Status == models.UserStatusSuspended && u.StatusReason == "spam" && u.SuspendedUntil.Equal(truncated)
+		})).Return(true, nil).Once()
+		mockRepo.On("CreateStatusTransition", mock.MatchedBy(func(transition *models.UserStatusTransition) bool {
+			return transition.From == models.UserStatusActive && transition.To == models.UserStatusSuspended && transition.ChangedBy == "9"
+		})).Return(nil).Once()
+		mockWorkflows.On("ScheduleUnsuspend", mock.Anything, uint(1), truncated).Return("user-suspension-1", nil).Once()
+
+		user, err := service.Suspend(context.Background(), 1, 2, &models.SuspendUserRequest{Reason: "spam", Until: &until}, "9")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserStatusSuspended, user.Status)
+		assert.False(t, user.IsActive)
+		mockRepo.AssertExpectations(t)
+		mockWorkflows.AssertExpectations(t)
+	})
+
+	t.Run("Scheduling Failure Is Not Fatal", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockWorkflows := new(MockSuspensionWorkflows)
+		service := newService(mockRepo, mockWorkflows)
+		until := now.Add(time.Hour)
+
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Status: models.UserStatusActive}, nil).Once()
+		mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(true, nil).Once()
+		mockRepo.On("CreateStatusTransition", mock.AnythingOfType("*models.UserStatusTransition")).Return(nil).Once()
+		mockWorkflows.On("ScheduleUnsuspend", mock.Anything, uint(1), until).Return("", errors.New("temporal unavailable")).Once()
+
+		_, err := service.Suspend(context.Background(), 1, 0, &models.SuspendUserRequest{Reason: "spam", Until: &until}, "9")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Until In The Past", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := newService(mockRepo, nil)
+		until := now.Add(-time.Minute)
+
+		_, err := service.Suspend(context.Background(), 1, 0, &models.SuspendUserRequest{Reason: "spam", Until: &until}, "9")
+
+		assert.ErrorIs(t, err, ErrInvalidSuspension)
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+	})
+
+	t.Run("Invalid Transition", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := newService(mockRepo, nil)
+
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Status: models.UserStatusDeactivated}, nil).Once()
+
+		_, err := service.Suspend(context.Background(), 1, 0, &models.SuspendUserRequest{Reason: "spam"}, "9")
+
+		assert.ErrorIs(t, err, ErrInvalidStatusTransition)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("Version Mismatch", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := newService(mockRepo, nil)
+
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Status: models.UserStatusActive, Version: 3}, nil).Once()
+
+		_, err := service.Suspend(context.Background(), 1, 2, &models.SuspendUserRequest{Reason: "spam"}, "9")
+
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+}
+
+func TestUserStatusService_Reactivate(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := NewUserStatusService(mockRepo, nil, new(MockLogger))
+	until := time.Now().Add(time.Hour)
+
+	mockRepo.On("Transaction").Return().Once()
+	mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Status: models.UserStatusSuspended, StatusReason: "spam", SuspendedUntil: &until}, nil).Once()
+	mockRepo.On("Update", mock.MatchedBy(func(u *models.User) bool {
+		return u.Status == models.UserStatusActive && u.StatusReason == "appeal granted" && u.SuspendedUntil == nil
+	})).Return(true, nil).Once()
+	mockRepo.On("CreateStatusTransition", mock.MatchedBy(func(transition *models.UserStatusTransition) bool {
+		return transition.From == models.UserStatusSuspended && transition.To == models.UserStatusActive
+	})).Return(nil).Once()
+
+	user, err := service.Reactivate(context.Background(), 1, 0, &models.ReactivateUserRequest{Reason: "appeal granted"}, "9")
+
+	assert.NoError(t, err)
+	assert.True(t, user.IsActive)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserStatusService_EndSuspension(t *testing.T) {
+	until := time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Ends A Matching Suspension", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserStatusService(mockRepo, nil, new(MockLogger))
+		stored := until
+
+		mockRepo.On("Transaction").Return().Once()
+		mockRepo.On("GetByID", uint(1)).Return(&models.User{ID: 1, Status: models.UserStatusSuspended, SuspendedUntil: &stored}, nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *models.User) bool { return u.Status == models.UserStatusActive })).Return(true, nil).Once()
+		mockRepo.On("CreateStatusTransition", mock.MatchedBy(func(transition *models.UserStatusTransition) bool {
+			return transition.ChangedBy == models.StatusChangedBySystem
+		})).Return(nil).Once()
+
+		assert.NoError(t, service.EndSuspension(context.Background(), 1, until))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Ignores Stale Timers", func(t *testing.T) {
+		extended := until.Add(24 * time.Hour)
+		for name, user := range map[string]*models.User{
+			"reactivated": {ID: 1, Status: models.UserStatusActive},
+			"extended":    {ID: 1, Status: models.UserStatusSuspended, SuspendedUntil: &extended},
+			"indefinite":  {ID: 1, Status: models.UserStatusSuspended},
+		} {
+			t.Run(name, func(t *testing.T) {
+				mockRepo := new(MockUserRepository)
+				service := NewUserStatusService(mockRepo, nil, new(MockLogger))
+
+				mockRepo.On("Transaction").Return().Once()
+				mockRepo.On("GetByID", uint(1)).Return(user, nil).Once()
+
+				assert.NoError(t, service.EndSuspension(context.Background(), 1, until))
+				mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+			})
+		}
+	})
+}
+
+func TestUserStatusService_CheckAccount(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	future, past := now.Add(time.Hour), now.Add(-time.Hour)
+
+	tests := map[string]struct {
+		user *models.User
+		err  error
+	}{
+		"active":               {&models.User{Status: models.UserStatusActive}, nil},
+		"pending verification": {&models.User{Status: models.UserStatusPendingVerification}, ErrAccountPendingVerification},
+		"suspended":            {&models.User{Status: models.UserStatusSuspended, SuspendedUntil: &future}, ErrAccountSuspended},
+		"suspension over":      {&models.User{Status: models.UserStatusSuspended, SuspendedUntil: &past}, nil},
+		"locked":               {&models.User{Status: models.UserStatusLocked}, ErrAccountLocked},
+		"deactivated":          {&models.User{Status: models.UserStatusDeactivated}, ErrAccountDeactivated},
+		"deleted":              {nil, ErrAccountDeactivated},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(MockUserRepository)
+			service := NewUserStatusService(mockRepo, nil, new(MockLogger)).(*userStatusService)
+			service.now = func() time.Time { return now }
+
+			mockRepo.On("GetByID", uint(1)).Return(tc.user, nil).Once()
+
+			err := service.CheckAccount(context.Background(), 1)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+
+	t.Run("Suspension Names Its End", func(t *testing.T) {
+		err := AccountError(&models.User{Status: models.UserStatusSuspended, SuspendedUntil: &future}, now)
+		assert.EqualError(t, err, "account is suspended until 2026-01-01T13:00:00Z")
+	})
+}
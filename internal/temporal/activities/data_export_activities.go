@@ -0,0 +1,80 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Data export activities
+type DataExportInput struct {
+	ExportID string `json:"export_id"`
+}
+
+type DataExportResult struct {
+	ExportID    string     `json:"export_id"`
+	Status      string     `json:"status"`
+	DownloadURL string     `json:"download_url"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type FailDataExportInput struct {
+	ExportID string `json:"export_id"`
+	Error    string `json:"error"`
+}
+
+type SendDataExportEmailInput struct {
+	UserID      uint      `json:"user_id"`
+	Email       string    `json:"email"`
+	Name        string    `json:"name"`
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// BuildDataExport assembles the archive of a data export and returns the
+// link it can be downloaded from.
+func (a *Activities) BuildDataExport(ctx context.Context, input DataExportInput) (*DataExportResult, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Building data export", "exportID", input.ExportID)
+
+	export, err := a.dataExports.Build(ctx, input.ExportID)
+	if err != nil {
+		if errors.Is(err, service.ErrDataExportNotFound) || errors.Is(err, service.ErrUserNotFound) {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), "DataExportNotFound", err)
+		}
+		return nil, err
+	}
+
+	return &DataExportResult{
+		ExportID:    export.ID,
+		Status:      export.Status,
+		DownloadURL: a.dataExports.DownloadURL(export),
+		ExpiresAt:   export.ExpiresAt,
+	}, nil
+}
+
+func (a *Activities) FailDataExport(ctx context.Context, input FailDataExportInput) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Failing data export", "exportID", input.ExportID)
+
+	return a.dataExports.Fail(ctx, input.ExportID, input.Error)
+}
+
+func (a *Activities) SendDataExportEmail(ctx context.Context, input SendDataExportEmailInput) (SendEmailResult, error) {
+	logger := activity.GetLogger(ctx)
+	// The link is a credential, so only the recipient is logged
+	logger.Info("Sending data export email", "email", input.Email)
+
+	// Simulate email sending
+	time.Sleep(100 * time.Millisecond)
+
+	return SendEmailResult{
+		Success:   true,
+		MessageID: fmt.Sprintf("data-export-%d-%d", input.UserID, time.Now().Unix()),
+	}, nil
+}
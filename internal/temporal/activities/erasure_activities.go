@@ -0,0 +1,32 @@
+package activities
+
+import (
+	"context"
+	"errors"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Erasure activities
+type EraseUserDataInput struct {
+	RequestID string `json:"request_id"`
+}
+
+// EraseUserData runs the erasure hooks for a request whose grace period is
+// over. The hooks are idempotent, so a failed attempt is simply retried.
+func (a *Activities) EraseUserData(ctx context.Context, input EraseUserDataInput) (*models.ErasureRequest, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Erasing user data", "requestID", input.RequestID)
+
+	request, err := a.erasures.Execute(ctx, input.RequestID)
+	if err != nil {
+		if errors.Is(err, service.ErrErasureNotFound) {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), "ErasureNotFound", err)
+		}
+		return nil, err
+	}
+	return request, nil
+}
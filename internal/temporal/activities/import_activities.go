@@ -0,0 +1,74 @@
+package activities
+
+import (
+	"context"
+	"errors"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Import activities
+type ImportBatchInput struct {
+	ImportID  string `json:"import_id"`
+	BatchSize int    `json:"batch_size"`
+}
+
+type FinishImportInput struct {
+	ImportID string `json:"import_id"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ImportUserBatch applies the next batch of pending rows of an import. It
+// heartbeats after every row; if the activity is retried, the rows already
+// applied are skipped, and the users they created are recovered from the
+// last heartbeat so that none of them misses onboarding.
+func (a *Activities) ImportUserBatch(ctx context.Context, input ImportBatchInput) (*models.ImportBatchResult, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Importing user batch", "importID", input.ImportID, "batchSize", input.BatchSize)
+
+	var previous models.ImportBatchResult
+	if activity.HasHeartbeatDetails(ctx) {
+		if err := activity.GetHeartbeatDetails(ctx, &previous); err != nil {
+			logger.Warn("Failed to read import heartbeat", "error", err)
+		}
+	}
+
+	remaining := input.BatchSize - previous.Processed
+	if remaining <= 0 {
+		return &previous, nil
+	}
+
+	result, err := a.imports.ProcessBatch(ctx, input.ImportID, remaining, func(partial *models.ImportBatchResult) {
+		activity.RecordHeartbeat(ctx, merge(&previous, partial))
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrImportNotFound) {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), "ImportNotFound", err)
+		}
+		return nil, err
+	}
+
+	return merge(&previous, result), nil
+}
+
+func (a *Activities) FinishUserImport(ctx context.Context, input FinishImportInput) (*models.UserImport, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Finishing user import", "importID", input.ImportID)
+
+	return a.imports.Finish(ctx, input.ImportID, input.Error)
+}
+
+// merge combines the progress an earlier attempt of a batch heartbeated with
+// the result of the current attempt.
+func merge(previous, current *models.ImportBatchResult) *models.ImportBatchResult {
+	merged := *current
+	merged.Processed += previous.Processed
+	if merged.LastLine == 0 {
+		merged.LastLine = previous.LastLine
+	}
+	merged.Created = append(append([]models.ImportedUser{}, previous.Created...), current.Created...)
+	return &merged
+}
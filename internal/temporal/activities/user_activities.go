@@ -7,15 +7,24 @@ import (
 
 	"go.temporal.io/sdk/activity"
 	"github.com/sirupsen/logrus"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
 )
 
 type Activities struct {
-	logger *logrus.Logger
+	logger      *logrus.Logger
+	imports     service.UserImportService
+	erasures    service.ErasureService
+	dataExports service.DataExportService
+	statuses    service.UserStatusService
 }
 
-func NewActivities(logger *logrus.Logger) *Activities {
+func NewActivities(logger *logrus.Logger, imports service.UserImportService, erasures service.ErasureService, dataExports service.DataExportService, statuses service.UserStatusService) *Activities {
 	return &Activities{
-		logger: logger,
+		logger:      logger,
+		imports:     imports,
+		erasures:    erasures,
+		dataExports: dataExports,
+		statuses:    statuses,
 	}
 }
 
@@ -132,4 +141,6 @@ func RegisterActivities(w interface {
 	w.RegisterActivity(activities.CreateUserProfile)
 	w.RegisterActivity(activities.SendPushNotification)
 	w.RegisterActivity(activities.SendSMSNotification)
+	w.RegisterActivity(activities.ImportUserBatch)
+	w.RegisterActivity(activities.FinishUserImport)
 }
\ No newline at end of file
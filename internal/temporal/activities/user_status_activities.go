@@ -0,0 +1,23 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// User status activities
+type EndUserSuspensionInput struct {
+	UserID uint      `json:"user_id"`
+	Until  time.Time `json:"until"`
+}
+
+// EndUserSuspension reactivates a user whose suspension has run out. It does
+// nothing if the suspension was lifted or changed since it was scheduled.
+func (a *Activities) EndUserSuspension(ctx context.Context, input EndUserSuspensionInput) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Ending user suspension", "userID", input.UserID, "until", input.Until)
+
+	return a.statuses.EndSuspension(ctx, input.UserID, input.Until)
+}
@@ -8,6 +8,7 @@ import (
 	"go.temporal.io/sdk/worker"
 	"github.com/sirupsen/logrus"
 
+	"github.com/witslab-sahil/fiber-boilerplate/internal/service"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
 	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/workflows"
 )
@@ -18,7 +19,7 @@ type Worker struct {
 	logger *logrus.Logger
 }
 
-func NewWorker(c client.Client, taskQueue string, logger *logrus.Logger) (*Worker, error) {
+func NewWorker(c client.Client, taskQueue string, logger *logrus.Logger, imports service.UserImportService, erasures service.ErasureService, dataExports service.DataExportService, statuses service.UserStatusService) (*Worker, error) {
 	w := worker.New(c, taskQueue, worker.Options{
 		MaxConcurrentActivityExecutionSize:     10,
 		MaxConcurrentWorkflowTaskExecutionSize: 10,
@@ -26,14 +27,25 @@ func NewWorker(c client.Client, taskQueue string, logger *logrus.Logger) (*Worke
 
 	// Register workflows
 	w.RegisterWorkflow(workflows.UserOnboardingWorkflowFunc)
+	w.RegisterWorkflow(workflows.UserImportWorkflowFunc)
+	w.RegisterWorkflow(workflows.UserErasureWorkflowFunc)
+	w.RegisterWorkflow(workflows.DataExportWorkflowFunc)
+	w.RegisterWorkflow(workflows.UserSuspensionWorkflowFunc)
 
 	// Register activities
-	activityHandler := activities.NewActivities(logger)
+	activityHandler := activities.NewActivities(logger, imports, erasures, dataExports, statuses)
 	w.RegisterActivity(activityHandler.SendWelcomeEmail)
 	w.RegisterActivity(activityHandler.SendFollowUpEmail)
 	w.RegisterActivity(activityHandler.CreateUserProfile)
 	w.RegisterActivity(activityHandler.SendPushNotification)
 	w.RegisterActivity(activityHandler.SendSMSNotification)
+	w.RegisterActivity(activityHandler.ImportUserBatch)
+	w.RegisterActivity(activityHandler.FinishUserImport)
+	w.RegisterActivity(activityHandler.EraseUserData)
+	w.RegisterActivity(activityHandler.BuildDataExport)
+	w.RegisterActivity(activityHandler.FailDataExport)
+	w.RegisterActivity(activityHandler.SendDataExportEmail)
+	w.RegisterActivity(activityHandler.EndUserSuspension)
 
 	return &Worker{
 		client: c,
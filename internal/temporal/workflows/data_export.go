@@ -0,0 +1,151 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type DataExportInput struct {
+	ExportID string `json:"export_id"`
+	UserID   uint   `json:"user_id"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+type DataExportResult struct {
+	ExportID  string `json:"export_id"`
+	Status    string `json:"status"`
+	EmailSent bool   `json:"email_sent"`
+}
+
+// DataExportWorkflowFunc assembles a user's data export and emails them the
+// download link. A failed email does not fail the export, since the link is
+// also returned by the API.
+func DataExportWorkflowFunc(ctx workflow.Context, input DataExportInput) (DataExportResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting data export workflow", "exportID", input.ExportID)
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	activityHandler := &activities.Activities{}
+
+	var built activities.DataExportResult
+	err := workflow.ExecuteActivity(ctx, activityHandler.BuildDataExport, activities.DataExportInput{
+		ExportID: input.ExportID,
+	}).Get(ctx, &built)
+	if err != nil {
+		logger.Error("Failed to build data export", "error", err)
+		failErr := workflow.ExecuteActivity(ctx, activityHandler.FailDataExport, activities.FailDataExportInput{
+			ExportID: input.ExportID,
+			Error:    "failed to build data export",
+		}).Get(ctx, nil)
+		if failErr != nil {
+			logger.Error("Failed to record data export failure", "error", failErr)
+		}
+		return DataExportResult{ExportID: input.ExportID, Status: models.ExportStatusFailed}, err
+	}
+
+	result := DataExportResult{ExportID: built.ExportID, Status: built.Status}
+	if built.DownloadURL == "" || built.ExpiresAt == nil {
+		return result, nil
+	}
+
+	var emailResult activities.SendEmailResult
+	err = workflow.ExecuteActivity(ctx, activityHandler.SendDataExportEmail, activities.SendDataExportEmailInput{
+		UserID:      input.UserID,
+		Email:       input.Email,
+		Name:        input.Username,
+		DownloadURL: built.DownloadURL,
+		ExpiresAt:   *built.ExpiresAt,
+	}).Get(ctx, &emailResult)
+	if err != nil {
+		logger.Error("Failed to send data export email", "error", err)
+		return result, nil
+	}
+
+	result.EmailSent = emailResult.Success
+	return result, nil
+}
+
+// DataExportStarter starts data export workflows for the data export
+// service.
+type DataExportStarter struct {
+	client client.Client
+}
+
+func NewDataExportStarter(c client.Client) *DataExportStarter {
+	return &DataExportStarter{
+		client: c,
+	}
+}
+
+func (s *DataExportStarter) StartDataExport(ctx context.Context, export *models.DataExport, user *models.User) (string, error) {
+	options := client.StartWorkflowOptions{
+		ID: "data-export-" + export.ID,
+		// Data exports run on the same worker as onboarding
+		TaskQueue: OnboardingTaskQueue,
+	}
+
+	run, err := s.client.ExecuteWorkflow(ctx, options, DataExportWorkflowFunc, DataExportInput{
+		ExportID: export.ID,
+		UserID:   user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+	})
+	if err != nil {
+		return "", err
+	}
+	return run.GetID(), nil
+}
+
+// WorkflowSummary describes a workflow execution in a user's data export.
+type WorkflowSummary struct {
+	WorkflowID string     `json:"workflow_id"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"`
+	StartTime  *time.Time `json:"start_time,omitempty"`
+	CloseTime  *time.Time `json:"close_time,omitempty"`
+}
+
+// DescribeWorkflows summarizes the latest run of each workflow, skipping
+// those Temporal no longer knows about.
+func DescribeWorkflows(ctx context.Context, c client.Client, workflowIDs []string) ([]WorkflowSummary, error) {
+	summaries := make([]WorkflowSummary, 0, len(workflowIDs))
+	for _, id := range workflowIDs {
+		resp, err := c.DescribeWorkflowExecution(ctx, id, "")
+		if err != nil {
+			var notFound *serviceerror.NotFound
+			if errors.As(err, &notFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		info := resp.WorkflowExecutionInfo
+		summaries = append(summaries, WorkflowSummary{
+			WorkflowID: id,
+			Type:       info.GetType().GetName(),
+			Status:     info.Status.String(),
+			StartTime:  info.StartTime,
+			CloseTime:  info.CloseTime,
+		})
+	}
+	return summaries, nil
+}
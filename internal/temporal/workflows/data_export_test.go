@@ -0,0 +1,61 @@
+package workflows
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestDataExportWorkflow(t *testing.T) {
+	a := &activities.Activities{}
+	input := DataExportInput{ExportID: "exp", UserID: 1, Email: "alice@example.com", Username: "alice"}
+
+	t.Run("Builds The Archive And Emails The Link", func(t *testing.T) {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivity(a)
+
+		expires := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+		env.OnActivity(a.BuildDataExport, mock.Anything, activities.DataExportInput{ExportID: "exp"}).Return(&activities.DataExportResult{
+			ExportID: "exp", Status: models.ExportStatusCompleted, DownloadURL: "http://api.example.com/download", ExpiresAt: &expires,
+		}, nil).Once()
+		env.OnActivity(a.SendDataExportEmail, mock.Anything, activities.SendDataExportEmailInput{
+			UserID: 1, Email: "alice@example.com", Name: "alice", DownloadURL: "http://api.example.com/download", ExpiresAt: expires,
+		}).Return(activities.SendEmailResult{Success: true}, nil).Once()
+
+		env.ExecuteWorkflow(DataExportWorkflowFunc, input)
+
+		assert.True(t, env.IsWorkflowCompleted())
+		assert.NoError(t, env.GetWorkflowError())
+
+		var result DataExportResult
+		assert.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, DataExportResult{ExportID: "exp", Status: models.ExportStatusCompleted, EmailSent: true}, result)
+		env.AssertExpectations(t)
+	})
+
+	t.Run("Marks The Export Failed", func(t *testing.T) {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivity(a)
+
+		env.OnActivity(a.BuildDataExport, mock.Anything, mock.Anything).
+			Return(nil, temporal.NewNonRetryableApplicationError("data export not found", "DataExportNotFound", errors.New("data export not found"))).Once()
+		env.OnActivity(a.FailDataExport, mock.Anything, mock.MatchedBy(func(input activities.FailDataExportInput) bool {
+			return input.ExportID == "exp" && input.Error != ""
+		})).Return(nil).Once()
+
+		env.ExecuteWorkflow(DataExportWorkflowFunc, input)
+
+		assert.True(t, env.IsWorkflowCompleted())
+		assert.Error(t, env.GetWorkflowError())
+		env.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,108 @@
+package workflows
+
+import (
+	"context"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// CancelErasureSignal withdraws an erasure request during its grace period.
+const CancelErasureSignal = "cancel-erasure"
+
+type UserErasureInput struct {
+	RequestID   string        `json:"request_id"`
+	GracePeriod time.Duration `json:"grace_period"`
+}
+
+type UserErasureResult struct {
+	RequestID string `json:"request_id"`
+	Status    string `json:"status"`
+}
+
+// UserErasureWorkflowFunc waits out the grace period of an erasure request
+// and then erases the user's data, unless the request is cancelled first.
+// The activity checks the request's status too, so a cancellation whose
+// signal was lost still stops the erasure.
+func UserErasureWorkflowFunc(ctx workflow.Context, input UserErasureInput) (UserErasureResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting user erasure workflow", "requestID", input.RequestID, "gracePeriod", input.GracePeriod)
+
+	cancelled := false
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	timer := workflow.NewTimer(timerCtx, input.GracePeriod)
+
+	selector := workflow.NewSelector(ctx)
+	selector.AddFuture(timer, func(f workflow.Future) {})
+	selector.AddReceive(workflow.GetSignalChannel(ctx, CancelErasureSignal), func(c workflow.ReceiveChannel, more bool) {
+		c.Receive(ctx, nil)
+		cancelled = true
+		cancelTimer()
+	})
+	selector.Select(ctx)
+
+	if cancelled {
+		logger.Info("User erasure cancelled", "requestID", input.RequestID)
+		return UserErasureResult{RequestID: input.RequestID, Status: models.ErasureStatusCancelled}, nil
+	}
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Minute,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Hour,
+			// Erasure is a legal obligation; keep retrying until it succeeds
+			MaximumAttempts: 0,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	var request models.ErasureRequest
+	err := workflow.ExecuteActivity(ctx, (&activities.Activities{}).EraseUserData, activities.EraseUserDataInput{
+		RequestID: input.RequestID,
+	}).Get(ctx, &request)
+	if err != nil {
+		logger.Error("Failed to erase user data", "error", err)
+		return UserErasureResult{RequestID: input.RequestID}, err
+	}
+
+	return UserErasureResult{RequestID: request.ID, Status: request.Status}, nil
+}
+
+// UserErasureStarter starts and cancels erasure workflows for the erasure
+// service.
+type UserErasureStarter struct {
+	client client.Client
+}
+
+func NewUserErasureStarter(c client.Client) *UserErasureStarter {
+	return &UserErasureStarter{
+		client: c,
+	}
+}
+
+func (s *UserErasureStarter) StartErasure(ctx context.Context, requestID string, grace time.Duration) (string, error) {
+	options := client.StartWorkflowOptions{
+		ID: "user-erasure-" + requestID,
+		// Erasures run on the same worker as onboarding
+		TaskQueue: OnboardingTaskQueue,
+	}
+
+	run, err := s.client.ExecuteWorkflow(ctx, options, UserErasureWorkflowFunc, UserErasureInput{
+		RequestID:   requestID,
+		GracePeriod: grace,
+	})
+	if err != nil {
+		return "", err
+	}
+	return run.GetID(), nil
+}
+
+func (s *UserErasureStarter) CancelErasure(ctx context.Context, workflowID string) error {
+	return s.client.SignalWorkflow(ctx, workflowID, "", CancelErasureSignal, nil)
+}
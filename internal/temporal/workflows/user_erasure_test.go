@@ -0,0 +1,56 @@
+package workflows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestUserErasureWorkflow(t *testing.T) {
+	a := &activities.Activities{}
+	input := UserErasureInput{RequestID: "req", GracePeriod: 72 * time.Hour}
+
+	t.Run("Erases After The Grace Period", func(t *testing.T) {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivity(a)
+
+		env.OnActivity(a.EraseUserData, mock.Anything, activities.EraseUserDataInput{RequestID: "req"}).
+			Return(&models.ErasureRequest{ID: "req", Status: models.ErasureStatusCompleted}, nil).Once()
+
+		env.ExecuteWorkflow(UserErasureWorkflowFunc, input)
+
+		assert.True(t, env.IsWorkflowCompleted())
+		assert.NoError(t, env.GetWorkflowError())
+
+		var result UserErasureResult
+		assert.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, UserErasureResult{RequestID: "req", Status: models.ErasureStatusCompleted}, result)
+		env.AssertExpectations(t)
+	})
+
+	t.Run("Cancelled During The Grace Period", func(t *testing.T) {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivity(a)
+
+		env.RegisterDelayedCallback(func() {
+			env.SignalWorkflow(CancelErasureSignal, nil)
+		}, time.Hour)
+
+		env.ExecuteWorkflow(UserErasureWorkflowFunc, input)
+
+		assert.True(t, env.IsWorkflowCompleted())
+		assert.NoError(t, env.GetWorkflowError())
+
+		var result UserErasureResult
+		assert.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, models.ErasureStatusCancelled, result.Status)
+		env.AssertNotCalled(t, "EraseUserData", mock.Anything, mock.Anything)
+	})
+}
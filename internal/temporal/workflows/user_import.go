@@ -0,0 +1,165 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// DefaultImportBatchSize is the number of rows each import activity applies.
+	DefaultImportBatchSize = 100
+
+	// importBatchesPerRun bounds the history of one run; the workflow
+	// continues as new after this many batches.
+	importBatchesPerRun = 50
+)
+
+type UserImportInput struct {
+	ImportID  string `json:"import_id"`
+	BatchSize int    `json:"batch_size"`
+}
+
+type UserImportResult struct {
+	ImportID  string `json:"import_id"`
+	Status    string `json:"status"`
+	Processed int    `json:"processed"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Failed    int    `json:"failed"`
+}
+
+// UserImportWorkflowFunc applies the rows of an import in batches, starting
+// an onboarding workflow for every user created when the import asked for it.
+// Progress lives in the database, so the workflow can continue as new on long
+// imports without carrying any state over.
+func UserImportWorkflowFunc(ctx workflow.Context, input UserImportInput) (UserImportResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting user import workflow", "importID", input.ImportID)
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		HeartbeatTimeout:    30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	activityHandler := &activities.Activities{}
+
+	for batches := 0; ; batches++ {
+		if batches == importBatchesPerRun {
+			return UserImportResult{}, workflow.NewContinueAsNewError(ctx, UserImportWorkflowFunc, input)
+		}
+
+		var batch models.ImportBatchResult
+		err := workflow.ExecuteActivity(ctx, activityHandler.ImportUserBatch, activities.ImportBatchInput{
+			ImportID:  input.ImportID,
+			BatchSize: input.BatchSize,
+		}).Get(ctx, &batch)
+		if err != nil {
+			logger.Error("Failed to import user batch", "error", err)
+			finishUserImport(ctx, input.ImportID, err.Error())
+			return UserImportResult{ImportID: input.ImportID, Status: models.ImportStatusFailed}, err
+		}
+
+		startOnboarding(ctx, batch.Created)
+
+		if batch.Done {
+			break
+		}
+	}
+
+	job, err := finishUserImport(ctx, input.ImportID, "")
+	if err != nil {
+		return UserImportResult{ImportID: input.ImportID}, err
+	}
+
+	return UserImportResult{
+		ImportID:  job.ID,
+		Status:    job.Status,
+		Processed: job.Processed,
+		Created:   job.Created,
+		Updated:   job.Updated,
+		Failed:    job.Failed,
+	}, nil
+}
+
+// startOnboarding starts an onboarding workflow for each user and waits until
+// they are running. They are abandoned rather than awaited, since onboarding
+// takes a day.
+func startOnboarding(ctx workflow.Context, users []models.ImportedUser) {
+	logger := workflow.GetLogger(ctx)
+
+	var futures []workflow.Future
+	for _, user := range users {
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID:        fmt.Sprintf("user-onboarding-%d", user.ID),
+			ParentClosePolicy: enumspb.PARENT_CLOSE_POLICY_ABANDON,
+		})
+		child := workflow.ExecuteChildWorkflow(childCtx, UserOnboardingWorkflowFunc, UserOnboardingInput{
+			UserID:   user.ID,
+			Email:    user.Email,
+			Username: user.Username,
+		})
+		futures = append(futures, child.GetChildWorkflowExecution())
+	}
+
+	for i, future := range futures {
+		if err := future.Get(ctx, nil); err != nil {
+			logger.Error("Failed to start onboarding", "userID", users[i].ID, "error", err)
+		}
+	}
+}
+
+func finishUserImport(ctx workflow.Context, importID, failure string) (*models.UserImport, error) {
+	var job models.UserImport
+	err := workflow.ExecuteActivity(ctx, (&activities.Activities{}).FinishUserImport, activities.FinishImportInput{
+		ImportID: importID,
+		Error:    failure,
+	}).Get(ctx, &job)
+	if err != nil {
+		workflow.GetLogger(ctx).Error("Failed to finish user import", "error", err)
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UserImportStarter starts import workflows for the import service.
+type UserImportStarter struct {
+	client client.Client
+}
+
+func NewUserImportStarter(c client.Client) *UserImportStarter {
+	return &UserImportStarter{
+		client: c,
+	}
+}
+
+func (s *UserImportStarter) StartImport(ctx context.Context, importID string) (string, error) {
+	options := client.StartWorkflowOptions{
+		ID: "user-import-" + importID,
+		// Imports run on the same worker as onboarding
+		TaskQueue: OnboardingTaskQueue,
+	}
+
+	run, err := s.client.ExecuteWorkflow(ctx, options, UserImportWorkflowFunc, UserImportInput{
+		ImportID:  importID,
+		BatchSize: DefaultImportBatchSize,
+	})
+	if err != nil {
+		return "", err
+	}
+	return run.GetID(), nil
+}
@@ -0,0 +1,69 @@
+package workflows
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/models"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestUserImportWorkflow(t *testing.T) {
+	a := &activities.Activities{}
+
+	t.Run("Imports In Batches And Onboards Created Users", func(t *testing.T) {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivity(a)
+		env.RegisterWorkflow(UserOnboardingWorkflowFunc)
+
+		input := activities.ImportBatchInput{ImportID: "job", BatchSize: 2}
+		env.OnActivity(a.ImportUserBatch, mock.Anything, input).Return(&models.ImportBatchResult{
+			Processed: 2,
+			Created:   []models.ImportedUser{{ID: 1, Email: "a@example.com", Username: "alice"}, {ID: 2, Email: "b@example.com", Username: "bob"}},
+		}, nil).Once()
+		env.OnActivity(a.ImportUserBatch, mock.Anything, input).Return(&models.ImportBatchResult{Processed: 1, Done: true}, nil).Once()
+		env.OnActivity(a.FinishUserImport, mock.Anything, activities.FinishImportInput{ImportID: "job"}).Return(&models.UserImport{
+			ID: "job", Status: models.ImportStatusCompleted, Processed: 3, Created: 2, Failed: 1,
+		}, nil).Once()
+
+		var onboarded []uint
+		env.OnWorkflow(UserOnboardingWorkflowFunc, mock.Anything, mock.MatchedBy(func(input UserOnboardingInput) bool {
+			onboarded = append(onboarded, input.UserID)
+			return true
+		})).Return(UserOnboardingResult{Success: true}, nil).Times(2)
+
+		env.ExecuteWorkflow(UserImportWorkflowFunc, UserImportInput{ImportID: "job", BatchSize: 2})
+
+		assert.True(t, env.IsWorkflowCompleted())
+		assert.NoError(t, env.GetWorkflowError())
+
+		var result UserImportResult
+		assert.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, UserImportResult{ImportID: "job", Status: models.ImportStatusCompleted, Processed: 3, Created: 2, Failed: 1}, result)
+		assert.ElementsMatch(t, []uint{1, 2}, onboarded)
+		env.AssertExpectations(t)
+	})
+
+	t.Run("Marks The Import Failed", func(t *testing.T) {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivity(a)
+
+		env.OnActivity(a.ImportUserBatch, mock.Anything, mock.Anything).
+			Return(nil, temporal.NewNonRetryableApplicationError("user import not found", "ImportNotFound", errors.New("user import not found"))).Once()
+		env.OnActivity(a.FinishUserImport, mock.Anything, mock.MatchedBy(func(input activities.FinishImportInput) bool {
+			return input.ImportID == "job" && input.Error != ""
+		})).Return(&models.UserImport{ID: "job", Status: models.ImportStatusFailed}, nil).Once()
+
+		env.ExecuteWorkflow(UserImportWorkflowFunc, UserImportInput{ImportID: "job", BatchSize: 2})
+
+		assert.True(t, env.IsWorkflowCompleted())
+		assert.Error(t, env.GetWorkflowError())
+		env.AssertExpectations(t)
+	})
+}
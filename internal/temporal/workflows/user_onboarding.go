@@ -14,8 +14,8 @@ const (
 )
 
 type UserOnboardingInput struct {
-	UserID   uint   `json:"user_id"`
-	Email    string `json:"email"`
+	UserID   uint   `json:"user_id" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
 	Username string `json:"username"`
 }
 
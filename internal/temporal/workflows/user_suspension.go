@@ -0,0 +1,86 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+type UserSuspensionInput struct {
+	UserID uint      `json:"user_id"`
+	Until  time.Time `json:"until"`
+}
+
+// UserSuspensionWorkflowFunc waits until a suspension runs out and then
+// reactivates the user. Every suspension with an end starts its own
+// workflow; the activity ignores those whose suspension has since been
+// lifted or changed.
+func UserSuspensionWorkflowFunc(ctx workflow.Context, input UserSuspensionInput) error {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting user suspension workflow", "userID", input.UserID, "until", input.Until)
+
+	if wait := input.Until.Sub(workflow.Now(ctx)); wait > 0 {
+		if err := workflow.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    10 * time.Minute,
+			// Keep retrying; until then logins already treat the suspension
+			// as over
+			MaximumAttempts: 0,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	err := workflow.ExecuteActivity(ctx, (&activities.Activities{}).EndUserSuspension, activities.EndUserSuspensionInput{
+		UserID: input.UserID,
+		Until:  input.Until,
+	}).Get(ctx, nil)
+	if err != nil {
+		logger.Error("Failed to end user suspension", "error", err)
+		return err
+	}
+	return nil
+}
+
+// UserSuspensionStarter schedules the end of suspensions for the user
+// status service.
+type UserSuspensionStarter struct {
+	client client.Client
+}
+
+func NewUserSuspensionStarter(c client.Client) *UserSuspensionStarter {
+	return &UserSuspensionStarter{
+		client: c,
+	}
+}
+
+func (s *UserSuspensionStarter) ScheduleUnsuspend(ctx context.Context, userID uint, until time.Time) (string, error) {
+	options := client.StartWorkflowOptions{
+		// One workflow per suspension end, so replacing a suspension does
+		// not collide with the timer of the one it replaces
+		ID: fmt.Sprintf("user-suspension-%d-%d", userID, until.Unix()),
+		// Suspensions run on the same worker as onboarding
+		TaskQueue: OnboardingTaskQueue,
+	}
+
+	run, err := s.client.ExecuteWorkflow(ctx, options, UserSuspensionWorkflowFunc, UserSuspensionInput{
+		UserID: userID,
+		Until:  until,
+	})
+	if err != nil {
+		return "", err
+	}
+	return run.GetID(), nil
+}
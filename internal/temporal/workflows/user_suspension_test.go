@@ -0,0 +1,33 @@
+package workflows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/witslab-sahil/fiber-boilerplate/internal/temporal/activities"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestUserSuspensionWorkflow(t *testing.T) {
+	a := &activities.Activities{}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(a)
+
+	start := env.Now()
+	until := start.Add(48 * time.Hour)
+	env.OnActivity(a.EndUserSuspension, mock.Anything, mock.MatchedBy(func(input activities.EndUserSuspensionInput) bool {
+		return input.UserID == 42 && input.Until.Equal(until)
+	})).Return(nil).Once()
+
+	env.ExecuteWorkflow(UserSuspensionWorkflowFunc, UserSuspensionInput{UserID: 42, Until: until})
+
+	assert.True(t, env.IsWorkflowCompleted())
+	assert.NoError(t, env.GetWorkflowError())
+	// The suspension is only ended once it has run out
+	assert.False(t, env.Now().Before(until))
+	env.AssertExpectations(t)
+}
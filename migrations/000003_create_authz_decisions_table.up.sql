@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS authz_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    timestamp TIMESTAMP NOT NULL,
+    request_id VARCHAR(64),
+    input_hash CHAR(64) NOT NULL,
+    principal VARCHAR(255),
+    method VARCHAR(16),
+    path TEXT,
+    allowed BOOLEAN NOT NULL,
+    matched_rules TEXT,
+    policy_revision VARCHAR(64),
+    latency_ms DOUBLE PRECISION,
+    error TEXT
+);
+
+CREATE INDEX idx_authz_decisions_timestamp ON authz_decisions(timestamp);
+CREATE INDEX idx_authz_decisions_request_id ON authz_decisions(request_id);
+CREATE INDEX idx_authz_decisions_input_hash ON authz_decisions(input_hash);
+CREATE INDEX idx_authz_decisions_principal ON authz_decisions(principal);
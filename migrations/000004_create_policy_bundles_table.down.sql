@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS policy_activations;
+DROP TABLE IF EXISTS policy_bundles;
\ No newline at end of file
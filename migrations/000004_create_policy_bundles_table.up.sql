@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS policy_bundles (
+    id SERIAL PRIMARY KEY,
+    revision VARCHAR(64) UNIQUE NOT NULL,
+    modules TEXT NOT NULL,
+    data TEXT,
+    description TEXT,
+    active BOOLEAN DEFAULT false,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    activated_at TIMESTAMP
+);
+
+CREATE INDEX idx_policy_bundles_active ON policy_bundles(active);
+
+CREATE TABLE IF NOT EXISTS policy_activations (
+    id SERIAL PRIMARY KEY,
+    revision VARCHAR(64) NOT NULL,
+    activated_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_policy_activations_revision ON policy_activations(revision);
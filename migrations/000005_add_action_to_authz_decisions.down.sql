@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_authz_decisions_action;
+
+ALTER TABLE authz_decisions DROP COLUMN IF EXISTS resource;
+ALTER TABLE authz_decisions DROP COLUMN IF EXISTS action;
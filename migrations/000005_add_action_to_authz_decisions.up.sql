@@ -0,0 +1,4 @@
+ALTER TABLE authz_decisions ADD COLUMN IF NOT EXISTS action VARCHAR(128);
+ALTER TABLE authz_decisions ADD COLUMN IF NOT EXISTS resource TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_authz_decisions_action ON authz_decisions(action);
@@ -0,0 +1,13 @@
+-- Trigram indexes serve the case-insensitive prefix and substring search
+-- behind GET /api/v1/users?q=, and the email_domain suffix filter.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (LOWER(username) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (LOWER(email) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_first_name_trgm ON users USING gin (LOWER(first_name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_last_name_trgm ON users USING gin (LOWER(last_name) gin_trgm_ops);
+
+-- Filters and sort keys
+CREATE INDEX IF NOT EXISTS idx_users_roles ON users USING gin (roles);
+CREATE INDEX IF NOT EXISTS idx_users_is_active ON users (is_active);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at);
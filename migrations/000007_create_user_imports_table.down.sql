@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_import_rows;
+DROP TABLE IF EXISTS user_imports;
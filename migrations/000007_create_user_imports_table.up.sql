@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS user_imports (
+    id VARCHAR(36) PRIMARY KEY,
+    format VARCHAR(16) NOT NULL,
+    mode VARCHAR(16) NOT NULL,
+    dry_run BOOLEAN DEFAULT false,
+    onboard BOOLEAN DEFAULT false,
+    status VARCHAR(16) NOT NULL,
+    workflow_id VARCHAR(255),
+    total_rows INTEGER DEFAULT 0,
+    processed INTEGER DEFAULT 0,
+    created INTEGER DEFAULT 0,
+    updated INTEGER DEFAULT 0,
+    failed INTEGER DEFAULT 0,
+    error TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_imports_status ON user_imports(status);
+
+-- One row per record of the uploaded file; the worker applies pending rows
+-- in file order and keeps the outcome for the error report
+CREATE TABLE IF NOT EXISTS user_import_rows (
+    id SERIAL PRIMARY KEY,
+    import_id VARCHAR(36) NOT NULL REFERENCES user_imports(id) ON DELETE CASCADE,
+    line INTEGER NOT NULL,
+    record TEXT,
+    status VARCHAR(16) NOT NULL,
+    error TEXT,
+    user_id INTEGER
+);
+
+CREATE INDEX idx_user_import_rows_import_line ON user_import_rows(import_id, line);
@@ -0,0 +1,18 @@
+-- Background exports; the file itself is kept in export storage under
+-- users-<id>.<format>
+CREATE TABLE IF NOT EXISTS user_exports (
+    id VARCHAR(36) PRIMARY KEY,
+    format VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    query TEXT,
+    fields TEXT,
+    row_count INTEGER DEFAULT 0,
+    error TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_exports_status ON user_exports(status);
+CREATE INDEX idx_user_exports_created_by ON user_exports(created_by);
@@ -0,0 +1,9 @@
+-- Fails if a deleted user shares an email or username with another user;
+-- purge or rename those first
+DROP INDEX IF EXISTS idx_users_email_live;
+DROP INDEX IF EXISTS idx_users_username_live;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
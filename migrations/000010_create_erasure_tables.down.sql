@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS erasure_certificates;
+DROP TABLE IF EXISTS erasure_requests;
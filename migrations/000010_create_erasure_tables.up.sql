@@ -0,0 +1,31 @@
+-- Requests to erase a user's personal data; user_id is not a foreign key
+-- since the user may be purged before the request completes
+CREATE TABLE IF NOT EXISTS erasure_requests (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    reason TEXT,
+    requested_by VARCHAR(255),
+    workflow_id VARCHAR(255),
+    grace_until TIMESTAMP NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_erasure_requests_user_id ON erasure_requests(user_id);
+CREATE INDEX idx_erasure_requests_status ON erasure_requests(status);
+
+-- Signed records of completed erasures; they hold no personal data
+CREATE TABLE IF NOT EXISTS erasure_certificates (
+    id VARCHAR(36) PRIMARY KEY,
+    request_id VARCHAR(36) NOT NULL REFERENCES erasure_requests(id),
+    user_id INTEGER NOT NULL,
+    steps TEXT,
+    requested_at TIMESTAMP NOT NULL,
+    erased_at TIMESTAMP NOT NULL,
+    signature VARCHAR(255) NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_erasure_certificates_request_id ON erasure_certificates(request_id);
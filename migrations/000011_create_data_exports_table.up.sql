@@ -0,0 +1,17 @@
+-- Data-subject access exports; the archive is kept in export storage under
+-- data-export-<id>.zip
+CREATE TABLE IF NOT EXISTS data_exports (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    workflow_id VARCHAR(255),
+    sections TEXT,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX idx_data_exports_user_id ON data_exports(user_id);
+CREATE INDEX idx_data_exports_status ON data_exports(status);
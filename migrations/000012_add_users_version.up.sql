@@ -0,0 +1,3 @@
+-- Bumped on every update; served as the user's ETag for optimistic
+-- concurrency control
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
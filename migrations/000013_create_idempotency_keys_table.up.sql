@@ -0,0 +1,17 @@
+-- Responses to requests made with an Idempotency-Key header, replayed for
+-- retries until expires_at
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) NOT NULL,
+    principal VARCHAR(255) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    response_status INTEGER,
+    response_headers TEXT,
+    response_body BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (key, principal, route)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
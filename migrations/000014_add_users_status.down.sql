@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS user_status_transitions;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_active BOOLEAN DEFAULT true;
+UPDATE users SET is_active = (status = 'active');
+CREATE INDEX IF NOT EXISTS idx_users_is_active ON users (is_active);
+
+DROP INDEX IF EXISTS idx_users_status;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_until;
+ALTER TABLE users DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS status;
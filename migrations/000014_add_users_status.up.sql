@@ -0,0 +1,25 @@
+-- Account status replaces is_active: pending_verification, active,
+-- suspended, locked or deactivated
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP;
+
+UPDATE users SET status = 'deactivated' WHERE is_active = false;
+
+DROP INDEX IF EXISTS idx_users_is_active;
+ALTER TABLE users DROP COLUMN IF EXISTS is_active;
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status);
+
+-- Audit trail of status changes
+CREATE TABLE IF NOT EXISTS user_status_transitions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    from_status VARCHAR(32) NOT NULL,
+    to_status VARCHAR(32) NOT NULL,
+    reason TEXT,
+    until TIMESTAMP,
+    changed_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_status_transitions_user_id ON user_status_transitions(user_id);
@@ -0,0 +1,42 @@
+-- A snapshot of a user after each change, without the password hash, for
+-- the change history and point-in-time reads
+CREATE TABLE IF NOT EXISTS user_revisions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    operation VARCHAR(16) NOT NULL,
+    snapshot TEXT,
+    changed_by VARCHAR(255),
+    request_id VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_revisions_user_created ON user_revisions(user_id, created_at);
+
+-- Existing users start their history as they stand now, deleted ones with
+-- their deletion. Timestamps are stored in UTC; converting them to
+-- timestamptz renders them with an offset, as the API does.
+INSERT INTO user_revisions (user_id, version, operation, snapshot, changed_by, created_at)
+SELECT
+    id,
+    version,
+    CASE WHEN deleted_at IS NULL THEN 'baseline' ELSE 'delete' END,
+    json_build_object(
+        'id', id,
+        'email', email,
+        'username', username,
+        'first_name', first_name,
+        'last_name', last_name,
+        'roles', roles,
+        'status', status,
+        'status_reason', status_reason,
+        'suspended_until', suspended_until AT TIME ZONE 'UTC',
+        'is_active', status = 'active',
+        'version', version,
+        'created_at', created_at AT TIME ZONE 'UTC',
+        'updated_at', updated_at AT TIME ZONE 'UTC',
+        'deleted_at', deleted_at AT TIME ZONE 'UTC'
+    )::text,
+    '',
+    COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+FROM users;
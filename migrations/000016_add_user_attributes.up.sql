@@ -0,0 +1,13 @@
+-- Custom attributes, validated against the latest user_attribute_schemas row
+ALTER TABLE users ADD COLUMN IF NOT EXISTS attributes JSONB;
+
+-- Serves attributes.<key>=value filters, which are containment queries
+CREATE INDEX IF NOT EXISTS idx_users_attributes ON users USING GIN (attributes jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS user_attribute_schemas (
+    id SERIAL PRIMARY KEY,
+    version INTEGER NOT NULL UNIQUE,
+    schema TEXT NOT NULL,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,22 @@
+-- Groups grant roles to their members; members are users or other groups
+CREATE TABLE IF NOT EXISTS groups (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    roles TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    member_type VARCHAR(16) NOT NULL,
+    member_id INTEGER NOT NULL,
+    added_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, member_type, member_id)
+);
+
+-- Resolving a user's groups walks memberships upwards from the member
+CREATE INDEX IF NOT EXISTS idx_group_members_member ON group_members (member_type, member_id);
@@ -0,0 +1,138 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, doc string) map[string]interface{} {
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(doc), &out))
+	return out
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		// add, remove and replace
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, nil},
+		{"add replaces member", `{"a":1}`, `[{"op":"add","path":"/a","value":[1]}]`, `{"a":[1]}`, nil},
+		{"add to missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`, ``, ErrCannotApply},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`, nil},
+		{"remove missing member", `{"a":1}`, `[{"op":"remove","path":"/b"}]`, ``, ErrCannotApply},
+		{"replace member", `{"a":{"b":1}}`, `[{"op":"replace","path":"/a/b","value":"x"}]`, `{"a":{"b":"x"}}`, nil},
+		{"replace missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":1}]`, ``, ErrCannotApply},
+		{"replace whole document", `{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`, nil},
+		{"result is not an object", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, ``, ErrCannotApply},
+
+		// arrays
+		{"add at index", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`, nil},
+		{"add at start", `{"a":[2]}`, `[{"op":"add","path":"/a/0","value":1}]`, `{"a":[1,2]}`, nil},
+		{"add at length", `{"a":[1]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2]}`, nil},
+		{"append with dash", `{"a":[1,2]}`, `[{"op":"add","path":"/a/-","value":3}]`, `{"a":[1,2,3]}`, nil},
+		{"append to empty array", `{"a":[]}`, `[{"op":"add","path":"/a/-","value":1}]`, `{"a":[1]}`, nil},
+		{"add past length", `{"a":[1]}`, `[{"op":"add","path":"/a/2","value":2}]`, ``, ErrCannotApply},
+		{"add at negative index", `{"a":[1]}`, `[{"op":"add","path":"/a/-1","value":2}]`, ``, ErrCannotApply},
+		{"add at leading zero", `{"a":[1,2]}`, `[{"op":"add","path":"/a/01","value":2}]`, ``, ErrCannotApply},
+		{"remove at index", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`, nil},
+		{"remove last element", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1]}`, nil},
+		{"remove out of range", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/2"}]`, ``, ErrCannotApply},
+		{"remove with dash", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/-"}]`, ``, ErrCannotApply},
+		{"replace out of range", `{"a":[1]}`, `[{"op":"replace","path":"/a/1","value":2}]`, ``, ErrCannotApply},
+		{"replace in nested array", `{"a":[{"b":[1,2]}]}`, `[{"op":"replace","path":"/a/0/b/1","value":3}]`, `{"a":[{"b":[1,3]}]}`, nil},
+		{"index into scalar", `{"a":1}`, `[{"op":"add","path":"/a/0/b","value":1}]`, ``, ErrCannotApply},
+
+		// move and copy
+		{"move member", `{"a":{"b":1},"c":{}}`, `[{"op":"move","from":"/a/b","path":"/c/d"}]`, `{"a":{},"c":{"d":1}}`, nil},
+		{"move to itself", `{"a":1}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":1}`, nil},
+		{"move into own child", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, ``, ErrCannotApply},
+		{"move into own grandchild", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, ``, ErrCannotApply},
+		{"move to sibling with shared prefix", `{"a":1}`, `[{"op":"move","from":"/a","path":"/ab"}]`, `{"ab":1}`, nil},
+		{"move within array", `{"a":[1,2,3]}`, `[{"op":"move","from":"/a/0","path":"/a/-"}]`, `{"a":[2,3,1]}`, nil},
+		{"move between arrays", `{"a":[1,2],"b":[3]}`, `[{"op":"move","from":"/a/1","path":"/b/0"}]`, `{"a":[1],"b":[2,3]}`, nil},
+		{"move missing member", `{"a":1}`, `[{"op":"move","from":"/b","path":"/c"}]`, ``, ErrCannotApply},
+		{"move without from", `{"a":1}`, `[{"op":"move","path":"/b"}]`, ``, ErrInvalidPatch},
+		{"copy member", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`, nil},
+		{"copy into own child", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/a/c"}]`, `{"a":{"b":1,"c":{"b":1}}}`, nil},
+		{"copy is independent", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`, nil},
+		{"copy array element", `{"a":[1,2]}`, `[{"op":"copy","from":"/a/1","path":"/a/0"}]`, `{"a":[2,1,2]}`, nil},
+		{"copy out of range", `{"a":[1]}`, `[{"op":"copy","from":"/a/1","path":"/b"}]`, ``, ErrCannotApply},
+
+		// escaping
+		{"escaped slash", `{"a/b":1}`, `[{"op":"replace","path":"/a~1b","value":2}]`, `{"a/b":2}`, nil},
+		{"escaped tilde", `{"a~b":1}`, `[{"op":"remove","path":"/a~0b"}]`, `{}`, nil},
+		{"tilde one is not slash", `{"~1":1}`, `[{"op":"add","path":"/~01","value":2}]`, `{"~1":2}`, nil},
+		{"escaped from", `{"a/b":1}`, `[{"op":"move","from":"/a~1b","path":"/c~0d"}]`, `{"c~d":1}`, nil},
+		{"empty member name", `{"":1}`, `[{"op":"replace","path":"/","value":2}]`, `{"":2}`, nil},
+
+		// test
+		{"test nested object", `{"a":{"b":{"c":[1,{"d":true}]}}}`, `[{"op":"test","path":"/a/b","value":{"c":[1,{"d":true}]}}]`, `{"a":{"b":{"c":[1,{"d":true}]}}}`, nil},
+		{"test nested array element", `{"a":[{"b":[1,2]}]}`, `[{"op":"test","path":"/a/0/b/1","value":2}]`, `{"a":[{"b":[1,2]}]}`, nil},
+		{"test array order", `{"a":[1,2]}`, `[{"op":"test","path":"/a","value":[2,1]}]`, ``, ErrTestFailed},
+		{"test object member", `{"a":{"b":1}}`, `[{"op":"test","path":"/a","value":{"b":1,"c":2}}]`, ``, ErrTestFailed},
+		{"test number type", `{"a":1}`, `[{"op":"test","path":"/a","value":"1"}]`, ``, ErrTestFailed},
+		{"test missing member", `{"a":1}`, `[{"op":"test","path":"/b","value":1}]`, ``, ErrCannotApply},
+		{"failed test undoes patch", `{"a":1}`, `[{"op":"add","path":"/b","value":2},{"op":"test","path":"/a","value":2}]`, ``, ErrTestFailed},
+
+		// malformed patches
+		{"not an array", `{}`, `{"op":"add"}`, ``, ErrInvalidPatch},
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a"}]`, ``, ErrInvalidPatch},
+		{"no path", `{}`, `[{"op":"add","value":1}]`, ``, ErrInvalidPatch},
+		{"no value", `{}`, `[{"op":"add","path":"/a"}]`, ``, ErrInvalidPatch},
+		{"path without slash", `{}`, `[{"op":"add","path":"a","value":1}]`, ``, ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := decode(t, tt.doc)
+			got, err := Apply(doc, []byte(tt.patch))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, decode(t, tt.want), got)
+			assert.Equal(t, decode(t, tt.doc), doc, "the document is left unchanged")
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{"sets member", `{"a":1}`, `{"b":2}`, `{"a":1,"b":2}`, nil},
+		{"null removes member", `{"a":1,"b":2}`, `{"a":null}`, `{"b":2}`, nil},
+		{"merges nested objects", `{"a":{"b":1,"c":2}}`, `{"a":{"c":null,"d":3}}`, `{"a":{"b":1,"d":3}}`, nil},
+		{"replaces arrays", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`, nil},
+		{"object replaces scalar", `{"a":1}`, `{"a":{"b":null,"c":1}}`, `{"a":{"c":1}}`, nil},
+		{"not an object", `{"a":1}`, `[1]`, ``, ErrInvalidPatch},
+		{"not json", `{"a":1}`, `{`, ``, ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := decode(t, tt.doc)
+			got, err := MergePatch(doc, []byte(tt.patch))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, decode(t, tt.want), got)
+			assert.Equal(t, decode(t, tt.doc), doc, "the document is left unchanged")
+		})
+	}
+}
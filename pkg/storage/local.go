@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local stores files in a directory on the local disk. Names are flat: any
+// directory part is discarded, so callers cannot escape the directory.
+type Local struct {
+	dir string
+}
+
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &Local{dir: dir}, nil
+}
+
+// Create opens name for writing, replacing any existing file. The file only
+// appears under name once the writer is closed, so readers never see a
+// partial file.
+func (s *Local) Create(name string) (io.WriteCloser, error) {
+	file, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return &localFile{File: file, path: s.path(name)}, nil
+}
+
+func (s *Local) Open(name string) (io.ReadCloser, error) {
+	return os.Open(s.path(name))
+}
+
+func (s *Local) Remove(name string) error {
+	err := os.Remove(s.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *Local) path(name string) string {
+	return filepath.Join(s.dir, filepath.Base(name))
+}
+
+// localFile renames the temporary file into place when closed.
+type localFile struct {
+	*os.File
+	path string
+}
+
+func (f *localFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	if err := os.Rename(f.File.Name(), f.path); err != nil {
+		os.Remove(f.File.Name())
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	return nil
+}
@@ -12,4 +12,9 @@ func HashPassword(password string) (string, error) {
 func CheckPassword(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// UnusablePassword is stored for accounts created without a password, such as
+// imported users. It is not a bcrypt hash, so CheckPassword never accepts
+// anything against it.
+const UnusablePassword = "!"
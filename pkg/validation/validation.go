@@ -0,0 +1,244 @@
+// Package validation checks structs against the rules declared in their
+// `validate` or `binding` struct tags, e.g. `binding:"required,email"`.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError reports a field that broke a rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors lists every field error found in a struct.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// RuleFunc reports whether value satisfies a rule. param is the text after
+// "=" in the tag, e.g. "3" for min=3.
+type RuleFunc func(value reflect.Value, param string) bool
+
+type rule struct {
+	check   RuleFunc
+	message string
+}
+
+var (
+	mu    sync.RWMutex
+	rules = map[string]rule{
+		"required": {required, "is required"},
+		"email":    {email, "must be a valid email address"},
+		"oneof":    {oneOf, "must be one of: %s"},
+	}
+)
+
+// RegisterRule adds a rule that tags can refer to by name. message follows
+// the field name in the error and may contain %s for the rule's param.
+func RegisterRule(name string, check RuleFunc, message string) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules[name] = rule{check: check, message: message}
+}
+
+// Validate checks v, a struct or a pointer to one, and returns Errors if any
+// field breaks its rules. Nested structs are checked too, with their fields
+// named after the parent, e.g. "input.email", as are the structs in slices,
+// e.g. "updates[0].id". A tag naming an unknown rule is a programming error
+// and panics.
+func Validate(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs Errors
+	validateStruct(value, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(value reflect.Value, prefix string, errs *Errors) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+		name = prefix + name
+
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok {
+			tag = field.Tag.Get("binding")
+		}
+		fieldValue := value.Field(i)
+		if !validateField(fieldValue, name, tag, errs) {
+			continue
+		}
+
+		nested := reflect.Indirect(fieldValue)
+		switch {
+		case nested.Kind() == reflect.Struct && nested.Type().PkgPath() != "time":
+			validateStruct(nested, name+".", errs)
+		case nested.Kind() == reflect.Slice || nested.Kind() == reflect.Array:
+			for j := 0; j < nested.Len(); j++ {
+				if item := reflect.Indirect(nested.Index(j)); item.Kind() == reflect.Struct && item.Type().PkgPath() != "time" {
+					validateStruct(item, fmt.Sprintf("%s[%d].", name, j), errs)
+				}
+			}
+		}
+	}
+}
+
+// validateField applies the rules in tag to value, stopping at the first one
+// it breaks, and reports whether it passed.
+func validateField(value reflect.Value, name, tag string, errs *Errors) bool {
+	if tag == "" || tag == "-" {
+		return true
+	}
+
+	for _, spec := range strings.Split(tag, ",") {
+		ruleName, param, _ := strings.Cut(strings.TrimSpace(spec), "=")
+		switch ruleName {
+		case "":
+			continue
+		case "omitempty":
+			if isEmpty(value) {
+				return true
+			}
+			continue
+		case "required":
+			// A pointer only has to be set; what it points at is checked by
+			// the other rules
+			if value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					*errs = append(*errs, newFieldError(name, ruleName, "is required", param))
+					return false
+				}
+				continue
+			}
+		}
+
+		target := reflect.Indirect(value)
+		if !target.IsValid() {
+			continue
+		}
+
+		var ok bool
+		var message string
+		switch ruleName {
+		case "min", "max":
+			ok, message = bound(target, ruleName, param)
+		default:
+			mu.RLock()
+			r, found := rules[ruleName]
+			mu.RUnlock()
+			if !found {
+				panic(fmt.Sprintf("validation: unknown rule %q on %s", ruleName, name))
+			}
+			ok, message = r.check(target, param), r.message
+		}
+		if !ok {
+			*errs = append(*errs, newFieldError(name, ruleName, message, param))
+			return false
+		}
+	}
+	return true
+}
+
+func newFieldError(field, rule, message, param string) FieldError {
+	if strings.Contains(message, "%s") {
+		message = fmt.Sprintf(message, strings.ReplaceAll(param, " ", ", "))
+	}
+	return FieldError{Field: field, Rule: rule, Message: field + " " + message}
+}
+
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+func isEmpty(value reflect.Value) bool {
+	return !value.IsValid() || value.IsZero()
+}
+
+func required(value reflect.Value, _ string) bool {
+	if value.Kind() == reflect.String {
+		return strings.TrimSpace(value.String()) != ""
+	}
+	return !value.IsZero()
+}
+
+func email(value reflect.Value, _ string) bool {
+	if value.Kind() != reflect.String {
+		return false
+	}
+	address, err := mail.ParseAddress(value.String())
+	return err == nil && address.Address == value.String()
+}
+
+func oneOf(value reflect.Value, param string) bool {
+	s := fmt.Sprint(value.Interface())
+	for _, option := range strings.Fields(param) {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
+// bound checks min and max: the length of strings (in characters) and
+// collections, or the value of numbers.
+func bound(value reflect.Value, rule, param string) (bool, string) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: %s needs a number, got %q", rule, param))
+	}
+
+	var n float64
+	var unit string
+	switch value.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n, unit = float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	default:
+		return false, "cannot be checked against " + rule
+	}
+
+	if rule == "min" {
+		return n >= limit, "must be at least %s" + unit
+	}
+	return n <= limit, "must be at most %s" + unit
+}
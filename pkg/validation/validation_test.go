@@ -0,0 +1,263 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fieldErrors validates v and returns the field errors it found.
+func fieldErrors(t *testing.T, v interface{}) Errors {
+	err := Validate(v)
+	if err == nil {
+		return nil
+	}
+	errs, ok := err.(Errors)
+	require.True(t, ok, "Validate returned %T", err)
+	return errs
+}
+
+func TestValidate_Rules(t *testing.T) {
+	type required struct {
+		Name  string `json:"name" binding:"required"`
+		Count int    `json:"count" binding:"required"`
+	}
+	type email struct {
+		Email string `json:"email" binding:"email"`
+	}
+	type oneOf struct {
+		Status string `json:"status" binding:"oneof=active locked"`
+		Level  int    `json:"level" binding:"oneof=1 2"`
+	}
+	type bounds struct {
+		Name  string   `json:"name" binding:"min=2,max=4"`
+		Tags  []string `json:"tags" binding:"max=2"`
+		Age   int      `json:"age" binding:"min=18"`
+		Size  uint     `json:"size" binding:"max=10"`
+		Ratio float64  `json:"ratio" binding:"max=0.5"`
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string // "field rule" pairs
+	}{
+		{"required set", required{Name: "a", Count: 1}, nil},
+		{"required missing", required{}, []string{"name required", "count required"}},
+		{"required blank string", required{Name: "  ", Count: 1}, []string{"name required"}},
+		{"email valid", email{Email: "alice@example.com"}, nil},
+		{"email invalid", email{Email: "alice"}, []string{"email email"}},
+		{"email with display name", email{Email: "Alice <alice@example.com>"}, []string{"email email"}},
+		{"oneof valid", oneOf{Status: "locked", Level: 2}, nil},
+		{"oneof invalid", oneOf{Status: "gone", Level: 3}, []string{"status oneof", "level oneof"}},
+		{"bounds valid", bounds{Name: "abc", Tags: []string{"a"}, Age: 18, Size: 10, Ratio: 0.5}, nil},
+		{"string too short", bounds{Name: "a", Age: 18}, []string{"name min"}},
+		{"string too long", bounds{Name: "abcde", Age: 18}, []string{"name max"}},
+		{"string counts characters", bounds{Name: "éééé", Age: 18}, nil},
+		{"too many items", bounds{Name: "ab", Tags: []string{"a", "b", "c"}, Age: 18}, []string{"tags max"}},
+		{"number too small", bounds{Name: "ab", Age: 17}, []string{"age min"}},
+		{"unsigned too large", bounds{Name: "ab", Age: 18, Size: 11}, []string{"size max"}},
+		{"float too large", bounds{Name: "ab", Age: 18, Ratio: 0.6}, []string{"ratio max"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, fieldErr := range fieldErrors(t, tt.value) {
+				got = append(got, fieldErr.Field+" "+fieldErr.Rule)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate_Messages(t *testing.T) {
+	type request struct {
+		Name   string `json:"name" binding:"min=3"`
+		Status string `json:"status" binding:"oneof=active locked"`
+	}
+
+	errs := fieldErrors(t, request{Name: "a", Status: "gone"})
+
+	require.Len(t, errs, 2)
+	assert.Equal(t, "name must be at least 3 characters", errs[0].Message)
+	assert.Equal(t, "status must be one of: active, locked", errs[1].Message)
+	assert.Equal(t, "name must be at least 3 characters; status must be one of: active, locked", errs.Error())
+}
+
+func TestValidate_StopsAtFirstBrokenRule(t *testing.T) {
+	type request struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	errs := fieldErrors(t, request{})
+
+	require.Len(t, errs, 1)
+	assert.Equal(t, "required", errs[0].Rule)
+}
+
+func TestValidate_FieldNames(t *testing.T) {
+	type request struct {
+		Named   string `json:"named,omitempty" binding:"required"`
+		Untyped string `binding:"required"`
+		Skipped string `json:"-" binding:"required"`
+		hidden  string `binding:"required"`
+	}
+
+	errs := fieldErrors(t, request{hidden: ""})
+
+	require.Len(t, errs, 2)
+	assert.Equal(t, "named", errs[0].Field)
+	assert.Equal(t, "Untyped", errs[1].Field)
+}
+
+func TestValidate_ValidateTagWins(t *testing.T) {
+	type request struct {
+		Name string `json:"name" validate:"max=2" binding:"required"`
+	}
+
+	assert.NoError(t, Validate(request{}))
+	assert.Error(t, Validate(request{Name: "abc"}))
+}
+
+func TestValidate_Nested(t *testing.T) {
+	type address struct {
+		City string `json:"city" binding:"required"`
+	}
+	type item struct {
+		ID uint `json:"id" binding:"required"`
+	}
+	type request struct {
+		Address  address    `json:"address"`
+		Billing  *address   `json:"billing"`
+		Items    []item     `json:"items" binding:"max=3"`
+		Pointers []*item    `json:"pointers"`
+		Created  time.Time  `json:"created"`
+		Expires  *time.Time `json:"expires"`
+	}
+
+	errs := fieldErrors(t, &request{
+		Billing:  &address{},
+		Items:    []item{{ID: 1}, {}},
+		Pointers: []*item{{}},
+	})
+
+	var fields []string
+	for _, fieldErr := range errs {
+		fields = append(fields, fieldErr.Field)
+	}
+	assert.Equal(t, []string{"address.city", "billing.city", "items[1].id", "pointers[0].id"}, fields)
+
+	t.Run("Nil Pointer Is Not Descended", func(t *testing.T) {
+		errs := fieldErrors(t, &request{Address: address{City: "Paris"}})
+		assert.Empty(t, errs)
+	})
+
+	t.Run("Broken Collection Is Not Descended", func(t *testing.T) {
+		errs := fieldErrors(t, &request{Address: address{City: "Paris"}, Items: []item{{}, {}, {}, {}}})
+		require.Len(t, errs, 1)
+		assert.Equal(t, "items", errs[0].Field)
+	})
+}
+
+func TestValidate_Pointers(t *testing.T) {
+	type request struct {
+		Name   *string `json:"name" binding:"required,min=3"`
+		Nick   *string `json:"nick" binding:"omitempty,min=3"`
+		Status *string `json:"status" binding:"oneof=active locked"`
+	}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name  string
+		value request
+		want  []string
+	}{
+		{"required pointer missing", request{}, []string{"name required"}},
+		{"required pointer to empty string", request{Name: str("")}, []string{"name min"}},
+		{"pointed-at value checked", request{Name: str("ab")}, []string{"name min"}},
+		{"nil pointer skips rules", request{Name: str("abc")}, nil},
+		{"omitempty pointer set", request{Name: str("abc"), Nick: str("ab")}, []string{"nick min"}},
+		{"optional pointer set", request{Name: str("abc"), Status: str("gone")}, []string{"status oneof"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, fieldErr := range fieldErrors(t, tt.value) {
+				got = append(got, fieldErr.Field+" "+fieldErr.Rule)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate_OmitEmpty(t *testing.T) {
+	type request struct {
+		Email string   `json:"email" binding:"omitempty,email"`
+		Tags  []string `json:"tags" binding:"omitempty,min=2"`
+		Age   int      `json:"age" binding:"omitempty,min=18"`
+	}
+
+	assert.NoError(t, Validate(request{}))
+	errs := fieldErrors(t, request{Email: "alice", Tags: []string{"a"}, Age: 17})
+	require.Len(t, errs, 3)
+	assert.Equal(t, "email", errs[0].Rule)
+	assert.Equal(t, "min", errs[1].Rule)
+	assert.Equal(t, "min", errs[2].Rule)
+}
+
+func TestValidate_NotAStruct(t *testing.T) {
+	assert.NoError(t, Validate("text"))
+	assert.NoError(t, Validate(nil))
+	assert.NoError(t, Validate(map[string]string{}))
+}
+
+func TestValidate_UnknownRulePanics(t *testing.T) {
+	type request struct {
+		Name string `json:"name" binding:"required,nosuchrule"`
+	}
+
+	assert.PanicsWithValue(t, `validation: unknown rule "nosuchrule" on name`, func() {
+		Validate(request{Name: "a"})
+	})
+
+	// Rules after a broken one are not reached
+	assert.NotPanics(t, func() {
+		Validate(request{})
+	})
+}
+
+func TestValidate_BadBoundPanics(t *testing.T) {
+	type request struct {
+		Name string `json:"name" binding:"min=three"`
+	}
+
+	assert.Panics(t, func() {
+		Validate(request{})
+	})
+}
+
+func TestRegisterRule(t *testing.T) {
+	RegisterRule("lowercase", func(value reflect.Value, _ string) bool {
+		return value.String() == strings.ToLower(value.String())
+	}, "must be lowercase")
+	RegisterRule("prefixed", func(value reflect.Value, param string) bool {
+		return strings.HasPrefix(value.String(), param)
+	}, "must start with %s")
+
+	type request struct {
+		Name string `json:"name" binding:"lowercase"`
+		Code string `json:"code" binding:"prefixed=X-"`
+	}
+
+	assert.NoError(t, Validate(request{Name: "alice", Code: "X-1"}))
+	errs := fieldErrors(t, request{Name: "Alice", Code: "1"})
+	require.Len(t, errs, 2)
+	assert.Equal(t, FieldError{Field: "name", Rule: "lowercase", Message: "name must be lowercase"}, errs[0])
+	assert.Equal(t, FieldError{Field: "code", Rule: "prefixed", Message: "code must start with X-"}, errs[1])
+}